@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"strings"
 	"sync"
 	"time"
 	"trade-go/config"
+	"trade-go/indicators"
 	"trade-go/llmapi"
 	"trade-go/models"
 )
@@ -280,7 +282,11 @@ func buildPrompt(pd models.PriceData, pos *models.Position, lastSignals []models
 			name = "阳线"
 		}
 		change := (k.Close - k.Open) / k.Open * 100
-		klines.WriteString(fmt.Sprintf("K线%d: %s 开盘:%.2f 收盘:%.2f 涨跌:%+.2f%%\n", i+1, name, k.Open, k.Close, change))
+		closeLabel := "收盘"
+		if pd.Quality.LastOpen && i == len(last5)-1 {
+			closeLabel = "最新(未收盘)"
+		}
+		klines.WriteString(fmt.Sprintf("K线%d: %s 开盘:%.2f %s:%.2f 涨跌:%+.2f%%\n", i+1, name, k.Open, closeLabel, k.Close, change))
 	}
 
 	var lastSigText string
@@ -296,16 +302,20 @@ func buildPrompt(pd models.PriceData, pos *models.Position, lastSignals []models
 		posLoss = fmt.Sprintf("%.2f", pos.UnrealizedPnL)
 	}
 
-	sma5Pct, sma20Pct, sma50Pct := 0.0, 0.0, 0.0
-	if t.SMA5 != 0 {
-		sma5Pct = (pd.Price - t.SMA5) / t.SMA5 * 100
-	}
-	if t.SMA20 != 0 {
-		sma20Pct = (pd.Price - t.SMA20) / t.SMA20 * 100
-	}
-	if t.SMA50 != 0 {
-		sma50Pct = (pd.Price - t.SMA50) / t.SMA50 * 100
+	ip := indicators.NormalizeParams(pd.Indicators)
+	maLine := func(period int, v float64) string {
+		pct := 0.0
+		if v != 0 {
+			pct = (pd.Price - v) / v * 100
+		}
+		return fmt.Sprintf("- %d周期: %.2f | 价格相对: %+.2f%%", period, v, pct)
 	}
+	maText := strings.Join([]string{
+		maLine(ip.SMAFast, t.SMAFast),
+		maLine(ip.SMAShort, t.SMAShort),
+		maLine(ip.SMAMedium, t.SMAMedium),
+		maLine(ip.SMALong, t.SMALong),
+	}, "\n")
 
 	policyPrompt := strings.TrimSpace(os.Getenv("TRADING_AI_POLICY_PROMPT"))
 	if policyPrompt == "" {
@@ -348,19 +358,19 @@ func buildPrompt(pd models.PriceData, pos *models.Position, lastSignals []models
 
 【技术指标】
 移动平均线:
-- 5周期: %.2f | 价格相对: %+.2f%%
-- 20周期: %.2f | 价格相对: %+.2f%%
-- 50周期: %.2f | 价格相对: %+.2f%%
+%s
 
 趋势分析:
-- 短期趋势: %s | 中期趋势: %s | 整体趋势: %s | MACD方向: %s
+- 短期趋势(%d周期): %s | 中期趋势(%d周期): %s | 整体趋势: %s | MACD(%d,%d,%d)方向: %s
 
 动量指标:
-- RSI: %.2f (%s) | MACD: %.4f | 信号线: %.4f
-- 布林带位置: %.2f%% (%s)
+- RSI(%d): %.2f (%s) | MACD: %.4f | 信号线: %.4f
+- 布林带(%d,%.1f)位置: %.2f%% (%s)
 
 关键水平:
-- 静态阻力: %.2f | 静态支撑: %.2f
+- 静态阻力(近%d根): %.2f | 静态支撑: %.2f
+
+%s
 
 %s
 
@@ -392,17 +402,21 @@ func buildPrompt(pd models.PriceData, pos *models.Position, lastSignals []models
 - 最高: $%.2f | 最低: $%.2f | 成交量: %.2f BTC | 变化: %+.2f%%
 - 持仓: %s | 盈亏: %s USDT
 
+【合约数据】
+%s
+
 【输出要求】
 只返回JSON对象，字段必须齐全：
 {"signal":"BUY|SELL|HOLD","reason":"<=80字","stop_loss":数字,"take_profit":数字,"confidence":"HIGH|MEDIUM|LOW","strategy_combo":"策略标识字符串"}
 
 禁止输出markdown、代码块、解释性前后缀。`,
 		symbol, timeframe, klines.String(),
-		t.SMA5, sma5Pct, t.SMA20, sma20Pct, t.SMA50, sma50Pct,
-		tr.ShortTerm, tr.MediumTerm, tr.Overall, tr.MACD,
-		t.RSI, rsiStatus(t.RSI), t.MACD, t.MACDSignal,
-		t.BBPosition*100, bbPosStr(t.BBPosition),
-		lv.StaticResistance, lv.StaticSupport,
+		maText,
+		ip.SMAShort, tr.ShortTerm, ip.SMAMedium, tr.MediumTerm, tr.Overall, ip.EMAFast, ip.EMASlow, ip.MACDSignal, tr.MACD,
+		ip.RSIPeriod, t.RSI, rsiStatus(t.RSI), t.MACD, t.MACDSignal,
+		ip.BBPeriod, ip.BBMultiplier, t.BBPosition*100, bbPosStr(t.BBPosition),
+		ip.SRLookback, lv.StaticResistance, lv.StaticSupport,
+		extendedIndicatorsText(t, pd.Price),
 		lastSigText,
 		cfg.PositionSizingMode, cfg.HighConfidenceAmount, cfg.LowConfidenceAmount,
 		cfg.HighConfidenceMarginPct*100, cfg.LowConfidenceMarginPct*100, cfg.Leverage,
@@ -412,9 +426,105 @@ func buildPrompt(pd models.PriceData, pos *models.Position, lastSignals []models
 		pd.Price, pd.Timestamp.Format("2006-01-02 15:04:05"),
 		pd.High, pd.Low, pd.Volume, pd.PriceChange,
 		posText, posLoss,
+		derivativesText(pd.Derivatives),
 	)
 }
 
+// extendedIndicatorsText 扩展指标，预热不足（值为 0）的项标注为数据不足。
+func extendedIndicatorsText(t models.TechnicalIndicators, price float64) string {
+	na := "数据不足"
+	vs := func(v float64) string {
+		if v <= 0 || price <= 0 {
+			return na
+		}
+		return fmt.Sprintf("%.2f | 价格相对: %+.2f%%", v, (price-v)/v*100)
+	}
+	lines := []string{"扩展指标:"}
+
+	volatility := na
+	if t.ATR > 0 {
+		volatility = fmt.Sprintf("%.4f (占价格 %.2f%%)", t.ATR, t.ATRPct)
+	}
+	lines = append(lines, "- ATR(14): "+volatility)
+
+	trend := na
+	if t.ADX > 0 {
+		strength := "弱趋势/震荡"
+		if t.ADX >= 25 {
+			strength = "趋势明确"
+		}
+		trend = fmt.Sprintf("ADX %.2f (%s) | +DI %.2f | -DI %.2f", t.ADX, strength, t.PlusDI, t.MinusDI)
+	}
+	lines = append(lines, "- 趋向指标(14): "+trend)
+
+	stoch := na
+	if t.StochD > 0 || t.StochK > 0 {
+		stoch = fmt.Sprintf("%%K %.2f | %%D %.2f", t.StochK, t.StochD)
+	}
+	lines = append(lines, "- 随机指标(14,3,3): "+stoch)
+
+	lines = append(lines, "- VWAP(当日UTC): "+vs(t.VWAP))
+	lines = append(lines, "- VWAP(本周UTC): "+vs(t.VWAPWeekly))
+
+	obvTrend := "低于均线"
+	if t.OBV >= t.OBVMA {
+		obvTrend = "高于均线"
+	}
+	if t.OBVMA == 0 && t.OBV == 0 {
+		obvTrend = na
+	}
+	lines = append(lines, "- OBV相对20周期均线: "+obvTrend)
+
+	super := na
+	if t.SuperTrendDir != 0 {
+		dir := "空头"
+		if t.SuperTrendDir > 0 {
+			dir = "多头"
+		}
+		super = fmt.Sprintf("%s | 趋势线: %.2f", dir, t.SuperTrend)
+	}
+	lines = append(lines, "- SuperTrend(10,3): "+super)
+
+	cloud := na
+	if t.IchimokuSenkouA > 0 && t.IchimokuSenkouB > 0 {
+		top, bottom := math.Max(t.IchimokuSenkouA, t.IchimokuSenkouB), math.Min(t.IchimokuSenkouA, t.IchimokuSenkouB)
+		pos := "云中"
+		if price > top {
+			pos = "云上"
+		} else if price < bottom {
+			pos = "云下"
+		}
+		cloud = fmt.Sprintf("转换线 %.2f | 基准线 %.2f | 云层 %.2f-%.2f | 价格位于%s", t.IchimokuTenkan, t.IchimokuKijun, bottom, top, pos)
+	}
+	lines = append(lines, "- 一目均衡表(9,26,52): "+cloud)
+	return strings.Join(lines, "\n")
+}
+
+// derivativesText 资金费率为正时多头付费、空头收费；基差为标记价相对指数价的偏离。
+func derivativesText(m models.MarketStats) string {
+	if m.MarkPrice <= 0 {
+		return "- 不可用"
+	}
+	basis := 0.0
+	if m.IndexPrice > 0 {
+		basis = (m.MarkPrice - m.IndexPrice) / m.IndexPrice * 100
+	}
+	lines := []string{
+		fmt.Sprintf("- 标记价格: $%.2f | 指数价格: $%.2f | 基差: %+.4f%%", m.MarkPrice, m.IndexPrice, basis),
+		fmt.Sprintf("- 资金费率: %+.4f%%", m.FundingRate*100),
+	}
+	if m.NextFundingRate != 0 {
+		lines[1] += fmt.Sprintf(" | 下期预测: %+.4f%%", m.NextFundingRate*100)
+	}
+	if !m.NextFundingTime.IsZero() {
+		lines[1] += " | 下次结算: " + m.NextFundingTime.Format("2006-01-02 15:04:05")
+	}
+	if m.OpenInterest > 0 {
+		lines = append(lines, fmt.Sprintf("- 持仓量: %.2f", m.OpenInterest))
+	}
+	return strings.Join(lines, "\n")
+}
+
 func parseEnabledStrategiesFromEnv() []string {
 	raw := strings.TrimSpace(os.Getenv("AI_EXECUTION_STRATEGIES"))
 	if raw == "" {
@@ -3,10 +3,10 @@ package app
 import (
 	"fmt"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 	"trade-go/config"
+	"trade-go/exchange"
 	"trade-go/market"
 	"trade-go/server"
 	"trade-go/storage"
@@ -19,9 +19,10 @@ const (
 )
 
 type Runner struct {
-	bot    *trader.Bot
-	store  *storage.Store
-	stream *market.Stream
+	bot   *trader.Bot
+	store *storage.Store
+
+	streams *market.Manager
 }
 
 func NewRunner() *Runner {
@@ -96,53 +97,45 @@ func (r *Runner) runWeb() error {
 	if addr == "" {
 		addr = ":8080"
 	}
-	cfg := r.bot.TradeConfig()
 	wsEnabled := os.Getenv("ENABLE_WS_MARKET") == "true"
 	if wsEnabled {
-		r.stream = market.NewStream(cfg.Symbol, cfg.Timeframe)
-		if err := r.stream.Start(); err != nil {
-			fmt.Printf("行情WebSocket启动失败，回退REST: %v\n", err)
-			wsEnabled = false
+		r.streams = market.NewManager(r.bot.ActiveExchangeEnv())
+		r.bot.SetCandleStore(market.NewCandleStore(r.streams))
+		fmt.Printf("%s 行情WebSocket已启用 (%s)\n", r.streams.Exchange(), r.streams.Environment())
+		go r.followActiveExchange()
+		defer r.streams.Stop()
+	}
+	if os.Getenv("ENABLE_WS_USER_DATA") == "true" {
+		if err := r.bot.StartUserStream(); err != nil {
+			fmt.Printf("私有推送WebSocket启动失败，订单/持仓沿用REST轮询: %v\n", err)
 		} else {
-			fmt.Printf("Binance 行情WebSocket已启动: %s %s\n", cfg.Symbol, cfg.Timeframe)
-			defer func() { _ = r.stream.Stop() }()
+			fmt.Printf("私有推送WebSocket已启动: %s (%s)\n", r.bot.ActiveExchange(), exchange.NormalizeEnvironment(config.Config.ExchangeEnv))
+			defer r.bot.StopUserStream()
 		}
 	}
 	svc := server.NewService(r.bot, r.store)
+	if wsEnabled {
+		svc.SetMarketStreams(r.streams)
+	}
 	// Default-off runtime: live scheduler/realtime loop is started manually from UI/API.
 	// This prevents service restart from immediately resuming real trading.
-	svc.SetRealtimeLoopRunning(false)
 	fmt.Println("实盘调度默认关闭，请在前端点击“开始”后执行")
 	fmt.Printf("运行模式: %s\n", ModeWeb)
 	return server.Serve(addr, svc)
 }
 
-func (r *Runner) runRealtimeStrategyLoop(svc *server.Service) {
-	defer svc.SetRealtimeLoopRunning(false)
-	minIntervalSec := 10
-	if raw := strings.TrimSpace(os.Getenv("REALTIME_MIN_INTERVAL_SEC")); raw != "" {
-		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
-			minIntervalSec = n
-		}
-	}
-	fmt.Printf("策略触发模式: WebSocket事件驱动（最小执行间隔 %d 秒）\n", minIntervalSec)
-	var lastCloseTime int64
-	var lastSeen time.Time
-	lastRun := time.Time{}
+// followActiveExchange 切换交易所集成或环境后重建行情连接，避免用 A 所（或主网）K 线驱动 B 所（或测试网）交易。
+// 交易所与环境取自交易客户端，与 ReloadClients 在同一把锁下读取。
+func (r *Runner) followActiveExchange() {
 	for {
-		snap := r.stream.Snapshot()
-		if snap.KlineClosed && snap.KlineCloseTime > 0 && snap.KlineCloseTime != lastCloseTime {
-			lastCloseTime = snap.KlineCloseTime
-			svc.RunOnce()
-			lastRun = time.Now()
-			lastSeen = snap.UpdatedAt
-		} else if !snap.UpdatedAt.IsZero() && snap.UpdatedAt.After(lastSeen) &&
-			(lastRun.IsZero() || time.Since(lastRun) >= time.Duration(minIntervalSec)*time.Second) {
-			svc.RunOnce()
-			lastRun = time.Now()
-			lastSeen = snap.UpdatedAt
+		time.Sleep(10 * time.Second)
+		name, env := r.bot.ActiveExchangeEnv()
+		active, activeEnv := market.VenueName(name), exchange.NormalizeEnvironment(env)
+		from, fromEnv := r.streams.Exchange(), r.streams.Environment()
+		if active != from || activeEnv != fromEnv {
+			r.streams.Reconfigure(active, activeEnv)
+			fmt.Printf("行情WebSocket已切换: %s (%s) → %s (%s)\n", from, fromEnv, active, activeEnv)
 		}
-		time.Sleep(500 * time.Millisecond)
 	}
 }
 
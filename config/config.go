@@ -17,6 +17,7 @@ type TradeConfig struct {
 	HighConfidenceMarginPct          float64
 	LowConfidenceMarginPct           float64
 	Leverage                         int
+	MarginMode                       string // cross / isolated
 	Timeframe                        string
 	TestMode                         bool
 	DataPoints                       int
@@ -38,11 +39,25 @@ type TradeConfig struct {
 	AutoStrategyRegenLossStreak      int
 	AutoStrategyRegenDrawdownWarnPct float64
 	AutoStrategyRegenMinRR           float64
+	EntryOrderMode                   string
+	MakerEntryTimeoutSec             int
+	MakerEntryOffsetBps              float64
+	MaxSlippageBps                   float64 // 0 表示不做盘口滑点校验
+	SlippageAction                   string  // downsize / block
 
-	// Analysis periods
-	ShortTermPeriod  int
-	MediumTermPeriod int
-	LongTermPeriod   int
+	// Analysis periods（全局指标参数，可被习惯档位与生成策略覆盖）
+	ShortTermPeriod  int // 短期趋势均线
+	MediumTermPeriod int // 中期趋势均线
+	LongTermPeriod   int // 长期均线
+	SMAFastPeriod    int
+	EMAFastPeriod    int
+	EMASlowPeriod    int
+	MACDSignalPeriod int
+	RSIPeriod        int
+	BBPeriod         int
+	BBMultiplier     float64
+	VolumeMAPeriod   int
+	SRLookback       int // 静态支撑阻力回看 K 线数
 }
 
 type AppConfig struct {
@@ -50,12 +65,22 @@ type AppConfig struct {
 	AIBaseURL      string
 	AIModel        string
 	ActiveExchange string
+	ExchangeEnv    string // mainnet / testnet / demo
 	BinanceAPIKey  string
 	BinanceSecret  string
 	OKXAPIKey      string
 	OKXSecret      string
 	OKXPassword    string
-	Trade          TradeConfig
+	BybitAPIKey    string
+	BybitSecret    string
+	BybitBaseURL   string
+	// ACTIVE_EXCHANGE=sim 时的模拟交易所参数
+	SimInitialBalance float64
+	SimTakerFeeRate   float64
+	SimMakerFeeRate   float64
+	SimSlippageBps    float64
+	SimMarketSource   string // synthetic（默认，离线合成）/ binance
+	Trade             TradeConfig
 }
 
 var Config *AppConfig
@@ -66,15 +91,24 @@ func Load() {
 	}
 
 	Config = &AppConfig{
-		AIAPIKey:       getEnv("AI_API_KEY", ""),
-		AIBaseURL:      getEnv("AI_BASE_URL", ""),
-		AIModel:        getEnv("AI_MODEL", ""),
-		ActiveExchange: getEnv("ACTIVE_EXCHANGE", "binance"),
-		BinanceAPIKey:  getEnv("BINANCE_API_KEY", ""),
-		BinanceSecret:  getEnv("BINANCE_SECRET", ""),
-		OKXAPIKey:      getEnv("OKX_API_KEY", ""),
-		OKXSecret:      getEnv("OKX_SECRET", ""),
-		OKXPassword:    getEnv("OKX_PASSWORD", ""),
+		AIAPIKey:          getEnv("AI_API_KEY", ""),
+		AIBaseURL:         getEnv("AI_BASE_URL", ""),
+		AIModel:           getEnv("AI_MODEL", ""),
+		ActiveExchange:    getEnv("ACTIVE_EXCHANGE", "binance"),
+		ExchangeEnv:       getEnv("EXCHANGE_ENV", "mainnet"),
+		BinanceAPIKey:     getEnv("BINANCE_API_KEY", ""),
+		BinanceSecret:     getEnv("BINANCE_SECRET", ""),
+		OKXAPIKey:         getEnv("OKX_API_KEY", ""),
+		OKXSecret:         getEnv("OKX_SECRET", ""),
+		OKXPassword:       getEnv("OKX_PASSWORD", ""),
+		BybitAPIKey:       getEnv("BYBIT_API_KEY", ""),
+		BybitSecret:       getEnv("BYBIT_SECRET", ""),
+		BybitBaseURL:      getEnv("BYBIT_BASE_URL", ""),
+		SimInitialBalance: getEnvFloat("SIM_INITIAL_BALANCE", 10000),
+		SimTakerFeeRate:   getEnvFloat("SIM_TAKER_FEE_RATE", 0.0005),
+		SimMakerFeeRate:   getEnvFloat("SIM_MAKER_FEE_RATE", 0.0002),
+		SimSlippageBps:    getEnvFloat("SIM_SLIPPAGE_BPS", 1),
+		SimMarketSource:   getEnv("SIM_MARKET_SOURCE", "synthetic"),
 		Trade: TradeConfig{
 			Symbol:                           getEnv("TRADE_SYMBOL", "BTCUSDT"),
 			Amount:                           getEnvFloat("TRADE_AMOUNT", 0.01),
@@ -84,6 +118,7 @@ func Load() {
 			HighConfidenceMarginPct:          getEnvFloat("HIGH_CONFIDENCE_MARGIN_PCT", 0.05),
 			LowConfidenceMarginPct:           getEnvFloat("LOW_CONFIDENCE_MARGIN_PCT", 0.00),
 			Leverage:                         getEnvInt("LEVERAGE", 20),
+			MarginMode:                       getEnv("MARGIN_MODE", "cross"),
 			Timeframe:                        getEnv("TIMEFRAME", "15m"),
 			TestMode:                         getEnvBool("TEST_MODE", false),
 			DataPoints:                       getEnvInt("DATA_POINTS", 96),
@@ -105,9 +140,23 @@ func Load() {
 			AutoStrategyRegenLossStreak:      getEnvInt("AUTO_STRATEGY_REGEN_LOSS_STREAK", 3),
 			AutoStrategyRegenDrawdownWarnPct: getEnvFloat("AUTO_STRATEGY_REGEN_DRAWDOWN_WARN_PCT", 0.08),
 			AutoStrategyRegenMinRR:           getEnvFloat("AUTO_STRATEGY_REGEN_MIN_RR", 2.0),
+			EntryOrderMode:                   getEnv("ENTRY_ORDER_MODE", "market"),
+			MakerEntryTimeoutSec:             getEnvInt("MAKER_ENTRY_TIMEOUT_SEC", 30),
+			MakerEntryOffsetBps:              getEnvFloat("MAKER_ENTRY_OFFSET_BPS", 2),
+			MaxSlippageBps:                   getEnvFloat("MAX_SLIPPAGE_BPS", 20),
+			SlippageAction:                   getEnv("SLIPPAGE_ACTION", "downsize"),
 			ShortTermPeriod:                  getEnvInt("SHORT_TERM_PERIOD", 20),
 			MediumTermPeriod:                 getEnvInt("MEDIUM_TERM_PERIOD", 50),
 			LongTermPeriod:                   getEnvInt("LONG_TERM_PERIOD", 96),
+			SMAFastPeriod:                    getEnvInt("SMA_FAST_PERIOD", 5),
+			EMAFastPeriod:                    getEnvInt("EMA_FAST_PERIOD", 12),
+			EMASlowPeriod:                    getEnvInt("EMA_SLOW_PERIOD", 26),
+			MACDSignalPeriod:                 getEnvInt("MACD_SIGNAL_PERIOD", 9),
+			RSIPeriod:                        getEnvInt("RSI_PERIOD", 14),
+			BBPeriod:                         getEnvInt("BB_PERIOD", 20),
+			BBMultiplier:                     getEnvFloat("BB_MULTIPLIER", 2),
+			VolumeMAPeriod:                   getEnvInt("VOLUME_MA_PERIOD", 20),
+			SRLookback:                       getEnvInt("SR_LOOKBACK", 20),
 		},
 	}
 }
@@ -6,7 +6,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"io"
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -14,28 +14,44 @@ import (
 	"time"
 	"trade-go/config"
 	"trade-go/models"
-)
 
-const binanceBaseURL = "https://fapi.binance.com"
+	"github.com/gorilla/websocket"
+)
 
 type binanceClient struct {
-	apiKey     string
-	secret     string
-	httpClient *http.Client
+	env         string
+	baseURL     string
+	apiKey      string
+	secret      string
+	httpClient  *http.Client
+	instruments *instrumentCatalog
+	limiter     *rateLimiter
+	clock       *serverClock
+	posMode     *positionModeCache
 }
 
 func newBinanceClient(cfg *config.AppConfig) *binanceClient {
 	key := ""
 	secret := ""
+	env := EnvMainnet
 	if cfg != nil {
 		key = strings.TrimSpace(cfg.BinanceAPIKey)
 		secret = strings.TrimSpace(cfg.BinanceSecret)
+		env = cfg.ExchangeEnv
 	}
-	return &binanceClient{
+	ep := ResolveEndpoints("binance", env)
+	c := &binanceClient{
+		env:        ep.Env,
+		baseURL:    ep.REST,
 		apiKey:     key,
 		secret:     secret,
 		httpClient: &http.Client{Timeout: 15 * time.Second},
+		limiter:    sharedRateLimiter("binance", ep.Env, key),
+		clock:      sharedServerClock(scopedName("binance", ep.Env)),
 	}
+	c.instruments = newInstrumentCatalog(c.loadInstruments)
+	c.posMode = newPositionModeCache(c.loadPositionMode)
+	return c
 }
 
 func (c *binanceClient) signQuery(raw string) string {
@@ -45,72 +61,181 @@ func (c *binanceClient) signQuery(raw string) string {
 }
 
 func (c *binanceClient) requestPublic(path string, values url.Values) ([]byte, error) {
-	fullURL := binanceBaseURL + path
+	fullURL := c.baseURL + path
 	if len(values) > 0 {
 		fullURL += "?" + values.Encode()
 	}
-	req, err := http.NewRequest(http.MethodGet, fullURL, nil)
-	if err != nil {
-		return nil, err
-	}
-	resp, err := c.httpClient.Do(req)
+	status, body, err := c.limiter.do(c.httpClient, http.MethodGet, binanceRequestWeight(path, values), func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, fullURL, nil)
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("binance http %d: %s", resp.StatusCode, string(body))
+	if status >= 300 {
+		return nil, fmt.Errorf("binance http %d: %s", status, string(body))
 	}
 	return body, nil
 }
 
+// requestSigned 时间戳被拒（-1021）时请求未被处理，重新同步服务器时间后重试一次。
 func (c *binanceClient) requestSigned(method, path string, values url.Values) ([]byte, error) {
+	body, err := c.requestSignedOnce(method, path, values)
+	if isTimestampError(err) {
+		c.clock.invalidate()
+		body, err = c.requestSignedOnce(method, path, values)
+	}
+	return body, err
+}
+
+func (c *binanceClient) requestSignedOnce(method, path string, values url.Values) ([]byte, error) {
 	if values == nil {
 		values = url.Values{}
 	}
-	values.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
-	values.Set("recvWindow", "5000")
-	raw := values.Encode()
-	sig := c.signQuery(raw)
-	fullURL := binanceBaseURL + path + "?" + raw + "&signature=" + sig
-
-	req, err := http.NewRequest(method, fullURL, nil)
+	weight := binanceRequestWeight(path, values)
+	status, body, err := c.limiter.do(c.httpClient, method, weight, func() (*http.Request, error) {
+		// 每次重试重新生成时间戳与签名
+		values.Set("timestamp", strconv.FormatInt(c.clock.now(c.fetchServerTime).UnixMilli(), 10))
+		values.Set("recvWindow", "5000")
+		raw := values.Encode()
+		fullURL := c.baseURL + path + "?" + raw + "&signature=" + c.signQuery(raw)
+		req, err := http.NewRequest(method, fullURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-MBX-APIKEY", c.apiKey)
+		return req, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("X-MBX-APIKEY", c.apiKey)
+	if status >= 300 {
+		return nil, fmt.Errorf("binance http %d: %s", status, string(body))
+	}
+	return body, nil
+}
 
-	resp, err := c.httpClient.Do(req)
+func (c *binanceClient) fetchServerTime() (time.Time, error) {
+	data, err := c.requestPublic("/fapi/v1/time", nil)
 	if err != nil {
-		return nil, err
+		return time.Time{}, err
 	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("binance http %d: %s", resp.StatusCode, string(body))
+	var resp struct {
+		ServerTime int64 `json:"serverTime"`
 	}
-	return body, nil
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return time.Time{}, err
+	}
+	if resp.ServerTime <= 0 {
+		return time.Time{}, fmt.Errorf("binance server time invalid: %s", string(data))
+	}
+	return time.UnixMilli(resp.ServerTime), nil
 }
 
-func (c *binanceClient) FetchOHLCV(symbol, timeframe string, limit int) ([]models.OHLCV, error) {
+// binanceRequestWeight 常用接口的请求权重（参考官方文档），未列出的按 1 计。
+func binanceRequestWeight(path string, values url.Values) int {
+	switch path {
+	case "/fapi/v1/klines":
+		limit, _ := strconv.Atoi(values.Get("limit"))
+		switch {
+		case limit > 1000:
+			return 10
+		case limit > 500:
+			return 5
+		case limit >= 100:
+			return 2
+		default:
+			return 1
+		}
+	case "/fapi/v2/account", "/fapi/v2/positionRisk":
+		return 5
+	case "/fapi/v1/openOrders":
+		if values.Get("symbol") == "" {
+			return 40
+		}
+		return 1
+	case "/fapi/v1/exchangeInfo":
+		return 1
+	case "/fapi/v1/positionSide/dual":
+		return 30
+	case "/fapi/v1/income":
+		return 30
+	case "/fapi/v1/userTrades":
+		return 5
+	case "/fapi/v1/depth":
+		limit, _ := strconv.Atoi(values.Get("limit"))
+		switch {
+		case limit >= 1000:
+			return 20
+		case limit >= 500:
+			return 10
+		case limit >= 100:
+			return 5
+		default:
+			return 2
+		}
+	default:
+		return 1
+	}
+}
+
+func toBinanceInterval(timeframe string) string {
 	barMap := map[string]string{
 		"1m": "1m", "5m": "5m", "15m": "15m",
 		"30m": "30m", "1h": "1h", "4h": "4h", "1d": "1d",
 	}
-	interval, ok := barMap[timeframe]
-	if !ok {
-		interval = "15m"
+	if v, ok := barMap[timeframe]; ok {
+		return v
 	}
+	return "15m"
+}
+
+func (c *binanceClient) FetchOHLCV(symbol, timeframe string, limit int) ([]models.OHLCV, error) {
 	vals := url.Values{}
 	vals.Set("symbol", normalizeSymbol(symbol))
-	vals.Set("interval", interval)
+	vals.Set("interval", toBinanceInterval(timeframe))
 	vals.Set("limit", strconv.Itoa(limit))
 	data, err := c.requestPublic("/fapi/v1/klines", vals)
 	if err != nil {
 		return nil, err
 	}
+	return parseBinanceKlines(data)
+}
 
+// FetchOHLCVRange 按 startTime 游标分页，每页 1000 根。
+func (c *binanceClient) FetchOHLCVRange(symbol, timeframe string, start, end time.Time) ([]models.OHLCV, error) {
+	const pageSize = 1000
+	out := make([]models.OHLCV, 0, pageSize)
+	cursor := start.UnixMilli()
+	endMs := end.UnixMilli()
+	for cursor <= endMs {
+		vals := url.Values{}
+		vals.Set("symbol", normalizeSymbol(symbol))
+		vals.Set("interval", toBinanceInterval(timeframe))
+		vals.Set("startTime", strconv.FormatInt(cursor, 10))
+		vals.Set("endTime", strconv.FormatInt(endMs, 10))
+		vals.Set("limit", strconv.Itoa(pageSize))
+		data, err := c.requestPublic("/fapi/v1/klines", vals)
+		if err != nil {
+			return out, err
+		}
+		page, err := parseBinanceKlines(data)
+		if err != nil {
+			return out, err
+		}
+		if len(page) == 0 {
+			break
+		}
+		out = append(out, page...)
+		next := page[len(page)-1].Timestamp.UnixMilli() + 1
+		if next <= cursor || len(page) < pageSize {
+			break
+		}
+		cursor = next
+	}
+	return out, nil
+}
+
+func parseBinanceKlines(data []byte) ([]models.OHLCV, error) {
 	var rows [][]interface{}
 	if err := json.Unmarshal(data, &rows); err != nil {
 		return nil, err
@@ -138,6 +263,83 @@ func (c *binanceClient) FetchOHLCV(symbol, timeframe string, limit int) ([]model
 	return candles, nil
 }
 
+// FetchMarketStats premiumIndex 的 lastFundingRate 即本期将在 nextFundingTime 结算的费率；Binance 不提供下一期预测。
+func (c *binanceClient) FetchMarketStats(symbol string) (models.MarketStats, error) {
+	vals := url.Values{}
+	vals.Set("symbol", normalizeSymbol(symbol))
+	data, err := c.requestPublic("/fapi/v1/premiumIndex", vals)
+	if err != nil {
+		return models.MarketStats{}, err
+	}
+	var premium struct {
+		Symbol          string `json:"symbol"`
+		MarkPrice       string `json:"markPrice"`
+		IndexPrice      string `json:"indexPrice"`
+		LastFundingRate string `json:"lastFundingRate"`
+		NextFundingTime int64  `json:"nextFundingTime"`
+		Time            int64  `json:"time"`
+	}
+	if err := json.Unmarshal(data, &premium); err != nil {
+		return models.MarketStats{}, err
+	}
+	out := models.MarketStats{
+		Symbol:      normalizeSymbol(symbol),
+		MarkPrice:   toFloat(premium.MarkPrice),
+		IndexPrice:  toFloat(premium.IndexPrice),
+		FundingRate: toFloat(premium.LastFundingRate),
+		UpdatedAt:   time.UnixMilli(premium.Time),
+	}
+	if premium.NextFundingTime > 0 {
+		out.NextFundingTime = time.UnixMilli(premium.NextFundingTime)
+	}
+	data, err = c.requestPublic("/fapi/v1/openInterest", vals)
+	if err != nil {
+		return out, err
+	}
+	var oi struct {
+		OpenInterest string `json:"openInterest"`
+	}
+	if err := json.Unmarshal(data, &oi); err != nil {
+		return out, err
+	}
+	out.OpenInterest = toFloat(oi.OpenInterest)
+	return out, nil
+}
+
+// binanceDepthLimits depth 接口仅接受固定档位数。
+var binanceDepthLimits = []int{5, 10, 20, 50, 100, 500, 1000}
+
+func (c *binanceClient) FetchOrderBook(symbol string, depth int) (models.OrderBook, error) {
+	limit := binanceDepthLimits[len(binanceDepthLimits)-1]
+	for _, n := range binanceDepthLimits {
+		if depth <= n {
+			limit = n
+			break
+		}
+	}
+	vals := url.Values{}
+	vals.Set("symbol", normalizeSymbol(symbol))
+	vals.Set("limit", strconv.Itoa(limit))
+	data, err := c.requestPublic("/fapi/v1/depth", vals)
+	if err != nil {
+		return models.OrderBook{}, err
+	}
+	var resp struct {
+		T    int64      `json:"T"`
+		Bids [][]string `json:"bids"`
+		Asks [][]string `json:"asks"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return models.OrderBook{}, err
+	}
+	return models.OrderBook{
+		Symbol:    normalizeSymbol(symbol),
+		Bids:      parseBookLevels(resp.Bids, depth, 1),
+		Asks:      parseBookLevels(resp.Asks, depth, 1),
+		Timestamp: time.UnixMilli(resp.T),
+	}, nil
+}
+
 func (c *binanceClient) FetchBalance() (float64, error) {
 	data, err := c.requestSigned(http.MethodGet, "/fapi/v2/account", nil)
 	if err != nil {
@@ -214,6 +416,44 @@ func (c *binanceClient) FetchAvailableBalance() (float64, error) {
 	return 0, nil
 }
 
+// FetchBalances 多资产模式下 BTC/BNB 等抵押资产也会出现在 assets 中；接口不返回折算价值，仅对美元稳定币填写 USDValue。
+func (c *binanceClient) FetchBalances() ([]models.AssetBalance, error) {
+	data, err := c.requestSigned(http.MethodGet, "/fapi/v2/account", nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Assets []struct {
+			Asset            string `json:"asset"`
+			WalletBalance    string `json:"walletBalance"`
+			UnrealizedProfit string `json:"unrealizedProfit"`
+			MarginBalance    string `json:"marginBalance"`
+			AvailableBalance string `json:"availableBalance"`
+		} `json:"assets"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	out := []models.AssetBalance{}
+	for _, a := range resp.Assets {
+		b := models.AssetBalance{
+			Asset:            strings.ToUpper(strings.TrimSpace(a.Asset)),
+			WalletBalance:    toFloat(a.WalletBalance),
+			AvailableBalance: toFloat(a.AvailableBalance),
+			MarginBalance:    toFloat(a.MarginBalance),
+			UnrealizedPnL:    toFloat(a.UnrealizedProfit),
+		}
+		if b.WalletBalance == 0 && b.MarginBalance == 0 {
+			continue
+		}
+		if isUSDStable(b.Asset) {
+			b.USDValue = b.MarginBalance
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
 func (c *binanceClient) SetLeverage(symbol string, leverage int) error {
 	vals := url.Values{}
 	vals.Set("symbol", normalizeSymbol(symbol))
@@ -222,9 +462,27 @@ func (c *binanceClient) SetLeverage(symbol string, leverage int) error {
 	return err
 }
 
-func (c *binanceClient) FetchPosition(symbol string) (*models.Position, error) {
+// SetMarginMode -4046 表示已是目标模式；有持仓或挂单时交易所拒绝切换（-4047/-4048），原样返回错误。
+func (c *binanceClient) SetMarginMode(symbol, mode string) error {
+	marginType := "CROSSED"
+	if mode == models.MarginModeIsolated {
+		marginType = "ISOLATED"
+	}
 	vals := url.Values{}
 	vals.Set("symbol", normalizeSymbol(symbol))
+	vals.Set("marginType", marginType)
+	_, err := c.requestSigned(http.MethodPost, "/fapi/v1/marginType", vals)
+	if err != nil && strings.Contains(err.Error(), `"code":-4046`) {
+		return nil
+	}
+	return err
+}
+
+func (c *binanceClient) FetchPositions(symbol string) ([]models.Position, error) {
+	vals := url.Values{}
+	if strings.TrimSpace(symbol) != "" {
+		vals.Set("symbol", normalizeSymbol(symbol))
+	}
 	data, err := c.requestSigned(http.MethodGet, "/fapi/v2/positionRisk", vals)
 	if err != nil {
 		return nil, err
@@ -232,6 +490,8 @@ func (c *binanceClient) FetchPosition(symbol string) (*models.Position, error) {
 	var rows []struct {
 		Symbol           string `json:"symbol"`
 		PositionAmt      string `json:"positionAmt"`
+		PositionSide     string `json:"positionSide"`
+		MarginType       string `json:"marginType"`
 		EntryPrice       string `json:"entryPrice"`
 		UnRealizedProfit string `json:"unRealizedProfit"`
 		Leverage         string `json:"leverage"`
@@ -239,30 +499,69 @@ func (c *binanceClient) FetchPosition(symbol string) (*models.Position, error) {
 	if err := json.Unmarshal(data, &rows); err != nil {
 		return nil, err
 	}
-	if len(rows) == 0 {
-		return nil, nil
+	out := []models.Position{}
+	for _, row := range rows {
+		sz, _ := strconv.ParseFloat(row.PositionAmt, 64)
+		if sz == 0 {
+			continue
+		}
+		entry, _ := strconv.ParseFloat(row.EntryPrice, 64)
+		upl, _ := strconv.ParseFloat(row.UnRealizedProfit, 64)
+		lev, _ := strconv.ParseFloat(row.Leverage, 64)
+		// 双向模式下 positionSide 为 LONG/SHORT（空头数量为负），单向模式为 BOTH，按数量符号判断
+		side := strings.ToLower(strings.TrimSpace(row.PositionSide))
+		if side != "long" && side != "short" {
+			side = "long"
+			if sz < 0 {
+				side = "short"
+			}
+		}
+		out = append(out, models.Position{
+			Side:          side,
+			Size:          math.Abs(sz),
+			EntryPrice:    entry,
+			UnrealizedPnL: upl,
+			Leverage:      lev,
+			Symbol:        row.Symbol,
+			MarginMode:    models.NormalizeMarginMode(row.MarginType),
+		})
 	}
-	row := rows[0]
-	sz, _ := strconv.ParseFloat(row.PositionAmt, 64)
-	if sz == 0 {
-		return nil, nil
+	return out, nil
+}
+
+func (c *binanceClient) FetchPositionMode(symbol string) (string, error) {
+	return c.posMode.get(symbol)
+}
+
+// loadPositionMode Binance 持仓模式为账户级设置，与交易对无关。
+func (c *binanceClient) loadPositionMode(string) (string, error) {
+	data, err := c.requestSigned(http.MethodGet, "/fapi/v1/positionSide/dual", nil)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		DualSidePosition bool `json:"dualSidePosition"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", err
+	}
+	if resp.DualSidePosition {
+		return models.PositionModeHedge, nil
+	}
+	return models.PositionModeOneWay, nil
+}
+
+// setPositionSide 双向持仓模式下按订单方向指定 LONG/SHORT 腿；
+// 该模式不接受 reduceOnly 参数，平仓语义由 positionSide 与买卖方向共同决定。
+func (c *binanceClient) setPositionSide(vals url.Values, symbol, side string, reduceOnly bool) {
+	if c.posMode.hedge(symbol) {
+		vals.Set("positionSide", strings.ToUpper(positionLegForOrder(side, reduceOnly)))
+		return
+	}
+	vals.Set("positionSide", "BOTH")
+	if reduceOnly {
+		vals.Set("reduceOnly", "true")
 	}
-	entry, _ := strconv.ParseFloat(row.EntryPrice, 64)
-	upl, _ := strconv.ParseFloat(row.UnRealizedProfit, 64)
-	lev, _ := strconv.ParseFloat(row.Leverage, 64)
-	side := "long"
-	if sz < 0 {
-		side = "short"
-		sz = -sz
-	}
-	return &models.Position{
-		Side:          side,
-		Size:          sz,
-		EntryPrice:    entry,
-		UnrealizedPnL: upl,
-		Leverage:      lev,
-		Symbol:        row.Symbol,
-	}, nil
 }
 
 func (c *binanceClient) PlaceMarketOrder(symbol, side string, size float64, reduceOnly bool) error {
@@ -271,15 +570,13 @@ func (c *binanceClient) PlaceMarketOrder(symbol, side string, size float64, redu
 }
 
 func (c *binanceClient) PlaceMarketOrderWithResult(symbol, side string, size float64, reduceOnly bool) (models.OrderResult, error) {
+	size = c.instruments.roundSize(symbol, size)
 	vals := url.Values{}
 	vals.Set("symbol", normalizeSymbol(symbol))
 	vals.Set("side", strings.ToUpper(side))
 	vals.Set("type", "MARKET")
-	vals.Set("positionSide", "BOTH")
-	vals.Set("quantity", fmt.Sprintf("%.4f", size))
-	if reduceOnly {
-		vals.Set("reduceOnly", "true")
-	}
+	vals.Set("quantity", formatSize(size))
+	c.setPositionSide(vals, symbol, strings.ToLower(side), reduceOnly)
 	data, err := c.requestSigned(http.MethodPost, "/fapi/v1/order", vals)
 	if err != nil {
 		return models.OrderResult{}, err
@@ -303,6 +600,7 @@ func (c *binanceClient) PlaceMarketOrderWithResult(symbol, side string, size flo
 		State:      mapOrderState(resp.Status),
 		Symbol:     resp.Symbol,
 		Side:       strings.ToLower(resp.Side),
+		Type:       models.OrderTypeMarket,
 		Size:       size,
 		ReduceOnly: reduceOnly,
 	}, nil
@@ -316,32 +614,663 @@ func (c *binanceClient) FetchOrder(symbol, orderID string) (*models.OrderStatus,
 	if err != nil {
 		return nil, err
 	}
-	var row struct {
-		OrderID     int64  `json:"orderId"`
-		Symbol      string `json:"symbol"`
-		Status      string `json:"status"`
-		ExecutedQty string `json:"executedQty"`
-		AvgPrice    string `json:"avgPrice"`
-		Side        string `json:"side"`
-		ReduceOnly  bool   `json:"reduceOnly"`
-		UpdateTime  int64  `json:"updateTime"`
-	}
+	var row binanceOrderRow
 	if err := json.Unmarshal(data, &row); err != nil {
 		return nil, err
 	}
 	if row.OrderID == 0 {
 		return nil, nil
 	}
+	st := row.toStatus()
+	return &st, nil
+}
+
+type binanceOrderRow struct {
+	OrderID     int64  `json:"orderId"`
+	Symbol      string `json:"symbol"`
+	Status      string `json:"status"`
+	Type        string `json:"type"`
+	OrigQty     string `json:"origQty"`
+	Price       string `json:"price"`
+	ExecutedQty string `json:"executedQty"`
+	AvgPrice    string `json:"avgPrice"`
+	Side        string `json:"side"`
+	ReduceOnly  bool   `json:"reduceOnly"`
+	UpdateTime  int64  `json:"updateTime"`
+	Time        int64  `json:"time"`
+}
+
+func (row binanceOrderRow) toStatus() models.OrderStatus {
 	filled, _ := strconv.ParseFloat(row.ExecutedQty, 64)
 	avg, _ := strconv.ParseFloat(row.AvgPrice, 64)
-	return &models.OrderStatus{
+	size, _ := strconv.ParseFloat(row.OrigQty, 64)
+	price, _ := strconv.ParseFloat(row.Price, 64)
+	created := ""
+	if row.Time > 0 {
+		created = strconv.FormatInt(row.Time, 10)
+	}
+	return models.OrderStatus{
 		OrderID:    strconv.FormatInt(row.OrderID, 10),
 		State:      mapOrderState(row.Status),
+		Type:       fromBinanceOrderType(row.Type),
+		Size:       size,
+		Price:      price,
 		FilledSize: filled,
 		AvgPrice:   avg,
 		Symbol:     row.Symbol,
 		Side:       strings.ToLower(row.Side),
 		ReduceOnly: row.ReduceOnly,
 		UpdateTime: strconv.FormatInt(row.UpdateTime, 10),
+		CreateTime: created,
+	}
+}
+
+func fromBinanceOrderType(t string) string {
+	switch strings.ToUpper(strings.TrimSpace(t)) {
+	case "MARKET":
+		return models.OrderTypeMarket
+	case "LIMIT":
+		return models.OrderTypeLimit
+	case "STOP_MARKET", "STOP":
+		return models.OrderTypeStopMarket
+	case "TAKE_PROFIT_MARKET", "TAKE_PROFIT":
+		return models.OrderTypeTakeProfitMarket
+	default:
+		return strings.ToLower(strings.TrimSpace(t))
+	}
+}
+
+func (c *binanceClient) PlaceLimitOrder(symbol, side string, size, price float64, timeInForce string, reduceOnly bool) (models.OrderResult, error) {
+	side = strings.ToLower(strings.TrimSpace(side))
+	if side != "buy" && side != "sell" {
+		return models.OrderResult{}, fmt.Errorf("invalid side: %s", side)
+	}
+	if size <= 0 || price <= 0 {
+		return models.OrderResult{}, fmt.Errorf("invalid size/price: %.8f/%.8f", size, price)
+	}
+	size = c.instruments.roundSize(symbol, size)
+	price = c.instruments.roundPrice(symbol, price)
+	tif := "GTC"
+	switch timeInForce {
+	case models.TimeInForcePostOnly:
+		tif = "GTX"
+	case models.TimeInForceIOC:
+		tif = "IOC"
+	case models.TimeInForceGTC, "":
+	default:
+		return models.OrderResult{}, fmt.Errorf("unsupported time in force: %s", timeInForce)
+	}
+	vals := url.Values{}
+	vals.Set("symbol", normalizeSymbol(symbol))
+	vals.Set("side", strings.ToUpper(side))
+	vals.Set("type", "LIMIT")
+	vals.Set("timeInForce", tif)
+	vals.Set("quantity", formatSize(size))
+	vals.Set("price", formatSize(price))
+	c.setPositionSide(vals, symbol, side, reduceOnly)
+	data, err := c.requestSigned(http.MethodPost, "/fapi/v1/order", vals)
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	var row binanceOrderRow
+	if err := json.Unmarshal(data, &row); err != nil {
+		return models.OrderResult{}, err
+	}
+	if row.OrderID == 0 {
+		return models.OrderResult{}, fmt.Errorf("限价单下单失败: %s", string(data))
+	}
+	return models.OrderResult{
+		OrderID:     strconv.FormatInt(row.OrderID, 10),
+		State:       mapOrderState(row.Status),
+		Symbol:      row.Symbol,
+		Side:        side,
+		Type:        models.OrderTypeLimit,
+		Size:        size,
+		Price:       price,
+		TimeInForce: timeInForce,
+		ReduceOnly:  reduceOnly,
 	}, nil
 }
+
+func (c *binanceClient) CancelOrder(symbol, orderID string) error {
+	vals := url.Values{}
+	vals.Set("symbol", normalizeSymbol(symbol))
+	vals.Set("orderId", strings.TrimSpace(orderID))
+	_, err := c.requestSigned(http.MethodDelete, "/fapi/v1/order", vals)
+	return err
+}
+
+// AmendOrder 通过 PUT /fapi/v1/order 修改限价单；newSize/newPrice <= 0 时沿用原值。
+func (c *binanceClient) AmendOrder(symbol, orderID string, newSize, newPrice float64) (models.OrderResult, error) {
+	cur, err := c.FetchOrder(symbol, orderID)
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	if cur == nil {
+		return models.OrderResult{}, fmt.Errorf("订单不存在: %s", orderID)
+	}
+	if newSize <= 0 {
+		newSize = cur.Size
+	}
+	if newPrice <= 0 {
+		newPrice = cur.Price
+	}
+	newSize = c.instruments.roundSize(symbol, newSize)
+	newPrice = c.instruments.roundPrice(symbol, newPrice)
+	vals := url.Values{}
+	vals.Set("symbol", normalizeSymbol(symbol))
+	vals.Set("orderId", strings.TrimSpace(orderID))
+	vals.Set("side", strings.ToUpper(cur.Side))
+	vals.Set("quantity", formatSize(newSize))
+	vals.Set("price", formatSize(newPrice))
+	data, err := c.requestSigned(http.MethodPut, "/fapi/v1/order", vals)
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	var row binanceOrderRow
+	if err := json.Unmarshal(data, &row); err != nil {
+		return models.OrderResult{}, err
+	}
+	return models.OrderResult{
+		OrderID:    strconv.FormatInt(row.OrderID, 10),
+		State:      mapOrderState(row.Status),
+		Symbol:     row.Symbol,
+		Side:       strings.ToLower(row.Side),
+		Type:       models.OrderTypeLimit,
+		Size:       newSize,
+		Price:      newPrice,
+		ReduceOnly: row.ReduceOnly,
+	}, nil
+}
+
+func (c *binanceClient) FetchOpenOrders(symbol string) ([]models.OrderStatus, error) {
+	vals := url.Values{}
+	vals.Set("symbol", normalizeSymbol(symbol))
+	data, err := c.requestSigned(http.MethodGet, "/fapi/v1/openOrders", vals)
+	if err != nil {
+		return nil, err
+	}
+	var rows []binanceOrderRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+	out := make([]models.OrderStatus, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, row.toStatus())
+	}
+	return out, nil
+}
+
+func (c *binanceClient) PlaceProtectiveOrder(symbol, side, orderType string, size, triggerPrice float64) (models.OrderResult, error) {
+	side = strings.ToLower(strings.TrimSpace(side))
+	if side != "buy" && side != "sell" {
+		return models.OrderResult{}, fmt.Errorf("invalid side: %s", side)
+	}
+	if size <= 0 || triggerPrice <= 0 {
+		return models.OrderResult{}, fmt.Errorf("invalid size/trigger: %.8f/%.8f", size, triggerPrice)
+	}
+	size = c.instruments.roundSize(symbol, size)
+	triggerPrice = c.instruments.roundPrice(symbol, triggerPrice)
+	var binanceType string
+	switch orderType {
+	case models.OrderTypeStopMarket:
+		binanceType = "STOP_MARKET"
+	case models.OrderTypeTakeProfitMarket:
+		binanceType = "TAKE_PROFIT_MARKET"
+	default:
+		return models.OrderResult{}, fmt.Errorf("unsupported protective order type: %s", orderType)
+	}
+	vals := url.Values{}
+	vals.Set("symbol", normalizeSymbol(symbol))
+	vals.Set("side", strings.ToUpper(side))
+	vals.Set("type", binanceType)
+	vals.Set("quantity", formatSize(size))
+	vals.Set("stopPrice", formatSize(triggerPrice))
+	vals.Set("workingType", "MARK_PRICE")
+	c.setPositionSide(vals, symbol, side, true)
+	data, err := c.requestSigned(http.MethodPost, "/fapi/v1/order", vals)
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	var resp struct {
+		OrderID       int64  `json:"orderId"`
+		ClientOrderID string `json:"clientOrderId"`
+		Status        string `json:"status"`
+		Symbol        string `json:"symbol"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return models.OrderResult{}, err
+	}
+	if resp.OrderID == 0 {
+		return models.OrderResult{}, fmt.Errorf("条件单下单失败: %s", string(data))
+	}
+	return models.OrderResult{
+		OrderID:      strconv.FormatInt(resp.OrderID, 10),
+		ClientID:     resp.ClientOrderID,
+		State:        mapOrderState(resp.Status),
+		Symbol:       resp.Symbol,
+		Side:         side,
+		Type:         orderType,
+		Size:         size,
+		TriggerPrice: triggerPrice,
+		ReduceOnly:   true,
+	}, nil
+}
+
+func (c *binanceClient) CancelProtectiveOrder(symbol, orderID string) error {
+	vals := url.Values{}
+	vals.Set("symbol", normalizeSymbol(symbol))
+	vals.Set("orderId", strings.TrimSpace(orderID))
+	_, err := c.requestSigned(http.MethodDelete, "/fapi/v1/order", vals)
+	return err
+}
+
+// FetchProtectiveOrder Binance 条件单与普通订单共用查询接口。
+func (c *binanceClient) FetchProtectiveOrder(symbol, orderID string) (*models.OrderStatus, error) {
+	return c.FetchOrder(symbol, orderID)
+}
+
+func (c *binanceClient) FetchInstrument(symbol string) (models.Instrument, error) {
+	return c.instruments.get(symbol)
+}
+
+// loadInstruments 读取 exchangeInfo；最大杠杆需签名接口，未配置密钥时留空。
+func (c *binanceClient) loadInstruments() ([]models.Instrument, error) {
+	data, err := c.requestPublic("/fapi/v1/exchangeInfo", nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Symbols []struct {
+			Symbol       string `json:"symbol"`
+			Status       string `json:"status"`
+			ContractType string `json:"contractType"`
+			Filters      []struct {
+				FilterType string `json:"filterType"`
+				TickSize   string `json:"tickSize"`
+				StepSize   string `json:"stepSize"`
+				MinQty     string `json:"minQty"`
+				Notional   string `json:"notional"`
+			} `json:"filters"`
+		} `json:"symbols"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	maxLev := c.loadMaxLeverage()
+	out := make([]models.Instrument, 0, len(resp.Symbols))
+	for _, row := range resp.Symbols {
+		if row.ContractType != "" && row.ContractType != "PERPETUAL" {
+			continue
+		}
+		inst := models.Instrument{
+			Symbol:        row.Symbol,
+			ContractValue: 1,
+			MaxLeverage:   maxLev[row.Symbol],
+		}
+		for _, f := range row.Filters {
+			switch f.FilterType {
+			case "PRICE_FILTER":
+				inst.TickSize = toFloat(f.TickSize)
+			case "LOT_SIZE":
+				inst.StepSize = toFloat(f.StepSize)
+				inst.MinQty = toFloat(f.MinQty)
+			case "MIN_NOTIONAL":
+				inst.MinNotional = toFloat(f.Notional)
+			}
+		}
+		out = append(out, inst)
+	}
+	return out, nil
+}
+
+func (c *binanceClient) loadMaxLeverage() map[string]float64 {
+	out := map[string]float64{}
+	if c.apiKey == "" || c.secret == "" {
+		return out
+	}
+	data, err := c.requestSigned(http.MethodGet, "/fapi/v1/leverageBracket", nil)
+	if err != nil {
+		return out
+	}
+	var rows []struct {
+		Symbol   string `json:"symbol"`
+		Brackets []struct {
+			InitialLeverage float64 `json:"initialLeverage"`
+		} `json:"brackets"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return out
+	}
+	for _, row := range rows {
+		for _, b := range row.Brackets {
+			if b.InitialLeverage > out[row.Symbol] {
+				out[row.Symbol] = b.InitialLeverage
+			}
+		}
+	}
+	return out
+}
+
+// binanceIncomeTypes Binance incomeType 映射为统一流水类型。
+var binanceIncomeTypes = map[string]string{
+	"REALIZED_PNL": models.IncomeRealizedPnL,
+	"COMMISSION":   models.IncomeCommission,
+	"FUNDING_FEE":  models.IncomeFunding,
+}
+
+// FetchIncome 按 startTime 升序翻页；同一 tranId 可能同时对应已实现盈亏与手续费，ID 拼接 incomeType 区分。
+func (c *binanceClient) FetchIncome(since time.Time) ([]models.IncomeEvent, error) {
+	out := []models.IncomeEvent{}
+	start := since.UnixMilli()
+	for page := 0; page < maxHistoryPages; page++ {
+		vals := url.Values{}
+		vals.Set("startTime", strconv.FormatInt(start, 10))
+		vals.Set("limit", "1000")
+		data, err := c.requestSigned(http.MethodGet, "/fapi/v1/income", vals)
+		if err != nil {
+			return out, err
+		}
+		var rows []struct {
+			Symbol     string `json:"symbol"`
+			IncomeType string `json:"incomeType"`
+			Income     string `json:"income"`
+			Asset      string `json:"asset"`
+			Info       string `json:"info"`
+			Time       int64  `json:"time"`
+			TranID     int64  `json:"tranId"`
+			TradeID    string `json:"tradeId"`
+		}
+		if err := json.Unmarshal(data, &rows); err != nil {
+			return out, err
+		}
+		for _, row := range rows {
+			typ := binanceIncomeTypes[row.IncomeType]
+			if typ == "" {
+				typ = models.IncomeOther
+			}
+			out = append(out, models.IncomeEvent{
+				ID:      fmt.Sprintf("%d-%s", row.TranID, row.IncomeType),
+				Symbol:  row.Symbol,
+				Type:    typ,
+				Amount:  toFloat(row.Income),
+				Asset:   row.Asset,
+				TradeID: row.TradeID,
+				Info:    row.IncomeType,
+				Time:    time.UnixMilli(row.Time),
+			})
+		}
+		if len(rows) < 1000 {
+			break
+		}
+		// 下一页从本页最后一条时间开始，重复记录由存储层按 ID 去重
+		start = rows[len(rows)-1].Time
+	}
+	return out, nil
+}
+
+// FetchTradeFills userTrades 单次查询的时间跨度不能超过 7 天，按窗口向前推进。
+func (c *binanceClient) FetchTradeFills(symbol string, since time.Time) ([]models.TradeFill, error) {
+	out := []models.TradeFill{}
+	now := time.Now()
+	if earliest := now.Add(-historyLookback); since.Before(earliest) {
+		since = earliest
+	}
+	start := since.UnixMilli()
+	for page := 0; page < maxHistoryPages && start < now.UnixMilli(); page++ {
+		end := start + (7*24*time.Hour - time.Millisecond).Milliseconds()
+		vals := url.Values{}
+		vals.Set("symbol", normalizeSymbol(symbol))
+		vals.Set("startTime", strconv.FormatInt(start, 10))
+		vals.Set("endTime", strconv.FormatInt(end, 10))
+		vals.Set("limit", "1000")
+		data, err := c.requestSigned(http.MethodGet, "/fapi/v1/userTrades", vals)
+		if err != nil {
+			return out, err
+		}
+		var rows []struct {
+			ID              int64  `json:"id"`
+			OrderID         int64  `json:"orderId"`
+			Symbol          string `json:"symbol"`
+			Side            string `json:"side"`
+			Price           string `json:"price"`
+			Qty             string `json:"qty"`
+			RealizedPnl     string `json:"realizedPnl"`
+			Commission      string `json:"commission"`
+			CommissionAsset string `json:"commissionAsset"`
+			Time            int64  `json:"time"`
+			Maker           bool   `json:"maker"`
+		}
+		if err := json.Unmarshal(data, &rows); err != nil {
+			return out, err
+		}
+		for _, row := range rows {
+			out = append(out, models.TradeFill{
+				ID:          strconv.FormatInt(row.ID, 10),
+				OrderID:     strconv.FormatInt(row.OrderID, 10),
+				Symbol:      row.Symbol,
+				Side:        strings.ToLower(row.Side),
+				Size:        toFloat(row.Qty),
+				Price:       toFloat(row.Price),
+				Fee:         toFloat(row.Commission),
+				FeeAsset:    row.CommissionAsset,
+				RealizedPnL: toFloat(row.RealizedPnl),
+				Maker:       row.Maker,
+				Time:        time.UnixMilli(row.Time),
+			})
+		}
+		if len(rows) >= 1000 {
+			start = rows[len(rows)-1].Time
+			continue
+		}
+		start = end + 1
+	}
+	return out, nil
+}
+
+// requestAPIKey listenKey 接口只校验 API Key，不需要签名。
+func (c *binanceClient) requestAPIKey(method, path string) ([]byte, error) {
+	status, body, err := c.limiter.do(c.httpClient, method, 1, func() (*http.Request, error) {
+		req, err := http.NewRequest(method, c.baseURL+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-MBX-APIKEY", c.apiKey)
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if status >= 300 {
+		return nil, fmt.Errorf("binance http %d: %s", status, string(body))
+	}
+	return body, nil
+}
+
+func (c *binanceClient) userStreamSource() (userStreamSource, error) {
+	if c.apiKey == "" {
+		return nil, fmt.Errorf("binance API Key 未配置")
+	}
+	return &binanceUserStream{c: c, wsBase: ResolveEndpoints("binance", c.env).PrivateWS}, nil
+}
+
+// binanceUserStream listenKey 用户数据流：60 分钟不续期即失效，每 30 分钟 PUT 延长一次。
+type binanceUserStream struct {
+	c      *binanceClient
+	wsBase string
+}
+
+func (u *binanceUserStream) dial() (*websocket.Conn, error) {
+	// 已有有效 listenKey 时交易所返回同一个 key 并顺带续期
+	data, err := u.c.requestAPIKey(http.MethodPost, "/fapi/v1/listenKey")
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		ListenKey string `json:"listenKey"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	if resp.ListenKey == "" {
+		return nil, fmt.Errorf("binance listenKey 为空: %s", string(data))
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(u.wsBase+"/ws/"+resp.ListenKey, nil)
+	return conn, err
+}
+
+func (u *binanceUserStream) keepaliveInterval() time.Duration { return 30 * time.Minute }
+
+func (u *binanceUserStream) keepalive(*websocket.Conn) error {
+	_, err := u.c.requestAPIKey(http.MethodPut, "/fapi/v1/listenKey")
+	return err
+}
+
+func (u *binanceUserStream) close() {
+	_, _ = u.c.requestAPIKey(http.MethodDelete, "/fapi/v1/listenKey")
+}
+
+func (u *binanceUserStream) decode(msg []byte) ([]UserEvent, error) {
+	// 字段名大小写敏感（e/E、t/T 含义不同），encoding/json 大小写不敏感匹配，需把同名字段都声明出来
+	var head struct {
+		Type      string `json:"e"`
+		EventTime int64  `json:"E"`
+	}
+	if err := json.Unmarshal(msg, &head); err != nil {
+		return nil, nil
+	}
+	switch head.Type {
+	case "ORDER_TRADE_UPDATE":
+		return u.decodeOrder(msg)
+	case "ACCOUNT_UPDATE":
+		return u.decodeAccount(msg)
+	case "listenKeyExpired":
+		return nil, fmt.Errorf("binance listenKey 已过期")
+	}
+	return nil, nil
+}
+
+func (u *binanceUserStream) decodeOrder(msg []byte) ([]UserEvent, error) {
+	var ev struct {
+		Type      string `json:"e"`
+		EventTime int64  `json:"E"`
+		Order     struct {
+			Symbol      string `json:"s"`
+			Side        string `json:"S"`
+			OrderType   string `json:"o"`
+			OrigQty     string `json:"q"`
+			Price       string `json:"p"`
+			AvgPrice    string `json:"ap"`
+			ActivatePx  string `json:"AP"`
+			ExecType    string `json:"x"`
+			Status      string `json:"X"`
+			OrderID     int64  `json:"i"`
+			LastQty     string `json:"l"`
+			CumQty      string `json:"z"`
+			LastPrice   string `json:"L"`
+			FeeAsset    string `json:"N"`
+			Fee         string `json:"n"`
+			TradeTime   int64  `json:"T"`
+			TradeID     int64  `json:"t"`
+			Maker       bool   `json:"m"`
+			ReduceOnly  bool   `json:"R"`
+			RealizedPnL string `json:"rp"`
+		} `json:"o"`
+	}
+	if err := json.Unmarshal(msg, &ev); err != nil {
+		return nil, nil
+	}
+	o := ev.Order
+	row := binanceOrderRow{
+		OrderID:     o.OrderID,
+		Symbol:      o.Symbol,
+		Status:      o.Status,
+		Type:        o.OrderType,
+		OrigQty:     o.OrigQty,
+		Price:       o.Price,
+		ExecutedQty: o.CumQty,
+		AvgPrice:    o.AvgPrice,
+		Side:        o.Side,
+		ReduceOnly:  o.ReduceOnly,
+		UpdateTime:  o.TradeTime,
+	}
+	st := row.toStatus()
+	out := UserEvent{Kind: UserEventOrder, Order: &st, Time: time.UnixMilli(o.TradeTime)}
+	if o.ExecType == "TRADE" && o.TradeID > 0 {
+		out.Trade = &models.TradeFill{
+			ID:          strconv.FormatInt(o.TradeID, 10),
+			OrderID:     st.OrderID,
+			Symbol:      o.Symbol,
+			Side:        st.Side,
+			Size:        toFloat(o.LastQty),
+			Price:       toFloat(o.LastPrice),
+			Fee:         toFloat(o.Fee),
+			FeeAsset:    o.FeeAsset,
+			RealizedPnL: toFloat(o.RealizedPnL),
+			Maker:       o.Maker,
+			Time:        time.UnixMilli(o.TradeTime),
+		}
+	}
+	return []UserEvent{out}, nil
+}
+
+func (u *binanceUserStream) decodeAccount(msg []byte) ([]UserEvent, error) {
+	var ev struct {
+		Type      string `json:"e"`
+		EventTime int64  `json:"E"`
+		Account   struct {
+			Balances []struct {
+				Asset         string `json:"a"`
+				WalletBalance string `json:"wb"`
+			} `json:"B"`
+			Positions []struct {
+				Symbol        string `json:"s"`
+				Amount        string `json:"pa"`
+				EntryPrice    string `json:"ep"`
+				UnrealizedPnL string `json:"up"`
+				MarginType    string `json:"mt"`
+				PositionSide  string `json:"ps"`
+			} `json:"P"`
+		} `json:"a"`
+	}
+	if err := json.Unmarshal(msg, &ev); err != nil {
+		return nil, nil
+	}
+	at := time.UnixMilli(ev.EventTime)
+	out := []UserEvent{}
+	if len(ev.Account.Balances) > 0 {
+		balances := make([]models.AssetBalance, 0, len(ev.Account.Balances))
+		for _, b := range ev.Account.Balances {
+			balances = append(balances, models.AssetBalance{Asset: strings.ToUpper(b.Asset), WalletBalance: toFloat(b.WalletBalance)})
+		}
+		out = append(out, UserEvent{Kind: UserEventAccount, Balances: balances, Time: at})
+	}
+	if len(ev.Account.Positions) > 0 {
+		legs := make([]models.Position, 0, len(ev.Account.Positions))
+		for _, p := range ev.Account.Positions {
+			amt := toFloat(p.Amount)
+			side := strings.ToLower(p.PositionSide)
+			if side != "long" && side != "short" {
+				// 单向持仓按数量正负判断方向，已平仓时方向为空
+				switch {
+				case amt > 0:
+					side = "long"
+				case amt < 0:
+					side = "short"
+				default:
+					side = ""
+				}
+			}
+			legs = append(legs, models.Position{
+				Side:          side,
+				Size:          math.Abs(amt),
+				EntryPrice:    toFloat(p.EntryPrice),
+				UnrealizedPnL: toFloat(p.UnrealizedPnL),
+				Symbol:        p.Symbol,
+				MarginMode:    models.NormalizeMarginMode(p.MarginType),
+			})
+		}
+		out = append(out, UserEvent{Kind: UserEventPosition, Positions: legs, Time: at})
+	}
+	return out, nil
+}
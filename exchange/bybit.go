@@ -0,0 +1,1213 @@
+package exchange
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"trade-go/config"
+	"trade-go/models"
+)
+
+const (
+	bybitRecvWindow = "5000"
+	bybitCategory   = "linear"
+)
+
+// bybitClient Bybit v5 USDT 永续（linear）。baseURL 可通过 BYBIT_BASE_URL 覆盖，便于本地替身测试。
+type bybitClient struct {
+	env         string
+	apiKey      string
+	secret      string
+	baseURL     string
+	httpClient  *http.Client
+	instruments *instrumentCatalog
+	limiter     *rateLimiter
+	clock       *serverClock
+	posMode     *positionModeCache
+}
+
+func newBybitClient(cfg *config.AppConfig) *bybitClient {
+	key := ""
+	secret := ""
+	env := EnvMainnet
+	if cfg != nil {
+		env = cfg.ExchangeEnv
+	}
+	ep := ResolveEndpoints("bybit", env)
+	baseURL := ep.REST
+	if cfg != nil {
+		key = strings.TrimSpace(cfg.BybitAPIKey)
+		secret = strings.TrimSpace(cfg.BybitSecret)
+		if v := strings.TrimRight(strings.TrimSpace(cfg.BybitBaseURL), "/"); v != "" {
+			baseURL = v
+		}
+	}
+	c := &bybitClient{
+		env:        ep.Env,
+		apiKey:     key,
+		secret:     secret,
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+		limiter:    sharedRateLimiter("bybit", ep.Env, key),
+		clock:      sharedServerClock(scopedName("bybit", ep.Env)),
+	}
+	c.instruments = newInstrumentCatalog(c.loadInstruments)
+	c.posMode = newPositionModeCache(c.loadPositionMode)
+	return c
+}
+
+func toBybitInterval(timeframe string) string {
+	m := map[string]string{
+		"1m":  "1",
+		"3m":  "3",
+		"5m":  "5",
+		"15m": "15",
+		"30m": "30",
+		"1h":  "60",
+		"2h":  "120",
+		"4h":  "240",
+		"6h":  "360",
+		"12h": "720",
+		"1d":  "D",
+		"1w":  "W",
+		"1M":  "M",
+	}
+	if v, ok := m[strings.TrimSpace(timeframe)]; ok {
+		return v
+	}
+	return "15"
+}
+
+func toBybitSide(side string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(side)) {
+	case "buy":
+		return "Buy", nil
+	case "sell":
+		return "Sell", nil
+	default:
+		return "", fmt.Errorf("invalid side: %s", side)
+	}
+}
+
+func mapBybitOrderState(s string) string {
+	switch strings.TrimSpace(s) {
+	case "New", "Untriggered", "Triggered", "Created", "Active":
+		return "live"
+	case "PartiallyFilled":
+		return "partially_filled"
+	case "Filled":
+		return "filled"
+	case "Cancelled", "Rejected", "Deactivated", "PartiallyFilledCanceled":
+		return "canceled"
+	default:
+		return mapOrderState(s)
+	}
+}
+
+func fromBybitOrderType(orderType, stopOrderType string) string {
+	switch strings.TrimSpace(stopOrderType) {
+	case "StopLoss", "Stop":
+		return models.OrderTypeStopMarket
+	case "TakeProfit":
+		return models.OrderTypeTakeProfitMarket
+	}
+	switch strings.TrimSpace(orderType) {
+	case "Market":
+		return models.OrderTypeMarket
+	case "Limit":
+		return models.OrderTypeLimit
+	default:
+		return strings.ToLower(strings.TrimSpace(orderType))
+	}
+}
+
+func (c *bybitClient) sign(payload string) string {
+	h := hmac.New(sha256.New, []byte(c.secret))
+	h.Write([]byte(payload))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// checkBybitEnvelope 校验 v5 统一返回体 retCode。
+func checkBybitEnvelope(body []byte) error {
+	var envelope struct {
+		RetCode *int   `json:"retCode"`
+		RetMsg  string `json:"retMsg"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return err
+	}
+	if envelope.RetCode != nil && *envelope.RetCode != 0 {
+		return fmt.Errorf("bybit retCode=%d: %s", *envelope.RetCode, strings.TrimSpace(envelope.RetMsg))
+	}
+	return nil
+}
+
+func (c *bybitClient) requestPublic(path string, values url.Values) ([]byte, error) {
+	fullURL := c.baseURL + path
+	if len(values) > 0 {
+		fullURL += "?" + values.Encode()
+	}
+	status, body, err := c.limiter.do(c.httpClient, http.MethodGet, 1, func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, fullURL, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if status >= 300 {
+		return nil, fmt.Errorf("bybit http %d: %s", status, strings.TrimSpace(string(body)))
+	}
+	if err := checkBybitEnvelope(body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func (c *bybitClient) requestSigned(method, path string, query url.Values, payload any) ([]byte, error) {
+	body, err := c.requestSignedOnce(method, path, query, payload)
+	if isTimestampError(err) {
+		// retCode=10002 时间戳超出 recv_window，重新同步后重试一次
+		c.clock.invalidate()
+		body, err = c.requestSignedOnce(method, path, query, payload)
+	}
+	return body, err
+}
+
+// requestSignedOnce GET 签名原文为 query string，POST 为 JSON body。
+func (c *bybitClient) requestSignedOnce(method, path string, query url.Values, payload any) ([]byte, error) {
+	fullURL := c.baseURL + path
+	signTarget := ""
+	var bodyBytes []byte
+	if method == http.MethodGet {
+		if len(query) > 0 {
+			signTarget = query.Encode()
+			fullURL += "?" + signTarget
+		}
+	} else {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		signTarget = string(raw)
+		bodyBytes = raw
+	}
+	status, body, err := c.limiter.do(c.httpClient, method, 1, func() (*http.Request, error) {
+		ts := strconv.FormatInt(c.clock.now(c.fetchServerTime).UnixMilli(), 10)
+		var reader io.Reader
+		if bodyBytes != nil {
+			reader = bytes.NewReader(bodyBytes)
+		}
+		req, err := http.NewRequest(method, fullURL, reader)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-BAPI-API-KEY", c.apiKey)
+		req.Header.Set("X-BAPI-TIMESTAMP", ts)
+		req.Header.Set("X-BAPI-RECV-WINDOW", bybitRecvWindow)
+		req.Header.Set("X-BAPI-SIGN", c.sign(ts+c.apiKey+bybitRecvWindow+signTarget))
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if status >= 300 {
+		return nil, fmt.Errorf("bybit http %d: %s", status, strings.TrimSpace(string(body)))
+	}
+	if err := checkBybitEnvelope(body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func (c *bybitClient) fetchServerTime() (time.Time, error) {
+	data, err := c.requestPublic("/v5/market/time", nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var resp struct {
+		Time int64 `json:"time"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return time.Time{}, err
+	}
+	if resp.Time <= 0 {
+		return time.Time{}, fmt.Errorf("bybit server time invalid: %s", string(data))
+	}
+	return time.UnixMilli(resp.Time), nil
+}
+
+func (c *bybitClient) FetchOHLCV(symbol, timeframe string, limit int) ([]models.OHLCV, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+	vals := url.Values{}
+	vals.Set("category", bybitCategory)
+	vals.Set("symbol", normalizeSymbol(symbol))
+	vals.Set("interval", toBybitInterval(timeframe))
+	vals.Set("limit", strconv.Itoa(limit))
+	data, err := c.requestPublic("/v5/market/kline", vals)
+	if err != nil {
+		return nil, err
+	}
+	return parseBybitKlines(data)
+}
+
+// FetchOHLCVRange 以 end 游标自新向旧分页，每页 1000 根。
+func (c *bybitClient) FetchOHLCVRange(symbol, timeframe string, start, end time.Time) ([]models.OHLCV, error) {
+	const pageSize = 1000
+	startMs := start.UnixMilli()
+	endMs := end.UnixMilli()
+	var pages [][]models.OHLCV
+	for endMs >= startMs {
+		vals := url.Values{}
+		vals.Set("category", bybitCategory)
+		vals.Set("symbol", normalizeSymbol(symbol))
+		vals.Set("interval", toBybitInterval(timeframe))
+		vals.Set("start", strconv.FormatInt(startMs, 10))
+		vals.Set("end", strconv.FormatInt(endMs, 10))
+		vals.Set("limit", strconv.Itoa(pageSize))
+		data, err := c.requestPublic("/v5/market/kline", vals)
+		if err != nil {
+			return flattenCandlePages(pages, startMs), err
+		}
+		page, err := parseBybitKlines(data)
+		if err != nil {
+			return flattenCandlePages(pages, startMs), err
+		}
+		if len(page) == 0 {
+			break
+		}
+		pages = append(pages, page)
+		oldest := page[0].Timestamp.UnixMilli()
+		if oldest <= startMs || len(page) < pageSize {
+			break
+		}
+		endMs = oldest - 1
+	}
+	return flattenCandlePages(pages, startMs), nil
+}
+
+func parseBybitKlines(data []byte) ([]models.OHLCV, error) {
+	var resp struct {
+		Result struct {
+			List [][]string `json:"list"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	rows := resp.Result.List
+	candles := make([]models.OHLCV, 0, len(rows))
+	// Bybit 返回按时间倒序
+	for i := len(rows) - 1; i >= 0; i-- {
+		row := rows[i]
+		if len(row) < 6 {
+			continue
+		}
+		tsMs, _ := strconv.ParseInt(strings.TrimSpace(row[0]), 10, 64)
+		candles = append(candles, models.OHLCV{
+			Timestamp: time.UnixMilli(tsMs),
+			Open:      toFloat(row[1]),
+			High:      toFloat(row[2]),
+			Low:       toFloat(row[3]),
+			Close:     toFloat(row[4]),
+			Volume:    toFloat(row[5]),
+		})
+	}
+	return candles, nil
+}
+
+type bybitWallet struct {
+	TotalEquity           string `json:"totalEquity"`
+	TotalAvailableBalance string `json:"totalAvailableBalance"`
+	TotalMarginBalance    string `json:"totalMarginBalance"`
+	TotalWalletBalance    string `json:"totalWalletBalance"`
+	Coin                  []struct {
+		Coin                string `json:"coin"`
+		Equity              string `json:"equity"`
+		WalletBalance       string `json:"walletBalance"`
+		AvailableToWithdraw string `json:"availableToWithdraw"`
+		UnrealisedPnl       string `json:"unrealisedPnl"`
+		UsdValue            string `json:"usdValue"`
+		TotalPositionIM     string `json:"totalPositionIM"`
+		TotalOrderIM        string `json:"totalOrderIM"`
+		Locked              string `json:"locked"`
+	} `json:"coin"`
+}
+
+func (c *bybitClient) fetchWallet() (*bybitWallet, error) {
+	query := url.Values{}
+	query.Set("accountType", "UNIFIED")
+	data, err := c.requestSigned(http.MethodGet, "/v5/account/wallet-balance", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Result struct {
+			List []bybitWallet `json:"list"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Result.List) == 0 {
+		return nil, nil
+	}
+	return &resp.Result.List[0], nil
+}
+
+func (c *bybitClient) FetchBalance() (float64, error) {
+	w, err := c.fetchWallet()
+	if err != nil || w == nil {
+		return 0, err
+	}
+	if v, err := strconv.ParseFloat(strings.TrimSpace(w.TotalEquity), 64); err == nil && v > 0 {
+		return v, nil
+	}
+	if v, err := strconv.ParseFloat(strings.TrimSpace(w.TotalMarginBalance), 64); err == nil && v > 0 {
+		return v, nil
+	}
+	for _, coin := range w.Coin {
+		if !strings.EqualFold(strings.TrimSpace(coin.Coin), "USDT") {
+			continue
+		}
+		if v, err := strconv.ParseFloat(strings.TrimSpace(coin.Equity), 64); err == nil && v > 0 {
+			return v, nil
+		}
+		v, _ := strconv.ParseFloat(strings.TrimSpace(coin.WalletBalance), 64)
+		return v, nil
+	}
+	return 0, nil
+}
+
+// FetchMarketStats linear tickers 一次返回标记/指数价格、资金费率与持仓量。
+func (c *bybitClient) FetchMarketStats(symbol string) (models.MarketStats, error) {
+	query := url.Values{}
+	query.Set("category", bybitCategory)
+	query.Set("symbol", normalizeSymbol(symbol))
+	data, err := c.requestPublic("/v5/market/tickers", query)
+	if err != nil {
+		return models.MarketStats{}, err
+	}
+	var resp struct {
+		Time   int64 `json:"time"`
+		Result struct {
+			List []struct {
+				Symbol          string `json:"symbol"`
+				MarkPrice       string `json:"markPrice"`
+				IndexPrice      string `json:"indexPrice"`
+				FundingRate     string `json:"fundingRate"`
+				NextFundingTime string `json:"nextFundingTime"`
+				OpenInterest    string `json:"openInterest"`
+			} `json:"list"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return models.MarketStats{}, err
+	}
+	if len(resp.Result.List) == 0 {
+		return models.MarketStats{}, fmt.Errorf("bybit ticker 为空: %s", normalizeSymbol(symbol))
+	}
+	row := resp.Result.List[0]
+	out := models.MarketStats{
+		Symbol:       normalizeSymbol(row.Symbol),
+		MarkPrice:    toFloat(row.MarkPrice),
+		IndexPrice:   toFloat(row.IndexPrice),
+		FundingRate:  toFloat(row.FundingRate),
+		OpenInterest: toFloat(row.OpenInterest),
+		UpdatedAt:    time.UnixMilli(resp.Time),
+	}
+	if ms := int64(toFloat(row.NextFundingTime)); ms > 0 {
+		out.NextFundingTime = time.UnixMilli(ms)
+	}
+	return out, nil
+}
+
+// FetchOrderBook linear 盘口单次最多 500 档。
+func (c *bybitClient) FetchOrderBook(symbol string, depth int) (models.OrderBook, error) {
+	limit := depth
+	if limit <= 0 {
+		limit = 25
+	}
+	if limit > 500 {
+		limit = 500
+	}
+	query := url.Values{}
+	query.Set("category", bybitCategory)
+	query.Set("symbol", normalizeSymbol(symbol))
+	query.Set("limit", strconv.Itoa(limit))
+	data, err := c.requestPublic("/v5/market/orderbook", query)
+	if err != nil {
+		return models.OrderBook{}, err
+	}
+	var resp struct {
+		Result struct {
+			Bids [][]string `json:"b"`
+			Asks [][]string `json:"a"`
+			Ts   int64      `json:"ts"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return models.OrderBook{}, err
+	}
+	return models.OrderBook{
+		Symbol:    normalizeSymbol(symbol),
+		Bids:      parseBookLevels(resp.Result.Bids, depth, 1),
+		Asks:      parseBookLevels(resp.Result.Asks, depth, 1),
+		Timestamp: time.UnixMilli(resp.Result.Ts),
+	}, nil
+}
+
+// FetchBalances 统一账户新版本 availableToWithdraw 已废弃返回空串，此时按钱包余额扣除仓位/挂单占用与冻结估算。
+func (c *bybitClient) FetchBalances() ([]models.AssetBalance, error) {
+	w, err := c.fetchWallet()
+	if err != nil {
+		return nil, err
+	}
+	out := []models.AssetBalance{}
+	if w == nil {
+		return out, nil
+	}
+	for _, coin := range w.Coin {
+		b := models.AssetBalance{
+			Asset:         strings.ToUpper(strings.TrimSpace(coin.Coin)),
+			WalletBalance: toFloat(coin.WalletBalance),
+			MarginBalance: toFloat(coin.Equity),
+			UnrealizedPnL: toFloat(coin.UnrealisedPnl),
+			USDValue:      toFloat(coin.UsdValue),
+		}
+		if b.WalletBalance == 0 && b.MarginBalance == 0 {
+			continue
+		}
+		if v, err := strconv.ParseFloat(strings.TrimSpace(coin.AvailableToWithdraw), 64); err == nil {
+			b.AvailableBalance = v
+		} else {
+			b.AvailableBalance = math.Max(0, b.WalletBalance-toFloat(coin.TotalPositionIM)-toFloat(coin.TotalOrderIM)-toFloat(coin.Locked))
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+func (c *bybitClient) FetchAvailableBalance() (float64, error) {
+	w, err := c.fetchWallet()
+	if err != nil || w == nil {
+		return 0, err
+	}
+	if v, err := strconv.ParseFloat(strings.TrimSpace(w.TotalAvailableBalance), 64); err == nil && v >= 0 {
+		return v, nil
+	}
+	for _, coin := range w.Coin {
+		if !strings.EqualFold(strings.TrimSpace(coin.Coin), "USDT") {
+			continue
+		}
+		if v, err := strconv.ParseFloat(strings.TrimSpace(coin.AvailableToWithdraw), 64); err == nil && v >= 0 {
+			return v, nil
+		}
+	}
+	return 0, nil
+}
+
+func (c *bybitClient) SetLeverage(symbol string, leverage int) error {
+	payload := map[string]string{
+		"category":     bybitCategory,
+		"symbol":       normalizeSymbol(symbol),
+		"buyLeverage":  strconv.Itoa(leverage),
+		"sellLeverage": strconv.Itoa(leverage),
+	}
+	_, err := c.requestSigned(http.MethodPost, "/v5/position/set-leverage", nil, payload)
+	// 110043: leverage not modified
+	if err != nil && strings.Contains(err.Error(), "retCode=110043") {
+		return nil
+	}
+	return err
+}
+
+// SetMarginMode 经典账户按交易对切换（switch-isolated，需同时提交当前杠杆）。
+// 统一账户不支持按交易对切换（100028），保证金模式是账户级设置，会影响非本机器人的持仓，
+// 因此不代为切换：账户当前模式与配置一致时视为成功，否则返回错误提示手动调整。
+func (c *bybitClient) SetMarginMode(symbol, mode string) error {
+	tradeMode := 0
+	if mode == models.MarginModeIsolated {
+		tradeMode = 1
+	}
+	leverage := "10"
+	if rows, err := c.fetchPositionRows(symbol); err == nil {
+		for _, row := range rows {
+			if v := strings.TrimSpace(row.Leverage); v != "" {
+				leverage = v
+				break
+			}
+		}
+	}
+	_, err := c.requestSigned(http.MethodPost, "/v5/position/switch-isolated", nil, map[string]any{
+		"category":     bybitCategory,
+		"symbol":       normalizeSymbol(symbol),
+		"tradeMode":    tradeMode,
+		"buyLeverage":  leverage,
+		"sellLeverage": leverage,
+	})
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	// 110026: margin mode not modified
+	if strings.Contains(msg, "retCode=110026") {
+		return nil
+	}
+	if !strings.Contains(msg, "retCode=100028") {
+		return err
+	}
+	want := "REGULAR_MARGIN"
+	if mode == models.MarginModeIsolated {
+		want = "ISOLATED_MARGIN"
+	}
+	current, infoErr := c.accountMarginMode()
+	if infoErr != nil {
+		return fmt.Errorf("bybit 统一账户不支持按交易对切换保证金模式，且读取账户模式失败: %w", infoErr)
+	}
+	if current == want {
+		return nil
+	}
+	return fmt.Errorf("bybit 统一账户保证金模式为账户级设置（当前 %s，配置需要 %s），为避免影响其他持仓不自动切换，请在 Bybit 手动调整", current, want)
+}
+
+// accountMarginMode 统一账户的账户级保证金模式：ISOLATED_MARGIN / REGULAR_MARGIN / PORTFOLIO_MARGIN。
+func (c *bybitClient) accountMarginMode() (string, error) {
+	data, err := c.requestSigned(http.MethodGet, "/v5/account/info", nil, nil)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		Result struct {
+			MarginMode string `json:"marginMode"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Result.MarginMode), nil
+}
+
+type bybitPositionRow struct {
+	Symbol        string `json:"symbol"`
+	Side          string `json:"side"`
+	Size          string `json:"size"`
+	PositionIdx   int    `json:"positionIdx"`
+	TradeMode     int    `json:"tradeMode"`
+	AvgPrice      string `json:"avgPrice"`
+	UnrealisedPnl string `json:"unrealisedPnl"`
+	Leverage      string `json:"leverage"`
+}
+
+// fetchPositionRows symbol 为空时按结算币 USDT 查询全部持仓（接口要求 symbol 与 settleCoin 至少其一）。
+func (c *bybitClient) fetchPositionRows(symbol string) ([]bybitPositionRow, error) {
+	query := url.Values{}
+	query.Set("category", bybitCategory)
+	if strings.TrimSpace(symbol) != "" {
+		query.Set("symbol", normalizeSymbol(symbol))
+	} else {
+		query.Set("settleCoin", "USDT")
+	}
+	data, err := c.requestSigned(http.MethodGet, "/v5/position/list", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Result struct {
+			List []bybitPositionRow `json:"list"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Result.List, nil
+}
+
+func (c *bybitClient) FetchPositions(symbol string) ([]models.Position, error) {
+	rows, err := c.fetchPositionRows(symbol)
+	if err != nil {
+		return nil, err
+	}
+	out := []models.Position{}
+	for _, row := range rows {
+		size := toFloat(row.Size)
+		if size == 0 {
+			continue
+		}
+		// positionIdx 1/2 为双向模式的多/空腿，0 为单向模式按 side 判断
+		side := "long"
+		if row.PositionIdx == 2 || (row.PositionIdx == 0 && strings.EqualFold(strings.TrimSpace(row.Side), "Sell")) {
+			side = "short"
+		}
+		out = append(out, models.Position{
+			Side:          side,
+			Size:          size,
+			EntryPrice:    toFloat(row.AvgPrice),
+			UnrealizedPnL: toFloat(row.UnrealisedPnl),
+			Leverage:      toFloat(row.Leverage),
+			Symbol:        normalizeSymbol(row.Symbol),
+			MarginMode:    bybitMarginMode(row.TradeMode),
+		})
+	}
+	return out, nil
+}
+
+// bybitMarginMode tradeMode: 0 全仓，1 逐仓。
+func bybitMarginMode(tradeMode int) string {
+	if tradeMode == 1 {
+		return models.MarginModeIsolated
+	}
+	return models.MarginModeCross
+}
+
+func (c *bybitClient) FetchPositionMode(symbol string) (string, error) {
+	return c.posMode.get(symbol)
+}
+
+// loadPositionMode Bybit 无单独的模式查询接口；双向模式下持仓列表始终返回 positionIdx 1/2 两行（含空仓）。
+func (c *bybitClient) loadPositionMode(symbol string) (string, error) {
+	rows, err := c.fetchPositionRows(symbol)
+	if err != nil {
+		return "", err
+	}
+	for _, row := range rows {
+		if row.PositionIdx == 1 || row.PositionIdx == 2 {
+			return models.PositionModeHedge, nil
+		}
+	}
+	return models.PositionModeOneWay, nil
+}
+
+// positionIdx 单向模式为 0；双向模式多头腿为 1、空头腿为 2。
+func (c *bybitClient) positionIdx(symbol, side string, reduceOnly bool) int {
+	if !c.posMode.hedge(symbol) {
+		return 0
+	}
+	if positionLegForOrder(side, reduceOnly) == "long" {
+		return 1
+	}
+	return 2
+}
+
+func (c *bybitClient) PlaceMarketOrder(symbol, side string, size float64, reduceOnly bool) error {
+	_, err := c.PlaceMarketOrderWithResult(symbol, side, size, reduceOnly)
+	return err
+}
+
+func (c *bybitClient) createOrder(payload map[string]any) (string, string, error) {
+	data, err := c.requestSigned(http.MethodPost, "/v5/order/create", nil, payload)
+	if err != nil {
+		return "", "", err
+	}
+	var resp struct {
+		Result struct {
+			OrderID     string `json:"orderId"`
+			OrderLinkID string `json:"orderLinkId"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", "", err
+	}
+	if strings.TrimSpace(resp.Result.OrderID) == "" {
+		return "", "", fmt.Errorf("bybit 下单失败: 无订单ID")
+	}
+	return strings.TrimSpace(resp.Result.OrderID), strings.TrimSpace(resp.Result.OrderLinkID), nil
+}
+
+func (c *bybitClient) PlaceMarketOrderWithResult(symbol, side string, size float64, reduceOnly bool) (models.OrderResult, error) {
+	bybitSide, err := toBybitSide(side)
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	if size <= 0 {
+		return models.OrderResult{}, fmt.Errorf("invalid size: %.8f", size)
+	}
+	size = c.instruments.roundSize(symbol, size)
+	orderID, linkID, err := c.createOrder(map[string]any{
+		"category":    bybitCategory,
+		"symbol":      normalizeSymbol(symbol),
+		"side":        bybitSide,
+		"orderType":   "Market",
+		"qty":         formatSize(size),
+		"reduceOnly":  reduceOnly,
+		"positionIdx": c.positionIdx(symbol, strings.ToLower(side), reduceOnly),
+	})
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	return models.OrderResult{
+		OrderID:    orderID,
+		ClientID:   linkID,
+		State:      "live",
+		Symbol:     normalizeSymbol(symbol),
+		Side:       strings.ToLower(side),
+		Type:       models.OrderTypeMarket,
+		Size:       size,
+		ReduceOnly: reduceOnly,
+	}, nil
+}
+
+type bybitOrderRow struct {
+	OrderID       string `json:"orderId"`
+	Symbol        string `json:"symbol"`
+	OrderStatus   string `json:"orderStatus"`
+	OrderType     string `json:"orderType"`
+	StopOrderType string `json:"stopOrderType"`
+	Side          string `json:"side"`
+	Qty           string `json:"qty"`
+	Price         string `json:"price"`
+	CumExecQty    string `json:"cumExecQty"`
+	AvgPrice      string `json:"avgPrice"`
+	ReduceOnly    bool   `json:"reduceOnly"`
+	UpdatedTime   string `json:"updatedTime"`
+	CreatedTime   string `json:"createdTime"`
+}
+
+func (row bybitOrderRow) toStatus() models.OrderStatus {
+	return models.OrderStatus{
+		OrderID:    strings.TrimSpace(row.OrderID),
+		State:      mapBybitOrderState(row.OrderStatus),
+		Type:       fromBybitOrderType(row.OrderType, row.StopOrderType),
+		Size:       toFloat(row.Qty),
+		Price:      toFloat(row.Price),
+		FilledSize: toFloat(row.CumExecQty),
+		AvgPrice:   toFloat(row.AvgPrice),
+		Symbol:     normalizeSymbol(row.Symbol),
+		Side:       strings.ToLower(strings.TrimSpace(row.Side)),
+		ReduceOnly: row.ReduceOnly,
+		UpdateTime: strings.TrimSpace(row.UpdatedTime),
+		CreateTime: strings.TrimSpace(row.CreatedTime),
+	}
+}
+
+func (c *bybitClient) queryOrders(path string, query url.Values) ([]bybitOrderRow, error) {
+	data, err := c.requestSigned(http.MethodGet, path, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Result struct {
+			List []bybitOrderRow `json:"list"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Result.List, nil
+}
+
+// FetchOrder 先查活动订单，查不到再查历史订单。
+func (c *bybitClient) FetchOrder(symbol, orderID string) (*models.OrderStatus, error) {
+	query := url.Values{}
+	query.Set("category", bybitCategory)
+	query.Set("symbol", normalizeSymbol(symbol))
+	query.Set("orderId", strings.TrimSpace(orderID))
+	for _, path := range []string{"/v5/order/realtime", "/v5/order/history"} {
+		rows, err := c.queryOrders(path, query)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) > 0 {
+			st := rows[0].toStatus()
+			return &st, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *bybitClient) PlaceLimitOrder(symbol, side string, size, price float64, timeInForce string, reduceOnly bool) (models.OrderResult, error) {
+	bybitSide, err := toBybitSide(side)
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	if size <= 0 || price <= 0 {
+		return models.OrderResult{}, fmt.Errorf("invalid size/price: %.8f/%.8f", size, price)
+	}
+	size = c.instruments.roundSize(symbol, size)
+	price = c.instruments.roundPrice(symbol, price)
+	tif := "GTC"
+	switch timeInForce {
+	case models.TimeInForcePostOnly:
+		tif = "PostOnly"
+	case models.TimeInForceIOC:
+		tif = "IOC"
+	case models.TimeInForceGTC, "":
+	default:
+		return models.OrderResult{}, fmt.Errorf("unsupported time in force: %s", timeInForce)
+	}
+	orderID, linkID, err := c.createOrder(map[string]any{
+		"category":    bybitCategory,
+		"symbol":      normalizeSymbol(symbol),
+		"side":        bybitSide,
+		"orderType":   "Limit",
+		"qty":         formatSize(size),
+		"price":       formatSize(price),
+		"timeInForce": tif,
+		"reduceOnly":  reduceOnly,
+		"positionIdx": c.positionIdx(symbol, strings.ToLower(side), reduceOnly),
+	})
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	return models.OrderResult{
+		OrderID:     orderID,
+		ClientID:    linkID,
+		State:       "live",
+		Symbol:      normalizeSymbol(symbol),
+		Side:        strings.ToLower(side),
+		Type:        models.OrderTypeLimit,
+		Size:        size,
+		Price:       price,
+		TimeInForce: timeInForce,
+		ReduceOnly:  reduceOnly,
+	}, nil
+}
+
+func (c *bybitClient) CancelOrder(symbol, orderID string) error {
+	_, err := c.requestSigned(http.MethodPost, "/v5/order/cancel", nil, map[string]string{
+		"category": bybitCategory,
+		"symbol":   normalizeSymbol(symbol),
+		"orderId":  strings.TrimSpace(orderID),
+	})
+	return err
+}
+
+// AmendOrder newSize/newPrice <= 0 时不修改对应字段。
+func (c *bybitClient) AmendOrder(symbol, orderID string, newSize, newPrice float64) (models.OrderResult, error) {
+	payload := map[string]string{
+		"category": bybitCategory,
+		"symbol":   normalizeSymbol(symbol),
+		"orderId":  strings.TrimSpace(orderID),
+	}
+	if newSize > 0 {
+		newSize = c.instruments.roundSize(symbol, newSize)
+		payload["qty"] = formatSize(newSize)
+	}
+	if newPrice > 0 {
+		newPrice = c.instruments.roundPrice(symbol, newPrice)
+		payload["price"] = formatSize(newPrice)
+	}
+	if len(payload) == 3 {
+		return models.OrderResult{}, fmt.Errorf("amend 需要 newSize 或 newPrice")
+	}
+	if _, err := c.requestSigned(http.MethodPost, "/v5/order/amend", nil, payload); err != nil {
+		return models.OrderResult{}, err
+	}
+	return models.OrderResult{
+		OrderID: strings.TrimSpace(orderID),
+		State:   "live",
+		Symbol:  normalizeSymbol(symbol),
+		Type:    models.OrderTypeLimit,
+		Size:    newSize,
+		Price:   newPrice,
+	}, nil
+}
+
+func (c *bybitClient) FetchOpenOrders(symbol string) ([]models.OrderStatus, error) {
+	query := url.Values{}
+	query.Set("category", bybitCategory)
+	query.Set("symbol", normalizeSymbol(symbol))
+	query.Set("openOnly", "0")
+	rows, err := c.queryOrders("/v5/order/realtime", query)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.OrderStatus, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, row.toStatus())
+	}
+	return out, nil
+}
+
+func (c *bybitClient) PlaceProtectiveOrder(symbol, side, orderType string, size, triggerPrice float64) (models.OrderResult, error) {
+	bybitSide, err := toBybitSide(side)
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	if size <= 0 || triggerPrice <= 0 {
+		return models.OrderResult{}, fmt.Errorf("invalid size/trigger: %.8f/%.8f", size, triggerPrice)
+	}
+	size = c.instruments.roundSize(symbol, size)
+	triggerPrice = c.instruments.roundPrice(symbol, triggerPrice)
+	// triggerDirection: 1 价格上涨触发，2 价格下跌触发
+	rising := false
+	switch orderType {
+	case models.OrderTypeStopMarket:
+		rising = bybitSide == "Buy"
+	case models.OrderTypeTakeProfitMarket:
+		rising = bybitSide == "Sell"
+	default:
+		return models.OrderResult{}, fmt.Errorf("unsupported protective order type: %s", orderType)
+	}
+	direction := 2
+	if rising {
+		direction = 1
+	}
+	orderID, linkID, err := c.createOrder(map[string]any{
+		"category":         bybitCategory,
+		"symbol":           normalizeSymbol(symbol),
+		"side":             bybitSide,
+		"orderType":        "Market",
+		"qty":              formatSize(size),
+		"triggerPrice":     formatSize(triggerPrice),
+		"triggerDirection": direction,
+		"triggerBy":        "MarkPrice",
+		"reduceOnly":       true,
+		"closeOnTrigger":   true,
+		"positionIdx":      c.positionIdx(symbol, strings.ToLower(side), true),
+	})
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	return models.OrderResult{
+		OrderID:      orderID,
+		ClientID:     linkID,
+		State:        "live",
+		Symbol:       normalizeSymbol(symbol),
+		Side:         strings.ToLower(side),
+		Type:         orderType,
+		Size:         size,
+		TriggerPrice: triggerPrice,
+		ReduceOnly:   true,
+	}, nil
+}
+
+func (c *bybitClient) CancelProtectiveOrder(symbol, orderID string) error {
+	return c.CancelOrder(symbol, orderID)
+}
+
+// FetchProtectiveOrder Bybit 条件单与普通订单共用查询接口。
+func (c *bybitClient) FetchProtectiveOrder(symbol, orderID string) (*models.OrderStatus, error) {
+	return c.FetchOrder(symbol, orderID)
+}
+
+func (c *bybitClient) FetchInstrument(symbol string) (models.Instrument, error) {
+	return c.instruments.get(symbol)
+}
+
+// loadInstruments 分页读取 linear 合约规格。
+func (c *bybitClient) loadInstruments() ([]models.Instrument, error) {
+	out := []models.Instrument{}
+	cursor := ""
+	for page := 0; page < 10; page++ {
+		query := url.Values{}
+		query.Set("category", bybitCategory)
+		query.Set("limit", "1000")
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		data, err := c.requestPublic("/v5/market/instruments-info", query)
+		if err != nil {
+			return nil, err
+		}
+		var resp struct {
+			Result struct {
+				NextPageCursor string `json:"nextPageCursor"`
+				List           []struct {
+					Symbol       string `json:"symbol"`
+					ContractType string `json:"contractType"`
+					PriceFilter  struct {
+						TickSize string `json:"tickSize"`
+					} `json:"priceFilter"`
+					LotSizeFilter struct {
+						QtyStep          string `json:"qtyStep"`
+						MinOrderQty      string `json:"minOrderQty"`
+						MinNotionalValue string `json:"minNotionalValue"`
+					} `json:"lotSizeFilter"`
+					LeverageFilter struct {
+						MaxLeverage string `json:"maxLeverage"`
+					} `json:"leverageFilter"`
+				} `json:"list"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, err
+		}
+		for _, row := range resp.Result.List {
+			if row.ContractType != "" && row.ContractType != "LinearPerpetual" {
+				continue
+			}
+			out = append(out, models.Instrument{
+				Symbol:        row.Symbol,
+				TickSize:      toFloat(row.PriceFilter.TickSize),
+				StepSize:      toFloat(row.LotSizeFilter.QtyStep),
+				MinQty:        toFloat(row.LotSizeFilter.MinOrderQty),
+				MinNotional:   toFloat(row.LotSizeFilter.MinNotionalValue),
+				ContractValue: 1,
+				MaxLeverage:   toFloat(row.LeverageFilter.MaxLeverage),
+			})
+		}
+		cursor = strings.TrimSpace(resp.Result.NextPageCursor)
+		if cursor == "" {
+			break
+		}
+	}
+	return out, nil
+}
+
+// walkHistory Bybit 历史接口单次时间跨度不超过 7 天：按窗口从 since 推进到当前，窗口内按 cursor 从新到旧翻页。
+// 翻页中断或达到上限时返回 HistoryIncompleteError，Through 为未走完窗口的起点。
+func (c *bybitClient) walkHistory(path string, query url.Values, since time.Time, handle func(data []byte) (nextCursor string, err error)) error {
+	now := time.Now()
+	if earliest := now.Add(-historyLookback); since.Before(earliest) {
+		since = earliest
+	}
+	pages := 0
+	for start := since; start.Before(now); start = start.Add(historyLookback) {
+		cursor := ""
+		for {
+			if pages >= maxHistoryPages {
+				return &HistoryIncompleteError{Through: start}
+			}
+			pages++
+			q := url.Values{}
+			for k, v := range query {
+				q[k] = v
+			}
+			q.Set("startTime", strconv.FormatInt(start.UnixMilli(), 10))
+			q.Set("endTime", strconv.FormatInt(start.Add(historyLookback).UnixMilli()-1, 10))
+			if cursor != "" {
+				q.Set("cursor", cursor)
+			}
+			data, err := c.requestSigned(http.MethodGet, path, q, nil)
+			if err != nil {
+				return &HistoryIncompleteError{Through: start, Err: err}
+			}
+			if cursor, err = handle(data); err != nil {
+				return &HistoryIncompleteError{Through: start, Err: err}
+			}
+			if cursor == "" {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// FetchIncome 统一账户交易流水：TRADE 的 cashFlow 为平仓盈亏，fee/funding 为正表示支出。
+func (c *bybitClient) FetchIncome(since time.Time) ([]models.IncomeEvent, error) {
+	out := []models.IncomeEvent{}
+	query := url.Values{"accountType": {"UNIFIED"}, "category": {bybitCategory}, "limit": {"50"}}
+	err := c.walkHistory("/v5/account/transaction-log", query, since, func(data []byte) (string, error) {
+		var resp struct {
+			Result struct {
+				List []struct {
+					ID              string `json:"id"`
+					Symbol          string `json:"symbol"`
+					Type            string `json:"type"`
+					Change          string `json:"change"`
+					CashFlow        string `json:"cashFlow"`
+					Fee             string `json:"fee"`
+					Funding         string `json:"funding"`
+					Currency        string `json:"currency"`
+					TransactionTime string `json:"transactionTime"`
+					TradeID         string `json:"tradeId"`
+					OrderID         string `json:"orderId"`
+				} `json:"list"`
+				NextPageCursor string `json:"nextPageCursor"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return "", err
+		}
+		for _, row := range resp.Result.List {
+			base := models.IncomeEvent{
+				Symbol:  normalizeSymbol(row.Symbol),
+				Asset:   row.Currency,
+				TradeID: row.TradeID,
+				OrderID: row.OrderID,
+				Info:    row.Type,
+				Time:    time.UnixMilli(int64(toFloat(row.TransactionTime))),
+			}
+			add := func(suffix, typ string, amount float64) {
+				if amount == 0 {
+					return
+				}
+				ev := base
+				ev.ID = row.ID + suffix
+				ev.Type = typ
+				ev.Amount = amount
+				out = append(out, ev)
+			}
+			switch row.Type {
+			case "TRADE":
+				add("-pnl", models.IncomeRealizedPnL, toFloat(row.CashFlow))
+				add("-fee", models.IncomeCommission, -toFloat(row.Fee))
+			case "SETTLEMENT":
+				add("", models.IncomeFunding, -toFloat(row.Funding))
+			default:
+				add("", models.IncomeOther, toFloat(row.Change))
+			}
+		}
+		return resp.Result.NextPageCursor, nil
+	})
+	sortIncomeEvents(out)
+	return out, err
+}
+
+// FetchTradeFills 成交明细不含逐笔平仓盈亏，RealizedPnL 以流水为准。
+func (c *bybitClient) FetchTradeFills(symbol string, since time.Time) ([]models.TradeFill, error) {
+	out := []models.TradeFill{}
+	query := url.Values{"category": {bybitCategory}, "symbol": {normalizeSymbol(symbol)}, "limit": {"100"}}
+	err := c.walkHistory("/v5/execution/list", query, since, func(data []byte) (string, error) {
+		var resp struct {
+			Result struct {
+				List []struct {
+					ExecID      string `json:"execId"`
+					OrderID     string `json:"orderId"`
+					Symbol      string `json:"symbol"`
+					Side        string `json:"side"`
+					ExecQty     string `json:"execQty"`
+					ExecPrice   string `json:"execPrice"`
+					ExecFee     string `json:"execFee"`
+					FeeCurrency string `json:"feeCurrency"`
+					ExecType    string `json:"execType"`
+					IsMaker     bool   `json:"isMaker"`
+					ExecTime    string `json:"execTime"`
+				} `json:"list"`
+				NextPageCursor string `json:"nextPageCursor"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return "", err
+		}
+		for _, row := range resp.Result.List {
+			if row.ExecType != "" && row.ExecType != "Trade" {
+				continue
+			}
+			feeAsset := row.FeeCurrency
+			if feeAsset == "" {
+				feeAsset = "USDT"
+			}
+			out = append(out, models.TradeFill{
+				ID:       row.ExecID,
+				OrderID:  row.OrderID,
+				Symbol:   normalizeSymbol(row.Symbol),
+				Side:     strings.ToLower(row.Side),
+				Size:     toFloat(row.ExecQty),
+				Price:    toFloat(row.ExecPrice),
+				Fee:      toFloat(row.ExecFee),
+				FeeAsset: feeAsset,
+				Maker:    row.IsMaker,
+				Time:     time.UnixMilli(int64(toFloat(row.ExecTime))),
+			})
+		}
+		return resp.Result.NextPageCursor, nil
+	})
+	sortTradeFills(out)
+	return out, err
+}
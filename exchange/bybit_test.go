@@ -0,0 +1,307 @@
+package exchange
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"trade-go/config"
+	"trade-go/models"
+)
+
+const (
+	testBybitKey    = "test-key"
+	testBybitSecret = "test-secret"
+)
+
+// fakeBybit 本地 Bybit v5 替身：校验签名，维护订单与单向持仓，可按路径注入 retCode。
+type fakeBybit struct {
+	t        *testing.T
+	mu       sync.Mutex
+	orders   map[string]map[string]any
+	position map[string]any
+	nextID   int
+	failures map[string][]int
+	calls    map[string]int
+	badSigs  int
+	// accountMode 统一账户的账户级保证金模式
+	accountMode string
+}
+
+func newFakeBybit(t *testing.T) (*fakeBybit, *bybitClient) {
+	f := &fakeBybit{
+		t:           t,
+		orders:      map[string]map[string]any{},
+		failures:    map[string][]int{},
+		calls:       map[string]int{},
+		accountMode: "REGULAR_MARGIN",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	c := newBybitClient(&config.AppConfig{
+		BybitAPIKey:  testBybitKey,
+		BybitSecret:  testBybitSecret,
+		BybitBaseURL: srv.URL,
+	})
+	return f, c
+}
+
+// fail 令 path 的后续请求依次返回给定 retCode。
+func (f *fakeBybit) fail(path string, codes ...int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[path] = append(f.failures[path], codes...)
+}
+
+func (f *fakeBybit) callCount(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[path]
+}
+
+func (f *fakeBybit) reply(w http.ResponseWriter, code int, msg string, result any) {
+	if result == nil {
+		result = map[string]any{}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"retCode": code, "retMsg": msg, "result": result, "time": time.Now().UnixMilli()})
+}
+
+func (f *fakeBybit) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := r.URL.Path
+	f.calls[path]++
+
+	switch path {
+	case "/v5/market/time":
+		f.reply(w, 0, "OK", map[string]any{"timeSecond": fmt.Sprint(time.Now().Unix())})
+		return
+	case "/v5/market/instruments-info":
+		f.reply(w, 0, "OK", map[string]any{"list": []map[string]any{{
+			"symbol":         "BTCUSDT",
+			"contractType":   "LinearPerpetual",
+			"priceFilter":    map[string]string{"tickSize": "0.10"},
+			"lotSizeFilter":  map[string]string{"qtyStep": "0.001", "minOrderQty": "0.001", "minNotionalValue": "5"},
+			"leverageFilter": map[string]string{"maxLeverage": "100"},
+		}}})
+		return
+	}
+
+	// 私有接口：签名原文为 timestamp+apiKey+recvWindow+(query 或 body)
+	target := r.URL.RawQuery
+	if r.Method == http.MethodPost {
+		target = string(body)
+	}
+	ts := r.Header.Get("X-BAPI-TIMESTAMP")
+	mac := hmac.New(sha256.New, []byte(testBybitSecret))
+	mac.Write([]byte(ts + r.Header.Get("X-BAPI-API-KEY") + r.Header.Get("X-BAPI-RECV-WINDOW") + target))
+	if r.Header.Get("X-BAPI-API-KEY") != testBybitKey || r.Header.Get("X-BAPI-SIGN") != hex.EncodeToString(mac.Sum(nil)) {
+		f.badSigs++
+		f.reply(w, 10004, "error sign!", nil)
+		return
+	}
+	if codes := f.failures[path]; len(codes) > 0 {
+		f.failures[path] = codes[1:]
+		f.reply(w, codes[0], "injected", nil)
+		return
+	}
+
+	var payload map[string]any
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &payload); err != nil {
+			f.t.Errorf("%s body 非 JSON: %v", path, err)
+		}
+	}
+	q := r.URL.Query()
+	switch path {
+	case "/v5/order/create":
+		f.nextID++
+		id := fmt.Sprintf("ord-%d", f.nextID)
+		od := map[string]any{
+			"orderId":       id,
+			"orderLinkId":   "",
+			"symbol":        payload["symbol"],
+			"side":          payload["side"],
+			"orderType":     payload["orderType"],
+			"qty":           payload["qty"],
+			"price":         payload["price"],
+			"reduceOnly":    payload["reduceOnly"],
+			"orderStatus":   "New",
+			"cumExecQty":    "0",
+			"avgPrice":      "",
+			"stopOrderType": "",
+			"createdTime":   "1700000000000",
+			"updatedTime":   "1700000000000",
+		}
+		if payload["triggerPrice"] != nil {
+			od["stopOrderType"] = "StopLoss"
+			od["orderStatus"] = "Untriggered"
+		} else if payload["orderType"] == "Market" {
+			od["orderStatus"] = "Filled"
+			od["cumExecQty"] = payload["qty"]
+			od["avgPrice"] = "30000"
+			f.position = map[string]any{
+				"symbol": payload["symbol"], "side": payload["side"], "size": payload["qty"], "positionIdx": 0,
+				"tradeMode": 0, "avgPrice": "30000", "unrealisedPnl": "0", "leverage": "10",
+			}
+		}
+		f.orders[id] = od
+		f.reply(w, 0, "OK", map[string]any{"orderId": id, "orderLinkId": ""})
+	case "/v5/order/cancel":
+		od := f.orders[fmt.Sprint(payload["orderId"])]
+		if od == nil || od["orderStatus"] == "Filled" || od["orderStatus"] == "Cancelled" {
+			f.reply(w, 110001, "order not exists or too late to cancel", nil)
+			return
+		}
+		od["orderStatus"] = "Cancelled"
+		f.reply(w, 0, "OK", map[string]any{"orderId": od["orderId"]})
+	case "/v5/order/realtime", "/v5/order/history":
+		list := []map[string]any{}
+		for id, od := range f.orders {
+			if want := q.Get("orderId"); want != "" && want != id {
+				continue
+			}
+			open := od["orderStatus"] == "New" || od["orderStatus"] == "Untriggered" || od["orderStatus"] == "PartiallyFilled"
+			if (path == "/v5/order/realtime") != open {
+				continue
+			}
+			list = append(list, od)
+		}
+		f.reply(w, 0, "OK", map[string]any{"list": list})
+	case "/v5/position/list":
+		list := []map[string]any{}
+		if f.position != nil {
+			list = append(list, f.position)
+		}
+		f.reply(w, 0, "OK", map[string]any{"list": list})
+	case "/v5/position/set-leverage", "/v5/position/switch-isolated":
+		f.reply(w, 0, "OK", nil)
+	case "/v5/account/info":
+		f.reply(w, 0, "OK", map[string]any{"marginMode": f.accountMode})
+	default:
+		f.reply(w, 10001, "unknown path "+path, nil)
+	}
+}
+
+func TestBybitSignedRequestsAndOrderRoundTrip(t *testing.T) {
+	f, c := newFakeBybit(t)
+
+	od, err := c.PlaceMarketOrderWithResult("btc-usdt", "buy", 0.0125, false)
+	if err != nil {
+		t.Fatalf("市价单失败: %v", err)
+	}
+	if od.OrderID == "" || od.Symbol != "BTCUSDT" || od.Side != "buy" || od.Size != 0.012 {
+		t.Fatalf("市价单结果异常（数量应按步长 0.001 向下取整）: %+v", od)
+	}
+	st, err := c.FetchOrder("BTCUSDT", od.OrderID)
+	if err != nil || st == nil {
+		t.Fatalf("查询已成交订单失败: %v %v", st, err)
+	}
+	if st.State != "filled" || st.FilledSize != 0.012 || st.AvgPrice != 30000 || st.CreateTime == "" {
+		t.Fatalf("订单状态映射异常: %+v", st)
+	}
+
+	legs, err := c.FetchPositions("BTCUSDT")
+	if err != nil {
+		t.Fatalf("查询持仓失败: %v", err)
+	}
+	if len(legs) != 1 || legs[0].Side != "long" || legs[0].Size != 0.012 || legs[0].EntryPrice != 30000 || legs[0].MarginMode != models.MarginModeCross {
+		t.Fatalf("持仓映射异常: %+v", legs)
+	}
+
+	limit, err := c.PlaceLimitOrder("BTCUSDT", "buy", 0.01, 29000.04, models.TimeInForcePostOnly, false)
+	if err != nil {
+		t.Fatalf("限价单失败: %v", err)
+	}
+	if limit.Price != 29000 {
+		t.Fatalf("限价应按 tick 0.1 取整: %v", limit.Price)
+	}
+	open, err := c.FetchOpenOrders("BTCUSDT")
+	if err != nil || len(open) != 1 || open[0].OrderID != limit.OrderID || open[0].State != "live" || open[0].Type != models.OrderTypeLimit {
+		t.Fatalf("挂单列表异常: %+v %v", open, err)
+	}
+	if err := c.CancelOrder("BTCUSDT", limit.OrderID); err != nil {
+		t.Fatalf("撤单失败: %v", err)
+	}
+	st, err = c.FetchOrder("BTCUSDT", limit.OrderID)
+	if err != nil || st == nil || st.State != "canceled" {
+		t.Fatalf("撤单后状态异常: %+v %v", st, err)
+	}
+
+	sl, err := c.PlaceProtectiveOrder("BTCUSDT", "sell", models.OrderTypeStopMarket, 0.012, 28000)
+	if err != nil || !sl.ReduceOnly || sl.TriggerPrice != 28000 {
+		t.Fatalf("止损单异常: %+v %v", sl, err)
+	}
+	pst, err := c.FetchProtectiveOrder("BTCUSDT", sl.OrderID)
+	if err != nil || pst == nil || pst.Type != models.OrderTypeStopMarket || pst.State != "live" {
+		t.Fatalf("止损单状态异常: %+v %v", pst, err)
+	}
+
+	if f.badSigs != 0 {
+		t.Fatalf("存在 %d 个签名校验失败的请求", f.badSigs)
+	}
+}
+
+func TestBybitRetCodeMapping(t *testing.T) {
+	f, c := newFakeBybit(t)
+
+	f.fail("/v5/order/cancel", 110001)
+	err := c.CancelOrder("BTCUSDT", "missing")
+	if err == nil || !strings.Contains(err.Error(), "retCode=110001") {
+		t.Fatalf("非零 retCode 应返回错误: %v", err)
+	}
+
+	// 110043 杠杆未变化视为成功
+	f.fail("/v5/position/set-leverage", 110043)
+	if err := c.SetLeverage("BTCUSDT", 10); err != nil {
+		t.Fatalf("110043 应视为成功: %v", err)
+	}
+
+	// 10002 时间戳超窗：重新对时后重试一次
+	before := f.callCount("/v5/position/list")
+	f.fail("/v5/position/list", 10002)
+	if _, err := c.FetchPositions("BTCUSDT"); err != nil {
+		t.Fatalf("10002 重试后应成功: %v", err)
+	}
+	if got := f.callCount("/v5/position/list") - before; got != 2 {
+		t.Fatalf("10002 应重试一次，实际请求 %d 次", got)
+	}
+	f.fail("/v5/position/list", 10002, 10002)
+	if _, err := c.FetchPositions("BTCUSDT"); err == nil || !strings.Contains(err.Error(), "retCode=10002") {
+		t.Fatalf("连续 10002 应返回错误: %v", err)
+	}
+
+	// 签名错误按 retCode 返回
+	c.secret = "wrong"
+	if _, err := c.FetchOpenOrders("BTCUSDT"); err == nil || !strings.Contains(err.Error(), "retCode=10004") {
+		t.Fatalf("签名错误应返回 10004: %v", err)
+	}
+}
+
+func TestBybitUnifiedAccountMarginModeNotSwitched(t *testing.T) {
+	f, c := newFakeBybit(t)
+
+	// 统一账户 100028：账户模式与配置一致时视为成功
+	f.fail("/v5/position/switch-isolated", 100028)
+	if err := c.SetMarginMode("BTCUSDT", models.MarginModeCross); err != nil {
+		t.Fatalf("账户模式一致时应成功: %v", err)
+	}
+	// 不一致时报错，且不调用账户级切换
+	f.fail("/v5/position/switch-isolated", 100028)
+	err := c.SetMarginMode("BTCUSDT", models.MarginModeIsolated)
+	if err == nil || !strings.Contains(err.Error(), "ISOLATED_MARGIN") {
+		t.Fatalf("账户模式不一致时应返回错误: %v", err)
+	}
+	if n := f.callCount("/v5/account/set-margin-mode"); n != 0 {
+		t.Fatalf("不应切换账户级保证金模式，实际调用 %d 次", n)
+	}
+}
@@ -2,6 +2,7 @@ package exchange
 
 import (
 	"fmt"
+	"time"
 	"trade-go/config"
 	"trade-go/models"
 )
@@ -9,19 +10,26 @@ import (
 // Client 统一交易所客户端，对外暴露稳定接口。
 type Client struct {
 	exchange string
+	env      string
 	impl     backend
 }
 
 func NewClient() *Client {
 	exName := normalizeExchangeName("")
+	env := EnvMainnet
 	if config.Config != nil {
 		exName = normalizeExchangeName(config.Config.ActiveExchange)
+		env = config.Config.ExchangeEnv
 	}
 
 	var impl backend
 	switch exName {
 	case "okx":
 		impl = newOKXClient(config.Config)
+	case "bybit":
+		impl = newBybitClient(config.Config)
+	case "sim":
+		return NewSimClient(DefaultSim())
 	default:
 		exName = "binance"
 		impl = newBinanceClient(config.Config)
@@ -29,6 +37,7 @@ func NewClient() *Client {
 
 	return &Client{
 		exchange: exName,
+		env:      ResolveEndpoints(exName, env).Env,
 		impl:     impl,
 	}
 }
@@ -40,6 +49,14 @@ func (c *Client) ActiveExchange() string {
 	return normalizeExchangeName(c.exchange)
 }
 
+// Environment 当前接入环境：mainnet / testnet / demo。
+func (c *Client) Environment() string {
+	if c == nil || c.env == "" {
+		return EnvMainnet
+	}
+	return c.env
+}
+
 func (c *Client) FetchOHLCV(symbol, timeframe string, limit int) ([]models.OHLCV, error) {
 	if c == nil || c.impl == nil {
 		return nil, fmt.Errorf("exchange client not initialized")
@@ -47,6 +64,41 @@ func (c *Client) FetchOHLCV(symbol, timeframe string, limit int) ([]models.OHLCV
 	return c.impl.FetchOHLCV(symbol, timeframe, limit)
 }
 
+func (c *Client) FetchMarketStats(symbol string) (models.MarketStats, error) {
+	if c == nil || c.impl == nil {
+		return models.MarketStats{}, fmt.Errorf("exchange client not initialized")
+	}
+	return c.impl.FetchMarketStats(symbol)
+}
+
+func (c *Client) FetchOrderBook(symbol string, depth int) (models.OrderBook, error) {
+	if c == nil || c.impl == nil {
+		return models.OrderBook{}, fmt.Errorf("exchange client not initialized")
+	}
+	return c.impl.FetchOrderBook(symbol, depth)
+}
+
+func (c *Client) FetchBalances() ([]models.AssetBalance, error) {
+	if c == nil || c.impl == nil {
+		return nil, fmt.Errorf("exchange client not initialized")
+	}
+	return c.impl.FetchBalances()
+}
+
+func (c *Client) FetchIncome(since time.Time) ([]models.IncomeEvent, error) {
+	if c == nil || c.impl == nil {
+		return nil, fmt.Errorf("exchange client not initialized")
+	}
+	return c.impl.FetchIncome(since)
+}
+
+func (c *Client) FetchTradeFills(symbol string, since time.Time) ([]models.TradeFill, error) {
+	if c == nil || c.impl == nil {
+		return nil, fmt.Errorf("exchange client not initialized")
+	}
+	return c.impl.FetchTradeFills(symbol, since)
+}
+
 func (c *Client) FetchBalance() (float64, error) {
 	if c == nil || c.impl == nil {
 		return 0, fmt.Errorf("exchange client not initialized")
@@ -68,11 +120,40 @@ func (c *Client) SetLeverage(symbol string, leverage int) error {
 	return c.impl.SetLeverage(symbol, leverage)
 }
 
+// SetMarginMode 设置交易对的保证金模式（cross / isolated）。
+func (c *Client) SetMarginMode(symbol, mode string) error {
+	if c == nil || c.impl == nil {
+		return fmt.Errorf("exchange client not initialized")
+	}
+	return c.impl.SetMarginMode(symbol, models.NormalizeMarginMode(mode))
+}
+
+// FetchPosition 返回主腿持仓（见 models.PrimaryPosition）；双向持仓需完整信息时使用 FetchPositions。
 func (c *Client) FetchPosition(symbol string) (*models.Position, error) {
+	legs, err := c.FetchPositions(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return models.PrimaryPosition(legs), nil
+}
+
+func (c *Client) FetchPositions(symbol string) ([]models.Position, error) {
 	if c == nil || c.impl == nil {
 		return nil, fmt.Errorf("exchange client not initialized")
 	}
-	return c.impl.FetchPosition(symbol)
+	return c.impl.FetchPositions(symbol)
+}
+
+// FetchAllPositions 账户全部持仓，包括非本系统交易对。
+func (c *Client) FetchAllPositions() ([]models.Position, error) {
+	return c.FetchPositions("")
+}
+
+func (c *Client) FetchPositionMode(symbol string) (string, error) {
+	if c == nil || c.impl == nil {
+		return "", fmt.Errorf("exchange client not initialized")
+	}
+	return c.impl.FetchPositionMode(symbol)
 }
 
 func (c *Client) PlaceMarketOrder(symbol, side string, size float64, reduceOnly bool) error {
@@ -95,3 +176,59 @@ func (c *Client) FetchOrder(symbol, orderID string) (*models.OrderStatus, error)
 	}
 	return c.impl.FetchOrder(symbol, orderID)
 }
+
+func (c *Client) PlaceLimitOrder(symbol, side string, size, price float64, timeInForce string, reduceOnly bool) (models.OrderResult, error) {
+	if c == nil || c.impl == nil {
+		return models.OrderResult{}, fmt.Errorf("exchange client not initialized")
+	}
+	return c.impl.PlaceLimitOrder(symbol, side, size, price, timeInForce, reduceOnly)
+}
+
+func (c *Client) CancelOrder(symbol, orderID string) error {
+	if c == nil || c.impl == nil {
+		return fmt.Errorf("exchange client not initialized")
+	}
+	return c.impl.CancelOrder(symbol, orderID)
+}
+
+func (c *Client) AmendOrder(symbol, orderID string, newSize, newPrice float64) (models.OrderResult, error) {
+	if c == nil || c.impl == nil {
+		return models.OrderResult{}, fmt.Errorf("exchange client not initialized")
+	}
+	return c.impl.AmendOrder(symbol, orderID, newSize, newPrice)
+}
+
+func (c *Client) FetchOpenOrders(symbol string) ([]models.OrderStatus, error) {
+	if c == nil || c.impl == nil {
+		return nil, fmt.Errorf("exchange client not initialized")
+	}
+	return c.impl.FetchOpenOrders(symbol)
+}
+
+func (c *Client) PlaceProtectiveOrder(symbol, side, orderType string, size, triggerPrice float64) (models.OrderResult, error) {
+	if c == nil || c.impl == nil {
+		return models.OrderResult{}, fmt.Errorf("exchange client not initialized")
+	}
+	return c.impl.PlaceProtectiveOrder(symbol, side, orderType, size, triggerPrice)
+}
+
+func (c *Client) CancelProtectiveOrder(symbol, orderID string) error {
+	if c == nil || c.impl == nil {
+		return fmt.Errorf("exchange client not initialized")
+	}
+	return c.impl.CancelProtectiveOrder(symbol, orderID)
+}
+
+func (c *Client) FetchProtectiveOrder(symbol, orderID string) (*models.OrderStatus, error) {
+	if c == nil || c.impl == nil {
+		return nil, fmt.Errorf("exchange client not initialized")
+	}
+	return c.impl.FetchProtectiveOrder(symbol, orderID)
+}
+
+func (c *Client) FetchInstrument(symbol string) (models.Instrument, error) {
+	if c == nil || c.impl == nil {
+		return models.Instrument{}, fmt.Errorf("exchange client not initialized")
+	}
+	return c.impl.FetchInstrument(symbol)
+}
@@ -0,0 +1,134 @@
+package exchange
+
+import (
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+const serverClockResyncInterval = 10 * time.Minute
+
+// ClockStatus 本地时钟与交易所服务器时间的偏差，供 /api/system/runtime 展示。
+type ClockStatus struct {
+	Exchange  string    `json:"exchange"`
+	OffsetMs  int64     `json:"offset_ms"`
+	RTTMs     int64     `json:"rtt_ms"`
+	SyncedAt  time.Time `json:"synced_at,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// serverClock 记录交易所服务器时间偏移，签名时间戳使用本地时间加偏移。
+type serverClock struct {
+	exchange string
+	syncMu   sync.Mutex
+	mu       sync.RWMutex
+	offset   time.Duration
+	rtt      time.Duration
+	syncedAt time.Time
+	stale    bool
+	lastErr  string
+}
+
+var (
+	serverClocksMu sync.Mutex
+	serverClocks   = map[string]*serverClock{}
+)
+
+// sharedServerClock 同一交易所的客户端共用一个时钟偏移。
+func sharedServerClock(exchange string) *serverClock {
+	serverClocksMu.Lock()
+	defer serverClocksMu.Unlock()
+	if c, ok := serverClocks[exchange]; ok {
+		return c
+	}
+	c := &serverClock{exchange: exchange}
+	serverClocks[exchange] = c
+	return c
+}
+
+// ClockStatuses 返回已同步过的交易所时钟偏差。
+func ClockStatuses() []ClockStatus {
+	serverClocksMu.Lock()
+	items := make([]*serverClock, 0, len(serverClocks))
+	for _, c := range serverClocks {
+		items = append(items, c)
+	}
+	serverClocksMu.Unlock()
+	out := make([]ClockStatus, 0, len(items))
+	for _, c := range items {
+		c.mu.RLock()
+		out = append(out, ClockStatus{
+			Exchange:  c.exchange,
+			OffsetMs:  c.offset.Milliseconds(),
+			RTTMs:     c.rtt.Milliseconds(),
+			SyncedAt:  c.syncedAt,
+			LastError: c.lastErr,
+		})
+		c.mu.RUnlock()
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Exchange < out[j].Exchange })
+	return out
+}
+
+// now 返回按服务器时间校正后的当前时间；偏移过期时先同步，同步失败沿用旧偏移。
+func (c *serverClock) now(fetch func() (time.Time, error)) time.Time {
+	c.mu.RLock()
+	needSync := c.stale || c.syncedAt.IsZero() || time.Since(c.syncedAt) > serverClockResyncInterval
+	c.mu.RUnlock()
+	if needSync {
+		c.sync(fetch)
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Now().Add(c.offset)
+}
+
+func (c *serverClock) sync(fetch func() (time.Time, error)) {
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+	// 并发等待期间其他调用已完成同步
+	c.mu.RLock()
+	fresh := !c.stale && !c.syncedAt.IsZero() && time.Since(c.syncedAt) <= serverClockResyncInterval
+	c.mu.RUnlock()
+	if fresh {
+		return
+	}
+
+	start := time.Now()
+	serverTime, err := fetch()
+	end := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		c.lastErr = err.Error()
+		// 避免每次签名都重试同步
+		c.syncedAt = end
+		c.stale = false
+		return
+	}
+	rtt := end.Sub(start)
+	c.offset = serverTime.Sub(start.Add(rtt / 2))
+	c.rtt = rtt
+	c.syncedAt = end
+	c.stale = false
+	c.lastErr = ""
+}
+
+// invalidate 收到时间戳错误后标记需要重新同步。
+func (c *serverClock) invalidate() {
+	c.mu.Lock()
+	c.stale = true
+	c.mu.Unlock()
+}
+
+// timestampErrorPattern 各交易所的时间戳超出接收窗口错误码，按完整错误码匹配（retCode=100028 不是 10002）。
+var timestampErrorPattern = regexp.MustCompile(`"code":-1021\b|code=50102\b|"code":"50102"|retCode=10002\b`)
+
+// isTimestampError 识别各交易所的时间戳超出接收窗口错误。
+func isTimestampError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return timestampErrorPattern.MatchString(err.Error())
+}
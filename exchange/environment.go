@@ -0,0 +1,71 @@
+package exchange
+
+import "strings"
+
+// 交易所环境：mainnet 实盘、testnet 测试网、demo 模拟盘
+const (
+	EnvMainnet = "mainnet"
+	EnvTestnet = "testnet"
+	EnvDemo    = "demo"
+)
+
+// Endpoints 某交易所在指定环境下的接入点。
+type Endpoints struct {
+	Env        string
+	REST       string
+	PublicWS   string
+	PrivateWS  string // Binance 为基础地址，连接时追加 /ws/<listenKey>
+	BusinessWS string // OKX K 线频道只在 business 端点提供
+	Simulated  bool   // OKX 模拟盘需附带 x-simulated-trading: 1
+}
+
+// scopedName 非主网环境的限流器/时钟与主网分开统计。
+func scopedName(exchange, env string) string {
+	if env == "" || env == EnvMainnet {
+		return exchange
+	}
+	return exchange + "-" + env
+}
+
+func NormalizeEnvironment(env string) string {
+	switch strings.ToLower(strings.TrimSpace(env)) {
+	case EnvTestnet:
+		return EnvTestnet
+	case EnvDemo:
+		return EnvDemo
+	default:
+		return EnvMainnet
+	}
+}
+
+// ResolveEndpoints Binance 无独立 demo 环境，demo 按测试网处理；OKX 测试网即模拟盘。
+func ResolveEndpoints(exchange, env string) Endpoints {
+	env = NormalizeEnvironment(env)
+	switch normalizeExchangeName(exchange) {
+	case "okx":
+		ep := Endpoints{Env: env, REST: "https://www.okx.com", PublicWS: "wss://ws.okx.com:8443/ws/v5/public", PrivateWS: "wss://ws.okx.com:8443/ws/v5/private", BusinessWS: "wss://ws.okx.com:8443/ws/v5/business"}
+		if env != EnvMainnet {
+			ep.Env = EnvDemo
+			ep.PublicWS = "wss://wspap.okx.com:8443/ws/v5/public"
+			ep.PrivateWS = "wss://wspap.okx.com:8443/ws/v5/private"
+			ep.BusinessWS = "wss://wspap.okx.com:8443/ws/v5/business"
+			ep.Simulated = true
+		}
+		return ep
+	case "bybit":
+		switch env {
+		case EnvTestnet:
+			return Endpoints{Env: env, REST: "https://api-testnet.bybit.com", PublicWS: "wss://stream-testnet.bybit.com/v5/public/linear", PrivateWS: "wss://stream-testnet.bybit.com/v5/private"}
+		case EnvDemo:
+			// Bybit 模拟盘只提供私有接口，公共行情沿用主网
+			return Endpoints{Env: env, REST: "https://api-demo.bybit.com", PublicWS: "wss://stream.bybit.com/v5/public/linear", PrivateWS: "wss://stream-demo.bybit.com/v5/private"}
+		default:
+			return Endpoints{Env: env, REST: "https://api.bybit.com", PublicWS: "wss://stream.bybit.com/v5/public/linear", PrivateWS: "wss://stream.bybit.com/v5/private"}
+		}
+	default:
+		if env != EnvMainnet {
+			return Endpoints{Env: EnvTestnet, REST: "https://testnet.binancefuture.com", PublicWS: "wss://stream.binancefuture.com", PrivateWS: "wss://stream.binancefuture.com"}
+		}
+		return Endpoints{Env: env, REST: "https://fapi.binance.com", PublicWS: "wss://fstream.binance.com", PrivateWS: "wss://fstream.binance.com"}
+	}
+}
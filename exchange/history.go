@@ -0,0 +1,53 @@
+package exchange
+
+import (
+	"fmt"
+	"time"
+	"trade-go/models"
+)
+
+// ohlcvRanger 支持按时间区间拉取历史 K 线的交易所后端。
+type ohlcvRanger interface {
+	FetchOHLCVRange(symbol, timeframe string, start, end time.Time) ([]models.OHLCV, error)
+}
+
+// FetchOHLCVRange 拉取开盘时间在 [start, end] 内的 K 线并按时间升序返回；
+// 中途失败时连同已取得的部分一并返回。
+func (c *Client) FetchOHLCVRange(symbol, timeframe string, start, end time.Time) ([]models.OHLCV, error) {
+	if c == nil || c.impl == nil {
+		return nil, fmt.Errorf("exchange client not initialized")
+	}
+	ranger, ok := c.impl.(ohlcvRanger)
+	if !ok {
+		return nil, fmt.Errorf("%s 暂不支持历史K线区间查询", c.ActiveExchange())
+	}
+	if end.Before(start) {
+		return nil, nil
+	}
+	return ranger.FetchOHLCVRange(symbol, timeframe, start, end)
+}
+
+// KlineSource 历史 K 线归档的数据来源标识：主网为交易所名，测试网/模拟盘追加环境后缀，避免与主网行情混存。
+func (c *Client) KlineSource() string {
+	if env := c.Environment(); env != EnvMainnet {
+		return c.ActiveExchange() + "-" + env
+	}
+	return c.ActiveExchange()
+}
+
+// flattenCandlePages 自新向旧分页取得的各页（页内升序）拼接为升序，并丢弃早于 startMs 的 K 线。
+func flattenCandlePages(pages [][]models.OHLCV, startMs int64) []models.OHLCV {
+	var out []models.OHLCV
+	for i := len(pages) - 1; i >= 0; i-- {
+		for _, k := range pages[i] {
+			if k.Timestamp.UnixMilli() < startMs {
+				continue
+			}
+			if n := len(out); n > 0 && !k.Timestamp.After(out[n-1].Timestamp) {
+				continue
+			}
+			out = append(out, k)
+		}
+	}
+	return out
+}
@@ -0,0 +1,82 @@
+package exchange
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"trade-go/models"
+)
+
+const instrumentCacheTTL = time.Hour
+
+// instrumentCatalog 按交易所缓存全部合约规格，过期后整体重新拉取。
+type instrumentCatalog struct {
+	mu       sync.RWMutex
+	load     func() ([]models.Instrument, error)
+	ttl      time.Duration
+	loadedAt time.Time
+	items    map[string]models.Instrument
+}
+
+func newInstrumentCatalog(load func() ([]models.Instrument, error)) *instrumentCatalog {
+	return &instrumentCatalog{load: load, ttl: instrumentCacheTTL}
+}
+
+func (c *instrumentCatalog) get(symbol string) (models.Instrument, error) {
+	key := normalizeSymbol(symbol)
+	c.mu.RLock()
+	inst, ok := c.items[key]
+	fresh := !c.loadedAt.IsZero() && time.Since(c.loadedAt) < c.ttl
+	c.mu.RUnlock()
+	if ok && fresh {
+		return inst, nil
+	}
+
+	if err := c.refresh(); err != nil {
+		// 拉取失败时沿用过期缓存，避免短暂网络问题阻断下单。
+		if ok {
+			return inst, nil
+		}
+		return models.Instrument{}, err
+	}
+	c.mu.RLock()
+	inst, ok = c.items[key]
+	c.mu.RUnlock()
+	if !ok {
+		return models.Instrument{}, fmt.Errorf("未找到交易对规格: %s", key)
+	}
+	return inst, nil
+}
+
+func (c *instrumentCatalog) refresh() error {
+	rows, err := c.load()
+	if err != nil {
+		return err
+	}
+	items := make(map[string]models.Instrument, len(rows))
+	for _, row := range rows {
+		items[normalizeSymbol(row.Symbol)] = row
+	}
+	c.mu.Lock()
+	c.items = items
+	c.loadedAt = time.Now()
+	c.mu.Unlock()
+	return nil
+}
+
+// roundSize 下单前按步长向下取整；规格不可用时原样返回，由交易所最终校验。
+func (c *instrumentCatalog) roundSize(symbol string, size float64) float64 {
+	inst, err := c.get(symbol)
+	if err != nil {
+		return size
+	}
+	return inst.RoundSize(size)
+}
+
+func (c *instrumentCatalog) roundPrice(symbol string, price float64) float64 {
+	inst, err := c.get(symbol)
+	if err != nil {
+		return price
+	}
+	return inst.RoundPrice(price)
+}
@@ -13,35 +13,67 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"trade-go/config"
 	"trade-go/models"
-)
 
-const okxBaseURL = "https://www.okx.com"
+	"github.com/gorilla/websocket"
+)
 
 type okxClient struct {
-	apiKey     string
-	secret     string
-	passphrase string
-	httpClient *http.Client
+	env         string
+	baseURL     string
+	simulated   bool
+	apiKey      string
+	secret      string
+	passphrase  string
+	httpClient  *http.Client
+	instruments *instrumentCatalog
+	limiter     *rateLimiter
+	clock       *serverClock
+	posMode     *positionModeCache
+
+	// OKX 保证金模式随订单 tdMode / 杠杆 mgnMode 传递，按交易对记录
+	mu          sync.Mutex
+	marginModes map[string]string
+	// defaultMarginMode 未调用 SetMarginMode 的交易对使用的模式，取自配置，客户端重建后仍然生效
+	defaultMarginMode string
+	leverages         map[string]int
 }
 
 func newOKXClient(cfg *config.AppConfig) *okxClient {
 	key := ""
 	secret := ""
 	passphrase := ""
+	env := EnvMainnet
+	marginMode := models.MarginModeCross
 	if cfg != nil {
 		key = strings.TrimSpace(cfg.OKXAPIKey)
 		secret = strings.TrimSpace(cfg.OKXSecret)
 		passphrase = strings.TrimSpace(cfg.OKXPassword)
+		env = cfg.ExchangeEnv
+		marginMode = models.NormalizeMarginMode(cfg.Trade.MarginMode)
 	}
-	return &okxClient{
+	ep := ResolveEndpoints("okx", env)
+	c := &okxClient{
+		env:        ep.Env,
+		baseURL:    ep.REST,
+		simulated:  ep.Simulated,
 		apiKey:     key,
 		secret:     secret,
 		passphrase: passphrase,
 		httpClient: &http.Client{Timeout: 15 * time.Second},
+		limiter:    sharedRateLimiter("okx", ep.Env, key),
+		clock:      sharedServerClock(scopedName("okx", ep.Env)),
+
+		marginModes:       map[string]string{},
+		defaultMarginMode: marginMode,
+		leverages:         map[string]int{},
 	}
+	c.instruments = newInstrumentCatalog(c.loadInstruments)
+	c.posMode = newPositionModeCache(c.loadPositionMode)
+	return c
 }
 
 func toOKXInstID(symbol string) string {
@@ -98,22 +130,25 @@ func (c *okxClient) signPayload(preHash string) string {
 }
 
 func (c *okxClient) requestPublic(path string, values url.Values) ([]byte, error) {
-	fullURL := okxBaseURL + path
+	fullURL := c.baseURL + path
 	if len(values) > 0 {
 		fullURL += "?" + values.Encode()
 	}
-	req, err := http.NewRequest(http.MethodGet, fullURL, nil)
-	if err != nil {
-		return nil, err
-	}
-	resp, err := c.httpClient.Do(req)
+	status, body, err := c.limiter.do(c.httpClient, http.MethodGet, 1, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, fullURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if c.simulated {
+			req.Header.Set("x-simulated-trading", "1")
+		}
+		return req, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("okx http %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	if status >= 300 {
+		return nil, fmt.Errorf("okx http %d: %s", status, strings.TrimSpace(string(body)))
 	}
 	var envelope struct {
 		Code string `json:"code"`
@@ -128,7 +163,17 @@ func (c *okxClient) requestPublic(path string, values url.Values) ([]byte, error
 	return body, nil
 }
 
+// requestSigned 时间戳过期（50102）时重新同步服务器时间后重试一次。
 func (c *okxClient) requestSigned(method, path string, query url.Values, body []byte) ([]byte, error) {
+	data, err := c.requestSignedOnce(method, path, query, body)
+	if isTimestampError(err) {
+		c.clock.invalidate()
+		data, err = c.requestSignedOnce(method, path, query, body)
+	}
+	return data, err
+}
+
+func (c *okxClient) requestSignedOnce(method, path string, query url.Values, body []byte) ([]byte, error) {
 	method = strings.ToUpper(strings.TrimSpace(method))
 	if method == "" {
 		method = http.MethodGet
@@ -142,31 +187,32 @@ func (c *okxClient) requestSigned(method, path string, query url.Values, body []
 	if len(body) > 0 {
 		bodyStr = string(body)
 	}
-	ts := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
-	signature := c.signPayload(ts + method + requestPath + bodyStr)
-
-	fullURL := okxBaseURL + requestPath
-	var reader io.Reader
-	if len(body) > 0 {
-		reader = bytes.NewReader(body)
-	}
-	req, err := http.NewRequest(method, fullURL, reader)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("OK-ACCESS-KEY", c.apiKey)
-	req.Header.Set("OK-ACCESS-SIGN", signature)
-	req.Header.Set("OK-ACCESS-TIMESTAMP", ts)
-	req.Header.Set("OK-ACCESS-PASSPHRASE", c.passphrase)
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := c.httpClient.Do(req)
+	fullURL := c.baseURL + requestPath
+	status, respBody, err := c.limiter.do(c.httpClient, method, 1, func() (*http.Request, error) {
+		ts := c.clock.now(c.fetchServerTime).UTC().Format("2006-01-02T15:04:05.000Z")
+		var reader io.Reader
+		if len(body) > 0 {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequest(method, fullURL, reader)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("OK-ACCESS-KEY", c.apiKey)
+		req.Header.Set("OK-ACCESS-SIGN", c.signPayload(ts+method+requestPath+bodyStr))
+		req.Header.Set("OK-ACCESS-TIMESTAMP", ts)
+		req.Header.Set("OK-ACCESS-PASSPHRASE", c.passphrase)
+		req.Header.Set("Content-Type", "application/json")
+		if c.simulated {
+			req.Header.Set("x-simulated-trading", "1")
+		}
+		return req, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	respBody, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("okx http %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	if status >= 300 {
+		return nil, fmt.Errorf("okx http %d: %s", status, strings.TrimSpace(string(respBody)))
 	}
 	var envelope struct {
 		Code string `json:"code"`
@@ -181,6 +227,29 @@ func (c *okxClient) requestSigned(method, path string, query url.Values, body []
 	return respBody, nil
 }
 
+func (c *okxClient) fetchServerTime() (time.Time, error) {
+	data, err := c.requestPublic("/api/v5/public/time", nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var resp struct {
+		Data []struct {
+			Ts string `json:"ts"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return time.Time{}, err
+	}
+	if len(resp.Data) == 0 {
+		return time.Time{}, fmt.Errorf("okx server time empty")
+	}
+	ms, err := strconv.ParseInt(strings.TrimSpace(resp.Data[0].Ts), 10, 64)
+	if err != nil || ms <= 0 {
+		return time.Time{}, fmt.Errorf("okx server time invalid: %s", resp.Data[0].Ts)
+	}
+	return time.UnixMilli(ms), nil
+}
+
 func (c *okxClient) FetchOHLCV(symbol, timeframe string, limit int) ([]models.OHLCV, error) {
 	vals := url.Values{}
 	vals.Set("instId", toOKXInstID(symbol))
@@ -196,6 +265,44 @@ func (c *okxClient) FetchOHLCV(symbol, timeframe string, limit int) ([]models.OH
 	if err != nil {
 		return nil, err
 	}
+	return parseOKXCandles(data)
+}
+
+// FetchOHLCVRange 历史 K 线接口按 after（早于该时间）自新向旧分页，每页 100 根。
+func (c *okxClient) FetchOHLCVRange(symbol, timeframe string, start, end time.Time) ([]models.OHLCV, error) {
+	const pageSize = 100
+	startMs := start.UnixMilli()
+	after := end.UnixMilli() + 1
+	var pages [][]models.OHLCV
+	for {
+		vals := url.Values{}
+		vals.Set("instId", toOKXInstID(symbol))
+		vals.Set("bar", toOKXBar(timeframe))
+		vals.Set("after", strconv.FormatInt(after, 10))
+		vals.Set("limit", strconv.Itoa(pageSize))
+		data, err := c.requestPublic("/api/v5/market/history-candles", vals)
+		if err != nil {
+			return flattenCandlePages(pages, startMs), err
+		}
+		page, err := parseOKXCandles(data)
+		if err != nil {
+			return flattenCandlePages(pages, startMs), err
+		}
+		if len(page) == 0 {
+			break
+		}
+		pages = append(pages, page)
+		oldest := page[0].Timestamp.UnixMilli()
+		if oldest <= startMs || oldest >= after || len(page) < pageSize {
+			break
+		}
+		after = oldest
+	}
+	return flattenCandlePages(pages, startMs), nil
+}
+
+// parseOKXCandles OKX 返回按时间倒序，转为升序。
+func parseOKXCandles(data []byte) ([]models.OHLCV, error) {
 	var resp struct {
 		Code string     `json:"code"`
 		Msg  string     `json:"msg"`
@@ -231,6 +338,111 @@ func (c *okxClient) FetchOHLCV(symbol, timeframe string, limit int) ([]models.OH
 	return candles, nil
 }
 
+// FetchMarketStats OKX 将资金费率、标记价格、指数价格与持仓量拆分在不同公共接口，逐个查询后合并。
+func (c *okxClient) FetchMarketStats(symbol string) (models.MarketStats, error) {
+	instID := toOKXInstID(symbol)
+	out := models.MarketStats{Symbol: normalizeSymbol(symbol), UpdatedAt: time.Now()}
+
+	var funding struct {
+		Data []struct {
+			FundingRate     string `json:"fundingRate"`
+			NextFundingRate string `json:"nextFundingRate"`
+			FundingTime     string `json:"fundingTime"`
+		} `json:"data"`
+	}
+	if err := c.getPublicJSON("/api/v5/public/funding-rate", url.Values{"instId": {instID}}, &funding); err != nil {
+		return out, err
+	}
+	if len(funding.Data) > 0 {
+		out.FundingRate = toFloat(funding.Data[0].FundingRate)
+		out.NextFundingRate = toFloat(funding.Data[0].NextFundingRate)
+		if ms := int64(toFloat(funding.Data[0].FundingTime)); ms > 0 {
+			out.NextFundingTime = time.UnixMilli(ms)
+		}
+	}
+
+	var mark struct {
+		Data []struct {
+			MarkPx string `json:"markPx"`
+		} `json:"data"`
+	}
+	if err := c.getPublicJSON("/api/v5/public/mark-price", url.Values{"instType": {"SWAP"}, "instId": {instID}}, &mark); err != nil {
+		return out, err
+	}
+	if len(mark.Data) > 0 {
+		out.MarkPrice = toFloat(mark.Data[0].MarkPx)
+	}
+
+	var index struct {
+		Data []struct {
+			IdxPx string `json:"idxPx"`
+		} `json:"data"`
+	}
+	if err := c.getPublicJSON("/api/v5/market/index-tickers", url.Values{"instId": {strings.TrimSuffix(instID, "-SWAP")}}, &index); err != nil {
+		return out, err
+	}
+	if len(index.Data) > 0 {
+		out.IndexPrice = toFloat(index.Data[0].IdxPx)
+	}
+
+	var oi struct {
+		Data []struct {
+			OiCcy string `json:"oiCcy"`
+		} `json:"data"`
+	}
+	if err := c.getPublicJSON("/api/v5/public/open-interest", url.Values{"instType": {"SWAP"}, "instId": {instID}}, &oi); err != nil {
+		return out, err
+	}
+	if len(oi.Data) > 0 {
+		out.OpenInterest = toFloat(oi.Data[0].OiCcy)
+	}
+	return out, nil
+}
+
+// FetchOrderBook OKX 盘口数量单位为合约张数，按 ctVal 折算为标的币数量；单次最多 400 档。
+func (c *okxClient) FetchOrderBook(symbol string, depth int) (models.OrderBook, error) {
+	sz := depth
+	if sz <= 0 {
+		sz = 20
+	}
+	if sz > 400 {
+		sz = 400
+	}
+	var resp struct {
+		Data []struct {
+			Asks [][]string `json:"asks"`
+			Bids [][]string `json:"bids"`
+			Ts   string     `json:"ts"`
+		} `json:"data"`
+	}
+	vals := url.Values{"instId": {toOKXInstID(symbol)}, "sz": {strconv.Itoa(sz)}}
+	if err := c.getPublicJSON("/api/v5/market/books", vals, &resp); err != nil {
+		return models.OrderBook{}, err
+	}
+	if len(resp.Data) == 0 {
+		return models.OrderBook{}, fmt.Errorf("okx 盘口为空: %s", toOKXInstID(symbol))
+	}
+	ctVal := 1.0
+	if inst, err := c.instruments.get(symbol); err == nil && inst.ContractValue > 0 {
+		ctVal = inst.ContractValue
+	}
+	row := resp.Data[0]
+	return models.OrderBook{
+		Symbol:    normalizeSymbol(symbol),
+		Bids:      parseBookLevels(row.Bids, depth, ctVal),
+		Asks:      parseBookLevels(row.Asks, depth, ctVal),
+		Timestamp: time.UnixMilli(int64(toFloat(row.Ts))),
+	}, nil
+}
+
+func (c *okxClient) getPublicJSON(path string, values url.Values, out any) error {
+	data, err := c.requestPublic(path, values)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
 func (c *okxClient) FetchBalance() (float64, error) {
 	query := url.Values{}
 	query.Set("ccy", "USDT")
@@ -277,6 +489,54 @@ func (c *okxClient) FetchBalance() (float64, error) {
 	return 0, nil
 }
 
+// FetchBalances 统一账户下各币种均可作为保证金；eq 为币种权益（含未实现盈亏），eqUsd 为折算美元价值。
+func (c *okxClient) FetchBalances() ([]models.AssetBalance, error) {
+	data, err := c.requestSigned(http.MethodGet, "/api/v5/account/balance", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Data []struct {
+			Details []okxBalanceDetail `json:"details"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	out := []models.AssetBalance{}
+	if len(resp.Data) == 0 {
+		return out, nil
+	}
+	for _, d := range resp.Data[0].Details {
+		b := d.toAssetBalance()
+		if b.WalletBalance == 0 && b.MarginBalance == 0 {
+			continue
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+type okxBalanceDetail struct {
+	Ccy      string `json:"ccy"`
+	Eq       string `json:"eq"`
+	CashBal  string `json:"cashBal"`
+	AvailBal string `json:"availBal"`
+	Upl      string `json:"upl"`
+	EqUsd    string `json:"eqUsd"`
+}
+
+func (d okxBalanceDetail) toAssetBalance() models.AssetBalance {
+	return models.AssetBalance{
+		Asset:            strings.ToUpper(strings.TrimSpace(d.Ccy)),
+		WalletBalance:    toFloat(d.CashBal),
+		AvailableBalance: toFloat(d.AvailBal),
+		MarginBalance:    toFloat(d.Eq),
+		UnrealizedPnL:    toFloat(d.Upl),
+		USDValue:         toFloat(d.EqUsd),
+	}
+}
+
 func (c *okxClient) FetchAvailableBalance() (float64, error) {
 	query := url.Values{}
 	query.Set("ccy", "USDT")
@@ -319,70 +579,165 @@ func (c *okxClient) FetchAvailableBalance() (float64, error) {
 	return 0, nil
 }
 
+// SetLeverage 逐仓 + 双向持仓模式下多空两腿杠杆需分别设置。
 func (c *okxClient) SetLeverage(symbol string, leverage int) error {
-	payload := map[string]string{
-		"instId":  toOKXInstID(symbol),
-		"lever":   strconv.Itoa(leverage),
-		"mgnMode": "cross",
+	mgnMode := c.marginMode(symbol)
+	posSides := []string{""}
+	if mgnMode == models.MarginModeIsolated && c.posMode.hedge(symbol) {
+		posSides = []string{"long", "short"}
 	}
-	body, _ := json.Marshal(payload)
-	_, err := c.requestSigned(http.MethodPost, "/api/v5/account/set-leverage", nil, body)
-	return err
+	for _, posSide := range posSides {
+		payload := map[string]string{
+			"instId":  toOKXInstID(symbol),
+			"lever":   strconv.Itoa(leverage),
+			"mgnMode": mgnMode,
+		}
+		if posSide != "" {
+			payload["posSide"] = posSide
+		}
+		body, _ := json.Marshal(payload)
+		if _, err := c.requestSigned(http.MethodPost, "/api/v5/account/set-leverage", nil, body); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	c.leverages[normalizeSymbol(symbol)] = leverage
+	c.mu.Unlock()
+	return nil
 }
 
-func (c *okxClient) FetchPosition(symbol string) (*models.Position, error) {
+// SetMarginMode OKX 没有独立的切换接口：记录模式供后续下单 tdMode 使用，
+// 已设置过杠杆时按新模式重新调用 set-leverage。
+func (c *okxClient) SetMarginMode(symbol, mode string) error {
+	key := normalizeSymbol(symbol)
+	c.mu.Lock()
+	c.marginModes[key] = mode
+	leverage := c.leverages[key]
+	c.mu.Unlock()
+	if leverage <= 0 {
+		return nil
+	}
+	return c.SetLeverage(symbol, leverage)
+}
+
+func (c *okxClient) marginMode(symbol string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if mode := c.marginModes[normalizeSymbol(symbol)]; mode != "" {
+		return mode
+	}
+	return c.defaultMarginMode
+}
+
+func (c *okxClient) FetchPositions(symbol string) ([]models.Position, error) {
 	query := url.Values{}
-	query.Set("instId", toOKXInstID(symbol))
+	if strings.TrimSpace(symbol) != "" {
+		query.Set("instId", toOKXInstID(symbol))
+	} else {
+		query.Set("instType", "SWAP")
+	}
 	data, err := c.requestSigned(http.MethodGet, "/api/v5/account/positions", query, nil)
 	if err != nil {
 		return nil, err
 	}
 	var resp struct {
-		Code string `json:"code"`
-		Msg  string `json:"msg"`
-		Data []struct {
-			InstID  string `json:"instId"`
-			Pos     string `json:"pos"`
-			AvgPx   string `json:"avgPx"`
-			Upl     string `json:"upl"`
-			Lever   string `json:"lever"`
-			PosSide string `json:"posSide"`
-		} `json:"data"`
+		Code string           `json:"code"`
+		Msg  string           `json:"msg"`
+		Data []okxPositionRow `json:"data"`
 	}
 	if err := json.Unmarshal(data, &resp); err != nil {
 		return nil, err
 	}
+	out := []models.Position{}
 	for _, row := range resp.Data {
-		posRaw, _ := strconv.ParseFloat(strings.TrimSpace(row.Pos), 64)
-		if posRaw == 0 {
+		pos := c.toPosition(row, symbol)
+		if pos.Size == 0 {
 			continue
 		}
-		side := strings.ToLower(strings.TrimSpace(row.PosSide))
-		if side != "long" && side != "short" {
-			if posRaw < 0 {
-				side = "short"
-			} else {
-				side = "long"
-			}
+		out = append(out, pos)
+	}
+	return out, nil
+}
+
+type okxPositionRow struct {
+	InstID  string `json:"instId"`
+	Pos     string `json:"pos"`
+	AvgPx   string `json:"avgPx"`
+	Upl     string `json:"upl"`
+	Lever   string `json:"lever"`
+	PosSide string `json:"posSide"`
+	MgnMode string `json:"mgnMode"`
+}
+
+// toPosition 张数按 ctVal 折算为标的币；pos 为 0（已平仓）时数量为 0，单向持仓方向为空。
+func (c *okxClient) toPosition(row okxPositionRow, symbol string) models.Position {
+	posRaw, _ := strconv.ParseFloat(strings.TrimSpace(row.Pos), 64)
+	side := strings.ToLower(strings.TrimSpace(row.PosSide))
+	if side != "long" && side != "short" {
+		switch {
+		case posRaw < 0:
+			side = "short"
+		case posRaw > 0:
+			side = "long"
+		default:
+			side = ""
 		}
-		entry, _ := strconv.ParseFloat(strings.TrimSpace(row.AvgPx), 64)
-		upl, _ := strconv.ParseFloat(strings.TrimSpace(row.Upl), 64)
-		lev, _ := strconv.ParseFloat(strings.TrimSpace(row.Lever), 64)
-		inst := strings.TrimSpace(row.InstID)
-		outSymbol := normalizeSymbol(symbol)
-		if inst != "" {
-			outSymbol = fromOKXInstID(inst)
-		}
-		return &models.Position{
-			Side:          side,
-			Size:          math.Abs(posRaw),
-			EntryPrice:    entry,
-			UnrealizedPnL: upl,
-			Leverage:      lev,
-			Symbol:        outSymbol,
-		}, nil
 	}
-	return nil, nil
+	entry, _ := strconv.ParseFloat(strings.TrimSpace(row.AvgPx), 64)
+	upl, _ := strconv.ParseFloat(strings.TrimSpace(row.Upl), 64)
+	lev, _ := strconv.ParseFloat(strings.TrimSpace(row.Lever), 64)
+	inst := strings.TrimSpace(row.InstID)
+	outSymbol := normalizeSymbol(symbol)
+	if inst != "" {
+		outSymbol = fromOKXInstID(inst)
+	}
+	return models.Position{
+		Side:          side,
+		Size:          c.fromContracts(outSymbol, math.Abs(posRaw)),
+		EntryPrice:    entry,
+		UnrealizedPnL: upl,
+		Leverage:      lev,
+		Symbol:        outSymbol,
+		MarginMode:    models.NormalizeMarginMode(row.MgnMode),
+	}
+}
+
+func (c *okxClient) FetchPositionMode(symbol string) (string, error) {
+	return c.posMode.get(symbol)
+}
+
+// loadPositionMode OKX 持仓模式为账户级设置：long_short_mode 双向 / net_mode 单向。
+func (c *okxClient) loadPositionMode(string) (string, error) {
+	data, err := c.requestSigned(http.MethodGet, "/api/v5/account/config", nil, nil)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		Data []struct {
+			PosMode string `json:"posMode"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Data) == 0 {
+		return "", fmt.Errorf("okx 账户配置为空")
+	}
+	if strings.TrimSpace(resp.Data[0].PosMode) == "long_short_mode" {
+		return models.PositionModeHedge, nil
+	}
+	return models.PositionModeOneWay, nil
+}
+
+// setPosSide 双向持仓模式下指定 posSide；该模式不支持 reduceOnly，平仓由 posSide 与买卖方向决定。
+func (c *okxClient) setPosSide(payload map[string]any, symbol, side string, reduceOnly bool) {
+	if c.posMode.hedge(symbol) {
+		payload["posSide"] = positionLegForOrder(side, reduceOnly)
+		return
+	}
+	if reduceOnly {
+		payload["reduceOnly"] = true
+	}
 }
 
 func (c *okxClient) PlaceMarketOrder(symbol, side string, size float64, reduceOnly bool) error {
@@ -398,16 +753,18 @@ func (c *okxClient) PlaceMarketOrderWithResult(symbol, side string, size float64
 	if size <= 0 {
 		return models.OrderResult{}, fmt.Errorf("invalid size: %.8f", size)
 	}
+	contracts, size, err := c.toContracts(symbol, size)
+	if err != nil {
+		return models.OrderResult{}, err
+	}
 	payload := map[string]any{
 		"instId":  toOKXInstID(symbol),
-		"tdMode":  "cross",
+		"tdMode":  c.marginMode(symbol),
 		"side":    side,
 		"ordType": "market",
-		"sz":      formatSize(size),
-	}
-	if reduceOnly {
-		payload["reduceOnly"] = true
+		"sz":      formatSize(contracts),
 	}
+	c.setPosSide(payload, symbol, side, reduceOnly)
 	body, _ := json.Marshal(payload)
 	data, err := c.requestSigned(http.MethodPost, "/api/v5/trade/order", nil, body)
 	if err != nil {
@@ -442,6 +799,7 @@ func (c *okxClient) PlaceMarketOrderWithResult(symbol, side string, size float64
 		State:      "live",
 		Symbol:     normalizeSymbol(symbol),
 		Side:       side,
+		Type:       models.OrderTypeMarket,
 		Size:       size,
 		ReduceOnly: reduceOnly,
 	}, nil
@@ -456,14 +814,333 @@ func (c *okxClient) FetchOrder(symbol, orderID string) (*models.OrderStatus, err
 		return nil, err
 	}
 	var resp struct {
-		Code string `json:"code"`
-		Msg  string `json:"msg"`
+		Code string        `json:"code"`
+		Msg  string        `json:"msg"`
+		Data []okxOrderRow `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, nil
+	}
+	st := c.orderStatus(resp.Data[0], symbol)
+	return &st, nil
+}
+
+type okxOrderRow struct {
+	OrdID      string `json:"ordId"`
+	InstID     string `json:"instId"`
+	State      string `json:"state"`
+	OrdType    string `json:"ordType"`
+	Sz         string `json:"sz"`
+	Px         string `json:"px"`
+	AccFillSz  string `json:"accFillSz"`
+	AvgPx      string `json:"avgPx"`
+	Side       string `json:"side"`
+	ReduceOnly string `json:"reduceOnly"`
+	UTime      string `json:"uTime"`
+	CTime      string `json:"cTime"`
+}
+
+func (row okxOrderRow) toStatus(symbol string) models.OrderStatus {
+	filled, _ := strconv.ParseFloat(strings.TrimSpace(row.AccFillSz), 64)
+	avg, _ := strconv.ParseFloat(strings.TrimSpace(row.AvgPx), 64)
+	size, _ := strconv.ParseFloat(strings.TrimSpace(row.Sz), 64)
+	price, _ := strconv.ParseFloat(strings.TrimSpace(row.Px), 64)
+	symbolOut := normalizeSymbol(symbol)
+	if strings.TrimSpace(row.InstID) != "" {
+		symbolOut = fromOKXInstID(row.InstID)
+	}
+	ordType := strings.ToLower(strings.TrimSpace(row.OrdType))
+	switch ordType {
+	case "limit", "post_only", "ioc", "fok":
+		ordType = models.OrderTypeLimit
+	}
+	return models.OrderStatus{
+		OrderID:    strings.TrimSpace(row.OrdID),
+		State:      mapOrderState(row.State),
+		Type:       ordType,
+		Size:       size,
+		Price:      price,
+		FilledSize: filled,
+		AvgPrice:   avg,
+		Symbol:     symbolOut,
+		Side:       strings.ToLower(strings.TrimSpace(row.Side)),
+		ReduceOnly: strings.EqualFold(strings.TrimSpace(row.ReduceOnly), "true"),
+		UpdateTime: strings.TrimSpace(row.UTime),
+		CreateTime: strings.TrimSpace(row.CTime),
+	}
+}
+
+func (c *okxClient) PlaceLimitOrder(symbol, side string, size, price float64, timeInForce string, reduceOnly bool) (models.OrderResult, error) {
+	side = strings.ToLower(strings.TrimSpace(side))
+	if side != "buy" && side != "sell" {
+		return models.OrderResult{}, fmt.Errorf("invalid side: %s", side)
+	}
+	if size <= 0 || price <= 0 {
+		return models.OrderResult{}, fmt.Errorf("invalid size/price: %.8f/%.8f", size, price)
+	}
+	ordType := "limit"
+	switch timeInForce {
+	case models.TimeInForcePostOnly:
+		ordType = "post_only"
+	case models.TimeInForceIOC:
+		ordType = "ioc"
+	case models.TimeInForceGTC, "":
+	default:
+		return models.OrderResult{}, fmt.Errorf("unsupported time in force: %s", timeInForce)
+	}
+	contracts, size, err := c.toContracts(symbol, size)
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	price = c.instruments.roundPrice(symbol, price)
+	payload := map[string]any{
+		"instId":  toOKXInstID(symbol),
+		"tdMode":  c.marginMode(symbol),
+		"side":    side,
+		"ordType": ordType,
+		"sz":      formatSize(contracts),
+		"px":      formatSize(price),
+	}
+	c.setPosSide(payload, symbol, side, reduceOnly)
+	body, _ := json.Marshal(payload)
+	data, err := c.requestSigned(http.MethodPost, "/api/v5/trade/order", nil, body)
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	ordID, clOrdID, err := parseOKXOrderAck(data, "下单")
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	return models.OrderResult{
+		OrderID:     ordID,
+		ClientID:    clOrdID,
+		State:       "live",
+		Symbol:      normalizeSymbol(symbol),
+		Side:        side,
+		Type:        models.OrderTypeLimit,
+		Size:        size,
+		Price:       price,
+		TimeInForce: timeInForce,
+		ReduceOnly:  reduceOnly,
+	}, nil
+}
+
+func (c *okxClient) CancelOrder(symbol, orderID string) error {
+	payload := map[string]string{
+		"instId": toOKXInstID(symbol),
+		"ordId":  strings.TrimSpace(orderID),
+	}
+	body, _ := json.Marshal(payload)
+	data, err := c.requestSigned(http.MethodPost, "/api/v5/trade/cancel-order", nil, body)
+	if err != nil {
+		return err
+	}
+	_, _, err = parseOKXOrderAck(data, "撤单")
+	return err
+}
+
+// AmendOrder newSize/newPrice <= 0 时不修改对应字段。
+func (c *okxClient) AmendOrder(symbol, orderID string, newSize, newPrice float64) (models.OrderResult, error) {
+	payload := map[string]string{
+		"instId": toOKXInstID(symbol),
+		"ordId":  strings.TrimSpace(orderID),
+	}
+	if newSize > 0 {
+		contracts, rounded, err := c.toContracts(symbol, newSize)
+		if err != nil {
+			return models.OrderResult{}, err
+		}
+		newSize = rounded
+		payload["newSz"] = formatSize(contracts)
+	}
+	if newPrice > 0 {
+		newPrice = c.instruments.roundPrice(symbol, newPrice)
+		payload["newPx"] = formatSize(newPrice)
+	}
+	if len(payload) == 2 {
+		return models.OrderResult{}, fmt.Errorf("amend 需要 newSize 或 newPrice")
+	}
+	body, _ := json.Marshal(payload)
+	data, err := c.requestSigned(http.MethodPost, "/api/v5/trade/amend-order", nil, body)
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	ordID, clOrdID, err := parseOKXOrderAck(data, "改单")
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	return models.OrderResult{
+		OrderID:  ordID,
+		ClientID: clOrdID,
+		State:    "live",
+		Symbol:   normalizeSymbol(symbol),
+		Type:     models.OrderTypeLimit,
+		Size:     newSize,
+		Price:    newPrice,
+	}, nil
+}
+
+func (c *okxClient) FetchOpenOrders(symbol string) ([]models.OrderStatus, error) {
+	query := url.Values{}
+	query.Set("instType", "SWAP")
+	query.Set("instId", toOKXInstID(symbol))
+	data, err := c.requestSigned(http.MethodGet, "/api/v5/trade/orders-pending", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Data []okxOrderRow `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	out := make([]models.OrderStatus, 0, len(resp.Data))
+	for _, row := range resp.Data {
+		out = append(out, c.orderStatus(row, symbol))
+	}
+	return out, nil
+}
+
+func parseOKXOrderAck(data []byte, action string) (string, string, error) {
+	var resp struct {
 		Data []struct {
-			OrdID      string `json:"ordId"`
+			OrdID   string `json:"ordId"`
+			ClOrdID string `json:"clOrdId"`
+			SCode   string `json:"sCode"`
+			SMsg    string `json:"sMsg"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", "", err
+	}
+	if len(resp.Data) == 0 {
+		return "", "", fmt.Errorf("okx %s失败: 空响应", action)
+	}
+	row := resp.Data[0]
+	if code := strings.TrimSpace(row.SCode); code != "" && code != "0" {
+		return "", "", fmt.Errorf("okx %s失败: code=%s msg=%s", action, code, strings.TrimSpace(row.SMsg))
+	}
+	if strings.TrimSpace(row.OrdID) == "" {
+		return "", "", fmt.Errorf("okx %s失败: 无订单ID", action)
+	}
+	return strings.TrimSpace(row.OrdID), strings.TrimSpace(row.ClOrdID), nil
+}
+
+func (c *okxClient) PlaceProtectiveOrder(symbol, side, orderType string, size, triggerPrice float64) (models.OrderResult, error) {
+	side = strings.ToLower(strings.TrimSpace(side))
+	if side != "buy" && side != "sell" {
+		return models.OrderResult{}, fmt.Errorf("invalid side: %s", side)
+	}
+	if size <= 0 || triggerPrice <= 0 {
+		return models.OrderResult{}, fmt.Errorf("invalid size/trigger: %.8f/%.8f", size, triggerPrice)
+	}
+	contracts, size, err := c.toContracts(symbol, size)
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	triggerPrice = c.instruments.roundPrice(symbol, triggerPrice)
+	payload := map[string]any{
+		"instId":  toOKXInstID(symbol),
+		"tdMode":  c.marginMode(symbol),
+		"side":    side,
+		"ordType": "conditional",
+		"sz":      formatSize(contracts),
+	}
+	c.setPosSide(payload, symbol, side, true)
+	switch orderType {
+	case models.OrderTypeStopMarket:
+		payload["slTriggerPx"] = formatSize(triggerPrice)
+		payload["slOrdPx"] = "-1"
+		payload["slTriggerPxType"] = "mark"
+	case models.OrderTypeTakeProfitMarket:
+		payload["tpTriggerPx"] = formatSize(triggerPrice)
+		payload["tpOrdPx"] = "-1"
+		payload["tpTriggerPxType"] = "mark"
+	default:
+		return models.OrderResult{}, fmt.Errorf("unsupported protective order type: %s", orderType)
+	}
+	body, _ := json.Marshal(payload)
+	data, err := c.requestSigned(http.MethodPost, "/api/v5/trade/order-algo", nil, body)
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	var resp struct {
+		Data []struct {
+			AlgoID      string `json:"algoId"`
+			AlgoClOrdID string `json:"algoClOrdId"`
+			SCode       string `json:"sCode"`
+			SMsg        string `json:"sMsg"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return models.OrderResult{}, err
+	}
+	if len(resp.Data) == 0 {
+		return models.OrderResult{}, fmt.Errorf("okx 条件单下单失败: 空响应")
+	}
+	row := resp.Data[0]
+	if code := strings.TrimSpace(row.SCode); code != "" && code != "0" {
+		return models.OrderResult{}, fmt.Errorf("okx 条件单下单失败: code=%s msg=%s", code, strings.TrimSpace(row.SMsg))
+	}
+	if strings.TrimSpace(row.AlgoID) == "" {
+		return models.OrderResult{}, fmt.Errorf("okx 条件单下单失败: 无算法单ID")
+	}
+	return models.OrderResult{
+		OrderID:      strings.TrimSpace(row.AlgoID),
+		ClientID:     strings.TrimSpace(row.AlgoClOrdID),
+		State:        "live",
+		Symbol:       normalizeSymbol(symbol),
+		Side:         side,
+		Type:         orderType,
+		Size:         size,
+		TriggerPrice: triggerPrice,
+		ReduceOnly:   true,
+	}, nil
+}
+
+func (c *okxClient) CancelProtectiveOrder(symbol, orderID string) error {
+	payload := []map[string]string{{
+		"instId": toOKXInstID(symbol),
+		"algoId": strings.TrimSpace(orderID),
+	}}
+	body, _ := json.Marshal(payload)
+	data, err := c.requestSigned(http.MethodPost, "/api/v5/trade/cancel-algos", nil, body)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Data []struct {
+			SCode string `json:"sCode"`
+			SMsg  string `json:"sMsg"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+	if len(resp.Data) > 0 {
+		if code := strings.TrimSpace(resp.Data[0].SCode); code != "" && code != "0" {
+			return fmt.Errorf("okx 撤销条件单失败: code=%s msg=%s", code, strings.TrimSpace(resp.Data[0].SMsg))
+		}
+	}
+	return nil
+}
+
+func (c *okxClient) FetchProtectiveOrder(symbol, orderID string) (*models.OrderStatus, error) {
+	query := url.Values{}
+	query.Set("algoId", strings.TrimSpace(orderID))
+	data, err := c.requestSigned(http.MethodGet, "/api/v5/trade/order-algo", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Data []struct {
+			AlgoID     string `json:"algoId"`
 			InstID     string `json:"instId"`
 			State      string `json:"state"`
-			AccFillSz  string `json:"accFillSz"`
-			AvgPx      string `json:"avgPx"`
+			Sz         string `json:"sz"`
 			Side       string `json:"side"`
 			ReduceOnly string `json:"reduceOnly"`
 			UTime      string `json:"uTime"`
@@ -476,20 +1153,460 @@ func (c *okxClient) FetchOrder(symbol, orderID string) (*models.OrderStatus, err
 		return nil, nil
 	}
 	row := resp.Data[0]
-	filled, _ := strconv.ParseFloat(strings.TrimSpace(row.AccFillSz), 64)
-	avg, _ := strconv.ParseFloat(strings.TrimSpace(row.AvgPx), 64)
+	state := mapOKXAlgoState(row.State)
+	filled := 0.0
+	if state == "filled" {
+		filled, _ = strconv.ParseFloat(strings.TrimSpace(row.Sz), 64)
+		filled = c.fromContracts(symbol, filled)
+	}
 	symbolOut := normalizeSymbol(symbol)
 	if strings.TrimSpace(row.InstID) != "" {
 		symbolOut = fromOKXInstID(row.InstID)
 	}
 	return &models.OrderStatus{
-		OrderID:    strings.TrimSpace(row.OrdID),
-		State:      mapOrderState(row.State),
+		OrderID:    strings.TrimSpace(row.AlgoID),
+		State:      state,
 		FilledSize: filled,
-		AvgPrice:   avg,
 		Symbol:     symbolOut,
 		Side:       strings.ToLower(strings.TrimSpace(row.Side)),
 		ReduceOnly: strings.EqualFold(strings.TrimSpace(row.ReduceOnly), "true"),
 		UpdateTime: strings.TrimSpace(row.UTime),
 	}, nil
 }
+
+// mapOKXAlgoState 将 OKX 算法单状态映射为统一订单状态（effective 视为已触发成交）。
+func mapOKXAlgoState(s string) string {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "live", "pause", "partially_effective":
+		return "live"
+	case "effective":
+		return "filled"
+	case "canceled", "order_failed":
+		return "canceled"
+	default:
+		return mapOrderState(s)
+	}
+}
+
+func (c *okxClient) FetchInstrument(symbol string) (models.Instrument, error) {
+	return c.instruments.get(symbol)
+}
+
+// loadInstruments 读取 USDT 永续规格；步长/最小量按 ctVal 折算为标的币数量。
+func (c *okxClient) loadInstruments() ([]models.Instrument, error) {
+	query := url.Values{}
+	query.Set("instType", "SWAP")
+	data, err := c.requestPublic("/api/v5/public/instruments", query)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data []struct {
+			InstID    string `json:"instId"`
+			SettleCcy string `json:"settleCcy"`
+			CtType    string `json:"ctType"`
+			TickSz    string `json:"tickSz"`
+			LotSz     string `json:"lotSz"`
+			MinSz     string `json:"minSz"`
+			CtVal     string `json:"ctVal"`
+			Lever     string `json:"lever"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Code != "" && resp.Code != "0" {
+		return nil, fmt.Errorf("okx instruments failed: code=%s msg=%s", resp.Code, resp.Msg)
+	}
+	out := make([]models.Instrument, 0, len(resp.Data))
+	for _, row := range resp.Data {
+		if !strings.EqualFold(row.CtType, "linear") {
+			continue
+		}
+		ctVal := toFloat(row.CtVal)
+		if ctVal <= 0 {
+			ctVal = 1
+		}
+		out = append(out, models.Instrument{
+			Symbol:        fromOKXInstID(row.InstID),
+			TickSize:      toFloat(row.TickSz),
+			StepSize:      toFloat(formatSize(toFloat(row.LotSz) * ctVal)),
+			MinQty:        toFloat(formatSize(toFloat(row.MinSz) * ctVal)),
+			ContractValue: ctVal,
+			MaxLeverage:   toFloat(row.Lever),
+		})
+	}
+	return out, nil
+}
+
+// toContracts 标的币数量换算为合约张数，返回张数与取整后的标的币数量。
+// 规格不可用时拒绝下单，避免把币数量当作张数提交。
+func (c *okxClient) toContracts(symbol string, qty float64) (float64, float64, error) {
+	inst, err := c.instruments.get(symbol)
+	if err != nil {
+		return 0, 0, fmt.Errorf("读取 OKX 合约面值失败: %v", err)
+	}
+	ctVal := inst.ContractValue
+	if ctVal <= 0 {
+		ctVal = 1
+	}
+	lotSz := toFloat(formatSize(inst.StepSize / ctVal))
+	contracts := models.FloorToStep(qty/ctVal, lotSz)
+	if contracts <= 0 {
+		return 0, 0, fmt.Errorf("下单数量 %.8f 不足 1 个最小下单单位(%s 张, 面值 %s)", qty, formatSize(lotSz), formatSize(ctVal))
+	}
+	return contracts, models.RoundToStep(contracts*ctVal, inst.StepSize), nil
+}
+
+// fromContracts 合约张数换算为标的币数量；规格不可用时原样返回。
+func (c *okxClient) fromContracts(symbol string, contracts float64) float64 {
+	inst, err := c.instruments.get(symbol)
+	if err != nil || inst.ContractValue <= 0 {
+		return contracts
+	}
+	return models.RoundToStep(contracts*inst.ContractValue, inst.StepSize)
+}
+
+func (c *okxClient) orderStatus(row okxOrderRow, symbol string) models.OrderStatus {
+	st := row.toStatus(symbol)
+	st.Size = c.fromContracts(symbol, st.Size)
+	st.FilledSize = c.fromContracts(symbol, st.FilledSize)
+	return st
+}
+
+// okxHistoryWindow OKX 历史接口窗口内只能从新到旧翻页，按天切窗口让游标可以逐窗口推进。
+const okxHistoryWindow = 24 * time.Hour
+
+// walkHistory OKX 历史接口按 ID 倒序返回：从 since 起按窗口向当前推进，窗口内从最新一页用 after 翻到窗口起点。
+// 翻页中断或达到上限时返回 HistoryIncompleteError，Through 为未走完窗口的起点。
+func (c *okxClient) walkHistory(path string, query url.Values, since time.Time, handle func(data []byte) (lastID string, lastTs int64, n int, err error)) error {
+	now := time.Now()
+	pages := 0
+	for start := since; start.Before(now); start = start.Add(okxHistoryWindow) {
+		after := ""
+		for {
+			if pages >= maxHistoryPages {
+				return &HistoryIncompleteError{Through: start}
+			}
+			pages++
+			q := url.Values{}
+			for k, v := range query {
+				q[k] = v
+			}
+			q.Set("begin", strconv.FormatInt(start.UnixMilli(), 10))
+			q.Set("end", strconv.FormatInt(start.Add(okxHistoryWindow).UnixMilli(), 10))
+			q.Set("limit", "100")
+			if after != "" {
+				q.Set("after", after)
+			}
+			data, err := c.requestSigned(http.MethodGet, path, q, nil)
+			if err != nil {
+				return &HistoryIncompleteError{Through: start, Err: err}
+			}
+			lastID, lastTs, n, err := handle(data)
+			if err != nil {
+				return &HistoryIncompleteError{Through: start, Err: err}
+			}
+			if n < 100 || lastID == "" || lastTs < start.UnixMilli() {
+				break
+			}
+			after = lastID
+		}
+	}
+	return nil
+}
+
+// FetchIncome 账单 type=2 为成交（pnl 为平仓盈亏、fee 为手续费，负数表示支出），type=8 为资金费；
+// 近 7 天使用 bills，更早的回补走 bills-archive。
+func (c *okxClient) FetchIncome(since time.Time) ([]models.IncomeEvent, error) {
+	path := "/api/v5/account/bills"
+	if time.Since(since) > historyLookback {
+		path = "/api/v5/account/bills-archive"
+	}
+	out := []models.IncomeEvent{}
+	err := c.walkHistory(path, url.Values{"instType": {"SWAP"}}, since, func(data []byte) (string, int64, int, error) {
+		var resp struct {
+			Data []struct {
+				BillID  string `json:"billId"`
+				InstID  string `json:"instId"`
+				Type    string `json:"type"`
+				Pnl     string `json:"pnl"`
+				Fee     string `json:"fee"`
+				BalChg  string `json:"balChg"`
+				Ccy     string `json:"ccy"`
+				OrdID   string `json:"ordId"`
+				TradeID string `json:"tradeId"`
+				Ts      string `json:"ts"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return "", 0, 0, err
+		}
+		var lastTs int64
+		for _, row := range resp.Data {
+			lastTs = int64(toFloat(row.Ts))
+			base := models.IncomeEvent{
+				Symbol:  fromOKXInstID(row.InstID),
+				Asset:   row.Ccy,
+				TradeID: row.TradeID,
+				OrderID: row.OrdID,
+				Info:    "type=" + row.Type,
+				Time:    time.UnixMilli(lastTs),
+			}
+			add := func(suffix, typ string, amount float64) {
+				if amount == 0 {
+					return
+				}
+				ev := base
+				ev.ID = row.BillID + suffix
+				ev.Type = typ
+				ev.Amount = amount
+				out = append(out, ev)
+			}
+			switch row.Type {
+			case "2":
+				add("-pnl", models.IncomeRealizedPnL, toFloat(row.Pnl))
+				add("-fee", models.IncomeCommission, toFloat(row.Fee))
+			case "8":
+				add("", models.IncomeFunding, toFloat(row.BalChg))
+			default:
+				add("", models.IncomeOther, toFloat(row.BalChg))
+			}
+		}
+		if len(resp.Data) == 0 {
+			return "", 0, 0, nil
+		}
+		return resp.Data[len(resp.Data)-1].BillID, lastTs, len(resp.Data), nil
+	})
+	sortIncomeEvents(out)
+	return out, err
+}
+
+// FetchTradeFills fills-history 保留近 3 个月成交；fillSz 为合约张数，按 ctVal 折算。
+func (c *okxClient) FetchTradeFills(symbol string, since time.Time) ([]models.TradeFill, error) {
+	out := []models.TradeFill{}
+	query := url.Values{"instType": {"SWAP"}, "instId": {toOKXInstID(symbol)}}
+	err := c.walkHistory("/api/v5/trade/fills-history", query, since, func(data []byte) (string, int64, int, error) {
+		var resp struct {
+			Data []struct {
+				InstID   string `json:"instId"`
+				TradeID  string `json:"tradeId"`
+				OrdID    string `json:"ordId"`
+				BillID   string `json:"billId"`
+				Side     string `json:"side"`
+				FillSz   string `json:"fillSz"`
+				FillPx   string `json:"fillPx"`
+				Fee      string `json:"fee"`
+				FeeCcy   string `json:"feeCcy"`
+				FillPnl  string `json:"fillPnl"`
+				ExecType string `json:"execType"`
+				Ts       string `json:"ts"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return "", 0, 0, err
+		}
+		var lastTs int64
+		for _, row := range resp.Data {
+			lastTs = int64(toFloat(row.Ts))
+			sym := fromOKXInstID(row.InstID)
+			out = append(out, models.TradeFill{
+				ID:          row.TradeID,
+				OrderID:     row.OrdID,
+				Symbol:      sym,
+				Side:        strings.ToLower(row.Side),
+				Size:        c.fromContracts(sym, toFloat(row.FillSz)),
+				Price:       toFloat(row.FillPx),
+				Fee:         -toFloat(row.Fee),
+				FeeAsset:    row.FeeCcy,
+				RealizedPnL: toFloat(row.FillPnl),
+				Maker:       row.ExecType == "M",
+				Time:        time.UnixMilli(lastTs),
+			})
+		}
+		if len(resp.Data) == 0 {
+			return "", 0, 0, nil
+		}
+		return resp.Data[len(resp.Data)-1].BillID, lastTs, len(resp.Data), nil
+	})
+	sortTradeFills(out)
+	return out, err
+}
+
+func (c *okxClient) userStreamSource() (userStreamSource, error) {
+	if c.apiKey == "" || c.secret == "" || c.passphrase == "" {
+		return nil, fmt.Errorf("okx API Key/Secret/Passphrase 未配置")
+	}
+	return &okxUserStream{c: c, url: ResolveEndpoints("okx", c.env).PrivateWS}, nil
+}
+
+// okxUserStream 私有频道 orders / positions / account；连接 30 秒无消息会被服务端断开，需定时发送 ping。
+type okxUserStream struct {
+	c   *okxClient
+	url string
+}
+
+func (u *okxUserStream) dial() (*websocket.Conn, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(u.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.login(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	sub := map[string]any{
+		"op": "subscribe",
+		"args": []map[string]string{
+			{"channel": "orders", "instType": "SWAP"},
+			{"channel": "positions", "instType": "SWAP"},
+			{"channel": "account"},
+		},
+	}
+	if err := conn.WriteJSON(sub); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// login 签名串为 timestamp + "GET" + "/users/self/verify"，timestamp 为秒级。
+func (u *okxUserStream) login(conn *websocket.Conn) error {
+	ts := strconv.FormatInt(u.c.clock.now(u.c.fetchServerTime).Unix(), 10)
+	req := map[string]any{
+		"op": "login",
+		"args": []map[string]string{{
+			"apiKey":     u.c.apiKey,
+			"passphrase": u.c.passphrase,
+			"timestamp":  ts,
+			"sign":       u.c.signPayload(ts + "GET" + "/users/self/verify"),
+		}},
+	}
+	if err := conn.WriteJSON(req); err != nil {
+		return err
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("okx ws login: %w", err)
+		}
+		var resp struct {
+			Event string `json:"event"`
+			Code  string `json:"code"`
+			Msg   string `json:"msg"`
+		}
+		if json.Unmarshal(msg, &resp) != nil {
+			continue
+		}
+		switch resp.Event {
+		case "login":
+			return nil
+		case "error":
+			return fmt.Errorf("okx ws login code=%s: %s", resp.Code, resp.Msg)
+		}
+	}
+}
+
+func (u *okxUserStream) keepaliveInterval() time.Duration { return 25 * time.Second }
+
+func (u *okxUserStream) keepalive(conn *websocket.Conn) error {
+	return conn.WriteMessage(websocket.TextMessage, []byte("ping"))
+}
+
+func (u *okxUserStream) close() {}
+
+func (u *okxUserStream) decode(msg []byte) ([]UserEvent, error) {
+	if string(msg) == "pong" {
+		return nil, nil
+	}
+	var resp struct {
+		Event string `json:"event"`
+		Code  string `json:"code"`
+		Msg   string `json:"msg"`
+		Arg   struct {
+			Channel string `json:"channel"`
+		} `json:"arg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(msg, &resp); err != nil {
+		return nil, nil
+	}
+	if resp.Event == "error" {
+		return nil, fmt.Errorf("okx ws code=%s: %s", resp.Code, resp.Msg)
+	}
+	if resp.Event != "" || len(resp.Data) == 0 {
+		return nil, nil
+	}
+	switch resp.Arg.Channel {
+	case "orders":
+		return u.decodeOrders(resp.Data), nil
+	case "positions":
+		var rows []okxPositionRow
+		if json.Unmarshal(resp.Data, &rows) != nil {
+			return nil, nil
+		}
+		legs := make([]models.Position, 0, len(rows))
+		for _, row := range rows {
+			legs = append(legs, u.c.toPosition(row, row.InstID))
+		}
+		return []UserEvent{{Kind: UserEventPosition, Positions: legs, Time: time.Now()}}, nil
+	case "account":
+		var rows []struct {
+			Details []okxBalanceDetail `json:"details"`
+		}
+		if json.Unmarshal(resp.Data, &rows) != nil || len(rows) == 0 {
+			return nil, nil
+		}
+		balances := make([]models.AssetBalance, 0, len(rows[0].Details))
+		for _, d := range rows[0].Details {
+			balances = append(balances, d.toAssetBalance())
+		}
+		return []UserEvent{{Kind: UserEventAccount, Balances: balances, Time: time.Now()}}, nil
+	}
+	return nil, nil
+}
+
+// decodeOrders 订单推送在订单状态基础上附带本次成交 fillSz/fillPx，fillFee 为负表示扣费。
+func (u *okxUserStream) decodeOrders(data json.RawMessage) []UserEvent {
+	var rows []struct {
+		okxOrderRow
+		TradeID    string `json:"tradeId"`
+		FillSz     string `json:"fillSz"`
+		FillPx     string `json:"fillPx"`
+		FillFee    string `json:"fillFee"`
+		FillFeeCcy string `json:"fillFeeCcy"`
+		FillPnl    string `json:"fillPnl"`
+		ExecType   string `json:"execType"`
+		FillTime   string `json:"fillTime"`
+	}
+	if json.Unmarshal(data, &rows) != nil {
+		return nil
+	}
+	out := make([]UserEvent, 0, len(rows))
+	for _, row := range rows {
+		sym := fromOKXInstID(row.InstID)
+		st := u.c.orderStatus(row.okxOrderRow, sym)
+		ev := UserEvent{Kind: UserEventOrder, Order: &st, Time: time.UnixMilli(int64(toFloat(row.UTime)))}
+		if row.TradeID != "" && toFloat(row.FillSz) > 0 {
+			ev.Trade = &models.TradeFill{
+				ID:          row.TradeID,
+				OrderID:     st.OrderID,
+				Symbol:      sym,
+				Side:        st.Side,
+				Size:        u.c.fromContracts(sym, toFloat(row.FillSz)),
+				Price:       toFloat(row.FillPx),
+				Fee:         -toFloat(row.FillFee),
+				FeeAsset:    row.FillFeeCcy,
+				RealizedPnL: toFloat(row.FillPnl),
+				Maker:       row.ExecType == "M",
+				Time:        time.UnixMilli(int64(toFloat(row.FillTime))),
+			}
+		}
+		out = append(out, ev)
+	}
+	return out
+}
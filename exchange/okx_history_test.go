@@ -0,0 +1,142 @@
+package exchange
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	"trade-go/config"
+)
+
+// fakeOKXBills 本地 OKX 账单替身：按 begin/end 过滤、ID 倒序返回，用 after 向更早翻页。
+type fakeOKXBills struct {
+	mu     sync.Mutex
+	ts     []int64 // 账单时间，下标即 billId
+	failAt int     // 第 failAt 次账单请求返回错误，0 表示不注入
+	calls  int
+}
+
+func (f *fakeOKXBills) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path == "/api/v5/public/time" {
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": "0", "data": []map[string]string{{"ts": strconv.FormatInt(time.Now().UnixMilli(), 10)}}})
+		return
+	}
+	f.calls++
+	if f.failAt > 0 && f.calls == f.failAt {
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": "51000", "msg": "parameter error"})
+		return
+	}
+	q := r.URL.Query()
+	begin, _ := strconv.ParseInt(q.Get("begin"), 10, 64)
+	end, _ := strconv.ParseInt(q.Get("end"), 10, 64)
+	after := len(f.ts)
+	if v := q.Get("after"); v != "" {
+		after, _ = strconv.Atoi(v)
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	rows := []map[string]string{}
+	for id := after - 1; id >= 0 && len(rows) < limit; id-- {
+		if f.ts[id] < begin || f.ts[id] > end {
+			continue
+		}
+		rows = append(rows, map[string]string{
+			"billId": strconv.Itoa(id),
+			"instId": "BTC-USDT-SWAP",
+			"type":   "8",
+			"balChg": "-0.1",
+			"ccy":    "USDT",
+			"ts":     strconv.FormatInt(f.ts[id], 10),
+		})
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"code": "0", "data": rows})
+}
+
+func newFakeOKXBills(t *testing.T, ts []int64) (*fakeOKXBills, *okxClient) {
+	sort.Slice(ts, func(i, j int) bool { return ts[i] < ts[j] })
+	f := &fakeOKXBills{ts: ts}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	c := newOKXClient(&config.AppConfig{OKXAPIKey: "k", OKXSecret: "s", OKXPassword: "p"})
+	c.baseURL = srv.URL
+	return f, c
+}
+
+// billTimes 在 [from, from+span) 内均匀生成 n 条账单时间。
+func billTimes(from time.Time, span time.Duration, n int) []int64 {
+	out := make([]int64, 0, n)
+	for i := 0; i < n; i++ {
+		out = append(out, from.Add(span*time.Duration(i)/time.Duration(n)).UnixMilli())
+	}
+	return out
+}
+
+func TestOKXIncomeWalksWindowsForward(t *testing.T) {
+	since := time.Now().Add(-3 * 24 * time.Hour).Truncate(time.Millisecond)
+	_, c := newFakeOKXBills(t, billTimes(since, 3*24*time.Hour-time.Minute, 450))
+
+	events, err := c.FetchIncome(since)
+	if err != nil {
+		t.Fatalf("完整翻页不应报错: %v", err)
+	}
+	if len(events) != 450 {
+		t.Fatalf("应取回全部账单: got %d", len(events))
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].Time.Before(events[i-1].Time) {
+			t.Fatalf("结果应按时间升序")
+		}
+	}
+}
+
+func TestOKXIncomePageCapDoesNotSkipGap(t *testing.T) {
+	since := time.Now().Add(-2 * 24 * time.Hour).Truncate(time.Millisecond)
+	// 第一天账单超过单次翻页上限
+	ts := billTimes(since, 24*time.Hour-time.Minute, maxHistoryPages*100+50)
+	ts = append(ts, billTimes(since.Add(24*time.Hour), time.Hour, 10)...)
+	_, c := newFakeOKXBills(t, ts)
+
+	events, err := c.FetchIncome(since)
+	var incomplete *HistoryIncompleteError
+	if !errors.As(err, &incomplete) || incomplete.Err != nil {
+		t.Fatalf("达到翻页上限应返回 HistoryIncompleteError: %v", err)
+	}
+	if !incomplete.Through.Equal(since) {
+		t.Fatalf("未走完的首个窗口起点之后不能视为完整: %s", incomplete.Through)
+	}
+	if len(events) != maxHistoryPages*100 {
+		t.Fatalf("已取到的账单仍应返回: got %d", len(events))
+	}
+}
+
+func TestOKXIncomeErrorReportsCompletedWindows(t *testing.T) {
+	since := time.Now().Add(-3 * 24 * time.Hour).Truncate(time.Millisecond)
+	ts := billTimes(since, 3*24*time.Hour-time.Minute, 30)
+	f, c := newFakeOKXBills(t, ts)
+	f.failAt = 2 // 第一天窗口一页取完，第二天窗口失败
+	firstDay := 0
+	for _, v := range ts {
+		if v <= since.Add(okxHistoryWindow).UnixMilli() {
+			firstDay++
+		}
+	}
+
+	events, err := c.FetchIncome(since)
+	var incomplete *HistoryIncompleteError
+	if !errors.As(err, &incomplete) || incomplete.Err == nil {
+		t.Fatalf("翻页失败应带原始错误: %v", err)
+	}
+	if want := since.Add(okxHistoryWindow); !incomplete.Through.Equal(want) {
+		t.Fatalf("Through 应为失败窗口的起点: got %s want %s", incomplete.Through, want)
+	}
+	if len(events) != firstDay {
+		t.Fatalf("第一天窗口的账单应全部返回: got %d want %d", len(events), firstDay)
+	}
+}
@@ -0,0 +1,66 @@
+package exchange
+
+import (
+	"sync"
+	"time"
+	"trade-go/models"
+)
+
+// 持仓模式需在交易所侧手动切换，变化很少，缓存较长时间以免每次下单都查询。
+const positionModeCacheTTL = 10 * time.Minute
+
+type positionModeEntry struct {
+	mode     string
+	loadedAt time.Time
+}
+
+// positionModeCache 按交易对缓存账户持仓模式（Binance/OKX 为账户级，Bybit 可按交易对设置）。
+type positionModeCache struct {
+	mu    sync.Mutex
+	load  func(symbol string) (string, error)
+	ttl   time.Duration
+	items map[string]positionModeEntry
+}
+
+func newPositionModeCache(load func(symbol string) (string, error)) *positionModeCache {
+	return &positionModeCache{load: load, ttl: positionModeCacheTTL, items: map[string]positionModeEntry{}}
+}
+
+func (c *positionModeCache) get(symbol string) (string, error) {
+	key := normalizeSymbol(symbol)
+	c.mu.Lock()
+	entry, ok := c.items[key]
+	c.mu.Unlock()
+	if ok && time.Since(entry.loadedAt) < c.ttl {
+		return entry.mode, nil
+	}
+	mode, err := c.load(symbol)
+	if err != nil {
+		// 查询失败时沿用过期缓存，避免短暂网络问题阻断下单。
+		if ok {
+			return entry.mode, nil
+		}
+		return models.PositionModeOneWay, err
+	}
+	if mode != models.PositionModeHedge {
+		mode = models.PositionModeOneWay
+	}
+	c.mu.Lock()
+	c.items[key] = positionModeEntry{mode: mode, loadedAt: time.Now()}
+	c.mu.Unlock()
+	return mode, nil
+}
+
+// hedge 下单路径使用：查询失败按单向持仓处理，参数不匹配时由交易所拒单暴露问题。
+func (c *positionModeCache) hedge(symbol string) bool {
+	mode, _ := c.get(symbol)
+	return mode == models.PositionModeHedge
+}
+
+// positionLegForOrder 推断订单作用的持仓腿：开仓买/平仓卖对应多头，其余对应空头。
+func positionLegForOrder(side string, reduceOnly bool) string {
+	if (side == "buy") != reduceOnly {
+		return "long"
+	}
+	return "short"
+}
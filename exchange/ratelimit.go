@@ -0,0 +1,289 @@
+package exchange
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	rateLimitMaxRetries = 3
+	rateLimitBaseDelay  = 300 * time.Millisecond
+	rateLimitMaxDelay   = 5 * time.Second
+	// 等待超过该时长直接报错，避免阻塞交易周期。
+	rateLimitMaxWait = 10 * time.Second
+)
+
+// RateLimitStatus 限流器运行状态，供 /api/system/runtime 展示。
+type RateLimitStatus struct {
+	Exchange       string    `json:"exchange"`
+	APIKey         string    `json:"api_key"`
+	Window         string    `json:"window"`
+	WeightLimit    int       `json:"weight_limit"`
+	WeightUsed     int       `json:"weight_used"`
+	ServerWeight   int       `json:"server_weight,omitempty"`
+	Throttled      bool      `json:"throttled"`
+	Banned         bool      `json:"banned"`
+	BannedUntil    time.Time `json:"banned_until,omitempty"`
+	ThrottledTotal int64     `json:"throttled_total"`
+	RetriesTotal   int64     `json:"retries_total"`
+	LastLimitedAt  time.Time `json:"last_limited_at,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+// rateLimiter 按交易所+API Key 共享，统计窗口内请求权重并处理 418/429 等限流响应。
+type rateLimiter struct {
+	mu           sync.Mutex
+	exchange     string
+	apiKey       string
+	window       time.Duration
+	limit        int
+	weightHeader string
+	isLimited    func(status int, body []byte) bool
+
+	windowStart    time.Time
+	used           int
+	serverUsed     int
+	bannedUntil    time.Time
+	throttledTotal int64
+	retriesTotal   int64
+	lastLimitedAt  time.Time
+	lastError      string
+}
+
+var (
+	rateLimitersMu sync.Mutex
+	rateLimiters   = map[string]*rateLimiter{}
+)
+
+// sharedRateLimiter 同一交易所、环境与 API Key 的所有客户端（实盘/模拟/回测/行情快照）共用一个限流器；
+// 限流规则只取决于交易所本身，环境只用于分开统计。
+func sharedRateLimiter(exchange, env, apiKey string) *rateLimiter {
+	key := exchange + "|" + NormalizeEnvironment(env) + "|" + apiKey
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+	if l, ok := rateLimiters[key]; ok {
+		return l
+	}
+	l := &rateLimiter{exchange: scopedName(exchange, NormalizeEnvironment(env)), apiKey: apiKey}
+	switch exchange {
+	case "binance":
+		// 官方上限 2400/分钟，预留余量
+		l.window, l.limit = time.Minute, 2000
+		l.weightHeader = "X-MBX-USED-WEIGHT-1M"
+		l.isLimited = func(status int, _ []byte) bool { return status == 418 || status == 429 }
+	case "okx":
+		l.window, l.limit = 2*time.Second, 20
+		l.isLimited = func(status int, body []byte) bool {
+			return status == 429 || strings.Contains(string(body), `"code":"50011"`) || strings.Contains(string(body), `"code":"50061"`)
+		}
+	default:
+		l.window, l.limit = time.Second, 10
+		l.isLimited = func(status int, body []byte) bool {
+			return status == 403 || status == 429 || strings.Contains(string(body), `"retCode":10006`) || strings.Contains(string(body), `"retCode":10018`)
+		}
+	}
+	rateLimiters[key] = l
+	return l
+}
+
+// RateLimitStatuses 返回所有限流器状态。
+func RateLimitStatuses() []RateLimitStatus {
+	rateLimitersMu.Lock()
+	items := make([]*rateLimiter, 0, len(rateLimiters))
+	for _, l := range rateLimiters {
+		items = append(items, l)
+	}
+	rateLimitersMu.Unlock()
+	out := make([]RateLimitStatus, 0, len(items))
+	for _, l := range items {
+		out = append(out, l.status())
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Exchange != out[j].Exchange {
+			return out[i].Exchange < out[j].Exchange
+		}
+		return out[i].APIKey < out[j].APIKey
+	})
+	return out
+}
+
+func (l *rateLimiter) status() RateLimitStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.rollWindow(now)
+	st := RateLimitStatus{
+		Exchange:       l.exchange,
+		APIKey:         maskAPIKey(l.apiKey),
+		Window:         l.window.String(),
+		WeightLimit:    l.limit,
+		WeightUsed:     l.used,
+		ServerWeight:   l.serverUsed,
+		Throttled:      l.used >= l.limit,
+		Banned:         now.Before(l.bannedUntil),
+		ThrottledTotal: l.throttledTotal,
+		RetriesTotal:   l.retriesTotal,
+		LastLimitedAt:  l.lastLimitedAt,
+		LastError:      l.lastError,
+	}
+	if st.Banned {
+		st.BannedUntil = l.bannedUntil
+	}
+	return st
+}
+
+func maskAPIKey(key string) string {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "public"
+	}
+	if len(key) <= 8 {
+		return "****"
+	}
+	return key[:4] + "****" + key[len(key)-4:]
+}
+
+func (l *rateLimiter) rollWindow(now time.Time) {
+	if now.Sub(l.windowStart) >= l.window {
+		l.windowStart = now
+		l.used = 0
+		l.serverUsed = 0
+	}
+}
+
+// acquire 占用权重；窗口额度不足时等待到下个窗口，封禁期内直接报错。
+func (l *rateLimiter) acquire(weight int) error {
+	if weight <= 0 {
+		weight = 1
+	}
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		if now.Before(l.bannedUntil) {
+			wait := l.bannedUntil.Sub(now)
+			l.mu.Unlock()
+			if wait > rateLimitMaxWait {
+				return fmt.Errorf("%s 接口限流中，%s 后恢复", l.exchange, wait.Round(time.Second))
+			}
+			time.Sleep(wait)
+			continue
+		}
+		l.rollWindow(now)
+		used := l.used
+		if l.serverUsed > used {
+			used = l.serverUsed
+		}
+		if used+weight <= l.limit {
+			l.used += weight
+			l.mu.Unlock()
+			return nil
+		}
+		wait := l.window - now.Sub(l.windowStart)
+		l.throttledTotal++
+		l.mu.Unlock()
+		if wait > rateLimitMaxWait {
+			return fmt.Errorf("%s 请求权重已用尽(%d/%d)，%s 后恢复", l.exchange, used, l.limit, wait.Round(time.Second))
+		}
+		time.Sleep(wait)
+	}
+}
+
+// observe 记录交易所返回的已用权重与限流信号。
+func (l *rateLimiter) observe(resp *http.Response, body []byte) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.weightHeader != "" {
+		if v, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get(l.weightHeader))); err == nil {
+			l.serverUsed = v
+		}
+	}
+	if !l.isLimited(resp.StatusCode, body) {
+		return false
+	}
+	now := time.Now()
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+	if retryAfter <= 0 {
+		retryAfter = l.window
+	}
+	l.bannedUntil = now.Add(retryAfter)
+	l.lastLimitedAt = now
+	l.lastError = fmt.Sprintf("http %d: %s", resp.StatusCode, truncateForStatus(body))
+	return true
+}
+
+func parseRetryAfter(v string) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(v); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+func truncateForStatus(body []byte) string {
+	s := strings.TrimSpace(string(body))
+	if len(s) > 200 {
+		return s[:200]
+	}
+	return s
+}
+
+func backoffDelay(attempt int) time.Duration {
+	d := rateLimitBaseDelay << attempt
+	if d > rateLimitMaxDelay {
+		d = rateLimitMaxDelay
+	}
+	// ±50% 抖动，避免多个客户端同时重试
+	return d/2 + time.Duration(rand.Int63n(int64(d)))
+}
+
+// do 经限流器发送请求，返回状态码与响应体。
+// 只有 GET 会在网络错误、5xx 和限流时按指数退避重试；build 每次重建请求以便重新签名。
+func (l *rateLimiter) do(client *http.Client, method string, weight int, build func() (*http.Request, error)) (int, []byte, error) {
+	attempts := 1
+	if method == http.MethodGet {
+		attempts = rateLimitMaxRetries + 1
+	}
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			l.mu.Lock()
+			l.retriesTotal++
+			l.mu.Unlock()
+			time.Sleep(backoffDelay(attempt - 1))
+		}
+		if err := l.acquire(weight); err != nil {
+			return 0, nil, err
+		}
+		req, err := build()
+		if err != nil {
+			return 0, nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		limited := l.observe(resp, body)
+		if (limited || resp.StatusCode >= 500) && attempt+1 < attempts {
+			lastErr = fmt.Errorf("%s http %d: %s", l.exchange, resp.StatusCode, strings.TrimSpace(string(body)))
+			continue
+		}
+		return resp.StatusCode, body, nil
+	}
+	return 0, nil, lastErr
+}
@@ -0,0 +1,47 @@
+package exchange
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestScopedRateLimiterKeepsExchangeRules(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-MBX-USED-WEIGHT-1M", "123")
+		if r.URL.Path == "/ban" {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(418)
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	l := sharedRateLimiter("binance", EnvTestnet, "testnet-weight-key")
+	if l == sharedRateLimiter("binance", EnvMainnet, "testnet-weight-key") {
+		t.Fatalf("测试网与主网应分开统计")
+	}
+	get := func(path string) {
+		_, _, err := l.do(srv.Client(), http.MethodPost, 1, func() (*http.Request, error) {
+			return http.NewRequest(http.MethodPost, srv.URL+path, nil)
+		})
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+	}
+	get("/ok")
+	st := l.status()
+	if st.Exchange != "binance-testnet" || st.WeightLimit != 2000 || st.ServerWeight != 123 {
+		t.Fatalf("测试网 Binance 应沿用 Binance 权重规则并读取权重头: %+v", st)
+	}
+	get("/ban")
+	if st := l.status(); !st.Banned {
+		t.Fatalf("测试网 Binance 的 418 应识别为封禁: %+v", st)
+	}
+
+	okx := sharedRateLimiter("okx", EnvDemo, "demo-key")
+	if !okx.isLimited(200, []byte(`{"code":"50011","msg":"too many requests"}`)) || okx.isLimited(403, nil) {
+		t.Fatalf("OKX 模拟盘应按 OKX 限流码判断")
+	}
+}
@@ -1,8 +1,12 @@
 package exchange
 
 import (
+	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"trade-go/models"
 )
 
 func normalizeExchangeName(exchange string) string {
@@ -10,11 +14,61 @@ func normalizeExchangeName(exchange string) string {
 	switch name {
 	case "okx":
 		return "okx"
+	case "bybit":
+		return "bybit"
+	case "sim":
+		return "sim"
 	default:
 		return "binance"
 	}
 }
 
+// NormalizeSymbol 统一交易对写法（BTC-USDT-SWAP / btc_usdt → BTCUSDT），供跨交易所比较。
+func NormalizeSymbol(symbol string) string {
+	return normalizeSymbol(symbol)
+}
+
+// OKXInstID 交易对映射为 OKX 永续合约 instId（BTCUSDT → BTC-USDT-SWAP）。
+func OKXInstID(symbol string) string {
+	return toOKXInstID(symbol)
+}
+
+// OKXBar K 线周期映射为 OKX bar 写法（1h → 1H）。
+func OKXBar(interval string) string {
+	return toOKXBar(interval)
+}
+
+// BybitInterval K 线周期映射为 Bybit interval 写法（1h → 60，1d → D）。
+func BybitInterval(timeframe string) string {
+	return toBybitInterval(timeframe)
+}
+
+// TimeframeDuration K 线周期时长（1m/15m/4h/1d/1w），无法识别时返回 0；1M 按 30 天计。
+func TimeframeDuration(tf string) time.Duration {
+	tf = strings.TrimSpace(tf)
+	if tf == "1M" {
+		return 30 * 24 * time.Hour
+	}
+	if len(tf) < 2 {
+		return 0
+	}
+	n, err := strconv.Atoi(tf[:len(tf)-1])
+	if err != nil || n <= 0 {
+		return 0
+	}
+	switch strings.ToLower(tf[len(tf)-1:]) {
+	case "m":
+		return time.Duration(n) * time.Minute
+	case "h":
+		return time.Duration(n) * time.Hour
+	case "d":
+		return time.Duration(n) * 24 * time.Hour
+	case "w":
+		return time.Duration(n) * 7 * 24 * time.Hour
+	}
+	return 0
+}
+
 func normalizeSymbol(symbol string) string {
 	s := strings.ToUpper(strings.TrimSpace(symbol))
 	s = strings.ReplaceAll(s, "-", "")
@@ -67,3 +121,61 @@ func formatSize(v float64) string {
 	}
 	return raw
 }
+
+// parseBookLevels 解析 [价格, 数量, ...] 形式的盘口档位，数量乘以 sizeMul（OKX 合约面值）并截取前 depth 档。
+func parseBookLevels(rows [][]string, depth int, sizeMul float64) []models.BookLevel {
+	out := make([]models.BookLevel, 0, len(rows))
+	for _, row := range rows {
+		if depth > 0 && len(out) >= depth {
+			break
+		}
+		if len(row) < 2 {
+			continue
+		}
+		px, sz := toFloat(row[0]), toFloat(row[1])*sizeMul
+		if px <= 0 || sz <= 0 {
+			continue
+		}
+		out = append(out, models.BookLevel{Price: px, Size: sz})
+	}
+	return out
+}
+
+// isUSDStable 与美元 1:1 计价的保证金资产。
+func isUSDStable(asset string) bool {
+	switch strings.ToUpper(strings.TrimSpace(asset)) {
+	case "USDT", "USDC", "FDUSD", "BFUSD", "USD":
+		return true
+	}
+	return false
+}
+
+// 历史流水/成交单次同步的翻页上限，避免首次回补时长时间占用请求权重。
+const maxHistoryPages = 20
+
+// historyLookback 交易所成交/流水接口普遍只保留近 7 天的快速查询，回补起点不早于此。
+const historyLookback = 7 * 24 * time.Hour
+
+// HistoryIncompleteError 历史翻页未覆盖 since 到当前的完整区间：早于 Through 的记录已全部返回，
+// 之后的可能有缺口，调用方的同步游标不得越过 Through。Err 为空表示只是达到单次翻页上限。
+type HistoryIncompleteError struct {
+	Through time.Time
+	Err     error
+}
+
+func (e *HistoryIncompleteError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("历史记录只完整同步到 %s: %v", e.Through.Format(time.RFC3339), e.Err)
+	}
+	return fmt.Sprintf("历史记录翻页达到上限，已完整同步到 %s", e.Through.Format(time.RFC3339))
+}
+
+func (e *HistoryIncompleteError) Unwrap() error { return e.Err }
+
+func sortIncomeEvents(items []models.IncomeEvent) {
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Time.Before(items[j].Time) })
+}
+
+func sortTradeFills(items []models.TradeFill) {
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Time.Before(items[j].Time) })
+}
@@ -0,0 +1,929 @@
+package exchange
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"trade-go/config"
+	"trade-go/models"
+)
+
+// 注入故障类型
+const (
+	SimFailTimeout = "timeout" // 请求已被撮合处理，但调用方收到超时错误
+	SimFailReject  = "reject"  // 请求被拒绝，不产生任何副作用
+	SimFailPartial = "partial" // 下单只成交 FillRatio 比例
+)
+
+// SimConfig 模拟交易所参数。
+type SimConfig struct {
+	InitialBalance float64
+	TakerFeeRate   float64
+	MakerFeeRate   float64
+	SlippageBps    float64
+	// Instrument 所有交易对共用的规格，Symbol 字段忽略
+	Instrument models.Instrument
+	// Source 可选行情源；未通过 FeedCandles 喂入数据时从这里拉取 K 线
+	Source func(symbol, timeframe string, limit int) ([]models.OHLCV, error)
+}
+
+// SimFailure 按操作名注入一次性或多次故障，Op 为 backend 方法名，"*" 匹配全部。
+type SimFailure struct {
+	Op        string
+	Kind      string
+	Times     int
+	FillRatio float64
+}
+
+// SimFill 模拟成交记录。
+type SimFill struct {
+	OrderID  string    `json:"order_id"`
+	Symbol   string    `json:"symbol"`
+	Side     string    `json:"side"`
+	Size     float64   `json:"size"`
+	Price    float64   `json:"price"`
+	Fee      float64   `json:"fee"`
+	Maker    bool      `json:"maker"`
+	Realized float64   `json:"realized_pnl"`
+	Time     time.Time `json:"time"`
+}
+
+type simPosition struct {
+	size  float64 // 正数多头，负数空头
+	entry float64
+}
+
+type simOrder struct {
+	status       models.OrderStatus
+	triggerPrice float64
+	timeInForce  string
+}
+
+// SimExchange 进程内模拟交易所：余额、持仓、杠杆、订单与成交全部保存在内存中，
+// 按喂入的 K 线或逐笔价格确定性撮合，便于离线复现完整交易周期。
+type SimExchange struct {
+	mu        sync.Mutex
+	cfg       SimConfig
+	wallet    float64
+	leverage  map[string]int
+	margin    map[string]string
+	positions map[string]*simPosition
+	marks     map[string]float64
+	candles   map[string][]models.OHLCV
+	orders    map[string]*simOrder
+	fills     []SimFill
+	failures  []*SimFailure
+	nextID    int64
+	now       time.Time
+}
+
+func NewSimExchange(cfg SimConfig) *SimExchange {
+	if cfg.InitialBalance <= 0 {
+		cfg.InitialBalance = 10000
+	}
+	if cfg.Instrument.StepSize <= 0 {
+		cfg.Instrument.StepSize = 0.001
+	}
+	if cfg.Instrument.TickSize <= 0 {
+		cfg.Instrument.TickSize = 0.1
+	}
+	if cfg.Instrument.MinQty <= 0 {
+		cfg.Instrument.MinQty = cfg.Instrument.StepSize
+	}
+	if cfg.Instrument.ContractValue <= 0 {
+		cfg.Instrument.ContractValue = 1
+	}
+	if cfg.Instrument.MaxLeverage <= 0 {
+		cfg.Instrument.MaxLeverage = 125
+	}
+	return &SimExchange{
+		cfg:       cfg,
+		wallet:    cfg.InitialBalance,
+		leverage:  map[string]int{},
+		margin:    map[string]string{},
+		positions: map[string]*simPosition{},
+		marks:     map[string]float64{},
+		candles:   map[string][]models.OHLCV{},
+		orders:    map[string]*simOrder{},
+	}
+}
+
+// NewSimClient 用模拟交易所构造统一客户端。
+func NewSimClient(sim *SimExchange) *Client {
+	return &Client{exchange: "sim", env: EnvDemo, impl: sim}
+}
+
+var (
+	defaultSimOnce sync.Once
+	defaultSim     *SimExchange
+)
+
+// DefaultSim ACTIVE_EXCHANGE=sim 时使用的进程级模拟交易所，默认以 SyntheticCandles 离线生成行情；
+// SIM_MARKET_SOURCE=binance 时改取 Binance 公共 K 线。状态在客户端重载之间保留。
+func DefaultSim() *SimExchange {
+	defaultSimOnce.Do(func() {
+		cfg := SimConfig{Source: SyntheticCandles}
+		if config.Config != nil {
+			cfg.InitialBalance = config.Config.SimInitialBalance
+			cfg.TakerFeeRate = config.Config.SimTakerFeeRate
+			cfg.MakerFeeRate = config.Config.SimMakerFeeRate
+			cfg.SlippageBps = config.Config.SimSlippageBps
+			if strings.EqualFold(strings.TrimSpace(config.Config.SimMarketSource), "binance") {
+				cfg.Source = newBinanceClient(nil).FetchOHLCV
+			}
+		}
+		defaultSim = NewSimExchange(cfg)
+	})
+	return defaultSim
+}
+
+// SyntheticCandles 离线合成行情：按周期对齐到当前时间，每根 K 线只由交易对与 K 线序号决定，
+// 重复调用同一时段得到相同数据。价格为围绕基准价的多周期正弦叠加伪随机扰动。
+func SyntheticCandles(symbol, timeframe string, limit int) ([]models.OHLCV, error) {
+	step := TimeframeDuration(timeframe)
+	if step <= 0 {
+		return nil, fmt.Errorf("sim 不支持的周期: %s", timeframe)
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	key := normalizeSymbol(symbol)
+	seed := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		seed = (seed ^ uint64(key[i])) * 1099511628211
+	}
+	base := 100 + float64(seed%50000)
+	if strings.HasPrefix(key, "BTC") {
+		base = 60000
+	}
+	noise := func(k int64, salt uint64) float64 {
+		x := seed ^ uint64(k)*0x9E3779B97F4A7C15 ^ salt
+		x ^= x >> 33
+		x *= 0xff51afd7ed558ccd
+		x ^= x >> 33
+		return float64(x%2001)/1000 - 1 // [-1,1]
+	}
+	price := func(k int64) float64 {
+		f := float64(k)
+		return base * (1 + 0.04*math.Sin(f/97) + 0.015*math.Sin(f/23) + 0.003*noise(k, 1))
+	}
+	last := time.Now().UTC().Truncate(step).UnixNano() / int64(step)
+	out := make([]models.OHLCV, 0, limit)
+	for k := last - int64(limit) + 1; k <= last; k++ {
+		open, closePx := price(k-1), price(k)
+		out = append(out, models.OHLCV{
+			Timestamp: time.Unix(0, k*int64(step)),
+			Open:      open,
+			High:      math.Max(open, closePx) * (1 + 0.001*math.Abs(noise(k, 2))),
+			Low:       math.Min(open, closePx) * (1 - 0.001*math.Abs(noise(k, 3))),
+			Close:     closePx,
+			Volume:    100 + 50*noise(k, 4),
+		})
+	}
+	return out, nil
+}
+
+// FeedCandles 追加 K 线并按每根 K 线的高低点撮合挂单与条件单。
+func (s *SimExchange) FeedCandles(symbol string, candles ...models.OHLCV) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := normalizeSymbol(symbol)
+	for _, c := range candles {
+		s.appendCandleLocked(key, c)
+		s.matchLocked(key, c.Low, c.High, c.Timestamp)
+		s.marks[key] = c.Close
+	}
+}
+
+// Tick 以单一成交价推进行情并撮合。
+func (s *SimExchange) Tick(symbol string, price float64, ts time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := normalizeSymbol(symbol)
+	s.matchLocked(key, price, price, ts)
+	s.marks[key] = price
+}
+
+// InjectFailure 注入故障，按注入顺序匹配。
+func (s *SimExchange) InjectFailure(f SimFailure) {
+	if f.Times <= 0 {
+		f.Times = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &f)
+}
+
+// Fills 返回全部成交记录副本。
+func (s *SimExchange) Fills() []SimFill {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SimFill(nil), s.fills...)
+}
+
+func (s *SimExchange) appendCandleLocked(key string, c models.OHLCV) {
+	rows := s.candles[key]
+	if n := len(rows); n > 0 && !c.Timestamp.After(rows[n-1].Timestamp) {
+		if c.Timestamp.Equal(rows[n-1].Timestamp) {
+			rows[n-1] = c
+		}
+		return
+	}
+	s.candles[key] = append(rows, c)
+	if !c.Timestamp.IsZero() {
+		s.now = c.Timestamp
+	}
+}
+
+func (s *SimExchange) clock() time.Time {
+	if s.now.IsZero() {
+		return time.Now()
+	}
+	return s.now
+}
+
+func (s *SimExchange) takeFailureLocked(op string) *SimFailure {
+	for i, f := range s.failures {
+		if f.Op != op && f.Op != "*" {
+			continue
+		}
+		out := *f
+		f.Times--
+		if f.Times <= 0 {
+			s.failures = append(s.failures[:i], s.failures[i+1:]...)
+		}
+		return &out
+	}
+	return nil
+}
+
+func (s *SimExchange) checkFailureLocked(op string) (*SimFailure, error) {
+	f := s.takeFailureLocked(op)
+	if f != nil && f.Kind == SimFailReject {
+		return nil, fmt.Errorf("sim %s rejected (injected)", op)
+	}
+	return f, nil
+}
+
+func (s *SimExchange) newOrderIDLocked() string {
+	s.nextID++
+	return strconv.FormatInt(s.nextID, 10)
+}
+
+func (s *SimExchange) leverageLocked(key string) int {
+	if lev := s.leverage[key]; lev > 0 {
+		return lev
+	}
+	return 1
+}
+
+func (s *SimExchange) unrealizedLocked() float64 {
+	total := 0.0
+	for key, p := range s.positions {
+		if mark := s.marks[key]; mark > 0 {
+			total += (mark - p.entry) * p.size
+		}
+	}
+	return total
+}
+
+func (s *SimExchange) usedMarginLocked() float64 {
+	total := 0.0
+	for key, p := range s.positions {
+		total += math.Abs(p.size) * p.entry / float64(s.leverageLocked(key))
+	}
+	return total
+}
+
+func (s *SimExchange) slipped(side string, price float64) float64 {
+	if side == "buy" {
+		return price * (1 + s.cfg.SlippageBps/10000)
+	}
+	return price * (1 - s.cfg.SlippageBps/10000)
+}
+
+// fillLocked 成交并更新持仓、余额；reduceOnly 时成交量不超过反向持仓。
+func (s *SimExchange) fillLocked(od *simOrder, size, price float64, maker bool) error {
+	key := normalizeSymbol(od.status.Symbol)
+	pos := s.positions[key]
+	signed := size
+	if od.status.Side == "sell" {
+		signed = -size
+	}
+	if od.status.ReduceOnly {
+		if pos == nil || pos.size*signed >= 0 {
+			return fmt.Errorf("sim reduce-only 订单无可平仓位")
+		}
+		if math.Abs(signed) > math.Abs(pos.size) {
+			signed = -pos.size
+			size = math.Abs(signed)
+		}
+	}
+
+	// 开仓部分校验保证金
+	opening := math.Abs(signed)
+	if pos != nil && pos.size*signed < 0 {
+		opening = math.Max(0, math.Abs(signed)-math.Abs(pos.size))
+	}
+	if opening > 0 {
+		equity := s.wallet + s.unrealizedLocked()
+		required := opening * price / float64(s.leverageLocked(key))
+		if required > equity-s.usedMarginLocked() {
+			return fmt.Errorf("sim 保证金不足: 需要 %.4f", required)
+		}
+	}
+
+	rate := s.cfg.TakerFeeRate
+	if maker {
+		rate = s.cfg.MakerFeeRate
+	}
+	fee := size * price * rate
+	realized := 0.0
+	if pos == nil {
+		pos = &simPosition{}
+		s.positions[key] = pos
+	}
+	switch {
+	case pos.size == 0 || pos.size*signed > 0:
+		total := pos.size + signed
+		pos.entry = (pos.entry*math.Abs(pos.size) + price*math.Abs(signed)) / math.Abs(total)
+		pos.size = total
+	default:
+		closed := math.Min(math.Abs(signed), math.Abs(pos.size))
+		dir := 1.0
+		if pos.size < 0 {
+			dir = -1
+		}
+		realized = (price - pos.entry) * closed * dir
+		pos.size += signed
+		if math.Abs(pos.size) < 1e-12 {
+			delete(s.positions, key)
+		} else if pos.size*dir < 0 {
+			// 反手：剩余部分以成交价开新仓
+			pos.entry = price
+		}
+	}
+	s.wallet += realized - fee
+
+	st := &od.status
+	prevFilled := st.FilledSize
+	st.FilledSize += size
+	st.AvgPrice = (st.AvgPrice*prevFilled + price*size) / st.FilledSize
+	st.UpdateTime = strconv.FormatInt(s.clock().UnixMilli(), 10)
+	if st.FilledSize+1e-12 >= st.Size {
+		st.State = "filled"
+	} else {
+		st.State = "partially_filled"
+	}
+	s.fills = append(s.fills, SimFill{
+		OrderID:  st.OrderID,
+		Symbol:   key,
+		Side:     st.Side,
+		Size:     size,
+		Price:    price,
+		Fee:      fee,
+		Maker:    maker,
+		Realized: realized,
+		Time:     s.clock(),
+	})
+	return nil
+}
+
+// matchLocked 按 [low, high] 价格区间撮合挂单与条件单，按订单号顺序处理保证结果确定。
+func (s *SimExchange) matchLocked(key string, low, high float64, ts time.Time) {
+	if !ts.IsZero() {
+		s.now = ts
+	}
+	ids := make([]string, 0, len(s.orders))
+	for id, od := range s.orders {
+		if normalizeSymbol(od.status.Symbol) == key && (od.status.State == "live" || od.status.State == "partially_filled") {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.ParseInt(ids[i], 10, 64)
+		b, _ := strconv.ParseInt(ids[j], 10, 64)
+		return a < b
+	})
+	for _, id := range ids {
+		od := s.orders[id]
+		st := &od.status
+		remaining := st.Size - st.FilledSize
+		switch st.Type {
+		case models.OrderTypeLimit:
+			if (st.Side == "buy" && low <= st.Price) || (st.Side == "sell" && high >= st.Price) {
+				if err := s.fillLocked(od, remaining, st.Price, true); err != nil {
+					st.State = "canceled"
+				}
+			}
+		case models.OrderTypeStopMarket, models.OrderTypeTakeProfitMarket:
+			triggered := false
+			rising := (st.Type == models.OrderTypeStopMarket) == (st.Side == "buy")
+			if rising {
+				triggered = high >= od.triggerPrice
+			} else {
+				triggered = low <= od.triggerPrice
+			}
+			if triggered {
+				if err := s.fillLocked(od, remaining, s.slipped(st.Side, od.triggerPrice), false); err != nil {
+					st.State = "canceled"
+				}
+			}
+		}
+	}
+}
+
+func (s *SimExchange) FetchOHLCV(symbol, timeframe string, limit int) ([]models.OHLCV, error) {
+	s.mu.Lock()
+	key := normalizeSymbol(symbol)
+	rows := s.candles[key]
+	source := s.cfg.Source
+	s.mu.Unlock()
+	if len(rows) == 0 && source != nil {
+		fetched, err := source(symbol, timeframe, limit)
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		for _, c := range fetched {
+			s.appendCandleLocked(key, c)
+		}
+		if n := len(fetched); n > 0 {
+			last := fetched[n-1]
+			s.matchLocked(key, last.Low, last.High, last.Timestamp)
+			s.marks[key] = last.Close
+		}
+		rows = s.candles[key]
+		// 外部行情每次都重新拉取，不缓存
+		delete(s.candles, key)
+		s.mu.Unlock()
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("sim 无 %s 行情数据", key)
+	}
+	if limit > 0 && len(rows) > limit {
+		rows = rows[len(rows)-limit:]
+	}
+	return append([]models.OHLCV(nil), rows...), nil
+}
+
+// FetchMarketStats 模拟盘不结算资金费，标记与指数价格均取最近成交价，持仓量为 0。
+func (s *SimExchange) FetchMarketStats(symbol string) (models.MarketStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.checkFailureLocked("FetchMarketStats"); err != nil {
+		return models.MarketStats{}, err
+	}
+	key := normalizeSymbol(symbol)
+	mark := s.marks[key]
+	return models.MarketStats{
+		Symbol:     key,
+		MarkPrice:  mark,
+		IndexPrice: mark,
+		UpdatedAt:  s.clock(),
+	}, nil
+}
+
+// FetchOrderBook 模拟盘市价单按固定滑点成交、不受数量影响，盘口以买卖各一档无限深度表示。
+func (s *SimExchange) FetchOrderBook(symbol string, depth int) (models.OrderBook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.checkFailureLocked("FetchOrderBook"); err != nil {
+		return models.OrderBook{}, err
+	}
+	key := normalizeSymbol(symbol)
+	mark := s.marks[key]
+	if mark <= 0 {
+		return models.OrderBook{}, fmt.Errorf("sim 无 %s 行情数据", key)
+	}
+	return models.OrderBook{
+		Symbol:    key,
+		Bids:      []models.BookLevel{{Price: s.slipped("sell", mark), Size: math.MaxFloat64}},
+		Asks:      []models.BookLevel{{Price: s.slipped("buy", mark), Size: math.MaxFloat64}},
+		Timestamp: s.clock(),
+	}, nil
+}
+
+// FetchIncome 由模拟成交记录派生：每笔成交一条手续费流水，平仓成交另加一条已实现盈亏；模拟盘不结算资金费。
+func (s *SimExchange) FetchIncome(since time.Time) ([]models.IncomeEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.checkFailureLocked("FetchIncome"); err != nil {
+		return nil, err
+	}
+	out := []models.IncomeEvent{}
+	for i, f := range s.fills {
+		if f.Time.Before(since) {
+			continue
+		}
+		id := strconv.Itoa(i + 1)
+		if f.Realized != 0 {
+			out = append(out, models.IncomeEvent{ID: id + "-pnl", Symbol: f.Symbol, Type: models.IncomeRealizedPnL, Amount: f.Realized, Asset: "USDT", TradeID: id, OrderID: f.OrderID, Time: f.Time})
+		}
+		if f.Fee != 0 {
+			out = append(out, models.IncomeEvent{ID: id + "-fee", Symbol: f.Symbol, Type: models.IncomeCommission, Amount: -f.Fee, Asset: "USDT", TradeID: id, OrderID: f.OrderID, Time: f.Time})
+		}
+	}
+	return out, nil
+}
+
+func (s *SimExchange) FetchTradeFills(symbol string, since time.Time) ([]models.TradeFill, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.checkFailureLocked("FetchTradeFills"); err != nil {
+		return nil, err
+	}
+	key := normalizeSymbol(symbol)
+	out := []models.TradeFill{}
+	for i, f := range s.fills {
+		if f.Symbol != key || f.Time.Before(since) {
+			continue
+		}
+		out = append(out, models.TradeFill{
+			ID:          strconv.Itoa(i + 1),
+			OrderID:     f.OrderID,
+			Symbol:      f.Symbol,
+			Side:        f.Side,
+			Size:        f.Size,
+			Price:       f.Price,
+			Fee:         f.Fee,
+			FeeAsset:    "USDT",
+			RealizedPnL: f.Realized,
+			Maker:       f.Maker,
+			Time:        f.Time,
+		})
+	}
+	return out, nil
+}
+
+func (s *SimExchange) FetchBalance() (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.checkFailureLocked("FetchBalance"); err != nil {
+		return 0, err
+	}
+	return s.wallet + s.unrealizedLocked(), nil
+}
+
+// FetchBalances 模拟盘只有 USDT 一种保证金资产。
+func (s *SimExchange) FetchBalances() ([]models.AssetBalance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.checkFailureLocked("FetchBalances"); err != nil {
+		return nil, err
+	}
+	upl := s.unrealizedLocked()
+	return []models.AssetBalance{{
+		Asset:            "USDT",
+		WalletBalance:    s.wallet,
+		AvailableBalance: math.Max(0, s.wallet+upl-s.usedMarginLocked()),
+		MarginBalance:    s.wallet + upl,
+		UnrealizedPnL:    upl,
+		USDValue:         s.wallet + upl,
+	}}, nil
+}
+
+func (s *SimExchange) FetchAvailableBalance() (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.checkFailureLocked("FetchAvailableBalance"); err != nil {
+		return 0, err
+	}
+	return math.Max(0, s.wallet+s.unrealizedLocked()-s.usedMarginLocked()), nil
+}
+
+func (s *SimExchange) SetLeverage(symbol string, leverage int) error {
+	if leverage <= 0 || float64(leverage) > s.cfg.Instrument.MaxLeverage {
+		return fmt.Errorf("sim invalid leverage: %d", leverage)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leverage[normalizeSymbol(symbol)] = leverage
+	return nil
+}
+
+// SetMarginMode 与真实交易所一致，持仓存在时不允许切换；仅记录模式，撮合不区分全仓/逐仓。
+func (s *SimExchange) SetMarginMode(symbol, mode string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := normalizeSymbol(symbol)
+	if s.marginModeLocked(key) == mode {
+		return nil
+	}
+	if p := s.positions[key]; p != nil && p.size != 0 {
+		return fmt.Errorf("sim 存在持仓，无法切换保证金模式")
+	}
+	s.margin[key] = mode
+	return nil
+}
+
+func (s *SimExchange) marginModeLocked(key string) string {
+	if mode := s.margin[key]; mode != "" {
+		return mode
+	}
+	return models.MarginModeCross
+}
+
+func (s *SimExchange) FetchPositions(symbol string) ([]models.Position, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.checkFailureLocked("FetchPositions"); err != nil {
+		return nil, err
+	}
+	keys := []string{normalizeSymbol(symbol)}
+	if strings.TrimSpace(symbol) == "" {
+		keys = keys[:0]
+		for key := range s.positions {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+	}
+	out := []models.Position{}
+	for _, key := range keys {
+		p := s.positions[key]
+		if p == nil || p.size == 0 {
+			continue
+		}
+		side := "long"
+		if p.size < 0 {
+			side = "short"
+		}
+		upl := 0.0
+		if mark := s.marks[key]; mark > 0 {
+			upl = (mark - p.entry) * p.size
+		}
+		out = append(out, models.Position{
+			Side:          side,
+			Size:          math.Abs(p.size),
+			EntryPrice:    p.entry,
+			UnrealizedPnL: upl,
+			Leverage:      float64(s.leverageLocked(key)),
+			Symbol:        key,
+			MarginMode:    s.marginModeLocked(key),
+		})
+	}
+	return out, nil
+}
+
+// FetchPositionMode 模拟交易所仅支持单向（净头寸）持仓。
+func (s *SimExchange) FetchPositionMode(symbol string) (string, error) {
+	return models.PositionModeOneWay, nil
+}
+
+func (s *SimExchange) PlaceMarketOrder(symbol, side string, size float64, reduceOnly bool) error {
+	_, err := s.PlaceMarketOrderWithResult(symbol, side, size, reduceOnly)
+	return err
+}
+
+func (s *SimExchange) PlaceMarketOrderWithResult(symbol, side string, size float64, reduceOnly bool) (models.OrderResult, error) {
+	side = strings.ToLower(strings.TrimSpace(side))
+	if side != "buy" && side != "sell" {
+		return models.OrderResult{}, fmt.Errorf("invalid side: %s", side)
+	}
+	size = s.cfg.Instrument.RoundSize(size)
+	if size <= 0 {
+		return models.OrderResult{}, fmt.Errorf("invalid size: %.8f", size)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := s.checkFailureLocked("PlaceMarketOrder")
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	key := normalizeSymbol(symbol)
+	mark := s.marks[key]
+	if mark <= 0 {
+		return models.OrderResult{}, fmt.Errorf("sim 无 %s 最新价格", key)
+	}
+	od := s.addOrderLocked(key, side, models.OrderTypeMarket, size, 0, reduceOnly)
+	fillSize := size
+	if f != nil && f.Kind == SimFailPartial && f.FillRatio > 0 && f.FillRatio < 1 {
+		fillSize = s.cfg.Instrument.RoundSize(size * f.FillRatio)
+	}
+	if fillSize > 0 {
+		if err := s.fillLocked(od, fillSize, s.slipped(side, mark), false); err != nil {
+			od.status.State = "canceled"
+			return models.OrderResult{}, err
+		}
+	}
+	// 市价单未成交部分直接撤销
+	if od.status.State != "filled" {
+		od.status.State = "canceled"
+	}
+	if f != nil && f.Kind == SimFailTimeout {
+		return models.OrderResult{}, fmt.Errorf("sim PlaceMarketOrder timeout (injected)")
+	}
+	return s.orderResultLocked(od), nil
+}
+
+func (s *SimExchange) addOrderLocked(key, side, orderType string, size, price float64, reduceOnly bool) *simOrder {
+	od := &simOrder{status: models.OrderStatus{
+		OrderID:    s.newOrderIDLocked(),
+		State:      "live",
+		Type:       orderType,
+		Size:       size,
+		Price:      price,
+		Symbol:     key,
+		Side:       side,
+		ReduceOnly: reduceOnly,
+		UpdateTime: strconv.FormatInt(s.clock().UnixMilli(), 10),
+		CreateTime: strconv.FormatInt(s.clock().UnixMilli(), 10),
+	}}
+	s.orders[od.status.OrderID] = od
+	return od
+}
+
+func (s *SimExchange) orderResultLocked(od *simOrder) models.OrderResult {
+	return models.OrderResult{
+		OrderID:      od.status.OrderID,
+		State:        od.status.State,
+		Symbol:       od.status.Symbol,
+		Side:         od.status.Side,
+		Type:         od.status.Type,
+		Size:         od.status.Size,
+		Price:        od.status.Price,
+		TriggerPrice: od.triggerPrice,
+		TimeInForce:  od.timeInForce,
+		ReduceOnly:   od.status.ReduceOnly,
+	}
+}
+
+func (s *SimExchange) FetchOrder(symbol, orderID string) (*models.OrderStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.checkFailureLocked("FetchOrder"); err != nil {
+		return nil, err
+	}
+	od := s.orders[strings.TrimSpace(orderID)]
+	if od == nil {
+		return nil, nil
+	}
+	st := od.status
+	return &st, nil
+}
+
+func (s *SimExchange) PlaceLimitOrder(symbol, side string, size, price float64, timeInForce string, reduceOnly bool) (models.OrderResult, error) {
+	side = strings.ToLower(strings.TrimSpace(side))
+	if side != "buy" && side != "sell" {
+		return models.OrderResult{}, fmt.Errorf("invalid side: %s", side)
+	}
+	size = s.cfg.Instrument.RoundSize(size)
+	price = s.cfg.Instrument.RoundPrice(price)
+	if size <= 0 || price <= 0 {
+		return models.OrderResult{}, fmt.Errorf("invalid size/price: %.8f/%.8f", size, price)
+	}
+	switch timeInForce {
+	case models.TimeInForceGTC, models.TimeInForcePostOnly, models.TimeInForceIOC, "":
+	default:
+		return models.OrderResult{}, fmt.Errorf("unsupported time in force: %s", timeInForce)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := s.checkFailureLocked("PlaceLimitOrder")
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	key := normalizeSymbol(symbol)
+	mark := s.marks[key]
+	crosses := mark > 0 && ((side == "buy" && price >= mark) || (side == "sell" && price <= mark))
+	if timeInForce == models.TimeInForcePostOnly && crosses {
+		return models.OrderResult{}, fmt.Errorf("sim post-only 订单会立即成交，已拒绝")
+	}
+	od := s.addOrderLocked(key, side, models.OrderTypeLimit, size, price, reduceOnly)
+	od.timeInForce = timeInForce
+	fillSize := size
+	if f != nil && f.Kind == SimFailPartial && f.FillRatio > 0 && f.FillRatio < 1 {
+		fillSize = s.cfg.Instrument.RoundSize(size * f.FillRatio)
+		crosses = crosses || mark > 0
+	}
+	if crosses && fillSize > 0 {
+		fillPrice := price
+		if mark > 0 {
+			fillPrice = math.Min(price, mark)
+			if side == "sell" {
+				fillPrice = math.Max(price, mark)
+			}
+		}
+		if err := s.fillLocked(od, fillSize, fillPrice, false); err != nil {
+			od.status.State = "canceled"
+			return models.OrderResult{}, err
+		}
+	}
+	if timeInForce == models.TimeInForceIOC && od.status.State != "filled" {
+		od.status.State = "canceled"
+	}
+	if f != nil && f.Kind == SimFailTimeout {
+		return models.OrderResult{}, fmt.Errorf("sim PlaceLimitOrder timeout (injected)")
+	}
+	return s.orderResultLocked(od), nil
+}
+
+func (s *SimExchange) CancelOrder(symbol, orderID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.checkFailureLocked("CancelOrder"); err != nil {
+		return err
+	}
+	od := s.orders[strings.TrimSpace(orderID)]
+	if od == nil {
+		return fmt.Errorf("sim 订单不存在: %s", orderID)
+	}
+	if od.status.State != "live" && od.status.State != "partially_filled" {
+		return fmt.Errorf("sim 订单已结束: %s", od.status.State)
+	}
+	od.status.State = "canceled"
+	od.status.UpdateTime = strconv.FormatInt(s.clock().UnixMilli(), 10)
+	return nil
+}
+
+func (s *SimExchange) AmendOrder(symbol, orderID string, newSize, newPrice float64) (models.OrderResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.checkFailureLocked("AmendOrder"); err != nil {
+		return models.OrderResult{}, err
+	}
+	od := s.orders[strings.TrimSpace(orderID)]
+	if od == nil || od.status.Type != models.OrderTypeLimit {
+		return models.OrderResult{}, fmt.Errorf("sim 限价单不存在: %s", orderID)
+	}
+	if od.status.State != "live" && od.status.State != "partially_filled" {
+		return models.OrderResult{}, fmt.Errorf("sim 订单已结束: %s", od.status.State)
+	}
+	if newSize > 0 {
+		newSize = s.cfg.Instrument.RoundSize(newSize)
+		if newSize < od.status.FilledSize {
+			return models.OrderResult{}, fmt.Errorf("sim 新数量小于已成交数量")
+		}
+		od.status.Size = newSize
+	}
+	if newPrice > 0 {
+		od.status.Price = s.cfg.Instrument.RoundPrice(newPrice)
+	}
+	return s.orderResultLocked(od), nil
+}
+
+func (s *SimExchange) FetchOpenOrders(symbol string) ([]models.OrderStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.checkFailureLocked("FetchOpenOrders"); err != nil {
+		return nil, err
+	}
+	key := normalizeSymbol(symbol)
+	out := []models.OrderStatus{}
+	for _, od := range s.orders {
+		if od.status.Symbol == key && (od.status.State == "live" || od.status.State == "partially_filled") {
+			out = append(out, od.status)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, _ := strconv.ParseInt(out[i].OrderID, 10, 64)
+		b, _ := strconv.ParseInt(out[j].OrderID, 10, 64)
+		return a < b
+	})
+	return out, nil
+}
+
+func (s *SimExchange) PlaceProtectiveOrder(symbol, side, orderType string, size, triggerPrice float64) (models.OrderResult, error) {
+	side = strings.ToLower(strings.TrimSpace(side))
+	if side != "buy" && side != "sell" {
+		return models.OrderResult{}, fmt.Errorf("invalid side: %s", side)
+	}
+	if !models.IsProtectiveOrderType(orderType) {
+		return models.OrderResult{}, fmt.Errorf("unsupported protective order type: %s", orderType)
+	}
+	size = s.cfg.Instrument.RoundSize(size)
+	triggerPrice = s.cfg.Instrument.RoundPrice(triggerPrice)
+	if size <= 0 || triggerPrice <= 0 {
+		return models.OrderResult{}, fmt.Errorf("invalid size/trigger: %.8f/%.8f", size, triggerPrice)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := s.checkFailureLocked("PlaceProtectiveOrder")
+	if err != nil {
+		return models.OrderResult{}, err
+	}
+	od := s.addOrderLocked(normalizeSymbol(symbol), side, orderType, size, 0, true)
+	od.triggerPrice = triggerPrice
+	if f != nil && f.Kind == SimFailTimeout {
+		return models.OrderResult{}, fmt.Errorf("sim PlaceProtectiveOrder timeout (injected)")
+	}
+	return s.orderResultLocked(od), nil
+}
+
+func (s *SimExchange) CancelProtectiveOrder(symbol, orderID string) error {
+	return s.CancelOrder(symbol, orderID)
+}
+
+func (s *SimExchange) FetchProtectiveOrder(symbol, orderID string) (*models.OrderStatus, error) {
+	return s.FetchOrder(symbol, orderID)
+}
+
+func (s *SimExchange) FetchInstrument(symbol string) (models.Instrument, error) {
+	inst := s.cfg.Instrument
+	inst.Symbol = normalizeSymbol(symbol)
+	return inst, nil
+}
@@ -1,15 +1,44 @@
 package exchange
 
-import "trade-go/models"
+import (
+	"time"
+	"trade-go/models"
+)
 
 // backend 定义统一交易所能力，便于多交易所扩展。
 type backend interface {
 	FetchOHLCV(symbol, timeframe string, limit int) ([]models.OHLCV, error)
+	// 资金费率、标记/指数价格与持仓量
+	FetchMarketStats(symbol string) (models.MarketStats, error)
+	// 前 depth 档盘口
+	FetchOrderBook(symbol string, depth int) (models.OrderBook, error)
 	FetchBalance() (float64, error)
 	FetchAvailableBalance() (float64, error)
+	// 按资产列出钱包/可用/保证金余额（含 USDC 等其他抵押资产），仅返回非零资产
+	FetchBalances() ([]models.AssetBalance, error)
 	SetLeverage(symbol string, leverage int) error
-	FetchPosition(symbol string) (*models.Position, error)
+	// 保证金模式：models.MarginModeCross / models.MarginModeIsolated
+	SetMarginMode(symbol, mode string) error
+	// 返回全部非零持仓腿：单向模式至多一腿，双向模式多空两腿可同时存在；symbol 为空时返回账户全部 USDT 永续持仓
+	FetchPositions(symbol string) ([]models.Position, error)
+	// 账户持仓模式：models.PositionModeOneWay / models.PositionModeHedge
+	FetchPositionMode(symbol string) (string, error)
 	PlaceMarketOrder(symbol, side string, size float64, reduceOnly bool) error
 	PlaceMarketOrderWithResult(symbol, side string, size float64, reduceOnly bool) (models.OrderResult, error)
 	FetchOrder(symbol, orderID string) (*models.OrderStatus, error)
+	PlaceLimitOrder(symbol, side string, size, price float64, timeInForce string, reduceOnly bool) (models.OrderResult, error)
+	CancelOrder(symbol, orderID string) error
+	AmendOrder(symbol, orderID string, newSize, newPrice float64) (models.OrderResult, error)
+	FetchOpenOrders(symbol string) ([]models.OrderStatus, error)
+	// 交易所侧只减仓止损/止盈条件单
+	PlaceProtectiveOrder(symbol, side, orderType string, size, triggerPrice float64) (models.OrderResult, error)
+	CancelProtectiveOrder(symbol, orderID string) error
+	FetchProtectiveOrder(symbol, orderID string) (*models.OrderStatus, error)
+	// 资金流水（已实现盈亏、手续费、资金费），返回 since 之后的记录并按时间升序；
+	// 区间未走完时同时返回已取到的部分与 HistoryIncompleteError
+	FetchIncome(since time.Time) ([]models.IncomeEvent, error)
+	// 逐笔成交历史，返回 since 之后的记录并按时间升序，区间未走完时同 FetchIncome
+	FetchTradeFills(symbol string, since time.Time) ([]models.TradeFill, error)
+	// 交易对规格（带 TTL 缓存）
+	FetchInstrument(symbol string) (models.Instrument, error)
 }
@@ -0,0 +1,242 @@
+package exchange
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"trade-go/models"
+
+	"github.com/gorilla/websocket"
+)
+
+// 私有推送事件类型
+const (
+	UserEventOrder    = "order"
+	UserEventPosition = "position"
+	UserEventAccount  = "account"
+)
+
+// UserEvent 私有 WebSocket 推送事件。订单事件的 Order 为累计状态，本次推送含成交时 Trade 为该笔成交明细；
+// 持仓事件中 Size 为 0 的腿表示已平仓。
+type UserEvent struct {
+	Kind      string
+	Order     *models.OrderStatus
+	Trade     *models.TradeFill
+	Positions []models.Position
+	Balances  []models.AssetBalance
+	Time      time.Time
+}
+
+type UserEventHandler func(UserEvent)
+
+// UserStreamStatus 私有推送连接状态，供 /api/system/runtime 展示。
+type UserStreamStatus struct {
+	Exchange    string    `json:"exchange"`
+	Connected   bool      `json:"connected"`
+	ConnectedAt time.Time `json:"connected_at,omitempty"`
+	LastEventAt time.Time `json:"last_event_at,omitempty"`
+	Reconnects  int       `json:"reconnects"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// userStreamer 支持私有推送的交易所后端。
+type userStreamer interface {
+	userStreamSource() (userStreamSource, error)
+}
+
+// userStreamSource 单个交易所私有推送的连接、保活与消息解析。
+type userStreamSource interface {
+	// dial 建立连接并完成鉴权与订阅
+	dial() (*websocket.Conn, error)
+	keepaliveInterval() time.Duration
+	// keepalive Binance 延长 listenKey 有效期，OKX 发送文本 ping
+	keepalive(conn *websocket.Conn) error
+	// decode 返回 error 时断开重连（如 listenKey 过期、订阅被拒）
+	decode(msg []byte) ([]UserEvent, error)
+	close()
+}
+
+// 断线重连退避区间
+const (
+	userStreamRetryMin = 5 * time.Second
+	userStreamRetryMax = time.Minute
+)
+
+// UserStream 私有推送订阅，断线后按退避自动重连；推送仅用于加速落库，REST 轮询仍是兜底。
+type UserStream struct {
+	exchange string
+	source   userStreamSource
+	handler  UserEventHandler
+	done     chan struct{}
+
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	stopped bool
+	status  UserStreamStatus
+}
+
+func (c *Client) StartUserStream(handler UserEventHandler) (*UserStream, error) {
+	if c == nil || c.impl == nil {
+		return nil, fmt.Errorf("exchange client not initialized")
+	}
+	streamer, ok := c.impl.(userStreamer)
+	if !ok {
+		return nil, fmt.Errorf("%s 暂不支持私有推送", c.ActiveExchange())
+	}
+	src, err := streamer.userStreamSource()
+	if err != nil {
+		return nil, err
+	}
+	s := &UserStream{
+		exchange: c.ActiveExchange(),
+		source:   src,
+		handler:  handler,
+		done:     make(chan struct{}),
+		status:   UserStreamStatus{Exchange: c.ActiveExchange()},
+	}
+	conn, err := src.dial()
+	if err != nil {
+		src.close()
+		return nil, err
+	}
+	s.setConn(conn)
+	go s.run(conn)
+	return s, nil
+}
+
+func (s *UserStream) Stop() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return nil
+	}
+	s.stopped = true
+	close(s.done)
+	conn := s.conn
+	s.conn = nil
+	s.status.Connected = false
+	s.mu.Unlock()
+	var err error
+	if conn != nil {
+		err = conn.Close()
+	}
+	s.source.close()
+	return err
+}
+
+func (s *UserStream) Status() UserStreamStatus {
+	if s == nil {
+		return UserStreamStatus{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+func (s *UserStream) setConn(conn *websocket.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		_ = conn.Close()
+		return
+	}
+	s.conn = conn
+	s.status.Connected = true
+	s.status.ConnectedAt = time.Now()
+}
+
+func (s *UserStream) setError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Connected = false
+	if err != nil {
+		s.status.LastError = err.Error()
+	}
+}
+
+func (s *UserStream) isStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+func (s *UserStream) run(conn *websocket.Conn) {
+	for {
+		s.serve(conn)
+		delay := userStreamRetryMin
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-time.After(delay):
+			}
+			c, err := s.source.dial()
+			if err == nil {
+				conn = c
+				break
+			}
+			s.setError(err)
+			if delay *= 2; delay > userStreamRetryMax {
+				delay = userStreamRetryMax
+			}
+		}
+		s.mu.Lock()
+		s.status.Reconnects++
+		s.mu.Unlock()
+		s.setConn(conn)
+		if s.isStopped() {
+			return
+		}
+	}
+}
+
+// serve 读取推送直到连接断开；保活失败时主动关闭连接触发重连。
+func (s *UserStream) serve(conn *websocket.Conn) {
+	stopKeepalive := make(chan struct{})
+	defer close(stopKeepalive)
+	go func() {
+		ticker := time.NewTicker(s.source.keepaliveInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopKeepalive:
+				return
+			case <-ticker.C:
+				if err := s.source.keepalive(conn); err != nil {
+					s.setError(fmt.Errorf("keepalive: %w", err))
+					_ = conn.Close()
+					return
+				}
+			}
+		}
+	}()
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			if !s.isStopped() {
+				s.setError(err)
+			}
+			return
+		}
+		events, err := s.source.decode(msg)
+		if err != nil {
+			s.setError(err)
+			_ = conn.Close()
+			return
+		}
+		if len(events) == 0 {
+			continue
+		}
+		s.mu.Lock()
+		s.status.LastEventAt = time.Now()
+		s.mu.Unlock()
+		for _, ev := range events {
+			if s.handler != nil {
+				s.handler(ev)
+			}
+		}
+	}
+}
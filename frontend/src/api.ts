@@ -112,7 +112,7 @@ export const deleteExchangeIntegration = (id: string) =>
 
 export const updateSettings = (payload: Record<string, any>) => http.post('/settings', payload)
 export const runNow = () => http.post('/run')
-export const startScheduler = () => http.post('/scheduler/start')
+export const startScheduler = (payload: Record<string, any> = {}) => http.post('/scheduler/start', payload)
 export const stopScheduler = () => http.post('/scheduler/stop')
 export const runPaperSimulateStep = (payload: Record<string, any>) =>
   http.post('/paper/simulate-step', payload, { timeout: 90000 })
@@ -124,3 +124,24 @@ export const stopPaperSimulation = () => http.post('/paper/stop')
 export const resetPaperPnL = (payload: Record<string, any> = {}) => http.post('/paper/reset-pnl', payload)
 export const resetPaperRiskBaseline = (payload: Record<string, any> = {}) =>
   http.post('/paper/risk/reset', payload, { timeout: 30000 })
+
+// SSE 推送：EventSource 无法设置请求头，令牌经查询串传递；断线后浏览器自动携带 Last-Event-ID 重连补发
+export const openEventStream = (topics: string[], onEvent: (topic: string, data: any) => void) => {
+  const params = new URLSearchParams()
+  if (topics.length) params.set('topics', topics.join(','))
+  const token = getAuthToken()
+  if (token) params.set('token', token)
+  const source = new EventSource(`/api/stream?${params.toString()}`)
+  for (const topic of [...topics, 'reset']) {
+    source.addEventListener(topic, (ev) => {
+      let data: any = null
+      try {
+        data = JSON.parse((ev as MessageEvent).data)
+      } catch {
+        return
+      }
+      onEvent(topic, data)
+    })
+  }
+  return source
+}
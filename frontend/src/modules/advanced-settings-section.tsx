@@ -142,6 +142,16 @@ export function AdvancedSettingsSection(p) {
                     <option value="false">false</option>
                   </select>
                 </label>
+                <label>
+                  <span>启用私有推送WS</span>
+                  <select
+                    value={String(systemSettings?.ENABLE_WS_USER_DATA || 'true').toLowerCase() === 'false' ? 'false' : 'true'}
+                    onChange={(e) => setSystemSettings((old) => ({ ...old, ENABLE_WS_USER_DATA: e.target.value }))}
+                  >
+                    <option value="true">true</option>
+                    <option value="false">false</option>
+                  </select>
+                </label>
                 <label>
                   <span>最小轮询间隔秒(1-300)</span>
                   <input
@@ -153,6 +163,26 @@ export function AdvancedSettingsSection(p) {
                     onChange={(e) => setSystemSettings((old) => ({ ...old, REALTIME_MIN_INTERVAL_SEC: e.target.value }))}
                   />
                 </label>
+                <label>
+                  <span>实盘触发方式</span>
+                  <select
+                    value={String(systemSettings?.LIVE_TRIGGER_MODE || 'scheduler').toLowerCase() === 'realtime' ? 'realtime' : 'scheduler'}
+                    onChange={(e) => setSystemSettings((old) => ({ ...old, LIVE_TRIGGER_MODE: e.target.value }))}
+                  >
+                    <option value="scheduler">scheduler</option>
+                    <option value="realtime">realtime</option>
+                  </select>
+                </label>
+                <label>
+                  <span>仅K线收盘触发</span>
+                  <select
+                    value={String(systemSettings?.REALTIME_CLOSED_CANDLE_ONLY || 'false').toLowerCase() === 'true' ? 'true' : 'false'}
+                    onChange={(e) => setSystemSettings((old) => ({ ...old, REALTIME_CLOSED_CANDLE_ONLY: e.target.value }))}
+                  >
+                    <option value="false">false</option>
+                    <option value="true">true</option>
+                  </select>
+                </label>
                 <label>
                   <span>启用策略LLM调用</span>
                   <select
@@ -40,6 +40,33 @@ export const envFieldGroups = [
       { key: 'HTTP_ADDR', label: '服务地址（:8080）' },
     ],
   },
+  {
+    title: '下单执行',
+    fields: [
+      { key: 'ENTRY_ORDER_MODE', label: '开仓方式（market/maker）' },
+      { key: 'MAKER_ENTRY_TIMEOUT_SEC', label: '挂单超时秒数（3-600）' },
+      { key: 'MAKER_ENTRY_OFFSET_BPS', label: '挂单价格偏移 bps（0-100）' },
+      { key: 'MAX_SLIPPAGE_BPS', label: '最大预估滑点 bps（0-500，0 关闭）' },
+      { key: 'SLIPPAGE_ACTION', label: '滑点超限处理（downsize/block）' },
+    ],
+  },
+  {
+    title: '技术指标（全局，可被习惯档位/生成策略覆盖）',
+    fields: [
+      { key: 'SMA_FAST_PERIOD', label: '快速均线周期（2-500）' },
+      { key: 'SHORT_TERM_PERIOD', label: '短期趋势均线周期（2-500）' },
+      { key: 'MEDIUM_TERM_PERIOD', label: '中期趋势均线周期（2-500）' },
+      { key: 'LONG_TERM_PERIOD', label: '长期均线周期（2-500）' },
+      { key: 'EMA_FAST_PERIOD', label: 'MACD 快线 EMA 周期' },
+      { key: 'EMA_SLOW_PERIOD', label: 'MACD 慢线 EMA 周期' },
+      { key: 'MACD_SIGNAL_PERIOD', label: 'MACD 信号线周期' },
+      { key: 'RSI_PERIOD', label: 'RSI 周期' },
+      { key: 'BB_PERIOD', label: '布林带周期' },
+      { key: 'BB_MULTIPLIER', label: '布林带标准差倍数（0-5）' },
+      { key: 'VOLUME_MA_PERIOD', label: '成交量均线周期' },
+      { key: 'SR_LOOKBACK', label: '支撑阻力回看K线数' },
+    ],
+  },
   {
     title: '自动评估',
     fields: [
@@ -83,7 +110,10 @@ export const systemSettingDefaults = {
   TIMEFRAME: '15m',
   DATA_POINTS: '96',
   ENABLE_WS_MARKET: 'true',
+  ENABLE_WS_USER_DATA: 'true',
   REALTIME_MIN_INTERVAL_SEC: '5',
+  LIVE_TRIGGER_MODE: 'scheduler',
+  REALTIME_CLOSED_CANDLE_ONLY: 'false',
   STRATEGY_LLM_ENABLED: 'true',
   STRATEGY_LLM_TIMEOUT_SEC: '60',
   TEST_MODE: 'false',
@@ -99,6 +129,23 @@ export const systemSettingDefaults = {
   AUTO_STRATEGY_REGEN_LOSS_STREAK: '3',
   AUTO_STRATEGY_REGEN_DRAWDOWN_WARN_PCT: '0.08',
   AUTO_STRATEGY_REGEN_MIN_RR: '2.0',
+  ENTRY_ORDER_MODE: 'market',
+  MAKER_ENTRY_TIMEOUT_SEC: '30',
+  MAKER_ENTRY_OFFSET_BPS: '2',
+  MAX_SLIPPAGE_BPS: '20',
+  SLIPPAGE_ACTION: 'downsize',
+  SMA_FAST_PERIOD: '5',
+  SHORT_TERM_PERIOD: '20',
+  MEDIUM_TERM_PERIOD: '50',
+  LONG_TERM_PERIOD: '96',
+  EMA_FAST_PERIOD: '12',
+  EMA_SLOW_PERIOD: '26',
+  MACD_SIGNAL_PERIOD: '9',
+  RSI_PERIOD: '14',
+  BB_PERIOD: '20',
+  BB_MULTIPLIER: '2',
+  VOLUME_MA_PERIOD: '20',
+  SR_LOOKBACK: '20',
 }
 
 export const strategyGeneratorPromptTemplateDefault = `你是资深量化策略研究员。请为 ${'${symbol}'} 在 ${'${habit}'} 交易习惯下生成一套可执行自动策略。
@@ -85,6 +85,13 @@ function classifyOpenDecision(code, signal, approved, reason) {
   const r = String(reason || '').trim()
   const rl = r.toLowerCase()
 
+  if (c.startsWith('candle_') || rl.includes('candle_') || r.includes('K线数据质量')) {
+    return {
+      text: 'K线数据质量阻断',
+      guide: 'K线存在无法修复的缺口、停滞或异常报价，等待行情恢复；持续出现时检查交易所连接与系统时钟。',
+    }
+  }
+
   const riskBlocked = (
     c === 'paper_risk_blocked' ||
     c === 'risk_blocked' ||
@@ -529,6 +536,16 @@ export function LivePageSection(p) {
               onBlur={() => setSettings((v) => ({ ...v, leverage: normalizeLeverage(v.leverage) }))}
             />
           </label>
+          <label>
+            <span>保证金模式</span>
+            <select
+              value={settings.marginMode || 'cross'}
+              onChange={(e) => setSettings((v) => ({ ...v, marginMode: e.target.value }))}
+            >
+              <option value="cross">全仓</option>
+              <option value="isolated">逐仓</option>
+            </select>
+          </label>
         </div>
 
         <div className="actions-row">
@@ -159,6 +159,15 @@ export function ExchangeConfigModal(p) {
           <select value={newExchange.exchange} onChange={(e) => setNewExchange((v) => ({ ...v, exchange: e.target.value }))}>
             <option value="binance">binance</option>
             <option value="okx">okx</option>
+            <option value="bybit">bybit</option>
+          </select>
+        </label>
+        <label>
+          <span>环境</span>
+          <select value={newExchange.environment || 'mainnet'} onChange={(e) => setNewExchange((v) => ({ ...v, environment: e.target.value }))}>
+            <option value="mainnet">实盘 (mainnet)</option>
+            <option value="testnet">测试网 (testnet)</option>
+            <option value="demo">模拟盘 (demo)</option>
           </select>
         </label>
         <label><span>API Key</span><input value={newExchange.api_key} onChange={(e) => setNewExchange((v) => ({ ...v, api_key: e.target.value }))} /></label>
@@ -1,6 +1,17 @@
 import { ActionButton } from '@/components/ui/action-button'
 import { Space, Tabs } from '@/components/ui/dashboard-primitives'
 
+function exchangeEnvLabel(env) {
+  switch (String(env || '').toLowerCase()) {
+    case 'testnet':
+      return '测试网'
+    case 'demo':
+      return '模拟盘'
+    default:
+      return '实盘'
+  }
+}
+
 export function SystemPageSection(p) {
   const {
     systemSubTab,
@@ -321,7 +332,10 @@ export function SystemPageSection(p) {
             <section className={`sub-window exchange-bind-status ${exchangeBound ? 'is-bound' : 'is-unbound'}`}>
               <h4>账号绑定状态</h4>
               {exchangeBound ? (
-                <p>已绑定交易账号，当前 ID：{activeExchangeId || '-'}</p>
+                <p>
+                  已绑定交易账号，当前 ID：{activeExchangeId || '-'}，环境：
+                  {exchangeEnvLabel(exchangeConfigs.find((x) => String(x.id) === activeExchangeId)?.environment)}
+                </p>
               ) : (
                 <p>未绑定交易账号，请在下方列表中选择一个账号进行绑定。</p>
               )}
@@ -332,6 +346,7 @@ export function SystemPageSection(p) {
                   <tr>
                     <th>ID</th>
                     <th>交易所</th>
+                    <th>环境</th>
                     <th>API Key</th>
                     <th>状态</th>
                     <th>操作</th>
@@ -342,6 +357,7 @@ export function SystemPageSection(p) {
                     <tr key={x.id} className={String(x.id) === activeExchangeId ? 'exchange-row-active' : ''}>
                       <td>{x.id}</td>
                       <td>{x.exchange}</td>
+                      <td>{exchangeEnvLabel(x.environment)}</td>
                       <td>{x.api_key ? `${String(x.api_key).slice(0, 6)}***` : '-'}</td>
                       <td>{String(x.id) === activeExchangeId ? '已绑定' : '未绑定'}</td>
                       <td>
@@ -366,7 +382,7 @@ export function SystemPageSection(p) {
                     </tr>
                   ))}
                   {!exchangeConfigs.length ? (
-                    <tr><td colSpan={5} className="muted">暂无交易所参数</td></tr>
+                    <tr><td colSpan={6} className="muted">暂无交易所参数</td></tr>
                   ) : null}
                 </tbody>
               </table>
@@ -70,6 +70,7 @@ import {
   deleteBacktestHistory,
   runNow,
   getPaperState,
+  openEventStream,
   updatePaperConfig,
   startPaperSimulation,
   stopPaperSimulation,
@@ -116,7 +117,10 @@ const ADVANCED_ENV_KEYS = [
   'TIMEFRAME',
   'DATA_POINTS',
   'ENABLE_WS_MARKET',
+  'ENABLE_WS_USER_DATA',
   'REALTIME_MIN_INTERVAL_SEC',
+  'LIVE_TRIGGER_MODE',
+  'REALTIME_CLOSED_CANDLE_ONLY',
   'STRATEGY_LLM_ENABLED',
   'STRATEGY_LLM_TIMEOUT_SEC',
   'TEST_MODE',
@@ -298,6 +302,7 @@ function normalizeHabitProfilesState(raw: any) {
       preferred_data_span: Number(row?.preferred_data_span ?? 0),
       description: String(row?.description || '').trim(),
       execution_hint: String(row?.execution_hint || '').trim(),
+      ...(row?.indicator_params && typeof row.indicator_params === 'object' ? { indicator_params: row.indicator_params } : {}),
     })
   }
   if (out.length) return out
@@ -424,6 +429,7 @@ function normalizeGeneratedStrategyItem(row) {
     prompt: generatorPrompt || strategyGeneratorPromptTemplateDefault,
     logic: String(row.logic || '').trim(),
     basis: String(row.basis || '').trim(),
+    indicatorParams: (row.indicator_params ?? row.indicatorParams) || null,
   }
 }
 
@@ -441,6 +447,7 @@ function toGeneratedStrategyPayload(row) {
     source: String(row?.source || 'workflow_generated').trim(),
     workflow_version: String(row?.workflowVersion || '').trim(),
     workflow_chain: parseStrategies(Array.isArray(row?.workflowChain) ? row.workflowChain : []),
+    ...(row?.indicatorParams ? { indicator_params: row.indicatorParams } : {}),
   }
 }
 
@@ -491,6 +498,7 @@ export function useDashboardController() {
     highConfidenceMarginPct: 5,
     lowConfidenceMarginPct: 0,
     leverage: 20,
+    marginMode: 'cross',
   })
   const [paperSettings, setPaperSettings] = useState({
     positionSizingMode: 'margin_pct',
@@ -543,6 +551,7 @@ export function useDashboardController() {
   const [newExchange, setNewExchange] = useState<Record<string, any>>({
     name: '',
     exchange: 'binance',
+    environment: 'mainnet',
     api_key: '',
     secret: '',
     passphrase: '',
@@ -670,17 +679,17 @@ export function useDashboardController() {
   const liveStrategyLabel = enabledStrategies.length ? enabledStrategies.join(' / ') : (activeStrategy || '-')
   const activeExchangeType = useMemo(() => {
     const fromAccount = String(account?.active_exchange || '').trim().toLowerCase()
-    if (fromAccount === 'okx' || fromAccount === 'binance') {
+    if (fromAccount === 'okx' || fromAccount === 'binance' || fromAccount === 'bybit') {
       return fromAccount
     }
     const fromRuntime = String(systemRuntime?.integration?.exchange?.exchange || '').trim().toLowerCase()
-    if (fromRuntime === 'okx' || fromRuntime === 'binance') {
+    if (fromRuntime === 'okx' || fromRuntime === 'binance' || fromRuntime === 'bybit') {
       return fromRuntime
     }
     const id = String(activeExchangeId || '').trim()
     const matched = exchangeConfigs.find((x) => String(x?.id || '').trim() === id)
     const fromConfig = String(matched?.exchange || '').trim().toLowerCase()
-    if (fromConfig === 'okx' || fromConfig === 'binance') {
+    if (fromConfig === 'okx' || fromConfig === 'binance' || fromConfig === 'bybit') {
       return fromConfig
     }
     return 'binance'
@@ -798,6 +807,7 @@ export function useDashboardController() {
             lowConfidenceMarginPct: Number(cfg.low_confidence_margin_pct ?? 0) * 100,
             leverage: Number(cfg.leverage ?? old.leverage ?? 20),
           }),
+          marginMode: String(cfg.margin_mode ?? old.marginMode ?? 'cross'),
         }))
       }
       if (!paperSettingsHydratedRef.current) {
@@ -1265,6 +1275,28 @@ export function useDashboardController() {
     return () => clearInterval(timer)
   }, [])
 
+  // 推送事件触发增量刷新，轮询保留作兜底；短时间内的多条事件合并为一次刷新
+  const loadPaperStateRef = useRef(loadPaperState)
+  loadPaperStateRef.current = loadPaperState
+  useEffect(() => {
+    let coreTimer: ReturnType<typeof setTimeout> | null = null
+    let paperTimer: ReturnType<typeof setTimeout> | null = null
+    const source = openEventStream(['cycle', 'signal', 'order', 'fill', 'position', 'paper', 'risk'], (topic) => {
+      if (topic === 'paper' || topic === 'reset') {
+        if (paperTimer) clearTimeout(paperTimer)
+        paperTimer = setTimeout(() => void loadPaperStateRef.current(true, false), 800)
+        if (topic === 'paper') return
+      }
+      if (coreTimer) clearTimeout(coreTimer)
+      coreTimer = setTimeout(() => refreshCore(true), 800)
+    })
+    return () => {
+      source.close()
+      if (coreTimer) clearTimeout(coreTimer)
+      if (paperTimer) clearTimeout(paperTimer)
+    }
+  }, [])
+
   useEffect(() => {
     if (!toast.visible) return undefined
     const timer = setTimeout(() => {
@@ -1866,6 +1898,7 @@ export function useDashboardController() {
       high_confidence_margin_pct: normalizedSettings.highConfidenceMarginPct / 100,
       low_confidence_margin_pct: normalizedSettings.lowConfidenceMarginPct / 100,
       leverage: normalizedSettings.leverage,
+      margin_mode: settings.marginMode === 'isolated' ? 'isolated' : 'cross',
     })
 
     const normalized = enabledStrategies.filter((x) => executionStrategyOptions.includes(x))
@@ -2400,6 +2433,7 @@ export function useDashboardController() {
         api_key: String(newExchange.api_key || '').trim(),
         secret: String(newExchange.secret || '').trim(),
         passphrase: String(newExchange.passphrase || '').trim(),
+        environment: String(newExchange.environment || 'mainnet').trim(),
       }
       if (String(payload.exchange).toLowerCase() === 'okx' && !String(payload.passphrase || '').trim()) {
         throw new Error('OKX 需要填写 passphrase')
@@ -2409,12 +2443,13 @@ export function useDashboardController() {
       setActiveExchangeId(String(res?.data?.active_exchange_id || ''))
       setExchangeBound(Boolean(res?.data?.exchange_bound))
       const boundExchange = String(res?.data?.active_exchange?.exchange || '').trim().toLowerCase()
-      if (boundExchange === 'okx' || boundExchange === 'binance') {
+      if (boundExchange === 'okx' || boundExchange === 'binance' || boundExchange === 'bybit') {
         setAccount((prev) => ({ ...prev, active_exchange: boundExchange }))
       }
       setNewExchange({
         name: '',
         exchange: 'binance',
+        environment: 'mainnet',
         api_key: '',
         secret: '',
         passphrase: '',
@@ -2447,7 +2482,7 @@ export function useDashboardController() {
       setActiveExchangeId(String(res?.data?.active_exchange_id || exchangeID))
       setExchangeBound(Boolean(res?.data?.exchange_bound))
       const boundExchange = String(res?.data?.active_exchange?.exchange || '').trim().toLowerCase()
-      if (boundExchange === 'okx' || boundExchange === 'binance') {
+      if (boundExchange === 'okx' || boundExchange === 'binance' || boundExchange === 'bybit') {
         setAccount((prev) => ({ ...prev, active_exchange: boundExchange }))
       }
       await loadSystemAndStrategies()
@@ -2480,7 +2515,7 @@ export function useDashboardController() {
       setActiveExchangeId(String(res?.data?.active_exchange_id || ''))
       setExchangeBound(Boolean(res?.data?.exchange_bound))
       const boundExchange = String(res?.data?.active_exchange?.exchange || '').trim().toLowerCase()
-      if (boundExchange === 'okx' || boundExchange === 'binance') {
+      if (boundExchange === 'okx' || boundExchange === 'binance' || boundExchange === 'bybit') {
         setAccount((prev) => ({ ...prev, active_exchange: boundExchange }))
       } else {
         setAccount((prev) => ({ ...prev, active_exchange: '' }))
@@ -3051,6 +3086,9 @@ export function useDashboardController() {
       if (res?.data?.history_warning) {
         showToast('warning', String(res.data.history_warning))
       }
+      if (res?.data?.kline_warning) {
+        showToast('warning', String(res.data.kline_warning))
+      }
     } catch (e) {
       setError(e?.response?.data?.error || e?.message || '回测失败')
     } finally {
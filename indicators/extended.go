@@ -0,0 +1,346 @@
+package indicators
+
+import (
+	"math"
+	"time"
+	"trade-go/models"
+)
+
+// 扩展指标默认参数
+const (
+	atrPeriod        = 14
+	adxPeriod        = 14
+	stochPeriod      = 14
+	stochSmoothK     = 3
+	stochSmoothD     = 3
+	obvMAPeriod      = 20
+	superTrendPeriod = 10
+	superTrendMult   = 3.0
+	ichimokuTenkan   = 9
+	ichimokuKijun    = 26
+	ichimokuSenkouB  = 52
+)
+
+// 以下序列函数与输入等长，预热期内（数据不足以得到标准值）为 NaN。
+
+// SMA 收盘价简单均线。
+func SMA(candles []models.OHLCV, period int) []float64 {
+	closes := make([]float64, len(candles))
+	for i, c := range candles {
+		closes[i] = c.Close
+	}
+	return strictSMA(closes, period)
+}
+
+// TrueRange 真实波幅，首根无前收盘，取最高价减最低价。
+func TrueRange(candles []models.OHLCV) []float64 {
+	out := make([]float64, len(candles))
+	for i, c := range candles {
+		tr := c.High - c.Low
+		if i > 0 {
+			prev := candles[i-1].Close
+			tr = math.Max(tr, math.Max(math.Abs(c.High-prev), math.Abs(c.Low-prev)))
+		}
+		out[i] = tr
+	}
+	return out
+}
+
+// ATR Wilder 平滑的平均真实波幅，第 period 根起有效（以前 period 根真实波幅均值为起点）。
+func ATR(candles []models.OHLCV, period int) []float64 {
+	return wilder(TrueRange(candles), 0, period)
+}
+
+// DMI 趋向指标 +DI/-DI 与 ADX（Wilder）。DI 自第 period+1 根起有效，ADX 再需 period 根 DX 预热。
+func DMI(candles []models.OHLCV, period int) (plusDI, minusDI, adx []float64) {
+	n := len(candles)
+	plusDI, minusDI, adx = nanSeries(n), nanSeries(n), nanSeries(n)
+	if period <= 0 || n <= period {
+		return
+	}
+	tr := TrueRange(candles)
+	plusDM := make([]float64, n)
+	minusDM := make([]float64, n)
+	for i := 1; i < n; i++ {
+		up := candles[i].High - candles[i-1].High
+		down := candles[i-1].Low - candles[i].Low
+		if up > down && up > 0 {
+			plusDM[i] = up
+		}
+		if down > up && down > 0 {
+			minusDM[i] = down
+		}
+	}
+	// Wilder 累计平滑：首值为第 1..period 根之和
+	var sTR, sPlus, sMinus float64
+	dx := nanSeries(n)
+	for i := 1; i < n; i++ {
+		if i <= period {
+			sTR += tr[i]
+			sPlus += plusDM[i]
+			sMinus += minusDM[i]
+			if i < period {
+				continue
+			}
+		} else {
+			sTR = sTR - sTR/float64(period) + tr[i]
+			sPlus = sPlus - sPlus/float64(period) + plusDM[i]
+			sMinus = sMinus - sMinus/float64(period) + minusDM[i]
+		}
+		if sTR == 0 {
+			plusDI[i], minusDI[i], dx[i] = 0, 0, 0
+			continue
+		}
+		plusDI[i] = 100 * sPlus / sTR
+		minusDI[i] = 100 * sMinus / sTR
+		if sum := plusDI[i] + minusDI[i]; sum > 0 {
+			dx[i] = 100 * math.Abs(plusDI[i]-minusDI[i]) / sum
+		} else {
+			dx[i] = 0
+		}
+	}
+	adx = wilder(dx, period, period)
+	return
+}
+
+// Stochastic 慢速随机指标：原始 %K 经 smoothK 周期 SMA 得到 %K，再经 smoothD 周期 SMA 得到 %D。
+func Stochastic(candles []models.OHLCV, period, smoothK, smoothD int) (k, d []float64) {
+	n := len(candles)
+	raw := nanSeries(n)
+	for i := period - 1; i < n && period > 0; i++ {
+		hh, ll := highestLowest(candles[i-period+1 : i+1])
+		if hh == ll {
+			raw[i] = 50
+			continue
+		}
+		raw[i] = 100 * (candles[i].Close - ll) / (hh - ll)
+	}
+	k = strictSMA(raw, smoothK)
+	d = strictSMA(k, smoothD)
+	return
+}
+
+// VWAP 按锚点分段累计的成交量加权均价（典型价 (H+L+C)/3）。anchor 返回 K 线所属时段的起点，
+// 序列第一根之前开始的时段数据不完整，记为 NaN；时段内成交量为 0 时同样为 NaN。
+func VWAP(candles []models.OHLCV, anchor func(time.Time) time.Time) []float64 {
+	out := nanSeries(len(candles))
+	if len(candles) == 0 {
+		return out
+	}
+	var session time.Time
+	complete := false
+	var pv, vol float64
+	for i, c := range candles {
+		start := anchor(c.Timestamp)
+		if i == 0 || !start.Equal(session) {
+			session = start
+			complete = i > 0 || !c.Timestamp.After(start)
+			pv, vol = 0, 0
+		}
+		pv += (c.High + c.Low + c.Close) / 3 * c.Volume
+		vol += c.Volume
+		if complete && vol > 0 {
+			out[i] = pv / vol
+		}
+	}
+	return out
+}
+
+// SessionAnchor UTC 自然日。
+func SessionAnchor(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// WeekAnchor UTC 周一 00:00。
+func WeekAnchor(t time.Time) time.Time {
+	day := SessionAnchor(t)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// OBV 能量潮，以首根为 0 起算，只有相对变化有意义。
+func OBV(candles []models.OHLCV) []float64 {
+	out := make([]float64, len(candles))
+	for i := 1; i < len(candles); i++ {
+		out[i] = out[i-1]
+		switch {
+		case candles[i].Close > candles[i-1].Close:
+			out[i] += candles[i].Volume
+		case candles[i].Close < candles[i-1].Close:
+			out[i] -= candles[i].Volume
+		}
+	}
+	return out
+}
+
+// SuperTrend 基于 ATR 通道的趋势线，dir 为 1（多头，线在价格下方）或 -1（空头），预热期内 dir 为 0。
+func SuperTrend(candles []models.OHLCV, period int, mult float64) (line []float64, dir []int) {
+	n := len(candles)
+	line = nanSeries(n)
+	dir = make([]int, n)
+	atr := ATR(candles, period)
+	var upper, lower float64
+	started := false
+	for i := 0; i < n; i++ {
+		if math.IsNaN(atr[i]) {
+			continue
+		}
+		c := candles[i]
+		mid := (c.High + c.Low) / 2
+		basicUpper, basicLower := mid+mult*atr[i], mid-mult*atr[i]
+		if !started {
+			// 与常见实现一致，首根有效 K 线按空头起算
+			upper, lower = basicUpper, basicLower
+			dir[i] = -1
+			started = true
+		} else {
+			prevClose := candles[i-1].Close
+			if basicUpper < upper || prevClose > upper {
+				upper = basicUpper
+			}
+			if basicLower > lower || prevClose < lower {
+				lower = basicLower
+			}
+			// 通道只向趋势方向收敛，收盘价突破收敛后的通道即反转
+			switch {
+			case dir[i-1] == -1 && c.Close > upper:
+				dir[i] = 1
+			case dir[i-1] == 1 && c.Close < lower:
+				dir[i] = -1
+			default:
+				dir[i] = dir[i-1]
+			}
+		}
+		if dir[i] == 1 {
+			line[i] = lower
+		} else {
+			line[i] = upper
+		}
+	}
+	return
+}
+
+// Ichimoku 一目均衡表。senkouA/senkouB 为当前 K 线对应的云层，即 kijun 根之前计算、向前平移的值。
+func Ichimoku(candles []models.OHLCV, tenkanPeriod, kijunPeriod, senkouBPeriod int) (tenkan, kijun, senkouA, senkouB []float64) {
+	n := len(candles)
+	tenkan = donchianMid(candles, tenkanPeriod)
+	kijun = donchianMid(candles, kijunPeriod)
+	spanB := donchianMid(candles, senkouBPeriod)
+	senkouA, senkouB = nanSeries(n), nanSeries(n)
+	shift := kijunPeriod
+	for i := shift; i < n; i++ {
+		senkouA[i] = (tenkan[i-shift] + kijun[i-shift]) / 2
+		senkouB[i] = spanB[i-shift]
+	}
+	return
+}
+
+// calculateExtended 填充扩展指标，预热不足的指标保持为 0。
+func calculateExtended(candles []models.OHLCV, out *models.TechnicalIndicators) {
+	n := len(candles)
+	if n == 0 {
+		return
+	}
+	price := candles[n-1].Close
+	out.ATR = last(ATR(candles, atrPeriod))
+	if out.ATR > 0 && price > 0 {
+		out.ATRPct = out.ATR / price * 100
+	}
+	plusDI, minusDI, adx := DMI(candles, adxPeriod)
+	out.PlusDI, out.MinusDI, out.ADX = last(plusDI), last(minusDI), last(adx)
+	k, d := Stochastic(candles, stochPeriod, stochSmoothK, stochSmoothD)
+	out.StochK, out.StochD = last(k), last(d)
+	out.VWAP = last(VWAP(candles, SessionAnchor))
+	out.VWAPWeekly = last(VWAP(candles, WeekAnchor))
+	obv := OBV(candles)
+	out.OBV = obv[n-1]
+	out.OBVMA = last(strictSMA(obv, obvMAPeriod))
+	line, dir := SuperTrend(candles, superTrendPeriod, superTrendMult)
+	out.SuperTrend, out.SuperTrendDir = last(line), dir[n-1]
+	tenkan, kijun, senkouA, senkouB := Ichimoku(candles, ichimokuTenkan, ichimokuKijun, ichimokuSenkouB)
+	out.IchimokuTenkan, out.IchimokuKijun = last(tenkan), last(kijun)
+	out.IchimokuSenkouA, out.IchimokuSenkouB = last(senkouA), last(senkouB)
+}
+
+func nanSeries(n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	return out
+}
+
+// last 序列最后一个值，未预热时为 0。
+func last(series []float64) float64 {
+	if len(series) == 0 || math.IsNaN(series[len(series)-1]) {
+		return 0
+	}
+	return series[len(series)-1]
+}
+
+// wilder 从 data[start] 起跳过 NaN 后，以前 period 个值的均值为起点做 Wilder 平滑（RMA）。
+func wilder(data []float64, start, period int) []float64 {
+	out := nanSeries(len(data))
+	if period <= 0 {
+		return out
+	}
+	for start < len(data) && math.IsNaN(data[start]) {
+		start++
+	}
+	if start+period > len(data) {
+		return out
+	}
+	sum := 0.0
+	for _, v := range data[start : start+period] {
+		sum += v
+	}
+	prev := sum / float64(period)
+	out[start+period-1] = prev
+	for i := start + period; i < len(data); i++ {
+		prev = (prev*float64(period-1) + data[i]) / float64(period)
+		out[i] = prev
+	}
+	return out
+}
+
+// strictSMA 窗口内全部为有效值时才输出，与 sma 不同不对开头做部分平均。
+func strictSMA(data []float64, period int) []float64 {
+	out := nanSeries(len(data))
+	if period <= 0 {
+		return out
+	}
+	for i := period - 1; i < len(data); i++ {
+		sum := 0.0
+		valid := true
+		for _, v := range data[i-period+1 : i+1] {
+			if math.IsNaN(v) {
+				valid = false
+				break
+			}
+			sum += v
+		}
+		if valid {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out
+}
+
+func highestLowest(candles []models.OHLCV) (float64, float64) {
+	hh, ll := candles[0].High, candles[0].Low
+	for _, c := range candles[1:] {
+		hh = math.Max(hh, c.High)
+		ll = math.Min(ll, c.Low)
+	}
+	return hh, ll
+}
+
+func donchianMid(candles []models.OHLCV, period int) []float64 {
+	out := nanSeries(len(candles))
+	for i := period - 1; i < len(candles) && period > 0; i++ {
+		hh, ll := highestLowest(candles[i-period+1 : i+1])
+		out[i] = (hh + ll) / 2
+	}
+	return out
+}
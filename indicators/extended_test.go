@@ -0,0 +1,173 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+	"time"
+	"trade-go/models"
+)
+
+// 参考值按教科书公式（Wilder 平滑、慢速 KD、典型价 VWAP 等）对同一组数据独立计算得到。
+var (
+	refHigh   = []float64{10.5, 11.2, 11.0, 11.8, 12.4, 12.1, 11.6, 11.9, 12.8, 13.1, 12.7, 12.2}
+	refLow    = []float64{9.8, 10.4, 10.3, 10.9, 11.6, 11.3, 10.8, 11.0, 11.9, 12.3, 11.8, 11.4}
+	refClose  = []float64{10.2, 11.0, 10.6, 11.6, 12.0, 11.5, 11.1, 11.8, 12.6, 12.5, 12.0, 11.6}
+	refVolume = []float64{100, 120, 90, 150, 200, 130, 110, 160, 210, 180, 140, 120}
+)
+
+var nan = math.NaN()
+
+// refCandles 1h K 线，首根在 UTC 22:00，前两根属于序列开始前已开始的时段。
+func refCandles() []models.OHLCV {
+	start := time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC)
+	out := make([]models.OHLCV, len(refClose))
+	for i := range out {
+		out[i] = models.OHLCV{
+			Timestamp: start.Add(time.Duration(i) * time.Hour),
+			Open:      refClose[i],
+			High:      refHigh[i],
+			Low:       refLow[i],
+			Close:     refClose[i],
+			Volume:    refVolume[i],
+		}
+	}
+	return out
+}
+
+// assertSeries 逐点比较，期望为 NaN 的位置必须为 NaN（预热期）。
+func assertSeries(t *testing.T, name string, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s 长度 %d，期望 %d", name, len(got), len(want))
+	}
+	for i := range want {
+		if math.IsNaN(want[i]) {
+			if !math.IsNaN(got[i]) {
+				t.Errorf("%s[%d] 预热期应为 NaN，得到 %v", name, i, got[i])
+			}
+			continue
+		}
+		if math.IsNaN(got[i]) || math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Errorf("%s[%d] = %v，期望 %v", name, i, got[i], want[i])
+		}
+	}
+}
+
+func TestATR(t *testing.T) {
+	assertSeries(t, "ATR", ATR(refCandles(), 3), []float64{
+		nan, nan, 0.8, 0.9333333333333332, 0.888888888888889, 0.8592592592592588,
+		0.8395061728395055, 0.8596707818930037, 0.9064471879286691, 0.8709647919524457,
+		0.8806431946349633, 0.8537621297566419,
+	})
+}
+
+func TestDMI(t *testing.T) {
+	plusDI, minusDI, adx := DMI(refCandles(), 3)
+	assertSeries(t, "+DI", plusDI, []float64{
+		nan, nan, nan, 51.72413793103448, 58.536585365853625, 40.67796610169491,
+		27.906976744186057, 29.78622327790977, 51.822289156626546, 47.45186270406027,
+		31.322638023036948, 21.554111062257142,
+	})
+	assertSeries(t, "-DI", minusDI, []float64{
+		nan, nan, nan, 3.448275862068953, 2.439024390243893, 13.135593220338938,
+		28.63372093023254, 18.717339667458415, 11.867469879518058, 8.246128087065712,
+		24.32693802131005, 32.33354327340143,
+	})
+	// ADX 需要 period 根 DX 预热，首个有效值在第 2*period-1 根
+	assertSeries(t, "ADX", adx, []float64{
+		nan, nan, nan, nan, nan, 76.89370078740164,
+		51.69091620616834, 42.06753212308059, 48.95619032844769, 56.10074501620954,
+		41.59082509743681, 34.39505951431507,
+	})
+}
+
+func TestStochastic(t *testing.T) {
+	k, d := Stochastic(refCandles(), 3, 2, 2)
+	assertSeries(t, "%K", k, []float64{
+		nan, nan, nan, 71.90476190476186, 83.80952380952377, 60.47619047619045,
+		29.374999999999957, 47.83653846153847, 83.46153846153847, 80.7142857142857,
+		43.406593406593394, 13.574660633484124,
+	})
+	assertSeries(t, "%D", d, []float64{
+		nan, nan, nan, nan, 77.8571428571428, 72.14285714285711,
+		44.925595238095205, 38.60576923076921, 65.64903846153847, 82.08791208791209,
+		62.06043956043955, 28.490627020038758,
+	})
+}
+
+func TestVWAPAnchored(t *testing.T) {
+	candles := refCandles()
+	// 前两根所在的自然日从序列开始前就已开始，累计不完整
+	assertSeries(t, "VWAP", VWAP(candles, SessionAnchor), []float64{
+		nan, nan, 10.633333333333333, 11.133333333333333, 11.527272727272727,
+		11.551461988304093, 11.489215686274509, 11.503968253968253, 11.689841269841269,
+		11.82791327913279, 11.862530413625304, 11.852125279642056,
+	})
+
+	// 时段起点恰好是首根 K 线时，首根即有效；成交量为 0 时无均价
+	aligned := candles[2:]
+	aligned[0].Volume = 0
+	got := VWAP(aligned, SessionAnchor)
+	if !math.IsNaN(got[0]) {
+		t.Errorf("零成交量时 VWAP 应为 NaN，得到 %v", got[0])
+	}
+	want := (refHigh[3] + refLow[3] + refClose[3]) / 3
+	if math.Abs(got[1]-want) > 1e-9 {
+		t.Errorf("VWAP[1] = %v，期望 %v", got[1], want)
+	}
+}
+
+func TestOBV(t *testing.T) {
+	// OBV 没有预热期，首根为 0
+	assertSeries(t, "OBV", OBV(refCandles()), []float64{
+		0, 120, 30, 180, 380, 250, 140, 300, 510, 330, 190, 70,
+	})
+}
+
+func TestSuperTrend(t *testing.T) {
+	line, dir := SuperTrend(refCandles(), 3, 1.5)
+	assertSeries(t, "SuperTrend", line, []float64{
+		nan, nan, 11.85, 11.85, 10.666666666666666, 10.666666666666666,
+		10.666666666666666, 10.666666666666666, 10.990329218106998, 11.39355281207133,
+		11.39355281207133, 11.39355281207133,
+	})
+	wantDir := []int{0, 0, -1, -1, 1, 1, 1, 1, 1, 1, 1, 1}
+	for i := range wantDir {
+		if dir[i] != wantDir[i] {
+			t.Errorf("dir[%d] = %d，期望 %d", i, dir[i], wantDir[i])
+		}
+	}
+}
+
+func TestIchimoku(t *testing.T) {
+	tenkan, kijun, senkouA, senkouB := Ichimoku(refCandles(), 2, 3, 4)
+	assertSeries(t, "tenkan", tenkan, []float64{
+		nan, 10.5, 10.75, 11.05, 11.65, 11.85, 11.45, 11.35, 11.9, 12.5, 12.45, 12.05,
+	})
+	assertSeries(t, "kijun", kijun, []float64{
+		nan, nan, 10.5, 11.05, 11.35, 11.65, 11.6, 11.45, 11.8, 12.05, 12.45, 12.25,
+	})
+	// 云层为 kijun 根之前的值向前平移，预热期叠加平移距离
+	assertSeries(t, "senkouA", senkouA, []float64{
+		nan, nan, nan, nan, nan, 10.625, 11.05, 11.5, 11.75, 11.525, 11.4, 11.85,
+	})
+	assertSeries(t, "senkouB", senkouB, []float64{
+		nan, nan, nan, nan, nan, nan, 10.8, 11.35, 11.35, 11.6, 11.6, 11.8,
+	})
+}
+
+func TestShortSeriesAllNaN(t *testing.T) {
+	candles := refCandles()[:2]
+	for name, series := range map[string][]float64{
+		"ATR":   ATR(candles, 3),
+		"ADX":   func() []float64 { _, _, adx := DMI(candles, 3); return adx }(),
+		"%D":    func() []float64 { _, d := Stochastic(candles, 3, 2, 2); return d }(),
+		"Super": func() []float64 { line, _ := SuperTrend(candles, 3, 1.5); return line }(),
+	} {
+		for i, v := range series {
+			if !math.IsNaN(v) {
+				t.Errorf("%s[%d] 数据不足时应为 NaN，得到 %v", name, i, v)
+			}
+		}
+	}
+}
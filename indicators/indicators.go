@@ -5,12 +5,18 @@ import (
 	"trade-go/models"
 )
 
-// Calculate 计算所有技术指标
+// Calculate 按默认参数计算所有技术指标
 func Calculate(candles []models.OHLCV) models.TechnicalIndicators {
+	return CalculateWithParams(candles, DefaultParams())
+}
+
+// CalculateWithParams 按指定参数计算所有技术指标，参数先经 NormalizeParams 规整。
+func CalculateWithParams(candles []models.OHLCV, p models.IndicatorParams) models.TechnicalIndicators {
 	n := len(candles)
 	if n == 0 {
 		return models.TechnicalIndicators{}
 	}
+	p = NormalizeParams(p)
 
 	closes := make([]float64, n)
 	highs := make([]float64, n)
@@ -24,48 +30,50 @@ func Calculate(candles []models.OHLCV) models.TechnicalIndicators {
 		volumes[i] = c.Volume
 	}
 
-	sma5 := sma(closes, 5)
-	sma20 := sma(closes, 20)
-	sma50 := sma(closes, 50)
+	smaFast := sma(closes, p.SMAFast)
+	smaShort := sma(closes, p.SMAShort)
+	smaMedium := sma(closes, p.SMAMedium)
+	smaLong := sma(closes, p.SMALong)
 
-	ema12 := ema(closes, 12)
-	ema26 := ema(closes, 26)
-	macdLine := ema12[n-1] - ema26[n-1]
+	emaFast := ema(closes, p.EMAFast)
+	emaSlow := ema(closes, p.EMASlow)
+	macdLine := emaFast[n-1] - emaSlow[n-1]
 
 	macdSeries := make([]float64, n)
 	for i := range candles {
-		macdSeries[i] = ema12[i] - ema26[i]
+		macdSeries[i] = emaFast[i] - emaSlow[i]
 	}
-	macdSignalSeries := ema(macdSeries, 9)
+	macdSignalSeries := ema(macdSeries, p.MACDSignal)
 	macdSignal := macdSignalSeries[n-1]
 	macdHist := macdLine - macdSignal
 
-	rsiVal := rsi(closes, 14)
+	rsiVal := rsi(closes, p.RSIPeriod)
 
-	bbMid := sma20[n-1]
-	bbStd := rollingStd(closes, 20)
-	bbUpper := bbMid + bbStd*2
-	bbLower := bbMid - bbStd*2
+	bbMid := sma(closes, p.BBPeriod)[n-1]
+	bbStd := rollingStd(closes, p.BBPeriod)
+	bbUpper := bbMid + bbStd*p.BBMultiplier
+	bbLower := bbMid - bbStd*p.BBMultiplier
 	bbPos := 0.0
 	if bbUpper-bbLower != 0 {
 		bbPos = (closes[n-1] - bbLower) / (bbUpper - bbLower)
 	}
 
-	volMA := sma(volumes, 20)
+	volMA := sma(volumes, p.VolumeMAPeriod)
 	volRatio := 0.0
 	if volMA[n-1] != 0 {
 		volRatio = volumes[n-1] / volMA[n-1]
 	}
 
-	resistance := max20(highs)
-	support := min20(lows)
+	resistance := highest(highs, p.SRLookback)
+	support := lowest(lows, p.SRLookback)
 
-	return models.TechnicalIndicators{
-		SMA5:        sma5[n-1],
-		SMA20:       sma20[n-1],
-		SMA50:       sma50[n-1],
-		EMA12:       ema12[n-1],
-		EMA26:       ema26[n-1],
+	out := models.TechnicalIndicators{
+		SMAFast:     smaFast[n-1],
+		SMAShort:    smaShort[n-1],
+		SMAMedium:   smaMedium[n-1],
+		SMALong:     smaLong[n-1],
+		EMAFast:     emaFast[n-1],
+		EMASlow:     emaSlow[n-1],
 		MACD:        macdLine,
 		MACDSignal:  macdSignal,
 		MACDHist:    macdHist,
@@ -79,6 +87,8 @@ func Calculate(candles []models.OHLCV) models.TechnicalIndicators {
 		Resistance:  resistance,
 		Support:     support,
 	}
+	calculateExtended(candles, &out)
+	return out
 }
 
 // AnalyzeTrend 趋势分析
@@ -86,11 +96,11 @@ func AnalyzeTrend(candles []models.OHLCV, ind models.TechnicalIndicators) models
 	currentPrice := candles[len(candles)-1].Close
 
 	shortTerm := "下跌"
-	if currentPrice > ind.SMA20 {
+	if currentPrice > ind.SMAShort {
 		shortTerm = "上涨"
 	}
 	mediumTerm := "下跌"
-	if currentPrice > ind.SMA50 {
+	if currentPrice > ind.SMAMedium {
 		mediumTerm = "上涨"
 	}
 
@@ -220,9 +230,10 @@ func rollingStd(data []float64, period int) float64 {
 	return math.Sqrt(variance)
 }
 
-func max20(data []float64) float64 {
+// highest 最近 period 个值的最大值
+func highest(data []float64, period int) float64 {
 	n := len(data)
-	start := n - 20
+	start := n - period
 	if start < 0 {
 		start = 0
 	}
@@ -235,9 +246,10 @@ func max20(data []float64) float64 {
 	return m
 }
 
-func min20(data []float64) float64 {
+// lowest 最近 period 个值的最小值
+func lowest(data []float64, period int) float64 {
 	n := len(data)
-	start := n - 20
+	start := n - period
 	if start < 0 {
 		start = 0
 	}
@@ -0,0 +1,102 @@
+package indicators
+
+import "trade-go/models"
+
+const (
+	minParamPeriod  = 2
+	maxParamPeriod  = 500
+	maxBBMultiplier = 5.0
+)
+
+// DefaultParams 未配置时的指标参数。
+func DefaultParams() models.IndicatorParams {
+	return models.IndicatorParams{
+		SMAFast:        5,
+		SMAShort:       20,
+		SMAMedium:      50,
+		SMALong:        96,
+		EMAFast:        12,
+		EMASlow:        26,
+		MACDSignal:     9,
+		RSIPeriod:      14,
+		BBPeriod:       20,
+		BBMultiplier:   2,
+		VolumeMAPeriod: 20,
+		SRLookback:     20,
+	}
+}
+
+// MergeParams override 中非零字段覆盖 base。
+func MergeParams(base, override models.IndicatorParams) models.IndicatorParams {
+	out := base
+	pick := func(dst *int, v int) {
+		if v != 0 {
+			*dst = v
+		}
+	}
+	pick(&out.SMAFast, override.SMAFast)
+	pick(&out.SMAShort, override.SMAShort)
+	pick(&out.SMAMedium, override.SMAMedium)
+	pick(&out.SMALong, override.SMALong)
+	pick(&out.EMAFast, override.EMAFast)
+	pick(&out.EMASlow, override.EMASlow)
+	pick(&out.MACDSignal, override.MACDSignal)
+	pick(&out.RSIPeriod, override.RSIPeriod)
+	pick(&out.BBPeriod, override.BBPeriod)
+	pick(&out.VolumeMAPeriod, override.VolumeMAPeriod)
+	pick(&out.SRLookback, override.SRLookback)
+	if override.BBMultiplier != 0 {
+		out.BBMultiplier = override.BBMultiplier
+	}
+	return out
+}
+
+// NormalizeParams 缺省项取默认值，周期限制在 [2,500]，布林带倍数限制在 (0,5]；
+// EMA 快线不小于慢线时两者一并恢复默认。
+func NormalizeParams(p models.IndicatorParams) models.IndicatorParams {
+	def := DefaultParams()
+	period := func(v, fallback int) int {
+		switch {
+		case v <= 0:
+			return fallback
+		case v < minParamPeriod:
+			return minParamPeriod
+		case v > maxParamPeriod:
+			return maxParamPeriod
+		}
+		return v
+	}
+	p.SMAFast = period(p.SMAFast, def.SMAFast)
+	p.SMAShort = period(p.SMAShort, def.SMAShort)
+	p.SMAMedium = period(p.SMAMedium, def.SMAMedium)
+	p.SMALong = period(p.SMALong, def.SMALong)
+	p.EMAFast = period(p.EMAFast, def.EMAFast)
+	p.EMASlow = period(p.EMASlow, def.EMASlow)
+	if p.EMAFast >= p.EMASlow {
+		p.EMAFast, p.EMASlow = def.EMAFast, def.EMASlow
+	}
+	p.MACDSignal = period(p.MACDSignal, def.MACDSignal)
+	p.RSIPeriod = period(p.RSIPeriod, def.RSIPeriod)
+	p.BBPeriod = period(p.BBPeriod, def.BBPeriod)
+	p.VolumeMAPeriod = period(p.VolumeMAPeriod, def.VolumeMAPeriod)
+	p.SRLookback = period(p.SRLookback, def.SRLookback)
+	if !(p.BBMultiplier > 0) {
+		p.BBMultiplier = def.BBMultiplier
+	}
+	if p.BBMultiplier > maxBBMultiplier {
+		p.BBMultiplier = maxBBMultiplier
+	}
+	return p
+}
+
+// RequiredBars 按参数完整计算全部均线、布林带与支撑阻力所需的 K 线数。
+func RequiredBars(p models.IndicatorParams) int {
+	p = NormalizeParams(p)
+	need := p.EMASlow + p.MACDSignal
+	for _, v := range []int{p.SMAFast, p.SMAShort, p.SMAMedium, p.SMALong, p.RSIPeriod + 1, p.BBPeriod, p.VolumeMAPeriod, p.SRLookback} {
+		if v > need {
+			need = v
+		}
+	}
+	return need
+}
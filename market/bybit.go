@@ -0,0 +1,97 @@
+package market
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"trade-go/exchange"
+)
+
+// bybitVenue v5 公共 linear 端点，topic 形如 tickers.BTCUSDT / kline.15.BTCUSDT；
+// 资金费率随 tickers 推送，不单独订阅。服务端要求定时发送 {"op":"ping"}。
+type bybitVenue struct {
+	endpoints exchange.Endpoints
+}
+
+func (v *bybitVenue) name() string { return "bybit" }
+
+func (v *bybitVenue) channels(symbol, timeframe string) []channel {
+	sym := exchange.NormalizeSymbol(symbol)
+	keys := []struct{ key, kind string }{
+		{"tickers." + sym, kindTicker},
+		{"kline." + exchange.BybitInterval(timeframe) + "." + sym, kindKline},
+	}
+	out := make([]channel, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, channel{key: k.key, kind: k.kind, group: "public", arg: k.key})
+	}
+	return out
+}
+
+func (v *bybitVenue) url(string) string { return v.endpoints.PublicWS }
+
+func (v *bybitVenue) subscribeMsg(args []any, subscribe bool) any {
+	op := "unsubscribe"
+	if subscribe {
+		op = "subscribe"
+	}
+	return map[string]any{"op": op, "args": args}
+}
+
+func (v *bybitVenue) maxPerConn() int       { return 0 }
+func (v *bybitVenue) ping() []byte          { return []byte(`{"op":"ping"}`) }
+func (v *bybitVenue) rotate() time.Duration { return 0 }
+
+// decode tickers 首条为快照、之后为只含变化字段的增量；K 线 confirm=true 即收盘，end 为收盘时间。
+func (v *bybitVenue) decode(msg []byte, _ func(string) string) []update {
+	var payload struct {
+		Topic string          `json:"topic"`
+		Data  json.RawMessage `json:"data"`
+	}
+	// 订阅应答与 pong 没有 topic 字段
+	if err := json.Unmarshal(msg, &payload); err != nil || payload.Topic == "" || len(payload.Data) == 0 {
+		return nil
+	}
+	u := update{key: payload.Topic}
+	switch {
+	case strings.HasPrefix(payload.Topic, "tickers."):
+		var t struct {
+			LastPrice   string `json:"lastPrice"`
+			FundingRate string `json:"fundingRate"`
+		}
+		if json.Unmarshal(payload.Data, &t) != nil {
+			return nil
+		}
+		u.price = t.LastPrice
+		u.funding = t.FundingRate
+	case strings.HasPrefix(payload.Topic, "kline."):
+		var rows []struct {
+			Start   int64  `json:"start"`
+			End     int64  `json:"end"`
+			Open    string `json:"open"`
+			High    string `json:"high"`
+			Low     string `json:"low"`
+			Close   string `json:"close"`
+			Volume  string `json:"volume"`
+			Confirm bool   `json:"confirm"`
+		}
+		if json.Unmarshal(payload.Data, &rows) != nil || len(rows) == 0 {
+			return nil
+		}
+		// 一条推送可能同时带上一根收盘与新开的一根，逐根输出
+		out := make([]update, 0, len(rows))
+		for _, row := range rows {
+			out = append(out, update{
+				key:       payload.Topic,
+				kline:     []string{strconv.FormatInt(row.Start, 10), row.Open, row.High, row.Low, row.Close, row.Volume},
+				closed:    row.Confirm,
+				closeTime: row.End,
+			})
+		}
+		return out
+	default:
+		return nil
+	}
+	return []update{u}
+}
@@ -0,0 +1,209 @@
+package market
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"trade-go/exchange"
+	"trade-go/models"
+)
+
+// 无人读取的 K 线序列保留时长下限，实际取该值与 3 个周期中的较大者
+const candleIdleTTL = 30 * time.Minute
+
+// FetchFunc REST 拉取最近 limit 根 K 线，用于首次加载与缺口回补。
+type FetchFunc func(symbol, timeframe string, limit int) ([]models.OHLCV, error)
+
+// CandleStore 按 (交易对, 周期) 缓存滚动 K 线：首次读取经 REST 加载，之后由行情推送更新
+// （含未收盘 K 线），检测到缺口或推送不健康时回补。实盘与模拟盘共用同一份数据。
+type CandleStore struct {
+	m      *Manager
+	mu     sync.Mutex
+	series map[string]*candleSeries
+}
+
+type candleSeries struct {
+	symbol    string
+	timeframe string
+	dur       time.Duration
+	sub       *Subscription
+
+	mu        sync.Mutex
+	exchange  string // 数据来源交易所，切换后整体重载
+	fetch     FetchFunc
+	bars      []models.OHLCV
+	capacity  int
+	seeded    bool
+	verified  time.Time // 该时间及之前的数据已与 REST 对齐，其中的缺口为交易所本身缺失
+	backfills int
+	lastUsed  time.Time
+}
+
+func NewCandleStore(m *Manager) *CandleStore {
+	cs := &CandleStore{m: m, series: map[string]*candleSeries{}}
+	go cs.releaseIdle()
+	return cs
+}
+
+// Candles 返回最近 limit 根 K 线（最后一根可能未收盘），与 FetchOHLCV 的结果一致。
+func (cs *CandleStore) Candles(symbol, timeframe string, limit int, fetch FetchFunc) ([]models.OHLCV, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("invalid candle limit: %d", limit)
+	}
+	s := cs.get(symbol, timeframe)
+	snap := s.sub.Snapshot()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetch = fetch
+	s.lastUsed = time.Now()
+	if ex := cs.m.Exchange(); ex != s.exchange {
+		s.exchange, s.bars, s.seeded = ex, nil, false
+	}
+	if limit > s.capacity {
+		s.capacity, s.seeded = limit, false
+	}
+	// 推送中断期间的数据不可信，按 REST 重新加载
+	if !s.seeded || !snap.Healthy {
+		if err := s.reloadLocked(); err != nil {
+			return nil, err
+		}
+	} else if k, ok := klineToOHLCV(snap.Kline); ok {
+		s.upsertLocked(k)
+		if s.hasGapLocked() {
+			if err := s.reloadLocked(); err != nil {
+				return nil, err
+			}
+			s.backfills++
+		}
+	}
+	n := len(s.bars)
+	if n > limit {
+		n = limit
+	}
+	out := make([]models.OHLCV, n)
+	copy(out, s.bars[len(s.bars)-n:])
+	return out, nil
+}
+
+func (cs *CandleStore) get(symbol, timeframe string) *candleSeries {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	timeframe = strings.TrimSpace(timeframe)
+	key := symbol + "|" + timeframe
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if s, ok := cs.series[key]; ok {
+		return s
+	}
+	s := &candleSeries{
+		symbol:    symbol,
+		timeframe: timeframe,
+		dur:       exchange.TimeframeDuration(timeframe),
+		sub:       cs.m.Subscribe(symbol, timeframe, "candles"),
+		lastUsed:  time.Now(),
+	}
+	cs.series[key] = s
+	go s.follow()
+	return s
+}
+
+// follow 收盘事件写入序列，推送丢弃事件造成的缺口在此回补。
+func (s *candleSeries) follow() {
+	for ev := range s.sub.Events() {
+		s.mu.Lock()
+		if s.seeded {
+			s.upsertLocked(ev.Candle)
+			if s.hasGapLocked() {
+				if err := s.reloadLocked(); err != nil {
+					fmt.Printf("K线缺口回补失败 %s %s: %v\n", s.symbol, s.timeframe, err)
+				} else {
+					s.backfills++
+				}
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// reloadLocked REST 拉取最近 capacity 根并与已有数据合并。
+func (s *candleSeries) reloadLocked() error {
+	if s.fetch == nil {
+		return fmt.Errorf("candle fetcher not set")
+	}
+	bars, err := s.fetch(s.symbol, s.timeframe, s.capacity)
+	if err != nil {
+		return err
+	}
+	if !s.seeded {
+		s.bars = nil
+	}
+	for _, k := range bars {
+		s.upsertLocked(k)
+	}
+	s.seeded = true
+	if n := len(s.bars); n > 0 {
+		s.verified = s.bars[n-1].Timestamp
+	}
+	return nil
+}
+
+// upsertLocked 按开盘时间插入或覆盖，超出容量时丢弃最旧的 K 线。
+func (s *candleSeries) upsertLocked(k models.OHLCV) {
+	i := sort.Search(len(s.bars), func(i int) bool { return !s.bars[i].Timestamp.Before(k.Timestamp) })
+	switch {
+	case i < len(s.bars) && s.bars[i].Timestamp.Equal(k.Timestamp):
+		s.bars[i] = k
+	case i == 0 && len(s.bars) >= s.capacity:
+		// 比窗口内全部数据都旧，无需保留
+	default:
+		s.bars = append(s.bars, models.OHLCV{})
+		copy(s.bars[i+1:], s.bars[i:])
+		s.bars[i] = k
+	}
+	if extra := len(s.bars) - s.capacity; extra > 0 {
+		s.bars = append([]models.OHLCV(nil), s.bars[extra:]...)
+	}
+}
+
+// hasGapLocked 最近一次 REST 对齐之后，相邻 K 线间隔超过 1.5 个周期即视为缺口（容忍 1M 周期的自然月差异）。
+func (s *candleSeries) hasGapLocked() bool {
+	if s.dur <= 0 {
+		return false
+	}
+	for i := 1; i < len(s.bars); i++ {
+		if !s.bars[i].Timestamp.After(s.verified) {
+			continue
+		}
+		if s.bars[i].Timestamp.Sub(s.bars[i-1].Timestamp) > s.dur*3/2 {
+			return true
+		}
+	}
+	return false
+}
+
+func (cs *CandleStore) releaseIdle() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		var idle []*candleSeries
+		cs.mu.Lock()
+		for key, s := range cs.series {
+			s.mu.Lock()
+			ttl := candleIdleTTL
+			if d := 3 * s.dur; d > ttl {
+				ttl = d
+			}
+			if time.Since(s.lastUsed) > ttl {
+				idle = append(idle, s)
+				delete(cs.series, key)
+			}
+			s.mu.Unlock()
+		}
+		cs.mu.Unlock()
+		for _, s := range idle {
+			s.sub.Close()
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package market
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 连接维护参数
+const (
+	reconnectMin = time.Second
+	reconnectMax = time.Minute
+	// 读超时：期间连一条消息（含心跳回包）都没有即判定连接失效并重连
+	readTimeout = 2 * StaleAfter
+	// Binance 单连接 24 小时强制断开，提前主动轮换
+	binanceRotateAfter = 23 * time.Hour
+	// OKX 30 秒无消息会断开连接，Bybit 建议每 20 秒发送一次 ping
+	pingInterval = 20 * time.Second
+	// 单条订阅报文携带的频道数上限
+	subscribeBatch = 50
+)
+
+// streamConn 单条行情连接，承载同一端点下的一组频道；断线后按退避重连并重新订阅全部频道。
+// 除 writeMu 外的字段受 Manager.mu 保护。
+type streamConn struct {
+	id       int
+	group    string
+	url      string
+	channels map[string]channel
+	done     chan struct{}
+	closed   bool
+
+	writeMu sync.Mutex
+
+	conn        *websocket.Conn
+	connected   bool
+	connectedAt time.Time
+	lastMsgAt   time.Time
+	reconnects  int
+	lastErr     string
+}
+
+// ConnStatus 单条行情连接状态。
+type ConnStatus struct {
+	Name          string    `json:"name"`
+	Channels      int       `json:"channels"`
+	Connected     bool      `json:"connected"`
+	ConnectedAt   time.Time `json:"connected_at,omitempty"`
+	LastMessageAt time.Time `json:"last_message_at,omitempty"`
+	Reconnects    int       `json:"reconnects"`
+	LastError     string    `json:"last_error,omitempty"`
+}
+
+func (sc *streamConn) name(v venue) string {
+	return fmt.Sprintf("%s-%s-%d", v.name(), sc.group, sc.id)
+}
+
+// write 连接上的文本写入串行化；协议 pong 走 WriteControl，可与之并发。
+func (sc *streamConn) write(c *websocket.Conn, v any) error {
+	sc.writeMu.Lock()
+	defer sc.writeMu.Unlock()
+	if b, ok := v.([]byte); ok {
+		return c.WriteMessage(websocket.TextMessage, b)
+	}
+	return c.WriteJSON(v)
+}
+
+// sendSubscribe 分批发送订阅/退订报文。
+func (sc *streamConn) sendSubscribe(c *websocket.Conn, v venue, chs []channel, subscribe bool) error {
+	for start := 0; start < len(chs); start += subscribeBatch {
+		end := start + subscribeBatch
+		if end > len(chs) {
+			end = len(chs)
+		}
+		args := make([]any, 0, end-start)
+		for _, ch := range chs[start:end] {
+			args = append(args, ch.arg)
+		}
+		if err := sc.write(c, v.subscribeMsg(args, subscribe)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dial 建连；收到服务端 ping 帧时顺延读超时。
+func dial(url string) (*websocket.Conn, error) {
+	c, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	_ = c.SetReadDeadline(time.Now().Add(readTimeout))
+	c.SetPingHandler(func(data string) error {
+		_ = c.SetReadDeadline(time.Now().Add(readTimeout))
+		return c.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(5*time.Second))
+	})
+	return c, nil
+}
+
+// supervise 建连并订阅当前全部频道，断开后按指数退避重连；计划内轮换立即重连。
+func (m *Manager) supervise(sc *streamConn) {
+	delay := time.Duration(0)
+	first := true
+	for {
+		select {
+		case <-sc.done:
+			return
+		case <-time.After(delay):
+		}
+		v := m.currentVenue()
+		var sent []channel
+		c, err := dial(sc.url)
+		if err == nil {
+			sent = m.connChannels(sc)
+			if err = sc.sendSubscribe(c, v, sent, true); err != nil {
+				_ = c.Close()
+			}
+		}
+		if err != nil {
+			m.markDown(sc, fmt.Errorf("%s: %w", sc.name(v), err))
+			if delay *= 2; delay < reconnectMin {
+				delay = reconnectMin
+			} else if delay > reconnectMax {
+				delay = reconnectMax
+			}
+			continue
+		}
+		if !m.markUp(sc, c, !first) {
+			_ = c.Close()
+			return
+		}
+		first = false
+		// 建连期间新增的频道在离线时跳过了补发，这里补订
+		if missed := missingChannels(m.connChannels(sc), sent); len(missed) > 0 {
+			if err := sc.sendSubscribe(c, v, missed, true); err != nil {
+				_ = c.Close()
+			}
+		}
+		rotated, err := m.serve(sc, v, c)
+		m.markDown(sc, err)
+		delay = reconnectMin
+		if rotated {
+			delay = 0
+		}
+	}
+}
+
+// serve 读取消息直到连接断开；返回是否为计划内轮换。
+func (m *Manager) serve(sc *streamConn, v venue, conn *websocket.Conn) (bool, error) {
+	stop := make(chan struct{})
+	defer close(stop)
+	rotated := make(chan struct{}, 1)
+	go func() {
+		var pingC, rotateC <-chan time.Time
+		if v.ping() != nil {
+			t := time.NewTicker(pingInterval)
+			defer t.Stop()
+			pingC = t.C
+		}
+		if d := v.rotate(); d > 0 {
+			t := time.NewTimer(d)
+			defer t.Stop()
+			rotateC = t.C
+		}
+		for {
+			select {
+			case <-stop:
+				return
+			case <-sc.done:
+				_ = conn.Close()
+				return
+			case <-pingC:
+				if err := sc.write(conn, v.ping()); err != nil {
+					_ = conn.Close()
+					return
+				}
+			case <-rotateC:
+				rotated <- struct{}{}
+				_ = conn.Close()
+				return
+			}
+		}
+	}()
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-rotated:
+				return true, fmt.Errorf("%s: 连接已满 %s，主动轮换", sc.name(v), v.rotate())
+			default:
+				return false, fmt.Errorf("%s: %w", sc.name(v), err)
+			}
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+		m.apply(sc, v.decode(msg, m.timeframeOf))
+	}
+}
+
+func (m *Manager) markUp(sc *streamConn, c *websocket.Conn, reconnect bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if sc.closed {
+		return false
+	}
+	sc.conn = c
+	sc.connected = true
+	sc.connectedAt = time.Now()
+	if reconnect {
+		sc.reconnects++
+	}
+	return true
+}
+
+func (m *Manager) markDown(sc *streamConn, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sc.conn = nil
+	sc.connected = false
+	if err != nil && !sc.closed {
+		sc.lastErr = err.Error()
+	}
+}
+
+func (m *Manager) connChannels(sc *streamConn) []channel {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]channel, 0, len(sc.channels))
+	for _, ch := range sc.channels {
+		out = append(out, ch)
+	}
+	return out
+}
+
+func missingChannels(all, sent []channel) []channel {
+	seen := make(map[string]bool, len(sent))
+	for _, ch := range sent {
+		seen[ch.key] = true
+	}
+	var out []channel
+	for _, ch := range all {
+		if !seen[ch.key] {
+			out = append(out, ch)
+		}
+	}
+	return out
+}
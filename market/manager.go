@@ -0,0 +1,498 @@
+package market
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"trade-go/exchange"
+)
+
+// 收盘事件缓冲，消费者处理不及时时丢弃最新事件并计数
+const eventBuffer = 16
+
+// Manager 行情订阅管理：按 (交易对, 周期) 引用计数订阅，相同频道只订阅一次，
+// 频道尽量复用同一端点的连接，超出单连接上限时再新建连接。实盘、模拟盘与前端各自持有 Subscription。
+type Manager struct {
+	mu       sync.RWMutex
+	exchange string
+	env      string
+	venue    venue
+	stopped  bool
+	nextID   int
+	conns    []*streamConn
+	channels map[string]*channelState
+	subs     map[*Subscription]struct{}
+}
+
+// channelState 频道引用计数与最新数据。
+type channelState struct {
+	ch        channel
+	refs      int
+	conn      *streamConn
+	timeframe string
+
+	price     string
+	funding   string
+	kline     []string
+	closed    bool
+	closeTime int64
+	emitted   int64 // 已派发收盘事件的收盘时间
+	updatedAt time.Time
+}
+
+// Subscription 单个消费者对 (交易对, 周期) 的订阅句柄。
+type Subscription struct {
+	m         *Manager
+	symbol    string
+	timeframe string
+	consumer  string
+	events    chan CandleEvent
+
+	// 以下字段受 Manager.mu 保护
+	keys    []string
+	dropped int
+	closed  bool
+}
+
+// SubscriptionStatus 按 (交易对, 周期) 聚合的订阅状态。
+type SubscriptionStatus struct {
+	Symbol    string    `json:"symbol"`
+	Timeframe string    `json:"timeframe"`
+	Consumers []string  `json:"consumers"`
+	Healthy   bool      `json:"healthy"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+	Dropped   int       `json:"dropped_events"`
+	// StaleChannels 超时未更新的频道 key
+	StaleChannels []string `json:"stale_channels,omitempty"`
+}
+
+// StreamStatus 行情流健康状态，供 /api/system/runtime 展示。
+type StreamStatus struct {
+	Exchange      string               `json:"exchange"`
+	Healthy       bool                 `json:"healthy"`
+	StaleAfterSec int                  `json:"stale_after_sec"`
+	Reconnects    int                  `json:"reconnects"`
+	Connections   []ConnStatus         `json:"connections"`
+	Subscriptions []SubscriptionStatus `json:"subscriptions"`
+}
+
+// NewManager exchangeName 跟随当前启用的交易所（binance/okx/bybit，其余如 sim 按 Binance 行情处理）；
+// env 为 mainnet/testnet/demo，决定连接主网还是测试网行情。
+func NewManager(exchangeName, env string) *Manager {
+	v := newVenue(exchangeName, env)
+	return &Manager{
+		exchange: v.name(),
+		env:      env,
+		venue:    v,
+		channels: map[string]*channelState{},
+		subs:     map[*Subscription]struct{}{},
+	}
+}
+
+// VenueName 行情来源交易所：okx、bybit 使用各自行情，其余均为 binance。
+func VenueName(exchangeName string) string {
+	switch name := strings.ToLower(strings.TrimSpace(exchangeName)); name {
+	case "okx", "bybit":
+		return name
+	}
+	return "binance"
+}
+
+// Exchange 行情来源交易所。
+func (m *Manager) Exchange() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.exchange
+}
+
+// Environment 行情连接的环境：mainnet / testnet / demo。
+func (m *Manager) Environment() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return exchange.NormalizeEnvironment(m.env)
+}
+
+func (m *Manager) currentVenue() venue {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.venue
+}
+
+// Subscribe consumer 仅用于状态展示（如 live / paper / ui）。连接在后台建立，
+// 首条行情到达前快照 Healthy 为 false。
+func (m *Manager) Subscribe(symbol, timeframe, consumer string) *Subscription {
+	if timeframe == "" {
+		timeframe = "15m"
+	}
+	sub := &Subscription{
+		m:         m,
+		symbol:    strings.ToUpper(strings.TrimSpace(symbol)),
+		timeframe: strings.TrimSpace(timeframe),
+		consumer:  consumer,
+		events:    make(chan CandleEvent, eventBuffer),
+	}
+	m.mu.Lock()
+	if m.stopped {
+		sub.closed = true
+		close(sub.events)
+		m.mu.Unlock()
+		return sub
+	}
+	m.subs[sub] = struct{}{}
+	pending, started := m.attachLocked(sub)
+	v := m.venue
+	m.mu.Unlock()
+	m.flushSubscribe(v, pending, true)
+	for _, sc := range started {
+		go m.supervise(sc)
+	}
+	return sub
+}
+
+// attachLocked 为订阅登记频道引用；返回需在已有连接上补发订阅的频道与新建的连接。
+func (m *Manager) attachLocked(sub *Subscription) (map[*streamConn][]channel, []*streamConn) {
+	pending := map[*streamConn][]channel{}
+	var started []*streamConn
+	sub.keys = sub.keys[:0]
+	for _, ch := range m.venue.channels(sub.symbol, sub.timeframe) {
+		sub.keys = append(sub.keys, ch.key)
+		st, ok := m.channels[ch.key]
+		if ok {
+			st.refs++
+			continue
+		}
+		sc, isNew := m.pickConnLocked(ch.group)
+		sc.channels[ch.key] = ch
+		m.channels[ch.key] = &channelState{ch: ch, refs: 1, conn: sc, timeframe: sub.timeframe}
+		if isNew {
+			started = append(started, sc)
+		} else {
+			// 新连接在 supervise 中一次性订阅，已有连接需补发
+			pending[sc] = append(pending[sc], ch)
+		}
+	}
+	return pending, started
+}
+
+// pickConnLocked 选择同端点且未满的连接，均已满时新建。
+func (m *Manager) pickConnLocked(group string) (*streamConn, bool) {
+	max := m.venue.maxPerConn()
+	for _, sc := range m.conns {
+		if sc.group == group && !sc.closed && (max <= 0 || len(sc.channels) < max) {
+			return sc, false
+		}
+	}
+	m.nextID++
+	sc := &streamConn{
+		id:       m.nextID,
+		group:    group,
+		url:      m.venue.url(group),
+		channels: map[string]channel{},
+		done:     make(chan struct{}),
+	}
+	m.conns = append(m.conns, sc)
+	return sc, true
+}
+
+// flushSubscribe 在线连接立即发送；离线连接在重连时会订阅全部频道，无需补发。
+func (m *Manager) flushSubscribe(v venue, pending map[*streamConn][]channel, subscribe bool) {
+	for sc, chs := range pending {
+		m.mu.RLock()
+		c := sc.conn
+		m.mu.RUnlock()
+		if c == nil {
+			continue
+		}
+		if err := sc.sendSubscribe(c, v, chs, subscribe); err != nil {
+			_ = c.Close()
+		}
+	}
+}
+
+// Close 释放订阅；频道引用归零时退订，连接上不再有频道时关闭连接。
+func (s *Subscription) Close() {
+	if s == nil {
+		return
+	}
+	m := s.m
+	m.mu.Lock()
+	if s.closed {
+		m.mu.Unlock()
+		return
+	}
+	s.closed = true
+	delete(m.subs, s)
+	close(s.events)
+	pending, idle := m.detachLocked(s)
+	v := m.venue
+	m.mu.Unlock()
+	m.flushSubscribe(v, pending, false)
+	for _, sc := range idle {
+		close(sc.done)
+	}
+}
+
+func (m *Manager) detachLocked(sub *Subscription) (map[*streamConn][]channel, []*streamConn) {
+	pending := map[*streamConn][]channel{}
+	for _, key := range sub.keys {
+		st, ok := m.channels[key]
+		if !ok {
+			continue
+		}
+		if st.refs--; st.refs > 0 {
+			continue
+		}
+		delete(m.channels, key)
+		delete(st.conn.channels, key)
+		pending[st.conn] = append(pending[st.conn], st.ch)
+	}
+	var idle []*streamConn
+	kept := m.conns[:0]
+	for _, sc := range m.conns {
+		if len(sc.channels) == 0 && !sc.closed {
+			sc.closed = true
+			delete(pending, sc)
+			idle = append(idle, sc)
+			continue
+		}
+		kept = append(kept, sc)
+	}
+	m.conns = kept
+	return pending, idle
+}
+
+// Reconfigure 切换行情来源交易所/环境：关闭现有连接，按新交易所的频道重建全部订阅。
+func (m *Manager) Reconfigure(exchangeName, env string) {
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return
+	}
+	old := m.conns
+	for _, sc := range old {
+		sc.closed = true
+	}
+	m.venue = newVenue(exchangeName, env)
+	m.exchange = m.venue.name()
+	m.env = env
+	m.conns = nil
+	m.channels = map[string]*channelState{}
+	var started []*streamConn
+	for sub := range m.subs {
+		_, s := m.attachLocked(sub)
+		started = append(started, s...)
+	}
+	m.mu.Unlock()
+	for _, sc := range old {
+		close(sc.done)
+	}
+	for _, sc := range started {
+		go m.supervise(sc)
+	}
+}
+
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return
+	}
+	m.stopped = true
+	conns := m.conns
+	for _, sc := range conns {
+		sc.closed = true
+	}
+	m.conns = nil
+	for sub := range m.subs {
+		sub.closed = true
+		close(sub.events)
+	}
+	m.subs = map[*Subscription]struct{}{}
+	m.mu.Unlock()
+	for _, sc := range conns {
+		close(sc.done)
+	}
+}
+
+func (m *Manager) timeframeOf(key string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if st, ok := m.channels[key]; ok {
+		return st.timeframe
+	}
+	return ""
+}
+
+// apply 写入推送数据，K 线收盘时向持有该频道的订阅派发事件（同一根 K 线只派发一次）。
+func (m *Manager) apply(sc *streamConn, updates []update) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sc.lastMsgAt = now
+	for _, u := range updates {
+		st, ok := m.channels[u.key]
+		if !ok || st.conn != sc {
+			continue
+		}
+		st.updatedAt = now
+		switch st.ch.kind {
+		case kindTicker:
+			// Bybit tickers 增量推送只带变化的字段，缺省字段保留上一次的值
+			if u.price != "" {
+				st.price = u.price
+			}
+			if u.funding != "" {
+				st.funding = u.funding
+			}
+		case kindFunding:
+			st.funding = u.funding
+		case kindKline:
+			st.kline = u.kline
+			st.closed = u.closed
+			st.closeTime = u.closeTime
+			if u.closed && u.closeTime > st.emitted {
+				st.emitted = u.closeTime
+				m.emitLocked(st, u)
+			}
+		}
+	}
+}
+
+func (m *Manager) emitLocked(st *channelState, u update) {
+	candle, ok := klineToOHLCV(u.kline)
+	if !ok {
+		return
+	}
+	for sub := range m.subs {
+		if !sub.hasKey(st.ch.key) {
+			continue
+		}
+		ev := CandleEvent{Symbol: sub.symbol, Timeframe: sub.timeframe, Candle: candle, CloseTime: u.closeTime}
+		select {
+		case sub.events <- ev:
+		default:
+			sub.dropped++
+		}
+	}
+}
+
+func (s *Subscription) hasKey(key string) bool {
+	for _, k := range s.keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Subscription) Symbol() string    { return s.symbol }
+func (s *Subscription) Timeframe() string { return s.timeframe }
+
+// Events 收盘 K 线事件；订阅关闭或管理器停止后通道关闭。
+func (s *Subscription) Events() <-chan CandleEvent {
+	return s.events
+}
+
+func (s *Subscription) Snapshot() StreamSnapshot {
+	if s == nil {
+		return StreamSnapshot{}
+	}
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	if s.closed {
+		return StreamSnapshot{}
+	}
+	return s.m.snapshotLocked(s.keys)
+}
+
+// snapshotLocked 按频道分别判断是否超时：某个频道冻结时即使其他频道仍在推送也标记为不健康。
+func (m *Manager) snapshotLocked(keys []string) StreamSnapshot {
+	out := StreamSnapshot{}
+	healthy := len(keys) > 0
+	now := time.Now()
+	for _, key := range keys {
+		st, ok := m.channels[key]
+		if !ok {
+			healthy = false
+			continue
+		}
+		if st.conn == nil || !st.conn.connected {
+			healthy = false
+		}
+		if st.updatedAt.After(out.UpdatedAt) {
+			out.UpdatedAt = st.updatedAt
+		}
+		if st.ch.kind != kindFunding && (st.updatedAt.IsZero() || now.Sub(st.updatedAt) > StaleAfter) {
+			out.StaleChannels = append(out.StaleChannels, key)
+		}
+		switch st.ch.kind {
+		case kindTicker:
+			if st.price != "" {
+				out.TickerPrice = st.price
+				out.Ticker = map[string]string{"last_price": st.price}
+			}
+			if st.funding != "" {
+				out.FundingRate = st.funding
+			}
+		case kindFunding:
+			out.FundingRate = st.funding
+		case kindKline:
+			out.Kline = append([]string(nil), st.kline...)
+			out.KlineClosed = st.closed
+			out.KlineCloseTime = st.closeTime
+		}
+	}
+	out.Healthy = healthy && len(out.StaleChannels) == 0
+	return out
+}
+
+func (m *Manager) Status() StreamStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := StreamStatus{
+		Exchange:      m.exchange,
+		Healthy:       !m.stopped && len(m.conns) > 0,
+		StaleAfterSec: int(StaleAfter / time.Second),
+		Connections:   make([]ConnStatus, 0, len(m.conns)),
+	}
+	for _, sc := range m.conns {
+		if !sc.connected {
+			out.Healthy = false
+		}
+		out.Reconnects += sc.reconnects
+		out.Connections = append(out.Connections, ConnStatus{
+			Name:          sc.name(m.venue),
+			Channels:      len(sc.channels),
+			Connected:     sc.connected,
+			ConnectedAt:   sc.connectedAt,
+			LastMessageAt: sc.lastMsgAt,
+			Reconnects:    sc.reconnects,
+			LastError:     sc.lastErr,
+		})
+	}
+	groups := map[string]*SubscriptionStatus{}
+	for sub := range m.subs {
+		id := sub.symbol + "|" + sub.timeframe
+		g, ok := groups[id]
+		if !ok {
+			snap := m.snapshotLocked(sub.keys)
+			g = &SubscriptionStatus{Symbol: sub.symbol, Timeframe: sub.timeframe, Healthy: snap.Healthy, UpdatedAt: snap.UpdatedAt, StaleChannels: snap.StaleChannels}
+			groups[id] = g
+		}
+		g.Consumers = append(g.Consumers, sub.consumer)
+		g.Dropped += sub.dropped
+	}
+	for _, g := range groups {
+		sort.Strings(g.Consumers)
+		out.Subscriptions = append(out.Subscriptions, *g)
+	}
+	sort.Slice(out.Subscriptions, func(i, j int) bool {
+		a, b := out.Subscriptions[i], out.Subscriptions[j]
+		if a.Symbol != b.Symbol {
+			return a.Symbol < b.Symbol
+		}
+		return a.Timeframe < b.Timeframe
+	})
+	return out
+}
@@ -0,0 +1,29 @@
+package market
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnapshotFlagsFrozenKlineChannel(t *testing.T) {
+	m := NewManager("binance", "mainnet")
+	sc := &streamConn{connected: true}
+	now := time.Now()
+	keys := []string{"btcusdt@ticker", "btcusdt@markPrice", "btcusdt@kline_15m"}
+	m.channels = map[string]*channelState{
+		keys[0]: {ch: channel{key: keys[0], kind: kindTicker}, conn: sc, price: "100", updatedAt: now},
+		// 资金费率推送间隔较长，不参与逐频道超时判断
+		keys[1]: {ch: channel{key: keys[1], kind: kindFunding}, conn: sc, funding: "0.0001", updatedAt: now.Add(-2 * StaleAfter)},
+		keys[2]: {ch: channel{key: keys[2], kind: kindKline}, conn: sc, updatedAt: now},
+	}
+	if snap := m.snapshotLocked(keys); !snap.Healthy || len(snap.StaleChannels) != 0 {
+		t.Fatalf("各频道都在推送时应健康: %+v", snap)
+	}
+
+	// K 线冻结而 ticker 仍在推送
+	m.channels[keys[2]].updatedAt = now.Add(-StaleAfter - time.Second)
+	snap := m.snapshotLocked(keys)
+	if snap.Healthy || len(snap.StaleChannels) != 1 || snap.StaleChannels[0] != keys[2] {
+		t.Fatalf("K 线频道超时应标记为不健康并指明频道: %+v", snap)
+	}
+}
@@ -0,0 +1,103 @@
+package market
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"trade-go/exchange"
+)
+
+// okxVenue tickers / funding-rate 在 public 端点，K 线在 business 端点；
+// 连接 30 秒无消息会被服务端断开，需定时发送文本 ping。
+type okxVenue struct {
+	endpoints exchange.Endpoints
+}
+
+func (v *okxVenue) name() string { return "okx" }
+
+func (v *okxVenue) channels(symbol, timeframe string) []channel {
+	instID := exchange.OKXInstID(symbol)
+	candle := "candle" + exchange.OKXBar(timeframe)
+	mk := func(name, kind, group string) channel {
+		return channel{
+			key:   name + ":" + instID,
+			kind:  kind,
+			group: group,
+			arg:   map[string]string{"channel": name, "instId": instID},
+		}
+	}
+	return []channel{
+		mk("tickers", kindTicker, "public"),
+		mk("funding-rate", kindFunding, "public"),
+		mk(candle, kindKline, "business"),
+	}
+}
+
+func (v *okxVenue) url(group string) string {
+	if group == "business" {
+		return v.endpoints.BusinessWS
+	}
+	return v.endpoints.PublicWS
+}
+
+func (v *okxVenue) subscribeMsg(args []any, subscribe bool) any {
+	op := "unsubscribe"
+	if subscribe {
+		op = "subscribe"
+	}
+	return map[string]any{"op": op, "args": args}
+}
+
+func (v *okxVenue) maxPerConn() int       { return 0 }
+func (v *okxVenue) ping() []byte          { return []byte("ping") }
+func (v *okxVenue) rotate() time.Duration { return 0 }
+
+// decode 归一化为与 Binance 相同的字段：K 线 confirm=1 即收盘，收盘时间按开盘时间 + 周期 - 1ms 推算。
+func (v *okxVenue) decode(msg []byte, timeframeOf func(key string) string) []update {
+	var payload struct {
+		Event string `json:"event"`
+		Arg   struct {
+			Channel string `json:"channel"`
+			InstID  string `json:"instId"`
+		} `json:"arg"`
+		Data json.RawMessage `json:"data"`
+	}
+	// 心跳回包 pong 不是 JSON，在此一并忽略
+	if err := json.Unmarshal(msg, &payload); err != nil || payload.Event != "" || len(payload.Data) == 0 {
+		return nil
+	}
+	u := update{key: payload.Arg.Channel + ":" + payload.Arg.InstID}
+	switch ch := payload.Arg.Channel; {
+	case ch == "tickers":
+		var rows []struct {
+			Last string `json:"last"`
+		}
+		if json.Unmarshal(payload.Data, &rows) != nil || len(rows) == 0 {
+			return nil
+		}
+		u.price = rows[0].Last
+	case ch == "funding-rate":
+		var rows []struct {
+			FundingRate string `json:"fundingRate"`
+		}
+		if json.Unmarshal(payload.Data, &rows) != nil || len(rows) == 0 {
+			return nil
+		}
+		u.funding = rows[0].FundingRate
+	case strings.HasPrefix(ch, "candle"):
+		// [ts, o, h, l, c, vol, volCcy, volCcyQuote, confirm]
+		var rows [][]string
+		if json.Unmarshal(payload.Data, &rows) != nil || len(rows) == 0 || len(rows[0]) < 9 {
+			return nil
+		}
+		row := rows[0]
+		openTime, _ := strconv.ParseInt(row[0], 10, 64)
+		u.kline = []string{row[0], row[1], row[2], row[3], row[4], row[5]}
+		u.closed = row[8] == "1"
+		u.closeTime = openTime + exchange.TimeframeDuration(timeframeOf(u.key)).Milliseconds() - 1
+	default:
+		return nil
+	}
+	return []update{u}
+}
@@ -3,11 +3,12 @@ package market
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/gorilla/websocket"
+	"trade-go/exchange"
+	"trade-go/models"
 )
 
 type StreamSnapshot struct {
@@ -18,22 +19,73 @@ type StreamSnapshot struct {
 	KlineCloseTime int64             `json:"kline_close_time"`
 	FundingRate    string            `json:"funding_rate"`
 	UpdatedAt      time.Time         `json:"updated_at"`
+	// 承载该订阅的连接全部在线，且 ticker、K 线频道各自在 StaleAfter 内收到过行情；不健康时实时触发应暂停
+	Healthy bool `json:"healthy"`
+	// StaleChannels 超时未更新的频道 key
+	StaleChannels []string `json:"stale_channels,omitempty"`
+}
+
+// CandleEvent 收盘 K 线事件，Candle.Timestamp 为开盘时间。
+type CandleEvent struct {
+	Symbol    string       `json:"symbol"`
+	Timeframe string       `json:"timeframe"`
+	Candle    models.OHLCV `json:"candle"`
+	CloseTime int64        `json:"close_time"`
+}
+
+// StaleAfter ticker / K 线频道超过该时长未收到行情即视为中断，快照标记为不健康。
+// 资金费率频道推送间隔较长（OKX 30~90 秒），不参与逐频道超时判断。
+const StaleAfter = 30 * time.Second
+
+// 频道数据类型
+const (
+	kindTicker  = "ticker"
+	kindFunding = "funding"
+	kindKline   = "kline"
+)
+
+// channel 交易所行情频道。key 与推送消息中的频道标识一致，用于路由。
+type channel struct {
+	key   string
+	kind  string
+	group string // 所在端点：Binance 只有 market，OKX 分 public / business，Bybit 只有 public
+	arg   any    // 订阅参数：Binance 为流名，OKX 为 {channel, instId}，Bybit 为 topic
 }
 
-type Stream struct {
-	symbol string
-	candle string
+// update 单条推送解析结果。
+type update struct {
+	key   string
+	price string
+	// funding Bybit 的资金费率随 tickers 推送，与价格同在一条更新中
+	funding   string
+	kline     []string // [openTime, o, h, l, c, v]
+	closed    bool
+	closeTime int64
+}
 
-	mu   sync.RWMutex
-	conn *websocket.Conn
-	last StreamSnapshot
+// venue 各交易所行情协议差异：频道命名、端点、订阅报文与消息解析。
+type venue interface {
+	name() string
+	channels(symbol, timeframe string) []channel
+	url(group string) string
+	subscribeMsg(args []any, subscribe bool) any
+	// maxPerConn 单连接可承载的频道数，0 表示不限
+	maxPerConn() int
+	// ping 应用层心跳报文，nil 表示只依赖协议 ping/pong
+	ping() []byte
+	// rotate >0 时连接满该时长主动轮换
+	rotate() time.Duration
+	decode(msg []byte, timeframeOf func(key string) string) []update
 }
 
-func NewStream(symbol, candle string) *Stream {
-	if candle == "" {
-		candle = "15m"
+func newVenue(exchangeName, env string) venue {
+	switch VenueName(exchangeName) {
+	case "okx":
+		return &okxVenue{endpoints: exchange.ResolveEndpoints("okx", env)}
+	case "bybit":
+		return &bybitVenue{endpoints: exchange.ResolveEndpoints("bybit", env)}
 	}
-	return &Stream{symbol: normalizeSymbol(symbol), candle: candle}
+	return &binanceVenue{endpoints: exchange.ResolveEndpoints("binance", env)}
 }
 
 func normalizeSymbol(symbol string) string {
@@ -44,100 +96,121 @@ func normalizeSymbol(symbol string) string {
 	return s
 }
 
-func (s *Stream) Start() error {
-	url := fmt.Sprintf(
-		"wss://fstream.binance.com/stream?streams=%s@ticker/%s@kline_%s/%s@markPrice",
-		s.symbol, s.symbol, s.candle, s.symbol,
-	)
-	c, _, err := websocket.DefaultDialer.Dial(url, nil)
-	if err != nil {
-		return err
-	}
-	s.conn = c
-	go s.readLoop()
-	return nil
+// binanceVenue 组合流端点 /stream，连接后用 SUBSCRIBE/UNSUBSCRIBE 增减频道；
+// 单连接最多 200 个流，服务端每 3 分钟发 ping 帧，24 小时强制断开。
+type binanceVenue struct {
+	endpoints exchange.Endpoints
+	reqID     int64
 }
 
-func (s *Stream) Stop() error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	if s.conn != nil {
-		err := s.conn.Close()
-		s.conn = nil
-		return err
+func (v *binanceVenue) name() string { return "binance" }
+
+func (v *binanceVenue) channels(symbol, timeframe string) []channel {
+	sym := normalizeSymbol(symbol)
+	keys := []struct{ key, kind string }{
+		{sym + "@ticker", kindTicker},
+		{sym + "@markPrice", kindFunding},
+		{sym + "@kline_" + timeframe, kindKline},
+	}
+	out := make([]channel, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, channel{key: k.key, kind: k.kind, group: "market", arg: k.key})
 	}
-	return nil
+	return out
 }
 
-func (s *Stream) Snapshot() StreamSnapshot {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return s.last
+func (v *binanceVenue) url(string) string { return v.endpoints.PublicWS + "/stream" }
+
+func (v *binanceVenue) subscribeMsg(args []any, subscribe bool) any {
+	method := "UNSUBSCRIBE"
+	if subscribe {
+		method = "SUBSCRIBE"
+	}
+	return map[string]any{"method": method, "params": args, "id": atomic.AddInt64(&v.reqID, 1)}
 }
 
-func (s *Stream) readLoop() {
-	for {
-		_, msg, err := s.conn.ReadMessage()
-		if err != nil {
-			return
+func (v *binanceVenue) maxPerConn() int       { return 200 }
+func (v *binanceVenue) ping() []byte          { return nil }
+func (v *binanceVenue) rotate() time.Duration { return binanceRotateAfter }
+
+func (v *binanceVenue) decode(msg []byte, _ func(string) string) []update {
+	var payload struct {
+		Stream string          `json:"stream"`
+		Data   json.RawMessage `json:"data"`
+	}
+	// 订阅应答 {"result":null,"id":1} 没有 stream 字段
+	if err := json.Unmarshal(msg, &payload); err != nil || payload.Stream == "" {
+		return nil
+	}
+	u := update{key: payload.Stream}
+	switch {
+	case strings.Contains(payload.Stream, "@ticker"):
+		var t struct {
+			LastPrice string `json:"c"`
 		}
-		var payload struct {
-			Stream string          `json:"stream"`
-			Data   json.RawMessage `json:"data"`
+		if json.Unmarshal(payload.Data, &t) != nil {
+			return nil
 		}
-		if err := json.Unmarshal(msg, &payload); err != nil {
-			continue
+		u.price = t.LastPrice
+	case strings.Contains(payload.Stream, "@markPrice"):
+		var m struct {
+			FundingRate string `json:"r"`
 		}
-
-		s.mu.Lock()
-		s.last.KlineClosed = false
-		s.last.UpdatedAt = time.Now()
-
-		switch {
-		case strings.Contains(payload.Stream, "@ticker"):
-			var t struct {
-				LastPrice string `json:"c"`
-			}
-			if json.Unmarshal(payload.Data, &t) == nil {
-				s.last.TickerPrice = t.LastPrice
-				if s.last.Ticker == nil {
-					s.last.Ticker = map[string]string{}
-				}
-				s.last.Ticker["last_price"] = t.LastPrice
-			}
-		case strings.Contains(payload.Stream, "@markPrice"):
-			var m struct {
-				FundingRate string `json:"r"`
-			}
-			if json.Unmarshal(payload.Data, &m) == nil {
-				s.last.FundingRate = m.FundingRate
-			}
-		case strings.Contains(payload.Stream, "@kline_"):
-			var k struct {
-				K struct {
-					OpenTime  int64  `json:"t"`
-					CloseTime int64  `json:"T"`
-					Open      string `json:"o"`
-					High      string `json:"h"`
-					Low       string `json:"l"`
-					Close     string `json:"c"`
-					Volume    string `json:"v"`
-					Closed    bool   `json:"x"`
-				} `json:"k"`
-			}
-			if json.Unmarshal(payload.Data, &k) == nil {
-				s.last.Kline = []string{
-					fmt.Sprintf("%d", k.K.OpenTime),
-					k.K.Open,
-					k.K.High,
-					k.K.Low,
-					k.K.Close,
-					k.K.Volume,
-				}
-				s.last.KlineClosed = k.K.Closed
-				s.last.KlineCloseTime = k.K.CloseTime
-			}
+		if json.Unmarshal(payload.Data, &m) != nil {
+			return nil
+		}
+		u.funding = m.FundingRate
+	case strings.Contains(payload.Stream, "@kline_"):
+		var k struct {
+			K struct {
+				OpenTime  int64  `json:"t"`
+				CloseTime int64  `json:"T"`
+				Open      string `json:"o"`
+				High      string `json:"h"`
+				Low       string `json:"l"`
+				Close     string `json:"c"`
+				Volume    string `json:"v"`
+				Closed    bool   `json:"x"`
+			} `json:"k"`
+		}
+		if json.Unmarshal(payload.Data, &k) != nil {
+			return nil
 		}
-		s.mu.Unlock()
+		u.kline = []string{
+			fmt.Sprintf("%d", k.K.OpenTime),
+			k.K.Open,
+			k.K.High,
+			k.K.Low,
+			k.K.Close,
+			k.K.Volume,
+		}
+		u.closed = k.K.Closed
+		u.closeTime = k.K.CloseTime
+	default:
+		return nil
+	}
+	return []update{u}
+}
+
+// klineToOHLCV 快照 K 线 [openTime, o, h, l, c, v] 转为 OHLCV。
+func klineToOHLCV(k []string) (models.OHLCV, bool) {
+	if len(k) < 6 {
+		return models.OHLCV{}, false
+	}
+	openTime, err := strconv.ParseInt(k[0], 10, 64)
+	if err != nil {
+		return models.OHLCV{}, false
+	}
+	vals := make([]float64, 5)
+	for i := range vals {
+		vals[i], _ = strconv.ParseFloat(k[i+1], 64)
 	}
+	return models.OHLCV{
+		Timestamp: time.UnixMilli(openTime),
+		Open:      vals[0],
+		High:      vals[1],
+		Low:       vals[2],
+		Close:     vals[3],
+		Volume:    vals[4],
+	}, true
 }
@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // OHLCV K线数据
 type OHLCV struct {
@@ -14,11 +19,13 @@ type OHLCV struct {
 
 // TechnicalIndicators 技术指标
 type TechnicalIndicators struct {
-	SMA5        float64
-	SMA20       float64
-	SMA50       float64
-	EMA12       float64
-	EMA26       float64
+	// 均线周期见 IndicatorParams
+	SMAFast     float64
+	SMAShort    float64
+	SMAMedium   float64
+	SMALong     float64
+	EMAFast     float64
+	EMASlow     float64
 	MACD        float64
 	MACDSignal  float64
 	MACDHist    float64
@@ -31,6 +38,25 @@ type TechnicalIndicators struct {
 	VolumeRatio float64
 	Resistance  float64
 	Support     float64
+
+	// 扩展指标，预热不足时为 0
+	ATR             float64
+	ATRPct          float64 // ATR 占当前价格的百分比
+	PlusDI          float64
+	MinusDI         float64
+	ADX             float64
+	StochK          float64
+	StochD          float64
+	VWAP            float64 // UTC 自然日锚定
+	VWAPWeekly      float64 // UTC 周一锚定
+	OBV             float64
+	OBVMA           float64
+	SuperTrend      float64
+	SuperTrendDir   int // 1 多头，-1 空头，0 未就绪
+	IchimokuTenkan  float64
+	IchimokuKijun   float64
+	IchimokuSenkouA float64 // 当前 K 线对应的云层
+	IchimokuSenkouB float64
 }
 
 // TrendAnalysis 趋势分析
@@ -52,20 +78,137 @@ type LevelsAnalysis struct {
 	PriceVsSupport    float64
 }
 
+// MarketStats 永续合约衍生数据：标记/指数价格、资金费率与持仓量。
+// 字段为 0 表示交易所未提供。
+type MarketStats struct {
+	Symbol          string    `json:"symbol"`
+	MarkPrice       float64   `json:"mark_price"`
+	IndexPrice      float64   `json:"index_price"`
+	FundingRate     float64   `json:"funding_rate"`      // 本期资金费率（下次结算时收取）
+	NextFundingRate float64   `json:"next_funding_rate"` // 下一期预测费率，仅 OKX 提供
+	NextFundingTime time.Time `json:"next_funding_time"`
+	OpenInterest    float64   `json:"open_interest"` // 标的币数量
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// FundingCost 持有 side（long/short，或开仓方向 buy/sell）名义价值 notional 一个资金费周期的预估成本，负数表示收取。
+func (m MarketStats) FundingCost(side string, notional float64) float64 {
+	cost := notional * m.FundingRate
+	if side == "short" || side == "sell" {
+		cost = -cost
+	}
+	return cost
+}
+
+// 资金流水类型
+const (
+	IncomeRealizedPnL = "realized_pnl"
+	IncomeCommission  = "commission"
+	IncomeFunding     = "funding"
+	IncomeOther       = "other" // 划转、赠金、强平费等
+)
+
+// IncomeEvent 交易所资金流水，Amount 为账户余额变动：收入为正，手续费/支付资金费为负。
+type IncomeEvent struct {
+	ID      string    `json:"id"` // 交易所流水号，同一交易所内唯一
+	Symbol  string    `json:"symbol"`
+	Type    string    `json:"type"`
+	Amount  float64   `json:"amount"`
+	Asset   string    `json:"asset"`
+	TradeID string    `json:"trade_id"`
+	OrderID string    `json:"order_id"`
+	Info    string    `json:"info"` // 交易所原始流水类型
+	Time    time.Time `json:"time"`
+}
+
+// TradeFill 交易所逐笔成交，Size 为标的币数量；Fee 为支付的手续费（正数），RealizedPnL 为该笔平仓盈亏。
+type TradeFill struct {
+	ID          string    `json:"id"`
+	OrderID     string    `json:"order_id"`
+	Symbol      string    `json:"symbol"`
+	Side        string    `json:"side"`
+	Size        float64   `json:"size"`
+	Price       float64   `json:"price"`
+	Fee         float64   `json:"fee"`
+	FeeAsset    string    `json:"fee_asset"`
+	RealizedPnL float64   `json:"realized_pnl"`
+	Maker       bool      `json:"maker"`
+	Time        time.Time `json:"time"`
+}
+
+// BookLevel 盘口一档，Size 为标的币数量（OKX 已按 ctVal 由张数折算）。
+type BookLevel struct {
+	Price float64 `json:"price"`
+	Size  float64 `json:"size"`
+}
+
+// OrderBook 盘口深度快照：Bids 价格从高到低，Asks 价格从低到高。
+type OrderBook struct {
+	Symbol    string      `json:"symbol"`
+	Bids      []BookLevel `json:"bids"`
+	Asks      []BookLevel `json:"asks"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// MidPrice 买一卖一中间价；任一侧为空时返回 0。
+func (b OrderBook) MidPrice() float64 {
+	if len(b.Bids) == 0 || len(b.Asks) == 0 {
+		return 0
+	}
+	return (b.Bids[0].Price + b.Asks[0].Price) / 2
+}
+
+// CandleQuality K 线数据质量检查结果，除 Spikes 外计数均为已修复的问题。
+type CandleQuality struct {
+	Bars       int      `json:"bars"`
+	Reordered  bool     `json:"reordered,omitempty"`   // 时间乱序已重排
+	Duplicates int      `json:"duplicates,omitempty"`  // 重复开盘时间，保留最后一根
+	Invalid    int      `json:"invalid,omitempty"`     // OHLC 非法被剔除
+	Gaps       int      `json:"gaps,omitempty"`        // 缺口段数
+	FilledBars int      `json:"filled_bars,omitempty"` // 按前收盘价补齐的缺失 K 线
+	Truncated  int      `json:"truncated,omitempty"`   // 无法补齐的大缺口之前被丢弃的 K 线
+	ZeroVolume int      `json:"zero_volume,omitempty"` // 零成交量占位 K 线（不含补齐与未收盘 K 线）
+	Outliers   int      `json:"outliers,omitempty"`    // 单根尖刺（收盘价偏离后立即回归）经确认为错误报价已修正
+	Spikes     int      `json:"spikes,omitempty"`      // 未确认为错误报价的尖刺，仅标记
+	LastOpen   bool     `json:"last_open"`             // 最后一根 K 线尚未收盘
+	Repairs    []string `json:"repairs,omitempty"`
+	Flags      []string `json:"flags,omitempty"` // 仅标记未修改的问题
+}
+
+// IndicatorParams 技术指标参数。逐层覆盖（全局 → 习惯档位 → 生成策略）时零值字段表示沿用上一层。
+type IndicatorParams struct {
+	SMAFast        int     `json:"sma_fast,omitempty"`
+	SMAShort       int     `json:"sma_short,omitempty"`  // 短期趋势均线
+	SMAMedium      int     `json:"sma_medium,omitempty"` // 中期趋势均线
+	SMALong        int     `json:"sma_long,omitempty"`
+	EMAFast        int     `json:"ema_fast,omitempty"`
+	EMASlow        int     `json:"ema_slow,omitempty"`
+	MACDSignal     int     `json:"macd_signal,omitempty"`
+	RSIPeriod      int     `json:"rsi_period,omitempty"`
+	BBPeriod       int     `json:"bb_period,omitempty"`
+	BBMultiplier   float64 `json:"bb_multiplier,omitempty"`
+	VolumeMAPeriod int     `json:"volume_ma_period,omitempty"`
+	SRLookback     int     `json:"sr_lookback,omitempty"` // 静态支撑阻力回看 K 线数
+}
+
 // PriceData 完整行情数据
 type PriceData struct {
-	Symbol      string
-	Price       float64
-	Timestamp   time.Time
-	High        float64
-	Low         float64
-	Volume      float64
-	Timeframe   string
-	PriceChange float64
-	KlineData   []OHLCV
-	Technical   TechnicalIndicators
-	Trend       TrendAnalysis
-	Levels      LevelsAnalysis
+	Symbol          string
+	Price           float64
+	Timestamp       time.Time
+	High            float64
+	Low             float64
+	Volume          float64
+	Timeframe       string
+	PriceChange     float64
+	KlineData       []OHLCV
+	Technical       TechnicalIndicators
+	Trend           TrendAnalysis
+	Levels          LevelsAnalysis
+	Derivatives     MarketStats
+	Quality         CandleQuality
+	Indicators      IndicatorParams // 本次计算使用的指标参数
+	IndicatorSource string          // 指标参数生效来源，如 global>habit:4h>strategy:名称
 }
 
 // TradeSignal AI 返回的交易信号
@@ -89,25 +232,164 @@ type Position struct {
 	UnrealizedPnL float64
 	Leverage      float64
 	Symbol        string
+	MarginMode    string // cross/isolated，交易所未返回时为空
+	External      bool   // 非本系统开仓：本机器人订单记录中没有对应的持仓腿
+}
+
+// AssetBalance 合约账户单个保证金资产余额，金额均以该资产计价；USDValue 为折算美元价值，交易所未提供时为 0。
+type AssetBalance struct {
+	Asset            string  `json:"asset"`
+	WalletBalance    float64 `json:"wallet_balance"`
+	AvailableBalance float64 `json:"available_balance"`
+	MarginBalance    float64 `json:"margin_balance"` // 钱包余额 + 未实现盈亏
+	UnrealizedPnL    float64 `json:"unrealized_pnl"`
+	USDValue         float64 `json:"usd_value"`
+}
+
+// 保证金模式：全仓共享账户保证金；逐仓按交易对隔离，单笔亏损不会波及整个账户。
+const (
+	MarginModeCross    = "cross"
+	MarginModeIsolated = "isolated"
+)
+
+// NormalizeMarginMode 未知取值按全仓处理，与交易所默认一致。
+func NormalizeMarginMode(mode string) string {
+	if strings.ToLower(strings.TrimSpace(mode)) == MarginModeIsolated {
+		return MarginModeIsolated
+	}
+	return MarginModeCross
+}
+
+// 持仓模式：单向持仓只有一个净头寸；双向持仓（Binance dual-side / OKX long_short_mode /
+// Bybit hedge）多空两腿独立存在。
+const (
+	PositionModeOneWay = "one_way"
+	PositionModeHedge  = "hedge"
+)
+
+// PrimaryPosition 从多腿持仓中取主腿：单向模式下即唯一持仓，双向模式两腿并存时取数量较大的一腿。
+func PrimaryPosition(legs []Position) *Position {
+	var out *Position
+	for i := range legs {
+		if legs[i].Size <= 0 {
+			continue
+		}
+		if out == nil || legs[i].Size > out.Size {
+			p := legs[i]
+			out = &p
+		}
+	}
+	return out
+}
+
+// PositionLeg 返回指定方向（long/short）的持仓腿，不存在时返回 nil。
+func PositionLeg(legs []Position, side string) *Position {
+	for i := range legs {
+		if legs[i].Side == side && legs[i].Size > 0 {
+			p := legs[i]
+			return &p
+		}
+	}
+	return nil
 }
 
 type OrderResult struct {
-	OrderID    string  `json:"order_id"`
-	ClientID   string  `json:"client_id"`
-	State      string  `json:"state"`
-	Symbol     string  `json:"symbol"`
-	Side       string  `json:"side"`
-	Size       float64 `json:"size"`
-	ReduceOnly bool    `json:"reduce_only"`
+	OrderID      string  `json:"order_id"`
+	ClientID     string  `json:"client_id"`
+	State        string  `json:"state"`
+	Symbol       string  `json:"symbol"`
+	Side         string  `json:"side"`
+	Type         string  `json:"type"`
+	Size         float64 `json:"size"`
+	Price        float64 `json:"price,omitempty"`
+	TriggerPrice float64 `json:"trigger_price,omitempty"`
+	TimeInForce  string  `json:"time_in_force,omitempty"`
+	ReduceOnly   bool    `json:"reduce_only"`
+}
+
+// 订单类型；止损/止盈为交易所侧保护性条件单（只减仓）
+const (
+	OrderTypeMarket           = "market"
+	OrderTypeLimit            = "limit"
+	OrderTypeStopMarket       = "stop_market"
+	OrderTypeTakeProfitMarket = "take_profit_market"
+)
+
+// 限价单有效方式：GTC 常规挂单、post_only 只做 maker、IOC 立即成交否则撤销
+const (
+	TimeInForceGTC      = "gtc"
+	TimeInForcePostOnly = "post_only"
+	TimeInForceIOC      = "ioc"
+)
+
+// IsProtectiveOrderType 判断是否为止损/止盈条件单
+func IsProtectiveOrderType(t string) bool {
+	return t == OrderTypeStopMarket || t == OrderTypeTakeProfitMarket
 }
 
 type OrderStatus struct {
 	OrderID    string  `json:"order_id"`
 	State      string  `json:"state"`
+	Type       string  `json:"type,omitempty"`
+	Size       float64 `json:"size,omitempty"`
+	Price      float64 `json:"price,omitempty"`
 	FilledSize float64 `json:"filled_size"`
 	AvgPrice   float64 `json:"avg_price"`
 	Symbol     string  `json:"symbol"`
 	Side       string  `json:"side"`
 	ReduceOnly bool    `json:"reduce_only"`
 	UpdateTime string  `json:"update_time"`
+	CreateTime string  `json:"create_time,omitempty"`
+}
+
+// Instrument 交易对规格。数量统一按标的币计，OKX 合约张数已按 ctVal 折算。
+type Instrument struct {
+	Symbol        string  `json:"symbol"`
+	TickSize      float64 `json:"tick_size"`
+	StepSize      float64 `json:"step_size"`
+	MinQty        float64 `json:"min_qty"`
+	MinNotional   float64 `json:"min_notional"`
+	ContractValue float64 `json:"contract_value"`
+	MaxLeverage   float64 `json:"max_leverage"`
+}
+
+// RoundSize 数量按步长向下取整，避免超出风控批准的仓位。
+func (i Instrument) RoundSize(v float64) float64 {
+	return FloorToStep(v, i.StepSize)
+}
+
+// RoundPrice 价格按最小变动价位就近取整。
+func (i Instrument) RoundPrice(v float64) float64 {
+	return RoundToStep(v, i.TickSize)
+}
+
+// FloorToStep step <= 0 时原样返回。
+func FloorToStep(v, step float64) float64 {
+	if step <= 0 || v <= 0 {
+		return v
+	}
+	n := math.Floor(v/step + 1e-9)
+	return trimToStepDecimals(n*step, step)
+}
+
+func RoundToStep(v, step float64) float64 {
+	if step <= 0 || v <= 0 {
+		return v
+	}
+	n := math.Round(v / step)
+	return trimToStepDecimals(n*step, step)
+}
+
+// trimToStepDecimals 去掉浮点乘法带来的尾差，如 0.1*3=0.30000000000000004。
+func trimToStepDecimals(v, step float64) float64 {
+	raw := strconv.FormatFloat(step, 'f', -1, 64)
+	decimals := 0
+	if idx := strings.IndexByte(raw, '.'); idx >= 0 {
+		decimals = len(raw) - idx - 1
+	}
+	out, err := strconv.ParseFloat(strconv.FormatFloat(v, 'f', decimals, 64), 64)
+	if err != nil {
+		return v
+	}
+	return out
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"trade-go/config"
+	"trade-go/models"
 )
 
 type Snapshot struct {
@@ -20,12 +21,23 @@ type OrderPlanInput struct {
 	Confidence    string
 	SuggestedSize float64
 	Leverage      int
+	// 交易所规格：数量按 StepSize 向下取整，低于 MinQty/MinNotional 时拒单；为 0 表示不限制。
+	StepSize    float64
+	MinQty      float64
+	MinNotional float64
 }
 
+// 规格不满足时的拒单原因码
+const (
+	CodeBelowMinQty      = "below_min_qty"
+	CodeBelowMinNotional = "below_min_notional"
+)
+
 type OrderPlan struct {
 	Approved bool
 	Size     float64
 	Reason   string
+	Code     string
 }
 
 type Engine struct {
@@ -101,5 +113,13 @@ func (e *Engine) BuildOrderPlan(in OrderPlanInput, s Snapshot) OrderPlan {
 		return OrderPlan{Approved: false, Reason: "风控后仓位为0"}
 	}
 
+	size = models.FloorToStep(size, in.StepSize)
+	if in.MinQty > 0 && size < in.MinQty {
+		return OrderPlan{Approved: false, Code: CodeBelowMinQty, Reason: fmt.Sprintf("仓位 %.8g 低于最小下单量 %.8g", size, in.MinQty)}
+	}
+	if in.MinNotional > 0 && size*in.Price < in.MinNotional {
+		return OrderPlan{Approved: false, Code: CodeBelowMinNotional, Reason: fmt.Sprintf("名义价值 %.4f 低于最小下单金额 %.4f", size*in.Price, in.MinNotional)}
+	}
+
 	return OrderPlan{Approved: true, Size: size}
 }
@@ -0,0 +1,102 @@
+package risk
+
+import (
+	"math"
+	"trade-go/models"
+)
+
+// 滑点超限原因码
+const CodeSlippageExceeded = "slippage_exceeded"
+
+// SlippageEstimate 市价单吃单预估；滑点以买一卖一中间价为基准（含半个价差），单位 bps。
+type SlippageEstimate struct {
+	Side        string  `json:"side"`
+	Size        float64 `json:"size"`
+	FilledSize  float64 `json:"filled_size"`
+	MidPrice    float64 `json:"mid_price"`
+	AvgPrice    float64 `json:"avg_price"`
+	WorstPrice  float64 `json:"worst_price"`
+	SlippageBps float64 `json:"slippage_bps"`
+	Levels      int     `json:"levels"`
+	// 盘口深度不足以成交全部数量
+	Insufficient bool `json:"insufficient"`
+}
+
+// bookSide 买单吃卖盘，卖单吃买盘。
+func bookSide(book models.OrderBook, side string) []models.BookLevel {
+	if side == "buy" {
+		return book.Asks
+	}
+	return book.Bids
+}
+
+// EstimateSlippage 按盘口逐档吃单，预估 size 数量市价单的成交均价与滑点。
+func EstimateSlippage(book models.OrderBook, side string, size float64) SlippageEstimate {
+	est := SlippageEstimate{Side: side, Size: size, MidPrice: book.MidPrice()}
+	if size <= 0 || est.MidPrice <= 0 {
+		est.Insufficient = size > 0
+		return est
+	}
+	remaining := size
+	cost := 0.0
+	for _, lv := range bookSide(book, side) {
+		if remaining <= 0 {
+			break
+		}
+		q := math.Min(remaining, lv.Size)
+		cost += q * lv.Price
+		est.FilledSize += q
+		est.WorstPrice = lv.Price
+		est.Levels++
+		remaining -= q
+	}
+	est.Insufficient = remaining > 0
+	if est.FilledSize > 0 {
+		est.AvgPrice = cost / est.FilledSize
+		est.SlippageBps = slippageBps(side, est.AvgPrice, est.MidPrice)
+	}
+	return est
+}
+
+// MaxSizeWithinSlippage 成交均价滑点不超过 maxBps 时可吃下的最大数量（受限于返回的盘口深度）。
+func MaxSizeWithinSlippage(book models.OrderBook, side string, maxBps float64) float64 {
+	mid := book.MidPrice()
+	if mid <= 0 || maxBps < 0 {
+		return 0
+	}
+	limit := mid * (1 + maxBps/10000)
+	if side != "buy" {
+		limit = mid * (1 - maxBps/10000)
+	}
+	filled, cost := 0.0, 0.0
+	for _, lv := range bookSide(book, side) {
+		within := lv.Price <= limit
+		if side != "buy" {
+			within = lv.Price >= limit
+		}
+		if within {
+			if lv.Size >= math.MaxFloat64-filled {
+				return math.MaxFloat64
+			}
+			filled += lv.Size
+			cost += lv.Size * lv.Price
+			continue
+		}
+		// 该档价格劣于上限：只吃到均价恰好等于上限为止
+		var q float64
+		if side == "buy" {
+			q = (limit*filled - cost) / (lv.Price - limit)
+		} else {
+			q = (cost - limit*filled) / (limit - lv.Price)
+		}
+		return filled + math.Max(0, math.Min(q, lv.Size))
+	}
+	return filled
+}
+
+func slippageBps(side string, avg, mid float64) float64 {
+	if side == "buy" {
+		return (avg - mid) / mid * 10000
+	}
+	return (mid - avg) / mid * 10000
+}
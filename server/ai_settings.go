@@ -368,6 +368,7 @@ func normalizeHabitProfiles(in []habitProfile) []habitProfile {
 		if cur.ExecutionHint == "" && hasDef {
 			cur.ExecutionHint = def.ExecutionHint
 		}
+		cur.IndicatorParams = normalizeIndicatorOverride(cur.IndicatorParams)
 		out = append(out, cur)
 	}
 
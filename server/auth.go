@@ -54,7 +54,7 @@ func (s *Service) authMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		token := parseBearerToken(r)
+		token := requestAuthToken(r)
 		if token == "" {
 			writeError(w, http.StatusUnauthorized, "missing auth token")
 			return
@@ -128,7 +128,7 @@ func resolveAuthPermissionPolicy(path, method string) authPermissionPolicy {
 		return authPermissionPolicy{Module: "builder", Need: storage.AccessEdit}
 	case "/api/skill-workflow", "/api/auto-strategy/regen-now", "/api/risk/reset":
 		return authPermissionPolicy{Module: "skill_workflow", Need: storage.AccessEdit}
-	case "/api/backtest", "/api/backtest-history/delete":
+	case "/api/backtest", "/api/backtest-history/delete", "/api/klines/sync", "/api/klines/import":
 		return authPermissionPolicy{Module: "backtest", Need: storage.AccessEdit}
 	case "/api/system-settings", "/api/system/restart",
 		"/api/integrations", "/api/integrations/llm", "/api/integrations/llm-product",
@@ -136,7 +136,7 @@ func resolveAuthPermissionPolicy(path, method string) authPermissionPolicy {
 		"/api/integrations/llm/test", "/api/integrations/llm/models", "/api/integrations/llm/update", "/api/integrations/llm/delete", "/api/integrations/llm/activate",
 		"/api/integrations/exchange", "/api/integrations/exchange/activate", "/api/integrations/exchange/delete":
 		return authPermissionPolicy{Module: "system", Need: storage.AccessEdit}
-	case "/api/settings", "/api/run", "/api/scheduler/start", "/api/scheduler/stop":
+	case "/api/settings", "/api/run", "/api/scheduler/start", "/api/scheduler/stop", "/api/assets/income/sync":
 		return authPermissionPolicy{Module: "live", Need: storage.AccessEdit}
 	case "/api/paper/simulate-step", "/api/paper/config", "/api/paper/start", "/api/paper/stop", "/api/paper/reset-pnl", "/api/paper/risk/reset":
 		return authPermissionPolicy{Module: "paper", Need: storage.AccessEdit}
@@ -158,6 +158,17 @@ func parseBearerToken(r *http.Request) string {
 	return strings.TrimSpace(parts[1])
 }
 
+// requestAuthToken 推送通道额外接受 ?token=（浏览器 EventSource 无法设置请求头）。
+func requestAuthToken(r *http.Request) string {
+	if token := parseBearerToken(r); token != "" {
+		return token
+	}
+	if r.URL.Path == "/api/stream" {
+		return strings.TrimSpace(r.URL.Query().Get("token"))
+	}
+	return ""
+}
+
 func (s *Service) newSession(principal authPrincipal) (string, error) {
 	token, err := storage.BuildSessionToken()
 	if err != nil {
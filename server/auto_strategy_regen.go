@@ -7,7 +7,6 @@ import (
 	"os"
 	"strings"
 	"time"
-	"trade-go/exchange"
 	"trade-go/indicators"
 	"trade-go/storage"
 )
@@ -128,15 +127,15 @@ func (s *Service) generateAutoStrategy(reason string, drawdown float64, rs stora
 	tf := profile.Timeframe
 	minRR := normalizeAutoRegenMinRR(cfg.AutoStrategyRegenMinRR)
 
-	client := exchange.NewClient()
-	candles, err := client.FetchOHLCV(symbol, tf, 120)
+	params, _ := resolveIndicatorParams(cfg, habit, nil)
+	candles, err := s.bot.RecentArchivedKlines(symbol, tf, indicatorKlineLimit(120, params))
 	if err != nil || len(candles) < 30 {
 		if err == nil {
 			err = fmt.Errorf("K线数据不足")
 		}
 		return generatedStrategyRecord{}, nil, err
 	}
-	ind := indicators.Calculate(candles)
+	ind := indicators.CalculateWithParams(candles, params)
 	trend := indicators.AnalyzeTrend(candles, ind)
 	levels := indicators.AnalyzeLevels(candles, ind)
 	cur := candles[len(candles)-1]
@@ -184,6 +183,7 @@ func (s *Service) generateAutoStrategy(reason string, drawdown float64, rs stora
 		Source:           "auto_regen",
 		WorkflowVersion:  loadSkillWorkflowConfig().Version,
 		WorkflowChain:    enabledSkillWorkflowSteps(loadSkillWorkflowConfig()),
+		IndicatorParams:  profile.IndicatorParams,
 	}
 	final, nextEnabled, _, err := s.saveAndActivateGeneratedStrategy(record)
 	if err != nil {
@@ -3,13 +3,13 @@ package server
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"math"
 	"net/http"
-	"net/url"
 	"strconv"
 	"strings"
 	"time"
+	"trade-go/indicators"
+	"trade-go/models"
 	"trade-go/storage"
 )
 
@@ -133,11 +133,20 @@ func (s *Service) handleBacktest(w http.ResponseWriter, r *http.Request) {
 		lowPct = 1
 	}
 
-	klines, err := fetchBinanceKlinesRange(pair, interval, startMs, endMs)
+	archived, err := s.bot.ArchivedKlines(pair, interval, time.UnixMilli(startMs), time.UnixMilli(endMs))
 	if err != nil {
 		writeError(w, http.StatusBadGateway, "fetch kline failed: "+err.Error())
 		return
 	}
+	klines := make([]klineItem, 0, len(archived.Bars))
+	bars := make([]models.OHLCV, 0, len(archived.Bars))
+	for _, k := range archived.Bars {
+		if k.High <= 0 || k.Low <= 0 || k.Close <= 0 {
+			continue
+		}
+		klines = append(klines, klineItem{TS: k.Timestamp.UnixMilli(), Open: k.Open, High: k.High, Low: k.Low, Close: k.Close})
+		bars = append(bars, k)
+	}
 	if len(klines) < 8 {
 		writeError(w, http.StatusBadRequest, "kline data not enough for backtest")
 		return
@@ -148,6 +157,10 @@ func (s *Service) handleBacktest(w http.ResponseWriter, r *http.Request) {
 	wins := 0
 	losses := 0
 	equity := initialMargin
+	atrs := indicators.ATR(bars, 14)
+	params, paramSource := resolveIndicatorParams(s.bot.TradeConfig(), normalizeHabitInput(req.Habit), backtestStrategies(req.StrategyName))
+	smaShort := indicators.SMA(bars, params.SMAShort)
+	smaMedium := indicators.SMA(bars, params.SMAMedium)
 	for i := 6; i < len(klines)-1; i += 2 {
 		cur := klines[i]
 		nxt := klines[i+1]
@@ -199,7 +212,12 @@ func (s *Service) handleBacktest(w http.ResponseWriter, r *http.Request) {
 		if size > maxSize {
 			size = maxSize
 		}
-		atr := math.Max(cur.High-cur.Low, cur.Close*0.001)
+		// ATR 预热前退回当根振幅
+		atr := atrs[i]
+		if math.IsNaN(atr) {
+			atr = cur.High - cur.Low
+		}
+		atr = math.Max(atr, cur.Close*0.001)
 		slMult := 1.1
 		tpMult := 1.9
 		if confidence == "HIGH" {
@@ -225,6 +243,11 @@ func (s *Service) handleBacktest(w http.ResponseWriter, r *http.Request) {
 			"趋势判定=%s（当前收盘%.4f vs 前一根%.4f），动量=%.2f%%，信心=%s，仓位模式=%s，杠杆=%dx",
 			side, cur.Close, prev.Close, movePct, confidence, sizingMode, leverage,
 		)
+		if !math.IsNaN(smaShort[i]) && !math.IsNaN(smaMedium[i]) {
+			orderBasis += fmt.Sprintf("，均线=收盘位于SMA%d(%.4f)%s，SMA%d位于SMA%d(%.4f)%s",
+				params.SMAShort, smaShort[i], aboveBelow(cur.Close, smaShort[i]),
+				params.SMAShort, params.SMAMedium, smaMedium[i], aboveBelow(smaShort[i], smaMedium[i]))
+		}
 
 		pnl := 0.0
 		if size > 0 {
@@ -298,6 +321,7 @@ func (s *Service) handleBacktest(w http.ResponseWriter, r *http.Request) {
 		Wins:                    wins,
 		Losses:                  losses,
 		Ratio:                   round(ratio, 6),
+		IndicatorParams:         json.RawMessage(mustJSON(params)),
 	}
 	saveRecords := make([]storage.BacktestRunRecord, 0, len(records))
 	for _, r := range records {
@@ -342,10 +366,16 @@ func (s *Service) handleBacktest(w http.ResponseWriter, r *http.Request) {
 		"losses":                     run.Losses,
 		"ratio":                      run.Ratio,
 		"ratio_infinite":             ratioInfinite,
+		"indicator_params":           params,
+		"indicator_source":           paramSource,
 	}
 	resp := map[string]any{
-		"summary": summary,
-		"records": records,
+		"summary":      summary,
+		"records":      records,
+		"kline_source": archived,
+	}
+	if archived.Warning != "" {
+		resp["kline_warning"] = archived.Warning
 	}
 	if !saved {
 		resp["history_warning"] = "回测已完成，但回测记录未写入SQLite（请检查数据库配置）"
@@ -427,6 +457,21 @@ func monthToMs(v string, endOfMonth bool) (int64, error) {
 	return t.UnixMilli(), nil
 }
 
+// backtestStrategies 回测指定策略时按该策略解析指标参数。
+func backtestStrategies(name string) []string {
+	if name = strings.TrimSpace(name); name == "" {
+		return nil
+	}
+	return []string{name}
+}
+
+func aboveBelow(a, b float64) string {
+	if a >= b {
+		return "上方"
+	}
+	return "下方"
+}
+
 func intervalByHabit(habit string) string {
 	switch strings.TrimSpace(habit) {
 	case "10m":
@@ -442,111 +487,6 @@ func intervalByHabit(habit string) string {
 	}
 }
 
-func fetchBinanceKlinesRange(symbol, interval string, startMs, endMs int64) ([]klineItem, error) {
-	client := &http.Client{Timeout: 15 * time.Second}
-	cursor := startMs
-	out := make([]klineItem, 0, 1200)
-
-	for cursor < endMs {
-		u := url.URL{Scheme: "https", Host: "api.binance.com", Path: "/api/v3/klines"}
-		q := u.Query()
-		q.Set("symbol", symbol)
-		q.Set("interval", interval)
-		q.Set("startTime", strconv.FormatInt(cursor, 10))
-		q.Set("endTime", strconv.FormatInt(endMs, 10))
-		q.Set("limit", "1000")
-		u.RawQuery = q.Encode()
-
-		resp, err := client.Get(u.String())
-		if err != nil {
-			return nil, err
-		}
-		raw, _ := io.ReadAll(resp.Body)
-		_ = resp.Body.Close()
-		if resp.StatusCode >= 300 {
-			return nil, fmt.Errorf("binance http %d", resp.StatusCode)
-		}
-
-		var arr [][]interface{}
-		if err := json.Unmarshal(raw, &arr); err != nil {
-			return nil, err
-		}
-		if len(arr) == 0 {
-			break
-		}
-
-		lastTs := int64(0)
-		for _, row := range arr {
-			if len(row) < 6 {
-				continue
-			}
-			ts, ok := asInt64(row[0])
-			if !ok {
-				continue
-			}
-			open, ok1 := asFloat(row[1])
-			high, ok2 := asFloat(row[2])
-			low, ok3 := asFloat(row[3])
-			closePrice, ok4 := asFloat(row[4])
-			if !(ok1 && ok2 && ok3 && ok4) {
-				continue
-			}
-			if high <= 0 || low <= 0 || closePrice <= 0 {
-				continue
-			}
-			out = append(out, klineItem{TS: ts, Open: open, High: high, Low: low, Close: closePrice})
-			if ts > lastTs {
-				lastTs = ts
-			}
-		}
-		if lastTs == 0 {
-			break
-		}
-		next := lastTs + 1
-		if next <= cursor {
-			break
-		}
-		cursor = next
-		if len(arr) < 1000 {
-			break
-		}
-		time.Sleep(80 * time.Millisecond)
-	}
-	return out, nil
-}
-
-func asInt64(v interface{}) (int64, bool) {
-	switch t := v.(type) {
-	case float64:
-		return int64(t), true
-	case int64:
-		return t, true
-	case string:
-		n, err := strconv.ParseInt(t, 10, 64)
-		if err != nil {
-			return 0, false
-		}
-		return n, true
-	default:
-		return 0, false
-	}
-}
-
-func asFloat(v interface{}) (float64, bool) {
-	switch t := v.(type) {
-	case float64:
-		return t, true
-	case string:
-		n, err := strconv.ParseFloat(t, 64)
-		if err != nil {
-			return 0, false
-		}
-		return n, true
-	default:
-		return 0, false
-	}
-}
-
 func round(v float64, digits int) float64 {
 	if digits < 0 {
 		return v
@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"trade-go/models"
 )
 
 const generatedStrategiesPath = "data/generated_strategies.json"
@@ -31,6 +32,8 @@ type generatedStrategyRecord struct {
 	Source           string   `json:"source"`
 	WorkflowVersion  string   `json:"workflow_version"`
 	WorkflowChain    []string `json:"workflow_chain"`
+	// 覆盖全局与习惯档位的指标参数
+	IndicatorParams *models.IndicatorParams `json:"indicator_params,omitempty"`
 }
 
 func defaultGeneratedStrategyStore() generatedStrategyStore {
@@ -142,6 +145,7 @@ func normalizeGeneratedStrategyRecords(items []generatedStrategyRecord) []genera
 			Source:           source,
 			WorkflowVersion:  strings.TrimSpace(it.WorkflowVersion),
 			WorkflowChain:    normalizeStringSlice(it.WorkflowChain),
+			IndicatorParams:  normalizeIndicatorOverride(it.IndicatorParams),
 		})
 	}
 	return out
@@ -324,6 +328,26 @@ func mapToStringSlice(m map[string]any, keys ...string) []string {
 	return nil
 }
 
+// mapToIndicatorParams 解析对象形式的指标参数，无法解析时视为未配置。
+func mapToIndicatorParams(m map[string]any, keys ...string) *models.IndicatorParams {
+	for _, key := range keys {
+		v, ok := m[key]
+		if !ok || v == nil {
+			continue
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		var p models.IndicatorParams
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil
+		}
+		return normalizeIndicatorOverride(&p)
+	}
+	return nil
+}
+
 func anyToString(v any) string {
 	switch t := v.(type) {
 	case string:
@@ -371,6 +395,7 @@ func (s *Service) handleGeneratedStrategies(w http.ResponseWriter, r *http.Reque
 				Source:           mapToString(row, "source"),
 				WorkflowVersion:  mapToString(row, "workflow_version", "workflowVersion"),
 				WorkflowChain:    mapToStringSlice(row, "workflow_chain", "workflowChain"),
+				IndicatorParams:  mapToIndicatorParams(row, "indicator_params", "indicatorParams"),
 			})
 		}
 		st := generatedStrategyStore{
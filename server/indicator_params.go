@@ -0,0 +1,84 @@
+package server
+
+import (
+	"strings"
+	"trade-go/config"
+	"trade-go/indicators"
+	"trade-go/models"
+	"trade-go/trader"
+)
+
+// resolveIndicatorParams 全局指标参数依次叠加习惯档位与 strategies 中首个带指标参数的生成策略，
+// 返回的 source 形如 global>habit:4h>strategy:名称。
+func resolveIndicatorParams(cfg config.TradeConfig, habit string, strategies []string) (models.IndicatorParams, string) {
+	p := trader.IndicatorParamsFromConfig(cfg)
+	sources := []string{"global"}
+	if strings.TrimSpace(habit) != "" {
+		if profile := habitProfileOf(habit); profile.IndicatorParams != nil {
+			p = indicators.MergeParams(p, *profile.IndicatorParams)
+			sources = append(sources, "habit:"+profile.Habit)
+		}
+	}
+	if rec, ok := firstStrategyWithIndicatorParams(readGeneratedStrategies().Strategies, strategies); ok {
+		p = indicators.MergeParams(p, *rec.IndicatorParams)
+		sources = append(sources, "strategy:"+rec.Name)
+	}
+	return indicators.NormalizeParams(p), strings.Join(sources, ">")
+}
+
+// liveIndicatorParams 实盘与模拟盘的解析入口：习惯档位按交易周期匹配，未指定策略时取实盘启用的策略。
+func liveIndicatorParams(cfg config.TradeConfig, strategies []string) (models.IndicatorParams, string) {
+	if len(strategies) == 0 {
+		strategies = parseEnabledStrategiesEnv("")
+	}
+	return resolveIndicatorParams(cfg, habitByTimeframe(cfg.Timeframe), strategies)
+}
+
+// firstStrategyWithIndicatorParams 按启用顺序（名称或 ID）查找首个配置了指标参数的生成策略。
+func firstStrategyWithIndicatorParams(items []generatedStrategyRecord, strategies []string) (generatedStrategyRecord, bool) {
+	for _, sel := range strategies {
+		key := strings.TrimSpace(sel)
+		if key == "" {
+			continue
+		}
+		for _, item := range items {
+			if item.IndicatorParams == nil {
+				continue
+			}
+			if strings.EqualFold(strings.TrimSpace(item.Name), key) || strings.TrimSpace(item.ID) == key {
+				return item, true
+			}
+		}
+	}
+	return generatedStrategyRecord{}, false
+}
+
+// indicatorKlineLimit 生成策略等场景的 K 线数，不足以覆盖指标周期时按指标需要拉取。
+func indicatorKlineLimit(base int, p models.IndicatorParams) int {
+	if need := indicators.RequiredBars(p); need > base {
+		return need
+	}
+	return base
+}
+
+// normalizeIndicatorOverride 清理习惯档位/生成策略上的覆盖参数：负数视为未配置，全部未配置时返回 nil。
+// 取值范围在最终合并后由 indicators.NormalizeParams 统一限制。
+func normalizeIndicatorOverride(in *models.IndicatorParams) *models.IndicatorParams {
+	if in == nil {
+		return nil
+	}
+	p := *in
+	for _, v := range []*int{&p.SMAFast, &p.SMAShort, &p.SMAMedium, &p.SMALong, &p.EMAFast, &p.EMASlow,
+		&p.MACDSignal, &p.RSIPeriod, &p.BBPeriod, &p.VolumeMAPeriod, &p.SRLookback} {
+		if *v < 0 {
+			*v = 0
+		}
+	}
+	if !(p.BBMultiplier > 0) {
+		p.BBMultiplier = 0
+	}
+	if p == (models.IndicatorParams{}) {
+		return nil
+	}
+	return &p
+}
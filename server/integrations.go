@@ -18,6 +18,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"trade-go/exchange"
 	"trade-go/llmapi"
 )
 
@@ -50,6 +51,8 @@ type exchangeIntegration struct {
 	APIKey    string `json:"api_key"`
 	Secret    string `json:"secret"`
 	Passphase string `json:"passphrase"`
+	// Environment mainnet / testnet / demo
+	Environment string `json:"environment"`
 }
 
 type integrationStore struct {
@@ -592,11 +595,16 @@ func (s *Service) handleAddExchangeIntegration(w http.ResponseWriter, r *http.Re
 		writeError(w, 400, "exchange/api_key/secret 必填")
 		return
 	}
+	if !isValidExchangeEnvironment(req.Environment) {
+		writeError(w, 400, "environment 仅支持 mainnet / testnet / demo")
+		return
+	}
+	req.Environment = exchange.ResolveEndpoints(req.Exchange, req.Environment).Env
 	if req.Name == "" {
 		req.Name = req.Exchange
 	}
-	if req.Exchange != "binance" && req.Exchange != "okx" {
-		writeError(w, 400, "当前仅支持 binance / okx")
+	if !isSupportedExchange(req.Exchange) {
+		writeError(w, 400, "当前仅支持 binance / okx / bybit")
 		return
 	}
 	if req.Exchange == "okx" && req.Passphase == "" {
@@ -679,9 +687,10 @@ func (s *Service) handleActivateExchangeIntegration(w http.ResponseWriter, r *ht
 		"active_exchange_id": store.ActiveExchangeID,
 		"exchange_bound":     true,
 		"active_exchange": map[string]any{
-			"id":       cfg.ID,
-			"exchange": cfg.Exchange,
-			"api_key":  maskKey(cfg.APIKey),
+			"id":          cfg.ID,
+			"exchange":    cfg.Exchange,
+			"environment": cfg.Environment,
+			"api_key":     maskKey(cfg.APIKey),
 		},
 	})
 }
@@ -1099,7 +1108,8 @@ func fetchLLMModelList(product, baseURL, apiKey string) (models []string, chatEn
 
 func validateBinanceIntegration(cfg exchangeIntegration) error {
 	cli := &http.Client{Timeout: 10 * time.Second}
-	pingResp, err := cli.Get("https://fapi.binance.com/fapi/v1/ping")
+	baseURL := exchange.ResolveEndpoints("binance", cfg.Environment).REST
+	pingResp, err := cli.Get(baseURL + "/fapi/v1/ping")
 	if err != nil {
 		return err
 	}
@@ -1115,7 +1125,7 @@ func validateBinanceIntegration(cfg exchangeIntegration) error {
 	h := hmac.New(sha256.New, []byte(cfg.Secret))
 	h.Write([]byte(raw))
 	sig := hex.EncodeToString(h.Sum(nil))
-	u := "https://fapi.binance.com/fapi/v2/account?" + raw + "&signature=" + sig
+	u := baseURL + "/fapi/v2/account?" + raw + "&signature=" + sig
 	req, err := http.NewRequest(http.MethodGet, u, nil)
 	if err != nil {
 		return err
@@ -1138,9 +1148,10 @@ func validateOKXIntegration(cfg exchangeIntegration) error {
 		return fmt.Errorf("okx passphrase 必填")
 	}
 	cli := &http.Client{Timeout: 12 * time.Second}
+	ep := exchange.ResolveEndpoints("okx", cfg.Environment)
 
 	// public ping
-	publicResp, err := cli.Get("https://www.okx.com/api/v5/public/time")
+	publicResp, err := cli.Get(ep.REST + "/api/v5/public/time")
 	if err != nil {
 		return err
 	}
@@ -1157,7 +1168,7 @@ func validateOKXIntegration(cfg exchangeIntegration) error {
 	h := hmac.New(sha256.New, []byte(cfg.Secret))
 	h.Write([]byte(preHash))
 	signature := base64.StdEncoding.EncodeToString(h.Sum(nil))
-	req, err := http.NewRequest(method, "https://www.okx.com"+pathWithQuery, nil)
+	req, err := http.NewRequest(method, ep.REST+pathWithQuery, nil)
 	if err != nil {
 		return err
 	}
@@ -1166,6 +1177,9 @@ func validateOKXIntegration(cfg exchangeIntegration) error {
 	req.Header.Set("OK-ACCESS-TIMESTAMP", ts)
 	req.Header.Set("OK-ACCESS-PASSPHRASE", cfg.Passphase)
 	req.Header.Set("Content-Type", "application/json")
+	if ep.Simulated {
+		req.Header.Set("x-simulated-trading", "1")
+	}
 	resp, err := cli.Do(req)
 	if err != nil {
 		return err
@@ -1190,14 +1204,87 @@ func validateOKXIntegration(cfg exchangeIntegration) error {
 	return nil
 }
 
+func bybitRESTBaseURL(env string) string {
+	if v := strings.TrimRight(strings.TrimSpace(os.Getenv("BYBIT_BASE_URL")), "/"); v != "" {
+		return v
+	}
+	return exchange.ResolveEndpoints("bybit", env).REST
+}
+
+func validateBybitIntegration(cfg exchangeIntegration) error {
+	cli := &http.Client{Timeout: 10 * time.Second}
+	baseURL := bybitRESTBaseURL(cfg.Environment)
+
+	publicResp, err := cli.Get(baseURL + "/v5/market/time")
+	if err != nil {
+		return err
+	}
+	_ = publicResp.Body.Close()
+	if publicResp.StatusCode >= 300 {
+		return fmt.Errorf("bybit public http %d", publicResp.StatusCode)
+	}
+
+	query := "accountType=UNIFIED"
+	ts := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	recvWindow := "5000"
+	h := hmac.New(sha256.New, []byte(cfg.Secret))
+	h.Write([]byte(ts + cfg.APIKey + recvWindow + query))
+	sig := hex.EncodeToString(h.Sum(nil))
+	req, err := http.NewRequest(http.MethodGet, baseURL+"/v5/account/wallet-balance?"+query, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-BAPI-API-KEY", cfg.APIKey)
+	req.Header.Set("X-BAPI-TIMESTAMP", ts)
+	req.Header.Set("X-BAPI-RECV-WINDOW", recvWindow)
+	req.Header.Set("X-BAPI-SIGN", sig)
+	resp, err := cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("bybit account http %d: %s", resp.StatusCode, string(body))
+	}
+	var parsed struct {
+		RetCode int    `json:"retCode"`
+		RetMsg  string `json:"retMsg"`
+	}
+	if err := json.Unmarshal(body, &parsed); err == nil && parsed.RetCode != 0 {
+		return fmt.Errorf("bybit retCode=%d: %s", parsed.RetCode, parsed.RetMsg)
+	}
+	return nil
+}
+
+func isSupportedExchange(name string) bool {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "binance", "okx", "bybit":
+		return true
+	default:
+		return false
+	}
+}
+
+func isValidExchangeEnvironment(env string) bool {
+	switch strings.ToLower(strings.TrimSpace(env)) {
+	case "", exchange.EnvMainnet, exchange.EnvTestnet, exchange.EnvDemo:
+		return true
+	default:
+		return false
+	}
+}
+
 func validateExchangeIntegration(cfg exchangeIntegration) error {
 	switch strings.ToLower(strings.TrimSpace(cfg.Exchange)) {
 	case "binance":
 		return validateBinanceIntegration(cfg)
 	case "okx":
 		return validateOKXIntegration(cfg)
+	case "bybit":
+		return validateBybitIntegration(cfg)
 	default:
-		return fmt.Errorf("当前仅支持 binance / okx")
+		return fmt.Errorf("当前仅支持 binance / okx / bybit")
 	}
 }
 
@@ -1297,10 +1384,11 @@ func normalizeIntegrationStore(cfg *integrationStore) {
 		cfg.Exchanges[i].APIKey = strings.TrimSpace(cfg.Exchanges[i].APIKey)
 		cfg.Exchanges[i].Secret = strings.TrimSpace(cfg.Exchanges[i].Secret)
 		cfg.Exchanges[i].Passphase = strings.TrimSpace(cfg.Exchanges[i].Passphase)
+		cfg.Exchanges[i].Environment = exchange.ResolveEndpoints(cfg.Exchanges[i].Exchange, cfg.Exchanges[i].Environment).Env
 		if cfg.Exchanges[i].Name == "" {
 			cfg.Exchanges[i].Name = cfg.Exchanges[i].Exchange
 		}
-		if cfg.Exchanges[i].Exchange != "binance" && cfg.Exchanges[i].Exchange != "okx" {
+		if !isSupportedExchange(cfg.Exchanges[i].Exchange) {
 			continue
 		}
 		nextExchanges = append(nextExchanges, cfg.Exchanges[i])
@@ -1323,7 +1411,7 @@ func isLegacyEnvBootstrapLLM(item llmIntegration) bool {
 
 func isLegacyEnvBootstrapExchange(item exchangeIntegration) bool {
 	name := strings.TrimSpace(strings.ToLower(item.Name))
-	return name == "env binance" || name == "env okx"
+	return name == "env binance" || name == "env okx" || name == "env bybit"
 }
 
 func syncEnvWithFrontendStore(cfg integrationStore) {
@@ -1362,6 +1450,7 @@ func syncEnvWithFrontendStore(cfg integrationStore) {
 		}
 		exName := strings.ToLower(strings.TrimSpace(active.Exchange))
 		updates["ACTIVE_EXCHANGE"] = exName
+		updates["EXCHANGE_ENV"] = exchange.NormalizeEnvironment(active.Environment)
 		switch exName {
 		case "okx":
 			updates["BINANCE_API_KEY"] = ""
@@ -1369,6 +1458,16 @@ func syncEnvWithFrontendStore(cfg integrationStore) {
 			updates["OKX_API_KEY"] = strings.TrimSpace(active.APIKey)
 			updates["OKX_SECRET"] = strings.TrimSpace(active.Secret)
 			updates["OKX_PASSWORD"] = strings.TrimSpace(active.Passphase)
+			updates["BYBIT_API_KEY"] = ""
+			updates["BYBIT_SECRET"] = ""
+		case "bybit":
+			updates["BINANCE_API_KEY"] = ""
+			updates["BINANCE_SECRET"] = ""
+			updates["OKX_API_KEY"] = ""
+			updates["OKX_SECRET"] = ""
+			updates["OKX_PASSWORD"] = ""
+			updates["BYBIT_API_KEY"] = strings.TrimSpace(active.APIKey)
+			updates["BYBIT_SECRET"] = strings.TrimSpace(active.Secret)
 		default:
 			updates["ACTIVE_EXCHANGE"] = "binance"
 			updates["BINANCE_API_KEY"] = strings.TrimSpace(active.APIKey)
@@ -1376,14 +1475,19 @@ func syncEnvWithFrontendStore(cfg integrationStore) {
 			updates["OKX_API_KEY"] = ""
 			updates["OKX_SECRET"] = ""
 			updates["OKX_PASSWORD"] = ""
+			updates["BYBIT_API_KEY"] = ""
+			updates["BYBIT_SECRET"] = ""
 		}
 	} else {
 		updates["ACTIVE_EXCHANGE"] = ""
+		updates["EXCHANGE_ENV"] = ""
 		updates["BINANCE_API_KEY"] = ""
 		updates["BINANCE_SECRET"] = ""
 		updates["OKX_API_KEY"] = ""
 		updates["OKX_SECRET"] = ""
 		updates["OKX_PASSWORD"] = ""
+		updates["BYBIT_API_KEY"] = ""
+		updates["BYBIT_SECRET"] = ""
 	}
 	if len(updates) == 0 {
 		return
@@ -1478,6 +1582,7 @@ func bindExchangeAccount(s *Service, cfg exchangeIntegration) error {
 	exName := strings.ToLower(strings.TrimSpace(cfg.Exchange))
 	updates := map[string]string{
 		"ACTIVE_EXCHANGE": exName,
+		"EXCHANGE_ENV":    exchange.NormalizeEnvironment(cfg.Environment),
 	}
 	switch exName {
 	case "binance":
@@ -1487,6 +1592,9 @@ func bindExchangeAccount(s *Service, cfg exchangeIntegration) error {
 		updates["OKX_API_KEY"] = strings.TrimSpace(cfg.APIKey)
 		updates["OKX_SECRET"] = strings.TrimSpace(cfg.Secret)
 		updates["OKX_PASSWORD"] = strings.TrimSpace(cfg.Passphase)
+	case "bybit":
+		updates["BYBIT_API_KEY"] = strings.TrimSpace(cfg.APIKey)
+		updates["BYBIT_SECRET"] = strings.TrimSpace(cfg.Secret)
 	default:
 		return fmt.Errorf("unsupported exchange: %s", exName)
 	}
@@ -1545,11 +1653,14 @@ func unbindLLMAccount(s *Service) error {
 func unbindExchangeAccount(s *Service) error {
 	updates := map[string]string{
 		"ACTIVE_EXCHANGE": "",
+		"EXCHANGE_ENV":    "",
 		"BINANCE_API_KEY": "",
 		"BINANCE_SECRET":  "",
 		"OKX_API_KEY":     "",
 		"OKX_SECRET":      "",
 		"OKX_PASSWORD":    "",
+		"BYBIT_API_KEY":   "",
+		"BYBIT_SECRET":    "",
 	}
 	if err := upsertDotEnv(".env", updates); err != nil {
 		return err
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"trade-go/exchange"
+	"trade-go/storage"
+)
+
+// K 线导入文件大小上限（data-vision 单月 1m 数据解压后约 4MB）
+const klineImportMaxBytes = 64 << 20
+
+func (s *Service) handleKlineArchive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.db == nil {
+		writeError(w, http.StatusServiceUnavailable, "SQLite 未启用，K线归档不可用")
+		return
+	}
+	series, err := s.db.KlineArchive()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "读取K线归档失败: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"series": series})
+}
+
+// handleKlineSync 预先下载区间内缺失的 K 线到归档，之后可离线回测。
+func (s *Service) handleKlineSync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req struct {
+		Symbol     string `json:"symbol"`
+		Interval   string `json:"interval"`
+		StartMonth string `json:"start_month"`
+		EndMonth   string `json:"end_month"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+	if symbol == "" {
+		symbol = strings.ToUpper(strings.TrimSpace(s.bot.TradeConfig().Symbol))
+	}
+	interval := strings.TrimSpace(req.Interval)
+	if exchange.TimeframeDuration(interval) <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid interval")
+		return
+	}
+	startMs, err := monthToMs(req.StartMonth, false)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid start_month")
+		return
+	}
+	endMs, err := monthToMs(req.EndMonth, true)
+	if err != nil || endMs <= startMs {
+		writeError(w, http.StatusBadRequest, "invalid end_month")
+		return
+	}
+	res, err := s.bot.ArchivedKlines(symbol, interval, time.UnixMilli(startMs), time.UnixMilli(endMs))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "同步K线失败: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"symbol":   symbol,
+		"interval": interval,
+		"bars":     len(res.Bars),
+		"result":   res,
+	})
+}
+
+// handleKlineImport 导入 CSV（或含 CSV 的 zip，如 Binance data-vision 月度文件）到归档。
+// 参数 source/symbol/interval 取自查询串；文件可为 multipart 的 file 字段或整个请求体。
+func (s *Service) handleKlineImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.db == nil {
+		writeError(w, http.StatusServiceUnavailable, "SQLite 未启用，K线归档不可用")
+		return
+	}
+	q := r.URL.Query()
+	source := strings.ToLower(strings.TrimSpace(q.Get("source")))
+	if source == "" {
+		source = "binance"
+	}
+	symbol := exchange.NormalizeSymbol(q.Get("symbol"))
+	interval := strings.TrimSpace(q.Get("interval"))
+	if symbol == "" || exchange.TimeframeDuration(interval) <= 0 {
+		writeError(w, http.StatusBadRequest, "symbol 与 interval 必填")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, klineImportMaxBytes)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "缺少上传文件 file")
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "读取上传文件失败: "+err.Error())
+		return
+	}
+	csvFiles, err := klineCSVFiles(raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "解析压缩包失败: "+err.Error())
+		return
+	}
+	parsed, inserted := 0, 0
+	for _, data := range csvFiles {
+		bars, err := storage.ParseKlineCSV(bytes.NewReader(data))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "解析CSV失败: "+err.Error())
+			return
+		}
+		n, err := s.db.SaveKlines(source, symbol, interval, bars)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "写入K线归档失败: "+err.Error())
+			return
+		}
+		parsed += len(bars)
+		inserted += n
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"source":   source,
+		"symbol":   symbol,
+		"interval": interval,
+		"parsed":   parsed,
+		"inserted": inserted,
+	})
+}
+
+// klineCSVFiles zip 包返回其中全部 .csv 文件，否则按单个 CSV 处理。
+func klineCSVFiles(raw []byte) ([][]byte, error) {
+	if !bytes.HasPrefix(raw, []byte("PK\x03\x04")) {
+		return [][]byte{raw}, nil
+	}
+	zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		return nil, err
+	}
+	var out [][]byte
+	for _, f := range zr.File {
+		if !strings.EqualFold(path.Ext(f.Name), ".csv") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, klineImportMaxBytes))
+		_ = rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, data)
+	}
+	return out, nil
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 	"trade-go/config"
 	"trade-go/llmapi"
+	"trade-go/models"
 	"trade-go/trader"
 )
 
@@ -204,6 +205,7 @@ func tradeConfigMap(cfg config.TradeConfig) map[string]any {
 		"high_confidence_margin_pct":            cfg.HighConfidenceMarginPct,
 		"low_confidence_margin_pct":             cfg.LowConfidenceMarginPct,
 		"leverage":                              cfg.Leverage,
+		"margin_mode":                           models.NormalizeMarginMode(cfg.MarginMode),
 		"timeframe":                             cfg.Timeframe,
 		"test_mode":                             cfg.TestMode,
 		"data_points":                           cfg.DataPoints,
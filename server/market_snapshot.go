@@ -54,7 +54,7 @@ func (s *Service) handleMarketSnapshot(w http.ResponseWriter, r *http.Request) {
 		changePct = (last.Close - prev.Close) / prev.Close * 100
 	}
 
-	writeJSON(w, http.StatusOK, map[string]any{
+	out := map[string]any{
 		"symbol":          symbol,
 		"timeframe":       timeframe,
 		"active_exchange": client.ActiveExchange(),
@@ -65,5 +65,28 @@ func (s *Service) handleMarketSnapshot(w http.ResponseWriter, r *http.Request) {
 		"volume":          last.Volume,
 		"timestamp":       last.Timestamp,
 		"change_pct":      changePct,
-	})
+	}
+	// 启用行情 WebSocket 时附带推送快照，健康时以推送最新价为准
+	if sub := s.uiMarketSubscription(symbol, timeframe); sub != nil {
+		snap := sub.Snapshot()
+		out["stream"] = snap
+		if price, err := strconv.ParseFloat(snap.TickerPrice, 64); snap.Healthy && err == nil && price > 0 {
+			out["price"] = price
+			if prev.Close > 0 {
+				out["change_pct"] = (price - prev.Close) / prev.Close * 100
+			}
+		}
+	}
+	// 合约数据失败不影响 K 线快照返回
+	if stats, err := client.FetchMarketStats(symbol); err != nil {
+		out["derivatives_error"] = err.Error()
+	} else {
+		out["mark_price"] = stats.MarkPrice
+		out["index_price"] = stats.IndexPrice
+		out["funding_rate"] = stats.FundingRate
+		out["next_funding_rate"] = stats.NextFundingRate
+		out["next_funding_time"] = stats.NextFundingTime
+		out["open_interest"] = stats.OpenInterest
+	}
+	writeJSON(w, http.StatusOK, out)
 }
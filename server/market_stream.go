@@ -0,0 +1,55 @@
+package server
+
+import (
+	"strings"
+	"time"
+	"trade-go/market"
+)
+
+// 前端行情订阅空闲超过该时长即释放，页面轮询期间持续续期
+const uiSubscriptionIdleTTL = 5 * time.Minute
+
+type uiSubscription struct {
+	sub      *market.Subscription
+	lastUsed time.Time
+}
+
+// uiMarketSubscription 前端查看的交易对/周期按需订阅并复用；未启用行情 WebSocket 时返回 nil。
+func (s *Service) uiMarketSubscription(symbol, timeframe string) *market.Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.streams == nil {
+		return nil
+	}
+	key := strings.ToUpper(strings.TrimSpace(symbol)) + "|" + strings.TrimSpace(timeframe)
+	if s.uiSubs == nil {
+		s.uiSubs = map[string]*uiSubscription{}
+	}
+	if u, ok := s.uiSubs[key]; ok {
+		u.lastUsed = time.Now()
+		return u.sub
+	}
+	u := &uiSubscription{sub: s.streams.Subscribe(symbol, timeframe, "ui"), lastUsed: time.Now()}
+	s.uiSubs[key] = u
+	return u.sub
+}
+
+// releaseIdleUISubscriptions 定期释放无人查看的前端订阅。
+func (s *Service) releaseIdleUISubscriptions() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		var idle []*market.Subscription
+		s.mu.Lock()
+		for key, u := range s.uiSubs {
+			if time.Since(u.lastUsed) > uiSubscriptionIdleTTL {
+				idle = append(idle, u.sub)
+				delete(s.uiSubs, key)
+			}
+		}
+		s.mu.Unlock()
+		for _, sub := range idle {
+			sub.Close()
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"trade-go/market"
 	"trade-go/trader"
 )
 
@@ -220,6 +221,7 @@ func writePaperRuntimeState(st paperRuntimeState) error {
 }
 
 func (s *Service) persistPaperStateLocked() error {
+	s.publishEvent("paper", "state", s.paperRuntimeSummaryLocked())
 	return writePaperRuntimeState(s.paperState)
 }
 
@@ -930,15 +932,32 @@ func (s *Service) resetPaperRiskBaseline(rawSymbol string) (string, string, erro
 }
 
 func (s *Service) paperLoop(ctx context.Context) {
+	// 启用行情 WebSocket 时订阅模拟交易对，K 线收盘即提前触发一轮（仍受最小间隔约束）
+	var sub *market.Subscription
+	defer func() { sub.Close() }()
+	streamsStopped := false
+	lastRun := time.Now()
 	s.runPaperCycle()
 	for {
 		s.mu.RLock()
 		running := s.paperState.Running
 		interval := s.paperState.Config.IntervalSec
+		symbol := s.paperState.Config.Symbol
 		s.mu.RUnlock()
 		if !running {
 			return
 		}
+		if m := s.marketStreams(); m != nil && !streamsStopped {
+			timeframe := s.bot.TradeConfig().Timeframe
+			if sub == nil || !strings.EqualFold(sub.Symbol(), symbol) || sub.Timeframe() != timeframe {
+				sub.Close()
+				sub = m.Subscribe(symbol, timeframe, "paper")
+			}
+		}
+		var candles <-chan market.CandleEvent
+		if sub != nil {
+			candles = sub.Events()
+		}
 		if interval < paperMinInterval {
 			interval = paperDefaultInterval
 		}
@@ -958,8 +977,19 @@ func (s *Service) paperLoop(ctx context.Context) {
 			timer.Stop()
 			return
 		case <-timer.C:
-			s.runPaperCycle()
+		case _, ok := <-candles:
+			timer.Stop()
+			if !ok {
+				// 管理器已停止，退回纯定时触发
+				sub, streamsStopped = nil, true
+				continue
+			}
+			if time.Since(lastRun) < paperMinInterval*time.Second {
+				continue
+			}
 		}
+		lastRun = time.Now()
+		s.runPaperCycle()
 	}
 }
 
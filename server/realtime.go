@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"trade-go/market"
+)
+
+// 实盘触发方式
+const (
+	triggerScheduler = "scheduler"
+	triggerRealtime  = "realtime"
+)
+
+const (
+	realtimeDefaultIntervalSec = 10
+	realtimeMaxIntervalSec     = 300
+	// 行情快照检查间隔
+	realtimePollInterval = 500 * time.Millisecond
+)
+
+// realtimeOptions 事件驱动触发参数：盘中行情更新至少间隔 MinIntervalSec 秒触发一次，
+// ClosedCandleOnly 时只在 K 线收盘时触发。
+type realtimeOptions struct {
+	MinIntervalSec   int  `json:"min_interval_sec"`
+	ClosedCandleOnly bool `json:"closed_candle_only"`
+}
+
+type startTriggerRequest struct {
+	Trigger          string `json:"trigger"`
+	MinIntervalSec   *int   `json:"min_interval_sec"`
+	ClosedCandleOnly *bool  `json:"closed_candle_only"`
+}
+
+// defaultRealtimeOptions 未在请求中指定的参数取系统设置。
+func defaultRealtimeOptions() realtimeOptions {
+	out := realtimeOptions{MinIntervalSec: realtimeDefaultIntervalSec}
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("REALTIME_MIN_INTERVAL_SEC"))); err == nil && n > 0 {
+		out.MinIntervalSec = n
+	}
+	out.ClosedCandleOnly, _ = strconv.ParseBool(strings.TrimSpace(os.Getenv("REALTIME_CLOSED_CANDLE_ONLY")))
+	return out
+}
+
+func defaultTriggerMode() string {
+	if strings.EqualFold(strings.TrimSpace(os.Getenv("LIVE_TRIGGER_MODE")), triggerRealtime) {
+		return triggerRealtime
+	}
+	return triggerScheduler
+}
+
+// StartRealtime 启动 WebSocket 事件驱动触发；定时调度运行中时直接切换。
+func (s *Service) StartRealtime(opts realtimeOptions) error {
+	if opts.MinIntervalSec < 1 || opts.MinIntervalSec > realtimeMaxIntervalSec {
+		return fmt.Errorf("min_interval_sec 应为 1-%d 的整数", realtimeMaxIntervalSec)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.streams == nil {
+		return errors.New("未启用行情 WebSocket（ENABLE_WS_MARKET=true 并重启服务后可用）")
+	}
+	if s.cancelScheduler != nil {
+		s.cancelScheduler()
+		s.cancelScheduler = nil
+	}
+	s.schedulerRunning = false
+	s.nextRunAt = time.Time{}
+	if s.cancelRealtime != nil {
+		s.cancelRealtime()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancelRealtime = cancel
+	s.realtimeLoopRunning = true
+	s.realtimeOpts = opts
+	s.realtimePaused = false
+	s.triggerMode = triggerRealtime
+	go s.realtimeLoop(ctx, s.streams, opts)
+	return nil
+}
+
+func (s *Service) StopRealtime() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopRealtimeLocked()
+	if !s.schedulerRunning {
+		s.triggerMode = "idle"
+	}
+}
+
+func (s *Service) stopRealtimeLocked() {
+	if s.cancelRealtime != nil {
+		s.cancelRealtime()
+		s.cancelRealtime = nil
+	}
+	s.realtimeLoopRunning = false
+	s.realtimePaused = false
+}
+
+// realtimeLoop K 线收盘立即执行；未限定收盘触发时，盘中行情更新按最小间隔执行。
+// 行情不健康（断线或超时无数据）期间暂停触发，实盘交易对/周期变更后改订新的组合。
+func (s *Service) realtimeLoop(ctx context.Context, streams *market.Manager, opts realtimeOptions) {
+	mode := "K线收盘触发"
+	if !opts.ClosedCandleOnly {
+		mode = fmt.Sprintf("收盘及盘中触发（最小执行间隔 %d 秒）", opts.MinIntervalSec)
+	}
+	fmt.Printf("策略触发模式: WebSocket事件驱动，%s\n", mode)
+	var sub *market.Subscription
+	defer func() { sub.Close() }()
+	var lastSeen, lastRun time.Time
+	paused := false
+	ticker := time.NewTicker(realtimePollInterval)
+	defer ticker.Stop()
+	for {
+		cfg := s.bot.TradeConfig()
+		if sub == nil || !strings.EqualFold(sub.Symbol(), cfg.Symbol) || sub.Timeframe() != cfg.Timeframe {
+			sub.Close()
+			sub = streams.Subscribe(cfg.Symbol, cfg.Timeframe, "live")
+			lastSeen = time.Time{}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-sub.Events():
+			if !ok {
+				// 管理器已停止
+				return
+			}
+			if !paused {
+				s.runRealtimeCycle(ctx)
+				lastRun = time.Now()
+			}
+			continue
+		case <-ticker.C:
+		}
+		snap := sub.Snapshot()
+		if !snap.Healthy {
+			if !paused {
+				fmt.Println("⚠️ 行情WebSocket不健康（断线或超时无数据），暂停事件触发")
+				paused = true
+				s.setRealtimePaused(true)
+			}
+			continue
+		}
+		if paused {
+			fmt.Println("行情WebSocket已恢复，继续事件触发")
+			paused = false
+			s.setRealtimePaused(false)
+		}
+		if opts.ClosedCandleOnly || !snap.UpdatedAt.After(lastSeen) {
+			continue
+		}
+		if lastRun.IsZero() || time.Since(lastRun) >= time.Duration(opts.MinIntervalSec)*time.Second {
+			lastSeen = snap.UpdatedAt
+			s.runRealtimeCycle(ctx)
+			lastRun = time.Now()
+		}
+	}
+}
+
+// runRealtimeCycle 切换触发方式后不再执行已排队的周期。
+func (s *Service) runRealtimeCycle(ctx context.Context) {
+	if ctx.Err() != nil {
+		return
+	}
+	s.runCycle()
+}
+
+func (s *Service) setRealtimePaused(paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.realtimeLoopRunning {
+		s.realtimePaused = paused
+	}
+}
+
+// handleStartScheduler trigger 为 scheduler（整点定时）或 realtime（WebSocket 事件驱动），
+// 未指定时取 LIVE_TRIGGER_MODE；运行中可直接切换。
+func (s *Service) handleStartScheduler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req startTriggerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid json body")
+		return
+	}
+	trigger := strings.ToLower(strings.TrimSpace(req.Trigger))
+	if trigger == "" {
+		trigger = defaultTriggerMode()
+	}
+	switch trigger {
+	case triggerScheduler:
+		s.StartScheduler()
+		writeJSON(w, http.StatusOK, map[string]any{"message": "scheduler started", "trigger_mode": triggerScheduler})
+	case triggerRealtime:
+		opts := defaultRealtimeOptions()
+		if req.MinIntervalSec != nil {
+			opts.MinIntervalSec = *req.MinIntervalSec
+		}
+		if req.ClosedCandleOnly != nil {
+			opts.ClosedCandleOnly = *req.ClosedCandleOnly
+		}
+		if err := s.StartRealtime(opts); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"message": "realtime trigger started", "trigger_mode": triggerRealtime, "realtime": opts})
+	default:
+		writeError(w, http.StatusBadRequest, "trigger 仅支持 scheduler/realtime")
+	}
+}
+
+// handleStopScheduler 停止当前的实盘触发（定时调度或事件驱动）。
+func (s *Service) handleStopScheduler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	s.StopScheduler()
+	s.StopRealtime()
+	writeJSON(w, http.StatusOK, map[string]string{"message": "scheduler stopped"})
+}
+
+// realtimeStatusLocked 事件驱动触发状态，未运行时为 nil。
+func (s *Service) realtimeStatusLocked() map[string]any {
+	if !s.realtimeLoopRunning {
+		return nil
+	}
+	return map[string]any{
+		"min_interval_sec":   s.realtimeOpts.MinIntervalSec,
+		"closed_candle_only": s.realtimeOpts.ClosedCandleOnly,
+		"paused":             s.realtimePaused,
+	}
+}
@@ -13,6 +13,8 @@ import (
 	"time"
 	"trade-go/ai"
 	"trade-go/config"
+	"trade-go/market"
+	"trade-go/models"
 	"trade-go/storage"
 	"trade-go/trader"
 )
@@ -29,6 +31,9 @@ type Service struct {
 	triggerMode                 string
 	nextRunAt                   time.Time
 	cancelScheduler             context.CancelFunc
+	cancelRealtime              context.CancelFunc
+	realtimeOpts                realtimeOptions
+	realtimePaused              bool
 	startedAt                   time.Time
 	restartCount                int
 	lastAutoStrategyRegenAt     time.Time
@@ -41,6 +46,11 @@ type Service struct {
 
 	authMu   sync.RWMutex
 	sessions map[string]authSession
+
+	streams *market.Manager
+	uiSubs  map[string]*uiSubscription
+
+	events *streamHub
 }
 
 func NewService(bot *trader.Bot, db *storage.Store) *Service {
@@ -53,7 +63,10 @@ func NewService(bot *trader.Bot, db *storage.Store) *Service {
 		triggerMode:                 "idle",
 		lastAutoStrategyRegenReason: "等待自动重生成触发",
 		sessions:                    map[string]authSession{},
+		events:                      newStreamHub(),
 	}
+	bot.SetEventHandler(svc.publishEvent)
+	bot.SetIndicatorParamsResolver(liveIndicatorParams)
 	svc.initLiveRuntime()
 	svc.initPaperRuntime()
 	return svc
@@ -76,11 +89,13 @@ func (s *Service) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/auth/audit-logs", s.handleAuthAuditLogs)
 
 	mux.HandleFunc("/api/status", s.handleStatus)
+	mux.HandleFunc("/api/stream", s.handleStream)
 	mux.HandleFunc("/api/account", s.handleAccount)
 	mux.HandleFunc("/api/assets/overview", s.handleAssetOverview)
 	mux.HandleFunc("/api/assets/trend", s.handleAssetTrend)
 	mux.HandleFunc("/api/assets/pnl-calendar", s.handleAssetPnLCalendar)
 	mux.HandleFunc("/api/assets/distribution", s.handleAssetDistribution)
+	mux.HandleFunc("/api/assets/income/sync", s.handleAssetIncomeSync)
 	mux.HandleFunc("/api/signals", s.handleSignals)
 	mux.HandleFunc("/api/market/snapshot", s.handleMarketSnapshot)
 	mux.HandleFunc("/api/trade-records", s.handleTradeRecords)
@@ -94,6 +109,9 @@ func (s *Service) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/backtest-history", s.handleBacktestHistory)
 	mux.HandleFunc("/api/backtest-history/detail", s.handleBacktestHistoryDetail)
 	mux.HandleFunc("/api/backtest-history/delete", s.handleBacktestHistoryDelete)
+	mux.HandleFunc("/api/klines/archive", s.handleKlineArchive)
+	mux.HandleFunc("/api/klines/sync", s.handleKlineSync)
+	mux.HandleFunc("/api/klines/import", s.handleKlineImport)
 	mux.HandleFunc("/api/system-settings", s.handleSystemSettings)
 	mux.HandleFunc("/api/integrations", s.handleIntegrations)
 	mux.HandleFunc("/api/integrations/llm", s.handleAddLLMIntegration)
@@ -135,8 +153,9 @@ func (s *Service) StartScheduler() {
 	ctx, cancel := context.WithCancel(context.Background())
 	s.cancelScheduler = cancel
 	s.schedulerRunning = true
-	s.realtimeLoopRunning = false
-	s.triggerMode = "scheduler"
+	// 事件驱动运行中时切换为定时调度
+	s.stopRealtimeLocked()
+	s.triggerMode = triggerScheduler
 	s.mu.Unlock()
 
 	go s.loop(ctx)
@@ -155,15 +174,20 @@ func (s *Service) StopScheduler() {
 	}
 }
 
-func (s *Service) SetRealtimeLoopRunning(running bool) {
+// SetMarketStreams 注入行情订阅管理器；未启用行情 WebSocket 时为空，各处回退 REST。
+func (s *Service) SetMarketStreams(m *market.Manager) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.realtimeLoopRunning = running
-	if running {
-		s.triggerMode = "realtime"
-	} else if !s.schedulerRunning {
-		s.triggerMode = "idle"
+	if s.streams == nil && m != nil {
+		go s.releaseIdleUISubscriptions()
 	}
+	s.streams = m
+}
+
+func (s *Service) marketStreams() *market.Manager {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.streams
 }
 
 func (s *Service) loop(ctx context.Context) {
@@ -228,6 +252,7 @@ func (s *Service) handleStatus(w http.ResponseWriter, r *http.Request) {
 		"scheduler_running":        s.schedulerRunning,
 		"realtime_running":         s.realtimeLoopRunning,
 		"trigger_mode":             s.triggerMode,
+		"realtime":                 s.realtimeStatusLocked(),
 		"next_run_at":              s.nextRunAt,
 		"runtime":                  snap,
 		"strategy_scores":          s.bot.StrategyComboScores(20),
@@ -256,7 +281,9 @@ func (s *Service) handleAccount(w http.ResponseWriter, r *http.Request) {
 
 	balance, balanceErr := s.bot.FetchBalance()
 	availableBalance, availableErr := s.bot.FetchAvailableBalance()
-	position, posErr := s.bot.FetchPosition()
+	positions, posErr := s.bot.FetchPositions()
+	position := models.PrimaryPosition(positions)
+	positionMode, _ := s.bot.PositionMode()
 	cfg := s.bot.TradeConfig()
 	activeExchange := s.bot.ActiveExchange()
 	positionSymbol := cfg.Symbol
@@ -268,6 +295,8 @@ func (s *Service) handleAccount(w http.ResponseWriter, r *http.Request) {
 		"balance":           balance,
 		"available_balance": availableBalance,
 		"position":          position,
+		"positions":         positions,
+		"position_mode":     positionMode,
 		"symbol":            cfg.Symbol,
 		"position_symbol":   positionSymbol,
 		"active_exchange":   activeExchange,
@@ -336,6 +365,8 @@ func (s *Service) handleAssetOverview(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
+	// 已实现盈亏拆分依赖交易所资金流水，读取前按节流间隔增量同步一次。
+	_, _ = s.bot.SyncIncomeHistory(false)
 	summary, ok := s.bot.EquitySummary()
 	totalFunds, totalErr := s.bot.FetchBalance()
 	availableFunds, availErr := s.bot.FetchAvailableBalance()
@@ -351,10 +382,21 @@ func (s *Service) handleAssetOverview(w http.ResponseWriter, r *http.Request) {
 	if (availErr != nil || math.IsNaN(summary.AvailableFunds) || math.IsInf(summary.AvailableFunds, 0) || summary.AvailableFunds < 0) && summary.TotalFunds > 0 {
 		summary.AvailableFunds = summary.TotalFunds
 	}
-	writeJSON(w, http.StatusOK, map[string]any{
+	resp := map[string]any{
 		"overview":        summary,
 		"active_exchange": s.bot.ActiveExchange(),
-	})
+	}
+	if balances, err := s.bot.FetchBalances(); err != nil {
+		resp["balances_error"] = err.Error()
+	} else {
+		resp["balances"] = balances
+	}
+	if positions, err := s.bot.FetchAllPositions(); err != nil {
+		resp["positions_error"] = err.Error()
+	} else {
+		resp["positions"] = positions
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func (s *Service) handleAssetTrend(w http.ResponseWriter, r *http.Request) {
@@ -402,6 +444,7 @@ func (s *Service) handleAssetPnLCalendar(w http.ResponseWriter, r *http.Request)
 	if month == "" {
 		month = time.Now().Format("2006-01")
 	}
+	_, _ = s.bot.SyncIncomeHistory(false)
 	items, ok := s.bot.DailyPnLByMonth(month)
 	if !ok {
 		items = nil
@@ -413,6 +456,20 @@ func (s *Service) handleAssetPnLCalendar(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// handleAssetIncomeSync 立即增量导入交易所资金流水与成交历史（不受节流限制）。
+func (s *Service) handleAssetIncomeSync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	result, err := s.bot.SyncIncomeHistory(true)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]any{"error": err.Error(), "result": result})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"result": result})
+}
+
 func (s *Service) handleAssetDistribution(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -421,7 +478,8 @@ func (s *Service) handleAssetDistribution(w http.ResponseWriter, r *http.Request
 	cfg := s.bot.TradeConfig()
 	totalFunds, _ := s.bot.FetchBalance()
 	availableFunds, availErr := s.bot.FetchAvailableBalance()
-	pos, _ := s.bot.FetchPosition()
+	balances, balancesErr := s.bot.FetchBalances()
+	positions, posErr := s.bot.FetchAllPositions()
 	cash := availableFunds
 	if availErr != nil || math.IsNaN(cash) || math.IsInf(cash, 0) {
 		cash = totalFunds
@@ -439,9 +497,16 @@ func (s *Service) handleAssetDistribution(w http.ResponseWriter, r *http.Request
 	if cash > total {
 		cash = total
 	}
-	hold := total - cash
-	label := "已占用保证金"
-	if hold <= 0 && pos != nil && pos.EntryPrice > 0 {
+
+	// 按持仓逐个估算占用保证金，外部持仓单独标注
+	items := []map[string]any{
+		{"label": "可用资金", "value": cash, "color": "#2b6cd0"},
+	}
+	held := 0.0
+	for _, pos := range positions {
+		if pos.EntryPrice <= 0 {
+			continue
+		}
 		lev := pos.Leverage
 		if lev <= 0 {
 			lev = float64(cfg.Leverage)
@@ -449,25 +514,36 @@ func (s *Service) handleAssetDistribution(w http.ResponseWriter, r *http.Request
 		if lev <= 0 {
 			lev = 1
 		}
-		hold = math.Abs(pos.Size*pos.EntryPrice) / lev
-		if hold < 0 {
-			hold = 0
-		}
-		if pos.Symbol != "" {
-			label = pos.Symbol + " 持仓保证金"
-		}
-		if total < cash+hold {
-			total = cash + hold
+		margin := math.Abs(pos.Size*pos.EntryPrice) / lev
+		label := pos.Symbol + " " + pos.Side + " 持仓保证金"
+		color := "#0f996e"
+		if pos.External {
+			label += "（外部持仓）"
+			color = "#d08a2b"
 		}
+		items = append(items, map[string]any{"label": label, "value": margin, "color": color, "external": pos.External})
+		held += margin
 	}
-	writeJSON(w, http.StatusOK, map[string]any{
+	if other := total - cash - held; other > 0.01 {
+		items = append(items, map[string]any{"label": "其他占用", "value": other, "color": "#8a94a6"})
+	} else if total < cash+held {
+		total = cash + held
+	}
+
+	resp := map[string]any{
 		"total":           total,
 		"active_exchange": s.bot.ActiveExchange(),
-		"items": []map[string]any{
-			{"label": "可用资金", "value": cash, "color": "#2b6cd0"},
-			{"label": label, "value": hold, "color": "#0f996e"},
-		},
-	})
+		"items":           items,
+		"positions":       positions,
+		"balances":        balances,
+	}
+	if balancesErr != nil {
+		resp["balances_error"] = balancesErr.Error()
+	}
+	if posErr != nil {
+		resp["positions_error"] = posErr.Error()
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func (s *Service) handleSettings(w http.ResponseWriter, r *http.Request) {
@@ -484,6 +560,7 @@ func (s *Service) handleSettings(w http.ResponseWriter, r *http.Request) {
 		HighConfidenceMarginPct          *float64 `json:"high_confidence_margin_pct"`
 		LowConfidenceMarginPct           *float64 `json:"low_confidence_margin_pct"`
 		Leverage                         *int     `json:"leverage"`
+		MarginMode                       *string  `json:"margin_mode"`
 		MaxRiskPerTradePct               *float64 `json:"max_risk_per_trade_pct"`
 		MaxPositionPct                   *float64 `json:"max_position_pct"`
 		MaxConsecutiveLosses             *int     `json:"max_consecutive_losses"`
@@ -516,6 +593,7 @@ func (s *Service) handleSettings(w http.ResponseWriter, r *http.Request) {
 		HighConfidenceMarginPct:          req.HighConfidenceMarginPct,
 		LowConfidenceMarginPct:           req.LowConfidenceMarginPct,
 		Leverage:                         req.Leverage,
+		MarginMode:                       req.MarginMode,
 		MaxRiskPerTradePct:               req.MaxRiskPerTradePct,
 		MaxPositionPct:                   req.MaxPositionPct,
 		MaxConsecutiveLosses:             req.MaxConsecutiveLosses,
@@ -560,6 +638,7 @@ func persistTradeConfigToEnv(cfg config.TradeConfig) error {
 		"HIGH_CONFIDENCE_MARGIN_PCT":            strconv.FormatFloat(cfg.HighConfidenceMarginPct, 'f', -1, 64),
 		"LOW_CONFIDENCE_MARGIN_PCT":             strconv.FormatFloat(cfg.LowConfidenceMarginPct, 'f', -1, 64),
 		"LEVERAGE":                              strconv.Itoa(cfg.Leverage),
+		"MARGIN_MODE":                           models.NormalizeMarginMode(cfg.MarginMode),
 		"TIMEFRAME":                             strings.TrimSpace(cfg.Timeframe),
 		"DATA_POINTS":                           strconv.Itoa(cfg.DataPoints),
 		"MAX_RISK_PER_TRADE_PCT":                strconv.FormatFloat(cfg.MaxRiskPerTradePct, 'f', -1, 64),
@@ -600,24 +679,6 @@ func (s *Service) handleRunNow(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]any{"message": "run completed"})
 }
 
-func (s *Service) handleStartScheduler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-		return
-	}
-	s.StartScheduler()
-	writeJSON(w, http.StatusOK, map[string]string{"message": "scheduler started"})
-}
-
-func (s *Service) handleStopScheduler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
-		return
-	}
-	s.StopScheduler()
-	writeJSON(w, http.StatusOK, map[string]string{"message": "scheduler stopped"})
-}
-
 func buildEnabledStrategyDetails(enabled []string) []map[string]any {
 	if len(enabled) == 0 {
 		return []map[string]any{}
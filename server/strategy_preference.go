@@ -9,7 +9,6 @@ import (
 	"strings"
 	"time"
 	"trade-go/config"
-	"trade-go/exchange"
 	"trade-go/indicators"
 	"trade-go/llmapi"
 )
@@ -73,6 +72,7 @@ func (s *Service) handleGenerateStrategyPreference(w http.ResponseWriter, r *htt
 		workflowVersion = "skill-workflow/v1"
 	}
 	workflowChain := enabledSkillWorkflowSteps(workflowCfg)
+	params, _ := resolveIndicatorParams(tradeCfg, habit, nil)
 	activateGenerated := func(gen generatedPreference, source string) (generatedPreference, generatedStrategyRecord, []string, generatedStrategyStore, error) {
 		gen.StrategyName = buildStandardStrategyName(symbol, habit, style, false)
 		record := generatedStrategyRecord{
@@ -88,6 +88,7 @@ func (s *Service) handleGenerateStrategyPreference(w http.ResponseWriter, r *htt
 			Source:           normalizeStrategySource(source),
 			WorkflowVersion:  workflowVersion,
 			WorkflowChain:    workflowChain,
+			IndicatorParams:  profile.IndicatorParams,
 		}
 		if record.Source == "" {
 			record.Source = "workflow_generated"
@@ -104,8 +105,7 @@ func (s *Service) handleGenerateStrategyPreference(w http.ResponseWriter, r *htt
 		return gen, final, enabled, store, nil
 	}
 
-	client := exchange.NewClient()
-	candles, err := client.FetchOHLCV(symbol, f, 120)
+	candles, err := s.bot.RecentArchivedKlines(symbol, f, indicatorKlineLimit(120, params))
 	if err != nil || len(candles) < 30 {
 		fb := fallbackGeneratedPreference(symbol, habit, f, style, minRR, req.AllowReversal, lowConfAction, directionBias, "行情抓取失败，回退模板生成", tradeCfg)
 		finalGenerated, stored, enabled, store, activateErr := activateGenerated(fb, "workflow_generated")
@@ -147,7 +147,7 @@ func (s *Service) handleGenerateStrategyPreference(w http.ResponseWriter, r *htt
 		return
 	}
 
-	ind := indicators.Calculate(candles)
+	ind := indicators.CalculateWithParams(candles, params)
 	trend := indicators.AnalyzeTrend(candles, ind)
 	levels := indicators.AnalyzeLevels(candles, ind)
 	cur := candles[len(candles)-1]
@@ -163,11 +163,12 @@ func (s *Service) handleGenerateStrategyPreference(w http.ResponseWriter, r *htt
 		"rsi":              ind.RSI,
 		"macd":             ind.MACD,
 		"macd_signal":      ind.MACDSignal,
-		"ema7":             ind.EMA12,
-		"ema25":            ind.EMA26,
-		"ema99":            ind.SMA50,
+		"ema7":             ind.EMAFast,
+		"ema25":            ind.EMASlow,
+		"ema99":            ind.SMAMedium,
 		"resistance":       levels.StaticResistance,
 		"support":          levels.StaticSupport,
+		"indicator_params": params,
 	}
 	skillPkg := buildStrategySkillPackage(
 		symbol,
@@ -210,11 +211,12 @@ func (s *Service) handleGenerateStrategyPreference(w http.ResponseWriter, r *htt
 			"rsi":              ind.RSI,
 			"macd":             ind.MACD,
 			"macd_signal":      ind.MACDSignal,
-			"ema7":             ind.EMA12,
-			"ema25":            ind.EMA26,
-			"ema99":            ind.SMA50,
+			"ema7":             ind.EMAFast,
+			"ema25":            ind.EMASlow,
+			"ema99":            ind.SMAMedium,
 			"resistance":       levels.StaticResistance,
 			"support":          levels.StaticSupport,
+			"indicator_params": params,
 			"selection": map[string]any{
 				"strategy_style":  style,
 				"min_rr":          minRR,
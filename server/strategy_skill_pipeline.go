@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 	"trade-go/config"
+	"trade-go/models"
 )
 
 type habitProfile struct {
@@ -20,6 +21,8 @@ type habitProfile struct {
 	Description       string  `json:"description"`
 	ExecutionHint     string  `json:"execution_hint"`
 	PreferredDataSpan int     `json:"preferred_data_span"`
+	// 覆盖全局指标参数，未配置的字段沿用全局
+	IndicatorParams *models.IndicatorParams `json:"indicator_params,omitempty"`
 }
 
 type strategySkillPackage struct {
@@ -244,8 +247,8 @@ func buildStrategySkillPackage(
 		RuntimeContext: map[string]any{
 			"execution_exchange": func() string {
 				ex := strings.ToLower(strings.TrimSpace(config.Config.ActiveExchange))
-				if ex == "okx" {
-					return "okx"
+				if ex == "okx" || ex == "bybit" || ex == "sim" {
+					return ex
 				}
 				return "binance"
 			}(),
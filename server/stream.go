@@ -0,0 +1,325 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 事件回放缓冲条数，断线重连时可补发该范围内的事件
+	streamReplaySize = 1000
+	// 单个客户端待发送队列，写不过来即断开，由客户端携带最后序号重连补发
+	streamClientBuffer = 256
+	streamHeartbeat    = 15 * time.Second
+	streamTickInterval = time.Second
+	// 单个连接可指定的 ticker 交易对上限
+	streamMaxTickerSymbols = 5
+)
+
+// 可订阅的推送主题；ticker 为实时行情，不分配序号也不回放
+var streamTopics = []string{"cycle", "signal", "order", "fill", "position", "paper", "risk", "ticker"}
+
+type streamEvent struct {
+	ID    uint64    `json:"id,omitempty"`
+	Topic string    `json:"topic"`
+	Kind  string    `json:"kind,omitempty"`
+	Time  time.Time `json:"time"`
+	Data  any       `json:"data"`
+}
+
+type streamClient struct {
+	topics map[string]bool
+	ch     chan streamEvent
+}
+
+// streamHub 推送事件按全局递增序号保存最近 streamReplaySize 条，并分发给订阅了对应主题的客户端。
+type streamHub struct {
+	mu      sync.Mutex
+	seq     uint64
+	replay  []streamEvent
+	clients map[*streamClient]struct{}
+}
+
+func newStreamHub() *streamHub {
+	return &streamHub{clients: map[*streamClient]struct{}{}}
+}
+
+func (h *streamHub) publish(topic, kind string, data any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	ev := streamEvent{ID: h.seq, Topic: topic, Kind: kind, Time: time.Now(), Data: data}
+	h.replay = append(h.replay, ev)
+	if extra := len(h.replay) - streamReplaySize; extra > 0 {
+		h.replay = append([]streamEvent(nil), h.replay[extra:]...)
+	}
+	for c := range h.clients {
+		if !c.topics[topic] {
+			continue
+		}
+		select {
+		case c.ch <- ev:
+		default:
+			// 慢客户端直接断开，重连后按序号补发
+			close(c.ch)
+			delete(h.clients, c)
+		}
+	}
+}
+
+// subscribe 注册客户端并返回 lastID 之后需补发的事件；reset 表示 lastID 之后的事件已部分丢失
+// （超出回放范围或服务已重启），客户端应重新拉取全量数据。
+func (h *streamHub) subscribe(topics map[string]bool, lastID uint64, resume bool) (c *streamClient, backlog []streamEvent, reset bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c = &streamClient{topics: topics, ch: make(chan streamEvent, streamClientBuffer)}
+	h.clients[c] = struct{}{}
+	if !resume {
+		return c, nil, false
+	}
+	if lastID > h.seq {
+		return c, nil, true
+	}
+	if len(h.replay) > 0 && lastID+1 < h.replay[0].ID {
+		reset = true
+	}
+	for _, ev := range h.replay {
+		if ev.ID > lastID && topics[ev.Topic] {
+			backlog = append(backlog, ev)
+		}
+	}
+	return c, backlog, reset
+}
+
+func (h *streamHub) unsubscribe(c *streamClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[c]; ok {
+		close(c.ch)
+		delete(h.clients, c)
+	}
+}
+
+func (h *streamHub) lastID() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.seq
+}
+
+// publishEvent 推送到 /api/stream 的订阅者。
+func (s *Service) publishEvent(topic, kind string, data any) {
+	s.events.publish(topic, kind, data)
+}
+
+// parseStreamTopics 逗号分隔的主题列表，为空时订阅全部主题。
+func parseStreamTopics(raw string) (map[string]bool, error) {
+	out := map[string]bool{}
+	for _, t := range strings.Split(raw, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
+			continue
+		}
+		known := false
+		for _, v := range streamTopics {
+			if v == t {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("未知主题: %s（可选 %s）", t, strings.Join(streamTopics, "/"))
+		}
+		out[t] = true
+	}
+	if len(out) == 0 {
+		for _, v := range streamTopics {
+			out[v] = true
+		}
+	}
+	return out, nil
+}
+
+// handleStream SSE 推送通道。查询参数：
+// topics 逗号分隔的订阅主题（默认全部）；last_id 或请求头 Last-Event-ID 为已收到的最后序号，重连时据此补发；
+// symbols 为 ticker 主题的交易对（默认实盘交易对，模拟盘运行时附带其交易对）。
+// 浏览器 EventSource 无法设置请求头，可用 ?token= 传递登录令牌。
+func (s *Service) handleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	q := r.URL.Query()
+	topics, err := parseStreamTopics(q.Get("topics"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rawLast := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if rawLast == "" {
+		rawLast = strings.TrimSpace(q.Get("last_id"))
+	}
+	var lastID uint64
+	if rawLast != "" {
+		if lastID, err = strconv.ParseUint(rawLast, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid last_id")
+			return
+		}
+	}
+	token := requestAuthToken(r)
+
+	client, backlog, reset := s.events.subscribe(topics, lastID, rawLast != "")
+	defer s.events.unsubscribe(client)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeStreamEvent(w, streamEvent{
+		Topic: "hello",
+		Time:  time.Now(),
+		Data:  map[string]any{"last_id": s.events.lastID(), "topics": topics},
+	}); err != nil {
+		return
+	}
+	if reset {
+		// 客户端收到后应重新拉取全量数据
+		_ = writeStreamEvent(w, streamEvent{Topic: "reset", Time: time.Now(), Data: map[string]any{"last_id": lastID}})
+	}
+	for _, ev := range backlog {
+		if err := writeStreamEvent(w, ev); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	var ticks *streamTicker
+	if topics["ticker"] {
+		ticks = s.newStreamTicker(q.Get("symbols"))
+	}
+	var tickC <-chan time.Time
+	if ticks != nil {
+		t := time.NewTicker(streamTickInterval)
+		defer t.Stop()
+		tickC = t.C
+	}
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-client.ch:
+			if !ok {
+				return
+			}
+			if err := writeStreamEvent(w, ev); err != nil {
+				return
+			}
+		case <-tickC:
+			for _, ev := range ticks.poll() {
+				if err := writeStreamEvent(w, ev); err != nil {
+					return
+				}
+			}
+		case <-heartbeat.C:
+			// 登出或会话过期后结束推送
+			if _, ok := s.lookupSession(token); !ok {
+				return
+			}
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeStreamEvent 按 SSE 格式输出；带序号的事件写入 id 字段，供 EventSource 重连时回传 Last-Event-ID。
+func writeStreamEvent(w http.ResponseWriter, ev streamEvent) error {
+	raw, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if ev.ID > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", ev.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Topic, raw)
+	return err
+}
+
+// streamTicker 单个推送连接的行情来源，复用前端行情订阅并只推送有更新的交易对。
+type streamTicker struct {
+	s       *Service
+	symbols []string
+	fixed   bool
+	seen    map[string]time.Time
+}
+
+func (s *Service) newStreamTicker(raw string) *streamTicker {
+	t := &streamTicker{s: s, seen: map[string]time.Time{}}
+	for _, sym := range strings.Split(raw, ",") {
+		if sym = strings.ToUpper(strings.TrimSpace(sym)); sym != "" && len(t.symbols) < streamMaxTickerSymbols {
+			t.symbols = append(t.symbols, sym)
+		}
+	}
+	t.fixed = len(t.symbols) > 0
+	return t
+}
+
+// tickerSymbols 未指定交易对时跟随实盘与运行中的模拟盘配置。
+func (t *streamTicker) tickerSymbols() []string {
+	if t.fixed {
+		return t.symbols
+	}
+	out := []string{strings.ToUpper(strings.TrimSpace(t.s.bot.TradeConfig().Symbol))}
+	t.s.mu.RLock()
+	paperSymbol := strings.ToUpper(strings.TrimSpace(t.s.paperState.Config.Symbol))
+	paperRunning := t.s.paperState.Running
+	t.s.mu.RUnlock()
+	if paperRunning && paperSymbol != "" && paperSymbol != out[0] {
+		out = append(out, paperSymbol)
+	}
+	return out
+}
+
+func (t *streamTicker) poll() []streamEvent {
+	timeframe := t.s.bot.TradeConfig().Timeframe
+	var out []streamEvent
+	for _, symbol := range t.tickerSymbols() {
+		sub := t.s.uiMarketSubscription(symbol, timeframe)
+		if sub == nil {
+			// 未启用行情 WebSocket
+			return nil
+		}
+		snap := sub.Snapshot()
+		if snap.TickerPrice == "" || !snap.UpdatedAt.After(t.seen[symbol]) {
+			continue
+		}
+		t.seen[symbol] = snap.UpdatedAt
+		out = append(out, streamEvent{
+			Topic: "ticker",
+			Time:  snap.UpdatedAt,
+			Data: map[string]any{
+				"symbol":  symbol,
+				"price":   snap.TickerPrice,
+				"kline":   snap.Kline,
+				"healthy": snap.Healthy,
+			},
+		})
+	}
+	return out
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 	"trade-go/config"
+	"trade-go/exchange"
 )
 
 func (s *Service) handleSystemRuntimeStatus(w http.ResponseWriter, r *http.Request) {
@@ -25,6 +26,7 @@ func (s *Service) handleSystemRuntimeStatus(w http.ResponseWriter, r *http.Reque
 	triggerMode := strings.TrimSpace(s.triggerMode)
 	nextRunAt := s.nextRunAt
 	paperNextRunAt := s.paperState.NextRunAt
+	streams := s.streams
 	s.mu.RUnlock()
 	if triggerMode == "" {
 		triggerMode = "idle"
@@ -90,6 +92,10 @@ func (s *Service) handleSystemRuntimeStatus(w http.ResponseWriter, r *http.Reque
 	exchangeBound := activeExchange != nil
 	exchangeReady := false
 	exchangeMsg := "未绑定交易所账号"
+	exchangeEnv := exchange.NormalizeEnvironment(config.Config.ExchangeEnv)
+	if activeExchange != nil {
+		exchangeEnv = exchange.ResolveEndpoints(activeExchange.Exchange, activeExchange.Environment).Env
+	}
 	if exchangeBound {
 		if _, err := s.bot.FetchBalance(); err != nil {
 			exchangeMsg = "交易所连通异常: " + err.Error()
@@ -97,6 +103,30 @@ func (s *Service) handleSystemRuntimeStatus(w http.ResponseWriter, r *http.Reque
 			exchangeReady = true
 			exchangeMsg = "交易所账号已连接"
 		}
+		exchangeMsg += "（" + exchangeEnvLabel(exchangeEnv) + "）"
+	}
+
+	rateLimits := exchange.RateLimitStatuses()
+	var userStream any
+	if st, ok := s.bot.UserStreamStatus(); ok {
+		userStream = st
+	}
+	var marketStream any
+	if streams != nil {
+		marketStream = streams.Status()
+	}
+	rateLimitStatus := "running"
+	rateLimitMsg := "请求权重正常"
+	for _, rl := range rateLimits {
+		if rl.Banned {
+			rateLimitStatus = "warning"
+			rateLimitMsg = fmt.Sprintf("%s 接口限流中，预计 %s 恢复", rl.Exchange, rl.BannedUntil.Format("15:04:05"))
+			break
+		}
+		if rl.Throttled {
+			rateLimitStatus = "warning"
+			rateLimitMsg = fmt.Sprintf("%s 请求权重已用尽(%d/%d)，请求排队中", rl.Exchange, rl.WeightUsed, rl.WeightLimit)
+		}
 	}
 
 	uptimeSec := int64(0)
@@ -138,6 +168,11 @@ func (s *Service) handleSystemRuntimeStatus(w http.ResponseWriter, r *http.Reque
 				"status":  boolStatus(exchangeReady, "connected", "warning"),
 				"message": exchangeMsg,
 			},
+			{
+				"name":    "交易所限流",
+				"status":  rateLimitStatus,
+				"message": rateLimitMsg,
+			},
 			{
 				"name":    "智能体连接",
 				"status":  llmStatus,
@@ -165,6 +200,11 @@ func (s *Service) handleSystemRuntimeStatus(w http.ResponseWriter, r *http.Reque
 					}
 					return activeExchange.Exchange
 				}(),
+				"environment":   exchangeEnv,
+				"rate_limits":   rateLimits,
+				"clock_drift":   exchange.ClockStatuses(),
+				"user_stream":   userStream,
+				"market_stream": marketStream,
 			},
 			"agent": map[string]any{
 				"configured":  llmConfigured,
@@ -233,6 +273,17 @@ func (s *Service) handleSystemSoftRestart(w http.ResponseWriter, r *http.Request
 	})
 }
 
+func exchangeEnvLabel(env string) string {
+	switch env {
+	case exchange.EnvTestnet:
+		return "测试网"
+	case exchange.EnvDemo:
+		return "模拟盘"
+	default:
+		return "实盘"
+	}
+}
+
 func boolStatus(v bool, t, f string) string {
 	if v {
 		return t
@@ -28,7 +28,10 @@ var editableEnvKeys = []string{
 	"DATA_POINTS",
 	"TEST_MODE",
 	"ENABLE_WS_MARKET",
+	"ENABLE_WS_USER_DATA",
 	"REALTIME_MIN_INTERVAL_SEC",
+	"LIVE_TRIGGER_MODE",
+	"REALTIME_CLOSED_CANDLE_ONLY",
 	"STRATEGY_LLM_ENABLED",
 	"STRATEGY_LLM_TIMEOUT_SEC",
 	"AUTO_REVIEW_ENABLED",
@@ -43,6 +46,38 @@ var editableEnvKeys = []string{
 	"AUTO_STRATEGY_REGEN_LOSS_STREAK",
 	"AUTO_STRATEGY_REGEN_DRAWDOWN_WARN_PCT",
 	"AUTO_STRATEGY_REGEN_MIN_RR",
+	"ENTRY_ORDER_MODE",
+	"MAKER_ENTRY_TIMEOUT_SEC",
+	"MAKER_ENTRY_OFFSET_BPS",
+	"MAX_SLIPPAGE_BPS",
+	"SLIPPAGE_ACTION",
+	"SMA_FAST_PERIOD",
+	"SHORT_TERM_PERIOD",
+	"MEDIUM_TERM_PERIOD",
+	"LONG_TERM_PERIOD",
+	"EMA_FAST_PERIOD",
+	"EMA_SLOW_PERIOD",
+	"MACD_SIGNAL_PERIOD",
+	"RSI_PERIOD",
+	"BB_PERIOD",
+	"BB_MULTIPLIER",
+	"VOLUME_MA_PERIOD",
+	"SR_LOOKBACK",
+}
+
+// 全局指标周期参数，习惯档位与生成策略可按 indicator_params 覆盖
+var indicatorPeriodEnvKeys = []string{
+	"SMA_FAST_PERIOD",
+	"SHORT_TERM_PERIOD",
+	"MEDIUM_TERM_PERIOD",
+	"LONG_TERM_PERIOD",
+	"EMA_FAST_PERIOD",
+	"EMA_SLOW_PERIOD",
+	"MACD_SIGNAL_PERIOD",
+	"RSI_PERIOD",
+	"BB_PERIOD",
+	"VOLUME_MA_PERIOD",
+	"SR_LOOKBACK",
 }
 
 func (s *Service) handleSystemSettings(w http.ResponseWriter, r *http.Request) {
@@ -194,6 +229,11 @@ func validateSystemSettings(settings map[string]string) (map[string]string, map[
 			errs["ENABLE_WS_MARKET"] = "仅支持 true/false"
 		}
 	}
+	if v := get("ENABLE_WS_USER_DATA"); v != "" {
+		if _, err := strconv.ParseBool(v); err != nil {
+			errs["ENABLE_WS_USER_DATA"] = "仅支持 true/false"
+		}
+	}
 	if v := get("TEST_MODE"); v != "" {
 		if _, err := strconv.ParseBool(v); err != nil {
 			errs["TEST_MODE"] = "仅支持 true/false"
@@ -224,6 +264,18 @@ func validateSystemSettings(settings map[string]string) (map[string]string, map[
 			errs["REALTIME_MIN_INTERVAL_SEC"] = "应为 1-300 的整数"
 		}
 	}
+	if v := get("LIVE_TRIGGER_MODE"); v != "" {
+		switch strings.ToLower(v) {
+		case triggerScheduler, triggerRealtime:
+		default:
+			errs["LIVE_TRIGGER_MODE"] = "仅支持 scheduler/realtime"
+		}
+	}
+	if v := get("REALTIME_CLOSED_CANDLE_ONLY"); v != "" {
+		if _, err := strconv.ParseBool(v); err != nil {
+			errs["REALTIME_CLOSED_CANDLE_ONLY"] = "仅支持 true/false"
+		}
+	}
 	if v := get("STRATEGY_LLM_TIMEOUT_SEC"); v != "" {
 		n, err := strconv.Atoi(v)
 		if err != nil || n < 1 || n > 300 {
@@ -299,6 +351,57 @@ func validateSystemSettings(settings map[string]string) (map[string]string, map[
 			errs["AUTO_STRATEGY_REGEN_MIN_RR"] = "应为 [1,10] 的数字"
 		}
 	}
+	if v := get("ENTRY_ORDER_MODE"); v != "" {
+		switch strings.ToLower(v) {
+		case "market", "maker":
+		default:
+			errs["ENTRY_ORDER_MODE"] = "仅支持 market / maker"
+		}
+	}
+	if v := get("MAKER_ENTRY_TIMEOUT_SEC"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 3 || n > 600 {
+			errs["MAKER_ENTRY_TIMEOUT_SEC"] = "应为 3-600 的整数"
+		}
+	}
+	if v := get("MAKER_ENTRY_OFFSET_BPS"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f > 100 {
+			errs["MAKER_ENTRY_OFFSET_BPS"] = "应为 [0,100] 的数字"
+		}
+	}
+	if v := get("MAX_SLIPPAGE_BPS"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f > 500 {
+			errs["MAX_SLIPPAGE_BPS"] = "应为 [0,500] 的数字"
+		}
+	}
+	if v := get("SLIPPAGE_ACTION"); v != "" {
+		switch strings.ToLower(v) {
+		case "downsize", "block":
+		default:
+			errs["SLIPPAGE_ACTION"] = "仅支持 downsize / block"
+		}
+	}
+	for _, key := range indicatorPeriodEnvKeys {
+		if v := get(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 2 || n > 500 {
+				errs[key] = "应为 2-500 的整数"
+			}
+		}
+	}
+	if v := get("BB_MULTIPLIER"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f <= 0 || f > 5 {
+			errs["BB_MULTIPLIER"] = "应为 (0,5] 的数字"
+		}
+	}
+	fast, errFast := strconv.Atoi(get("EMA_FAST_PERIOD"))
+	slow, errSlow := strconv.Atoi(get("EMA_SLOW_PERIOD"))
+	if _, bad := errs["EMA_FAST_PERIOD"]; !bad && errFast == nil && errSlow == nil && fast >= slow {
+		errs["EMA_FAST_PERIOD"] = "EMA 快线周期应小于慢线周期"
+	}
 
 	return errs, warns
 }
@@ -362,11 +465,15 @@ func applyRuntimeConfigFromEnv() {
 	cfg.AIBaseURL = os.Getenv("AI_BASE_URL")
 	cfg.AIModel = os.Getenv("AI_MODEL")
 	cfg.ActiveExchange = os.Getenv("ACTIVE_EXCHANGE")
+	cfg.ExchangeEnv = os.Getenv("EXCHANGE_ENV")
 	cfg.BinanceAPIKey = os.Getenv("BINANCE_API_KEY")
 	cfg.BinanceSecret = os.Getenv("BINANCE_SECRET")
 	cfg.OKXAPIKey = os.Getenv("OKX_API_KEY")
 	cfg.OKXSecret = os.Getenv("OKX_SECRET")
 	cfg.OKXPassword = os.Getenv("OKX_PASSWORD")
+	cfg.BybitAPIKey = os.Getenv("BYBIT_API_KEY")
+	cfg.BybitSecret = os.Getenv("BYBIT_SECRET")
+	cfg.BybitBaseURL = os.Getenv("BYBIT_BASE_URL")
 	sanitizeExecutionStrategiesEnv()
 	if v := strings.TrimSpace(os.Getenv("TRADE_SYMBOL")); v != "" {
 		cfg.Trade.Symbol = strings.ToUpper(v)
@@ -405,6 +512,9 @@ func applyRuntimeConfigFromEnv() {
 			cfg.Trade.Leverage = n
 		}
 	}
+	if v := strings.TrimSpace(os.Getenv("MARGIN_MODE")); v != "" {
+		cfg.Trade.MarginMode = strings.ToLower(v)
+	}
 	if v := strings.TrimSpace(os.Getenv("TIMEFRAME")); v != "" {
 		cfg.Trade.Timeframe = v
 	}
@@ -508,4 +618,50 @@ func applyRuntimeConfigFromEnv() {
 			cfg.Trade.AutoStrategyRegenMinRR = f
 		}
 	}
+	if v := strings.TrimSpace(os.Getenv("ENTRY_ORDER_MODE")); v != "" {
+		cfg.Trade.EntryOrderMode = strings.ToLower(v)
+	}
+	if v := strings.TrimSpace(os.Getenv("MAKER_ENTRY_TIMEOUT_SEC")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.Trade.MakerEntryTimeoutSec = n
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("MAKER_ENTRY_OFFSET_BPS")); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			cfg.Trade.MakerEntryOffsetBps = f
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("MAX_SLIPPAGE_BPS")); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			cfg.Trade.MaxSlippageBps = f
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("SLIPPAGE_ACTION")); v != "" {
+		cfg.Trade.SlippageAction = strings.ToLower(v)
+	}
+	periods := map[string]*int{
+		"SMA_FAST_PERIOD":    &cfg.Trade.SMAFastPeriod,
+		"SHORT_TERM_PERIOD":  &cfg.Trade.ShortTermPeriod,
+		"MEDIUM_TERM_PERIOD": &cfg.Trade.MediumTermPeriod,
+		"LONG_TERM_PERIOD":   &cfg.Trade.LongTermPeriod,
+		"EMA_FAST_PERIOD":    &cfg.Trade.EMAFastPeriod,
+		"EMA_SLOW_PERIOD":    &cfg.Trade.EMASlowPeriod,
+		"MACD_SIGNAL_PERIOD": &cfg.Trade.MACDSignalPeriod,
+		"RSI_PERIOD":         &cfg.Trade.RSIPeriod,
+		"BB_PERIOD":          &cfg.Trade.BBPeriod,
+		"VOLUME_MA_PERIOD":   &cfg.Trade.VolumeMAPeriod,
+		"SR_LOOKBACK":        &cfg.Trade.SRLookback,
+	}
+	for key, dst := range periods {
+		if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+			if n, err := strconv.Atoi(v); err == nil {
+				*dst = n
+			}
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv("BB_MULTIPLIER")); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			cfg.Trade.BBMultiplier = f
+		}
+	}
 }
@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+	"trade-go/config"
+	"trade-go/exchange"
+	"trade-go/models"
+)
+
+// PnLBreakdown 已实现盈亏拆分：成交盈亏 + 手续费 + 资金费 = 已实现盈亏（手续费、支付的资金费为负）。
+// 仅统计美元稳定币计价的流水，BNB 抵扣手续费等非美元资产流水不计入。
+type PnLBreakdown struct {
+	TradingPnL  float64 `json:"trading_pnl"`
+	Commission  float64 `json:"commission"`
+	Funding     float64 `json:"funding"`
+	RealizedPnL float64 `json:"realized_pnl"`
+}
+
+func (p *PnLBreakdown) add(incomeType string, amount float64) {
+	switch incomeType {
+	case models.IncomeRealizedPnL:
+		p.TradingPnL += amount
+	case models.IncomeCommission:
+		p.Commission += amount
+	case models.IncomeFunding:
+		p.Funding += amount
+	default:
+		return
+	}
+	p.RealizedPnL += amount
+}
+
+// 流水计价资产过滤条件
+const usdIncomeAssets = `UPPER(asset) IN ('USDT','USDC','USD','FDUSD','BFUSD')`
+
+// incomeScope 流水、成交与同步游标的归属键：主网为交易所名，测试网/模拟盘追加环境后缀（与 K 线来源同口径），
+// 切换环境后各自从头同步，不会续用另一环境的游标或混存流水。
+func incomeScope() string {
+	ex := currentExchange()
+	if ex == "sim" || config.Config == nil {
+		return ex
+	}
+	if env := exchange.ResolveEndpoints(ex, config.Config.ExchangeEnv).Env; env != exchange.EnvMainnet {
+		return ex + "-" + env
+	}
+	return ex
+}
+
+func (s *Store) migrateIncome() error {
+	schema := []string{
+		`CREATE TABLE IF NOT EXISTS income_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			exchange TEXT NOT NULL,
+			event_id TEXT NOT NULL,
+			symbol TEXT,
+			income_type TEXT NOT NULL,
+			amount REAL NOT NULL,
+			asset TEXT,
+			trade_id TEXT,
+			order_id TEXT,
+			info TEXT,
+			ts TEXT NOT NULL,
+			UNIQUE(exchange, event_id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS trade_fills (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			exchange TEXT NOT NULL,
+			trade_id TEXT NOT NULL,
+			order_id TEXT,
+			symbol TEXT,
+			side TEXT,
+			size REAL,
+			price REAL,
+			fee REAL,
+			fee_asset TEXT,
+			realized_pnl REAL,
+			maker INTEGER,
+			ts TEXT NOT NULL,
+			UNIQUE(exchange, trade_id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS sync_cursors (
+			exchange TEXT NOT NULL,
+			stream TEXT NOT NULL,
+			cursor_ts TEXT NOT NULL,
+			updated_at TEXT NOT NULL,
+			PRIMARY KEY(exchange, stream)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_income_events_exchange_ts ON income_events(exchange, ts);`,
+		`CREATE INDEX IF NOT EXISTS idx_trade_fills_exchange_ts ON trade_fills(exchange, ts);`,
+	}
+	for _, stmt := range schema {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveIncomeEvents 按 (exchange, event_id) 去重写入，返回新增条数。
+func (s *Store) SaveIncomeEvents(events []models.IncomeEvent) (int, error) {
+	if s == nil || len(events) == 0 {
+		return 0, nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	scope := incomeScope()
+	inserted := 0
+	for _, ev := range events {
+		if strings.TrimSpace(ev.ID) == "" {
+			continue
+		}
+		res, err := tx.Exec(
+			`INSERT INTO income_events (exchange, event_id, symbol, income_type, amount, asset, trade_id, order_id, info, ts)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			 ON CONFLICT(exchange, event_id) DO NOTHING`,
+			scope, ev.ID, ev.Symbol, ev.Type, ev.Amount, ev.Asset, ev.TradeID, ev.OrderID, ev.Info, ev.Time.Format(time.RFC3339),
+		)
+		if err != nil {
+			return inserted, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			inserted++
+		}
+	}
+	return inserted, tx.Commit()
+}
+
+// SaveTradeFills 按 (exchange, trade_id) 去重写入，返回新增条数。
+func (s *Store) SaveTradeFills(fills []models.TradeFill) (int, error) {
+	if s == nil || len(fills) == 0 {
+		return 0, nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	scope := incomeScope()
+	inserted := 0
+	for _, f := range fills {
+		if strings.TrimSpace(f.ID) == "" {
+			continue
+		}
+		res, err := tx.Exec(
+			`INSERT INTO trade_fills (exchange, trade_id, order_id, symbol, side, size, price, fee, fee_asset, realized_pnl, maker, ts)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			 ON CONFLICT(exchange, trade_id) DO NOTHING`,
+			scope, f.ID, f.OrderID, f.Symbol, f.Side, f.Size, f.Price, f.Fee, f.FeeAsset, f.RealizedPnL, boolToInt(f.Maker), f.Time.Format(time.RFC3339),
+		)
+		if err != nil {
+			return inserted, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			inserted++
+		}
+	}
+	return inserted, tx.Commit()
+}
+
+// SyncCursor 读取当前交易所与环境下某个同步流（如 income、fills:BTCUSDT）的增量游标。
+func (s *Store) SyncCursor(stream string) (time.Time, bool, error) {
+	if s == nil {
+		return time.Time{}, false, nil
+	}
+	var raw string
+	err := s.db.QueryRow(
+		`SELECT cursor_ts FROM sync_cursors WHERE exchange=? AND stream=?`,
+		incomeScope(), stream,
+	).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	ts, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return time.Time{}, false, nil
+	}
+	return ts, true, nil
+}
+
+func (s *Store) SetSyncCursor(stream string, ts time.Time) error {
+	if s == nil {
+		return nil
+	}
+	_, err := s.db.Exec(
+		`INSERT INTO sync_cursors (exchange, stream, cursor_ts, updated_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(exchange, stream) DO UPDATE SET cursor_ts=excluded.cursor_ts, updated_at=excluded.updated_at`,
+		incomeScope(), stream, ts.Format(time.RFC3339Nano), time.Now().Format(time.RFC3339),
+	)
+	return err
+}
+
+// incomeBreakdownByDay 按日期（ts 前 10 位，与 equity_curve 同口径）汇总 [start, end) 区间的已实现盈亏拆分；零值时间表示不限。
+func (s *Store) incomeBreakdownByDay(start, end time.Time) (map[string]PnLBreakdown, error) {
+	query := `SELECT ts, income_type, amount FROM income_events WHERE exchange=? AND ` + usdIncomeAssets
+	args := []any{incomeScope()}
+	if !start.IsZero() {
+		query += ` AND ts >= ?`
+		args = append(args, start.Format(time.RFC3339))
+	}
+	if !end.IsZero() {
+		query += ` AND ts < ?`
+		args = append(args, end.Format(time.RFC3339))
+	}
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]PnLBreakdown{}
+	for rows.Next() {
+		var ts, typ string
+		var amount float64
+		if err := rows.Scan(&ts, &typ, &amount); err != nil {
+			return nil, err
+		}
+		if len(ts) < 10 {
+			continue
+		}
+		day := out[ts[:10]]
+		day.add(typ, amount)
+		out[ts[:10]] = day
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,276 @@
+package storage
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"trade-go/models"
+)
+
+// KlineSeries 归档中一组 (来源, 交易对, 周期) 的覆盖范围。
+type KlineSeries struct {
+	Source   string    `json:"source"`
+	Symbol   string    `json:"symbol"`
+	Interval string    `json:"interval"`
+	Bars     int       `json:"bars"`
+	First    time.Time `json:"first"`
+	Last     time.Time `json:"last"`
+}
+
+func (s *Store) migrateKlines() error {
+	schema := []string{
+		`CREATE TABLE IF NOT EXISTS klines (
+			source TEXT NOT NULL,
+			symbol TEXT NOT NULL,
+			interval TEXT NOT NULL,
+			open_time INTEGER NOT NULL,
+			open REAL NOT NULL,
+			high REAL NOT NULL,
+			low REAL NOT NULL,
+			close REAL NOT NULL,
+			volume REAL NOT NULL,
+			PRIMARY KEY(source, symbol, interval, open_time)
+		) WITHOUT ROWID;`,
+		// 已向交易所请求过的区间（含返回为空的区间，如上市前或交易所缺口），避免重复下载
+		`CREATE TABLE IF NOT EXISTS kline_coverage (
+			source TEXT NOT NULL,
+			symbol TEXT NOT NULL,
+			interval TEXT NOT NULL,
+			start_time INTEGER NOT NULL,
+			end_time INTEGER NOT NULL,
+			PRIMARY KEY(source, symbol, interval, start_time)
+		) WITHOUT ROWID;`,
+	}
+	for _, stmt := range schema {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func klineKey(symbol, interval string) (string, string) {
+	return strings.ToUpper(strings.TrimSpace(symbol)), strings.TrimSpace(interval)
+}
+
+// SaveKlines 写入已收盘 K 线，按开盘时间去重（已归档的不覆盖），返回新增条数。
+func (s *Store) SaveKlines(source, symbol, interval string, bars []models.OHLCV) (int, error) {
+	if s == nil || len(bars) == 0 {
+		return 0, nil
+	}
+	symbol, interval = klineKey(symbol, interval)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(
+		`INSERT INTO klines (source, symbol, interval, open_time, open, high, low, close, volume)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(source, symbol, interval, open_time) DO NOTHING`,
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	inserted := 0
+	for _, k := range bars {
+		if !isFiniteNumber(k.Close) || k.Close <= 0 || k.High <= 0 || k.Low <= 0 {
+			continue
+		}
+		res, err := stmt.Exec(source, symbol, interval, k.Timestamp.UnixMilli(), k.Open, k.High, k.Low, k.Close, k.Volume)
+		if err != nil {
+			return inserted, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			inserted++
+		}
+	}
+	return inserted, tx.Commit()
+}
+
+// Klines 读取开盘时间在 [start, end] 内的归档 K 线，按时间升序。
+func (s *Store) Klines(source, symbol, interval string, start, end time.Time) ([]models.OHLCV, error) {
+	if s == nil {
+		return nil, nil
+	}
+	symbol, interval = klineKey(symbol, interval)
+	rows, err := s.db.Query(
+		`SELECT open_time, open, high, low, close, volume FROM klines
+		 WHERE source=? AND symbol=? AND interval=? AND open_time >= ? AND open_time <= ?
+		 ORDER BY open_time ASC`,
+		source, symbol, interval, start.UnixMilli(), end.UnixMilli(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []models.OHLCV
+	for rows.Next() {
+		var ts int64
+		var k models.OHLCV
+		if err := rows.Scan(&ts, &k.Open, &k.High, &k.Low, &k.Close, &k.Volume); err != nil {
+			return nil, err
+		}
+		k.Timestamp = time.UnixMilli(ts)
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+// KlineWindow 已下载过的时间区间 [Start, End]。
+type KlineWindow struct {
+	Start time.Time
+	End   time.Time
+}
+
+// SaveKlineCoverage 记录 [start, end] 已向交易所完整请求过，与重叠或相邻的已有区间合并。
+func (s *Store) SaveKlineCoverage(source, symbol, interval string, start, end time.Time) error {
+	if s == nil || end.Before(start) {
+		return nil
+	}
+	symbol, interval = klineKey(symbol, interval)
+	lo, hi := start.UnixMilli(), end.UnixMilli()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	rows, err := tx.Query(
+		`SELECT start_time, end_time FROM kline_coverage
+		 WHERE source=? AND symbol=? AND interval=? AND start_time <= ? AND end_time >= ?`,
+		source, symbol, interval, hi+1, lo-1,
+	)
+	if err != nil {
+		return err
+	}
+	var starts []int64
+	for rows.Next() {
+		var a, b int64
+		if err := rows.Scan(&a, &b); err != nil {
+			rows.Close()
+			return err
+		}
+		starts = append(starts, a)
+		lo, hi = min(lo, a), max(hi, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, a := range starts {
+		if _, err := tx.Exec(`DELETE FROM kline_coverage WHERE source=? AND symbol=? AND interval=? AND start_time=?`,
+			source, symbol, interval, a); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO kline_coverage (source, symbol, interval, start_time, end_time) VALUES (?, ?, ?, ?, ?)`,
+		source, symbol, interval, lo, hi); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// KlineCoverage 与 [start, end] 有交集的已下载区间，按起点升序。
+func (s *Store) KlineCoverage(source, symbol, interval string, start, end time.Time) ([]KlineWindow, error) {
+	if s == nil {
+		return nil, nil
+	}
+	symbol, interval = klineKey(symbol, interval)
+	rows, err := s.db.Query(
+		`SELECT start_time, end_time FROM kline_coverage
+		 WHERE source=? AND symbol=? AND interval=? AND start_time <= ? AND end_time >= ?
+		 ORDER BY start_time ASC`,
+		source, symbol, interval, end.UnixMilli(), start.UnixMilli(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []KlineWindow
+	for rows.Next() {
+		var a, b int64
+		if err := rows.Scan(&a, &b); err != nil {
+			return nil, err
+		}
+		out = append(out, KlineWindow{Start: time.UnixMilli(a), End: time.UnixMilli(b)})
+	}
+	return out, rows.Err()
+}
+
+// KlineArchive 列出归档覆盖范围。
+func (s *Store) KlineArchive() ([]KlineSeries, error) {
+	if s == nil {
+		return nil, nil
+	}
+	rows, err := s.db.Query(
+		`SELECT source, symbol, interval, COUNT(*), MIN(open_time), MAX(open_time) FROM klines
+		 GROUP BY source, symbol, interval ORDER BY source, symbol, interval`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []KlineSeries{}
+	for rows.Next() {
+		var item KlineSeries
+		var first, last int64
+		if err := rows.Scan(&item.Source, &item.Symbol, &item.Interval, &item.Bars, &first, &last); err != nil {
+			return nil, err
+		}
+		item.First = time.UnixMilli(first)
+		item.Last = time.UnixMilli(last)
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+// ParseKlineCSV 解析 Binance data-vision 格式的 K 线 CSV：
+// open_time, open, high, low, close, volume, ...；表头行会被跳过，微秒时间戳自动换算为毫秒。
+func ParseKlineCSV(r io.Reader) ([]models.OHLCV, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	var out []models.OHLCV
+	for line := 1; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return out, err
+		}
+		if len(row) < 6 {
+			return out, fmt.Errorf("第 %d 行字段不足: 需要 open_time,open,high,low,close,volume", line)
+		}
+		ts, err := strconv.ParseInt(strings.TrimSpace(row[0]), 10, 64)
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return out, fmt.Errorf("第 %d 行开盘时间无效: %s", line, row[0])
+		}
+		if ts > 1e14 {
+			ts /= 1000
+		}
+		vals := make([]float64, 5)
+		for i := range vals {
+			if vals[i], err = strconv.ParseFloat(strings.TrimSpace(row[i+1]), 64); err != nil {
+				return out, fmt.Errorf("第 %d 行第 %d 列数值无效: %s", line, i+2, row[i+1])
+			}
+		}
+		out = append(out, models.OHLCV{
+			Timestamp: time.UnixMilli(ts),
+			Open:      vals[0],
+			High:      vals[1],
+			Low:       vals[2],
+			Close:     vals[3],
+			Volume:    vals[4],
+		})
+	}
+	return out, nil
+}
@@ -7,6 +7,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 	"trade-go/config"
@@ -75,6 +76,14 @@ type AIDecisionPreview struct {
 	StrategyScore float64 `json:"strategy_score"`
 }
 
+type ProtectiveOrder struct {
+	OrderID   string  `json:"order_id"`
+	Symbol    string  `json:"symbol"`
+	Side      string  `json:"side"`
+	OrderType string  `json:"order_type"`
+	Size      float64 `json:"size"`
+}
+
 type EquityPoint struct {
 	Ts     string  `json:"ts"`
 	Equity float64 `json:"equity"`
@@ -87,12 +96,16 @@ type EquitySummary struct {
 	TodayPnLPct      float64 `json:"today_pnl_pct"`
 	CumulativePnL    float64 `json:"cumulative_pnl"`
 	CumulativePnLPct float64 `json:"cumulative_pnl_pct"`
+	// 基于交易所资金流水的已实现盈亏拆分
+	TodayRealized      PnLBreakdown `json:"today_realized"`
+	CumulativeRealized PnLBreakdown `json:"cumulative_realized"`
 }
 
 type DailyPnL struct {
-	Date      string  `json:"date"`
-	PnLAmount float64 `json:"pnl_amount"`
-	PnLPct    float64 `json:"pnl_pct"`
+	Date      string       `json:"date"`
+	PnLAmount float64      `json:"pnl_amount"`
+	PnLPct    float64      `json:"pnl_pct"`
+	Realized  PnLBreakdown `json:"realized"`
 }
 
 type BacktestRun struct {
@@ -117,6 +130,8 @@ type BacktestRun struct {
 	Wins                    int     `json:"wins"`
 	Losses                  int     `json:"losses"`
 	Ratio                   float64 `json:"ratio"`
+	// 本次回测使用的指标参数
+	IndicatorParams json.RawMessage `json:"indicator_params,omitempty"`
 }
 
 type BacktestRunRecord struct {
@@ -201,7 +216,8 @@ func (s *Store) migrate() error {
 			executed INTEGER DEFAULT 0,
 			risk_reason TEXT,
 			strategy_combo TEXT,
-			strategy_score REAL
+			strategy_score REAL,
+			indicator_params TEXT
 		);`,
 		`CREATE TABLE IF NOT EXISTS orders (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -209,6 +225,7 @@ func (s *Store) migrate() error {
 			exchange TEXT NOT NULL DEFAULT 'binance',
 			symbol TEXT,
 			side TEXT,
+			order_type TEXT DEFAULT 'market',
 			size REAL,
 			reduce_only INTEGER,
 			status TEXT,
@@ -292,7 +309,8 @@ func (s *Store) migrate() error {
 			return_pct REAL,
 			wins INTEGER,
 			losses INTEGER,
-			ratio REAL
+			ratio REAL,
+			indicator_params TEXT
 		);`,
 		`CREATE TABLE IF NOT EXISTS backtest_run_records (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -334,6 +352,12 @@ func (s *Store) migrate() error {
 	if err := s.migrateAuth(); err != nil {
 		return err
 	}
+	if err := s.migrateIncome(); err != nil {
+		return err
+	}
+	if err := s.migrateKlines(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -344,6 +368,7 @@ func (s *Store) migrateCompat() error {
 		`ALTER TABLE ai_decisions ADD COLUMN exchange TEXT DEFAULT 'binance';`,
 		`ALTER TABLE ai_decisions ADD COLUMN executed INTEGER DEFAULT 0;`,
 		`ALTER TABLE orders ADD COLUMN exchange TEXT DEFAULT 'binance';`,
+		`ALTER TABLE orders ADD COLUMN order_type TEXT DEFAULT 'market';`,
 		`ALTER TABLE fills ADD COLUMN exchange TEXT DEFAULT 'binance';`,
 		`ALTER TABLE position_snapshots ADD COLUMN exchange TEXT DEFAULT 'binance';`,
 		`ALTER TABLE equity_curve ADD COLUMN exchange TEXT DEFAULT 'binance';`,
@@ -351,6 +376,8 @@ func (s *Store) migrateCompat() error {
 		`ALTER TABLE backtest_run_records ADD COLUMN order_basis TEXT;`,
 		`ALTER TABLE backtest_run_records ADD COLUMN stop_loss REAL;`,
 		`ALTER TABLE backtest_run_records ADD COLUMN take_profit REAL;`,
+		`ALTER TABLE ai_decisions ADD COLUMN indicator_params TEXT;`,
+		`ALTER TABLE backtest_runs ADD COLUMN indicator_params TEXT;`,
 	}
 	for _, stmt := range alterStmts {
 		if _, err := s.db.Exec(stmt); err != nil {
@@ -398,8 +425,8 @@ func (s *Store) SaveAIDecision(ts time.Time, decision map[string]any) error {
 		return nil
 	}
 	_, err := s.db.Exec(
-		`INSERT INTO ai_decisions (ts, exchange, signal, confidence, reason, price, stop_loss, take_profit, suggested_size, approved_size, approved, executed, risk_reason, strategy_combo, strategy_score)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT INTO ai_decisions (ts, exchange, signal, confidence, reason, price, stop_loss, take_profit, suggested_size, approved_size, approved, executed, risk_reason, strategy_combo, strategy_score, indicator_params)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		ts.Format(time.RFC3339),
 		currentExchange(),
 		decision["signal"], decision["confidence"], decision["reason"],
@@ -407,25 +434,29 @@ func (s *Store) SaveAIDecision(ts time.Time, decision map[string]any) error {
 		decision["suggested_size"], decision["approved_size"], boolToInt(decision["approved"] == true),
 		boolToInt(decision["executed"] == true),
 		decision["risk_reason"], decision["strategy_combo"], decision["strategy_score"],
+		nullableJSON(decision["indicator_params"]),
 	)
 	return err
 }
 
-func (s *Store) SaveOrder(orderID, symbol, side string, size float64, reduceOnly bool, status string, payload any) error {
+func (s *Store) SaveOrder(orderID, symbol, side, orderType string, size float64, reduceOnly bool, status string, payload any) error {
 	if s == nil || orderID == "" {
 		return nil
 	}
+	if strings.TrimSpace(orderType) == "" {
+		orderType = "market"
+	}
 	raw, _ := json.Marshal(payload)
 	now := time.Now().Format(time.RFC3339)
 	_, err := s.db.Exec(
-		`INSERT INTO orders (order_id, exchange, symbol, side, size, reduce_only, status, payload, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`INSERT INTO orders (order_id, exchange, symbol, side, order_type, size, reduce_only, status, payload, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		 ON CONFLICT(order_id) DO UPDATE SET
 		 	exchange=excluded.exchange,
 		 	status=excluded.status,
 		 	payload=excluded.payload,
 		 	updated_at=excluded.updated_at`,
-		orderID, currentExchange(), symbol, side, size, boolToInt(reduceOnly), status, string(raw), now, now,
+		orderID, currentExchange(), symbol, side, orderType, size, boolToInt(reduceOnly), status, string(raw), now, now,
 	)
 	return err
 }
@@ -611,7 +642,10 @@ func (s *Store) OpenOrders() ([]string, error) {
 		return nil, nil
 	}
 	rows, err := s.db.Query(
-		`SELECT order_id FROM orders WHERE exchange=? AND status IN ('live','partially_filled') ORDER BY id DESC LIMIT 200`,
+		`SELECT order_id FROM orders
+		 WHERE exchange=? AND status IN ('live','partially_filled')
+		   AND COALESCE(order_type,'market') NOT IN ('stop_market','take_profit_market')
+		 ORDER BY id DESC LIMIT 200`,
 		currentExchange(),
 	)
 	if err != nil {
@@ -629,6 +663,109 @@ func (s *Store) OpenOrders() ([]string, error) {
 	return ids, nil
 }
 
+// OpenProtectiveOrders 返回当前交易所仍挂着的止损/止盈条件单。
+func (s *Store) OpenProtectiveOrders() ([]ProtectiveOrder, error) {
+	if s == nil {
+		return nil, nil
+	}
+	rows, err := s.db.Query(
+		`SELECT order_id, COALESCE(symbol,''), COALESCE(side,''), COALESCE(order_type,''), COALESCE(size,0)
+		 FROM orders
+		 WHERE exchange=? AND status IN ('live','partially_filled')
+		   AND order_type IN ('stop_market','take_profit_market')
+		 ORDER BY id DESC LIMIT 50`,
+		currentExchange(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ProtectiveOrder
+	for rows.Next() {
+		var item ProtectiveOrder
+		if err := rows.Scan(&item.OrderID, &item.Symbol, &item.Side, &item.OrderType, &item.Size); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+// BotPositionLeg 本机器人订单成交累计出的持仓腿。
+type BotPositionLeg struct {
+	Symbol string  `json:"symbol"`
+	Side   string  `json:"side"` // long / short
+	Size   float64 `json:"size"`
+}
+
+// BotPositionLegs 按本机器人在当前交易所的订单成交量累计各交易对多空腿的净持仓：
+// 非只减仓买/卖分别增加多/空腿，只减仓卖/买分别减少多/空腿，减到 0 以下按 0 计。
+// 成交量优先取订单状态中的 filled_size，没有时已成交订单按下单数量计。
+func (s *Store) BotPositionLegs() ([]BotPositionLeg, error) {
+	if s == nil {
+		return nil, nil
+	}
+	rows, err := s.db.Query(
+		`SELECT COALESCE(symbol,''), COALESCE(side,''), COALESCE(reduce_only,0), COALESCE(size,0), COALESCE(status,''), COALESCE(payload,'')
+		 FROM orders
+		 WHERE exchange=? AND status IN ('filled','partially_filled','canceled')
+		 ORDER BY id ASC`,
+		currentExchange(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	type legKey struct{ symbol, side string }
+	net := map[legKey]float64{}
+	var order []legKey
+	for rows.Next() {
+		var symbol, side, status, payload string
+		var reduceOnly int
+		var size float64
+		if err := rows.Scan(&symbol, &side, &reduceOnly, &size, &status, &payload); err != nil {
+			return nil, err
+		}
+		var detail struct {
+			FilledSize *float64 `json:"filled_size"`
+		}
+		_ = json.Unmarshal([]byte(payload), &detail)
+		filled := 0.0
+		switch {
+		case detail.FilledSize != nil:
+			filled = *detail.FilledSize
+		case status == "filled":
+			filled = size
+		}
+		if filled <= 0 || symbol == "" {
+			continue
+		}
+		leg := "long"
+		if (side == "sell") != (reduceOnly != 0) {
+			leg = "short"
+		}
+		key := legKey{symbol, leg}
+		if _, ok := net[key]; !ok {
+			order = append(order, key)
+		}
+		if reduceOnly != 0 {
+			net[key] = math.Max(net[key]-filled, 0)
+		} else {
+			net[key] += filled
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	out := make([]BotPositionLeg, 0, len(order))
+	for _, key := range order {
+		if net[key] > 0 {
+			out = append(out, BotPositionLeg{Symbol: key.symbol, Side: key.side, Size: net[key]})
+		}
+	}
+	return out, nil
+}
+
 func (s *Store) UpdateStrategyComboScore(combo string, equity float64) (float64, error) {
 	if s == nil || strings.TrimSpace(combo) == "" || equity <= 0 {
 		return 0, nil
@@ -919,7 +1056,7 @@ func (s *Store) EquitySummary() (EquitySummary, error) {
 		}
 	}
 	if !hasAny {
-		return out, nil
+		return out, s.fillRealizedSummary(&out, today)
 	}
 	out.TotalFunds = lastEquity
 	if hasToday {
@@ -938,9 +1075,29 @@ func (s *Store) EquitySummary() (EquitySummary, error) {
 	if out.AvailableFunds == 0 && lastBalance > 0 {
 		out.AvailableFunds = lastBalance
 	}
+	if err := s.fillRealizedSummary(&out, today); err != nil {
+		return out, err
+	}
 	return out, nil
 }
 
+func (s *Store) fillRealizedSummary(out *EquitySummary, today string) error {
+	days, err := s.incomeBreakdownByDay(time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+	for day, b := range days {
+		out.CumulativeRealized.TradingPnL += b.TradingPnL
+		out.CumulativeRealized.Commission += b.Commission
+		out.CumulativeRealized.Funding += b.Funding
+		out.CumulativeRealized.RealizedPnL += b.RealizedPnL
+		if day == today {
+			out.TodayRealized = b
+		}
+	}
+	return nil
+}
+
 func (s *Store) EquityTrendSince(since time.Time) ([]EquityPoint, error) {
 	if s == nil {
 		return nil, nil
@@ -1012,6 +1169,17 @@ func (s *Store) DailyPnLByMonth(month string) ([]DailyPnL, error) {
 			order = append(order, day)
 		}
 	}
+	realized, err := s.incomeBreakdownByDay(start, end)
+	if err != nil {
+		return nil, err
+	}
+	// 仅有资金流水（如资金费结算）而无权益快照的日期也需展示
+	for day := range realized {
+		if _, ok := agg[day]; !ok {
+			order = append(order, day)
+		}
+	}
+	sort.Strings(order)
 	out := make([]DailyPnL, 0, len(order))
 	for _, day := range order {
 		a := agg[day]
@@ -1020,7 +1188,7 @@ func (s *Store) DailyPnLByMonth(month string) ([]DailyPnL, error) {
 		if a.first != 0 {
 			pct = pnl / a.first * 100
 		}
-		out = append(out, DailyPnL{Date: day, PnLAmount: pnl, PnLPct: pct})
+		out = append(out, DailyPnL{Date: day, PnLAmount: pnl, PnLPct: pct, Realized: realized[day]})
 	}
 	return out, nil
 }
@@ -1044,13 +1212,14 @@ func (s *Store) SaveBacktestRun(run BacktestRun, records []BacktestRunRecord) (i
 			created_at, strategy, pair, habit, start_month, end_month, bars, initial_margin, leverage,
 			position_sizing_mode, high_confidence_amount, low_confidence_amount,
 			high_confidence_margin_pct, low_confidence_margin_pct,
-			total_pnl, final_equity, return_pct, wins, losses, ratio
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			total_pnl, final_equity, return_pct, wins, losses, ratio, indicator_params
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		createdAt,
 		run.Strategy, run.Pair, run.Habit, run.Start, run.End, run.Bars, run.InitialMargin, run.Leverage,
 		run.PositionSizingMode, run.HighConfidenceAmount, run.LowConfidenceAmount,
 		run.HighConfidenceMarginPct, run.LowConfidenceMarginPct,
 		run.TotalPnL, run.FinalEquity, run.ReturnPct, run.Wins, run.Losses, run.Ratio,
+		nullableJSON(run.IndicatorParams),
 	)
 	if err != nil {
 		return 0, err
@@ -1099,7 +1268,7 @@ func (s *Store) BacktestRuns(limit int) ([]BacktestRun, error) {
 			id, created_at, strategy, pair, habit, start_month, end_month, bars,
 			initial_margin, leverage, position_sizing_mode,
 			high_confidence_amount, low_confidence_amount, high_confidence_margin_pct, low_confidence_margin_pct,
-			total_pnl, final_equity, return_pct, wins, losses, ratio
+			total_pnl, final_equity, return_pct, wins, losses, ratio, COALESCE(indicator_params, '')
 		 FROM backtest_runs
 		 ORDER BY id DESC
 		 LIMIT ?`,
@@ -1112,15 +1281,21 @@ func (s *Store) BacktestRuns(limit int) ([]BacktestRun, error) {
 
 	var out []BacktestRun
 	for rows.Next() {
-		var item BacktestRun
+		var (
+			item   BacktestRun
+			params string
+		)
 		if err := rows.Scan(
 			&item.ID, &item.CreatedAt, &item.Strategy, &item.Pair, &item.Habit, &item.Start, &item.End, &item.Bars,
 			&item.InitialMargin, &item.Leverage, &item.PositionSizingMode,
 			&item.HighConfidenceAmount, &item.LowConfidenceAmount, &item.HighConfidenceMarginPct, &item.LowConfidenceMarginPct,
-			&item.TotalPnL, &item.FinalEquity, &item.ReturnPct, &item.Wins, &item.Losses, &item.Ratio,
+			&item.TotalPnL, &item.FinalEquity, &item.ReturnPct, &item.Wins, &item.Losses, &item.Ratio, &params,
 		); err != nil {
 			return nil, err
 		}
+		if params != "" {
+			item.IndicatorParams = json.RawMessage(params)
+		}
 		out = append(out, item)
 	}
 	return out, nil
@@ -1134,13 +1309,16 @@ func (s *Store) BacktestRunDetail(id int64) (BacktestRun, []BacktestRunRecord, e
 		return BacktestRun{}, nil, fmt.Errorf("invalid backtest id")
 	}
 
-	var run BacktestRun
+	var (
+		run    BacktestRun
+		params string
+	)
 	err := s.db.QueryRow(
 		`SELECT
 			id, created_at, strategy, pair, habit, start_month, end_month, bars,
 			initial_margin, leverage, position_sizing_mode,
 			high_confidence_amount, low_confidence_amount, high_confidence_margin_pct, low_confidence_margin_pct,
-			total_pnl, final_equity, return_pct, wins, losses, ratio
+			total_pnl, final_equity, return_pct, wins, losses, ratio, COALESCE(indicator_params, '')
 		 FROM backtest_runs
 		 WHERE id = ?`,
 		id,
@@ -1148,11 +1326,14 @@ func (s *Store) BacktestRunDetail(id int64) (BacktestRun, []BacktestRunRecord, e
 		&run.ID, &run.CreatedAt, &run.Strategy, &run.Pair, &run.Habit, &run.Start, &run.End, &run.Bars,
 		&run.InitialMargin, &run.Leverage, &run.PositionSizingMode,
 		&run.HighConfidenceAmount, &run.LowConfidenceAmount, &run.HighConfidenceMarginPct, &run.LowConfidenceMarginPct,
-		&run.TotalPnL, &run.FinalEquity, &run.ReturnPct, &run.Wins, &run.Losses, &run.Ratio,
+		&run.TotalPnL, &run.FinalEquity, &run.ReturnPct, &run.Wins, &run.Losses, &run.Ratio, &params,
 	)
 	if err != nil {
 		return BacktestRun{}, nil, err
 	}
+	if params != "" {
+		run.IndicatorParams = json.RawMessage(params)
+	}
 
 	rows, err := s.db.Query(
 		`SELECT seq, ts, side, confidence, COALESCE(order_basis, ''), size, leverage, entry, COALESCE(stop_loss, 0), COALESCE(take_profit, 0), exit, pnl
@@ -1225,13 +1406,31 @@ func boolToInt(v bool) int {
 	return 0
 }
 
+// nullableJSON 序列化为 JSON 文本，空值写入 NULL。
+func nullableJSON(v any) any {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case json.RawMessage:
+		if len(t) == 0 {
+			return nil
+		}
+		return string(t)
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return string(raw)
+}
+
 func currentExchange() string {
 	if config.Config == nil {
 		return "binance"
 	}
 	ex := strings.ToLower(strings.TrimSpace(config.Config.ActiveExchange))
-	if ex == "okx" {
-		return "okx"
+	if ex == "okx" || ex == "bybit" || ex == "sim" {
+		return ex
 	}
 	return "binance"
 }
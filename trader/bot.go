@@ -6,11 +6,13 @@ import (
 	"math"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"trade-go/ai"
 	"trade-go/config"
 	"trade-go/exchange"
 	"trade-go/indicators"
+	"trade-go/market"
 	"trade-go/models"
 	"trade-go/risk"
 	"trade-go/storage"
@@ -37,6 +39,7 @@ type TradeSettingsUpdate struct {
 	HighConfidenceMarginPct          *float64
 	LowConfidenceMarginPct           *float64
 	Leverage                         *int
+	MarginMode                       *string
 	MaxRiskPerTradePct               *float64
 	MaxPositionPct                   *float64
 	MaxConsecutiveLosses             *int
@@ -73,6 +76,14 @@ type Bot struct {
 	nextAutoReviewAt    time.Time
 	autoRiskProfile     string
 	autoReviewReason    string
+	protectiveOrders    []storage.ProtectiveOrder
+	incomeSyncMu        sync.Mutex
+	incomeSyncedAt      time.Time
+	userStreamMu        sync.Mutex
+	userStream          *exchange.UserStream
+	candles             *market.CandleStore
+	events              atomic.Value // EventHandler
+	indicatorResolver   atomic.Value // IndicatorParamsResolver
 }
 
 func NewBot() *Bot {
@@ -102,12 +113,68 @@ func (b *Bot) HasStore() bool {
 	return b.store != nil
 }
 
+// SetExchangeClient 替换交易所客户端，用于接入模拟交易所离线复现交易周期；K 线改由该客户端直接提供。
+func (b *Bot) SetExchangeClient(c *exchange.Client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.exchange = c
+	b.candles = nil
+}
+
+// SetCandleStore 启用行情推送维护的 K 线缓存，实盘与模拟盘周期不再每轮 REST 拉取 K 线。
+func (b *Bot) SetCandleStore(cs *market.CandleStore) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.candles = cs
+}
+
+// fetchCandles 优先读取 K 线缓存，未启用时直接请求交易所。
+func (b *Bot) fetchCandles(symbol, timeframe string, limit int) ([]models.OHLCV, error) {
+	b.mu.RLock()
+	client, cs := b.exchange, b.candles
+	b.mu.RUnlock()
+	if cs == nil {
+		return client.FetchOHLCV(symbol, timeframe, limit)
+	}
+	return cs.Candles(symbol, timeframe, limit, client.FetchOHLCV)
+}
+
+// fetchCheckedCandles 拉取 K 线并经质量检查与修复后再交给指标计算；模拟交易所回放历史行情时不检查时效。
+// 有效 K 线至少需覆盖 params 下全部指标的预热长度。
+func (b *Bot) fetchCheckedCandles(symbol, timeframe string, dataPoints int, params models.IndicatorParams) ([]models.OHLCV, models.CandleQuality, error) {
+	limit := indicatorFetchLimit(dataPoints, params)
+	candles, err := b.fetchCandles(symbol, timeframe, limit)
+	if err != nil {
+		return nil, models.CandleQuality{}, err
+	}
+	b.mu.RLock()
+	sim := b.exchange.ActiveExchange() == "sim"
+	b.mu.RUnlock()
+	now := time.Now()
+	if sim {
+		now = time.Time{}
+	}
+	out, q, err := validateCandles(candles, timeframe, limit, indicators.RequiredBars(params), now)
+	if err != nil {
+		return nil, q, err
+	}
+	if len(q.Repairs) > 0 {
+		fmt.Printf("K线数据已修复 %s %s: %s\n", symbol, timeframe, strings.Join(q.Repairs, "；"))
+	}
+	if len(q.Flags) > 0 {
+		fmt.Printf("K线数据异常（未修改） %s %s: %s\n", symbol, timeframe, strings.Join(q.Flags, "；"))
+	}
+	return out, q, nil
+}
+
 func (b *Bot) ReloadClients() (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("reload clients panic: %v", r)
 		}
 	}()
+	// 在释放 b.mu 之后执行
+	defer b.restartUserStream()
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	b.exchange = exchange.NewClient()
@@ -123,6 +190,12 @@ func (b *Bot) ReloadClients() (err error) {
 // Setup 初始化交易所设置
 func (b *Bot) Setup() error {
 	cfg := b.TradeConfig()
+	// 先切换保证金模式：OKX 杠杆按 mgnMode 分别设置
+	marginMode := models.NormalizeMarginMode(cfg.MarginMode)
+	if err := b.exchange.SetMarginMode(cfg.Symbol, marginMode); err != nil {
+		return fmt.Errorf("设置保证金模式失败: %w", err)
+	}
+	fmt.Printf("保证金模式: %s\n", marginMode)
 	if err := b.exchange.SetLeverage(cfg.Symbol, cfg.Leverage); err != nil {
 		return fmt.Errorf("设置杠杆失败: %w", err)
 	}
@@ -134,10 +207,14 @@ func (b *Bot) Setup() error {
 	}
 	fmt.Printf("当前USDT余额: %.2f\n", balance)
 
-	pos, err := b.exchange.FetchPosition(cfg.Symbol)
-	if err == nil && pos != nil {
-		_ = b.savePosition(*pos)
+	if mode, err := b.exchange.FetchPositionMode(cfg.Symbol); err == nil {
+		fmt.Printf("持仓模式: %s\n", mode)
+	}
+	legs, _ := b.exchange.FetchPositions(cfg.Symbol)
+	for _, leg := range legs {
+		_ = b.savePosition(leg)
 	}
+	pos := models.PrimaryPosition(legs)
 	_ = b.saveEquity(balance, pos)
 	_ = b.reconcileOpenOrders()
 	return nil
@@ -157,7 +234,7 @@ func (b *Bot) Run() {
 	priceData, err := b.fetchPriceData()
 	if err != nil {
 		fmt.Printf("获取行情失败: %v\n", err)
-		b.saveSkillStepAudit(cycleID, "market-read", "failed", "market_unavailable", "", marketReadAt,
+		b.saveSkillStepAudit(cycleID, "market-read", "failed", marketReadFailureCode(err), "", marketReadAt,
 			map[string]any{"symbol": cfg.Symbol, "timeframe": cfg.Timeframe},
 			map[string]any{"error": err.Error()},
 			"blocked")
@@ -167,18 +244,33 @@ func (b *Bot) Run() {
 	b.saveSkillStepAudit(cycleID, "market-read", "ok", "ok", "", marketReadAt,
 		map[string]any{"symbol": cfg.Symbol, "timeframe": cfg.Timeframe},
 		map[string]any{
-			"price":        priceData.Price,
-			"price_change": priceData.PriceChange,
-			"timestamp":    priceData.Timestamp.Format(time.RFC3339),
+			"price":            priceData.Price,
+			"price_change":     priceData.PriceChange,
+			"timestamp":        priceData.Timestamp.Format(time.RFC3339),
+			"quality":          priceData.Quality,
+			"indicator_params": priceData.Indicators,
+			"indicator_source": priceData.IndicatorSource,
 		},
 		"continue")
 	fmt.Printf("BTC当前价格: $%.2f | 变化: %+.2f%%\n", priceData.Price, priceData.PriceChange)
 
-	// 2. 获取持仓
-	currentPos, err := b.exchange.FetchPosition(cfg.Symbol)
+	// 2. 获取持仓（双向持仓模式下可能同时存在多空两腿，AI 与风控使用主腿）
+	legs, err := b.exchange.FetchPositions(cfg.Symbol)
+	currentPos := models.PrimaryPosition(legs)
 	if err != nil {
 		fmt.Printf("获取持仓失败: %v\n", err)
 		b.setRuntime(time.Now(), err.Error(), nil, &priceData, nil)
+	} else {
+		b.syncProtectiveOrders(legs)
+	}
+	if !cfg.TestMode {
+		_ = b.reconcileOpenOrders()
+		// 资金流水导入较慢，后台执行不阻塞本周期决策
+		go func() {
+			if _, err := b.SyncIncomeHistory(false); err != nil {
+				fmt.Printf("同步交易所资金流水失败: %v\n", err)
+			}
+		}()
 	}
 
 	// 2.5) auto-review (按配置在下单后间隔触发，自动收紧/恢复风险参数)
@@ -295,11 +387,11 @@ func (b *Bot) Run() {
 
 	// 3) risk-plan
 	riskPlanAt := time.Now()
-	tradeAmount, allow, riskReason := b.buildRiskPosition(signal, priceData, currentPos)
+	tradeAmount, allow, riskCode, riskReason := b.buildRiskPosition(signal, priceData, currentPos)
 	b.saveAIDecision(signal, priceData, tradeAmount, allow, riskReason, false)
 	if !allow {
 		fmt.Printf("⛔ 风控阻断: %s\n", riskReason)
-		b.saveSkillStepAudit(cycleID, "risk-plan", "failed", "risk_blocked", "", riskPlanAt,
+		b.saveSkillStepAudit(cycleID, "risk-plan", "failed", riskCode, "", riskPlanAt,
 			map[string]any{
 				"signal": signal,
 				"price":  priceData.Price,
@@ -325,7 +417,7 @@ func (b *Bot) Run() {
 
 	// 4) order-plan + execute
 	orderPlanAt := time.Now()
-	planOK, planCode, planReason, planOutput := b.preflightOrderPlan(signal, priceData, tradeAmount)
+	planOK, planCode, planReason, plannedAmount, planOutput := b.preflightOrderPlan(signal, priceData, tradeAmount)
 	if !planOK {
 		fmt.Printf("⛔ 下单前校验失败: %s\n", planReason)
 		b.saveAIDecision(signal, priceData, tradeAmount, false, planReason, false)
@@ -340,7 +432,9 @@ func (b *Bot) Run() {
 		_ = b.saveRiskEvent("risk_block", planReason)
 		return
 	}
-	execOK, execCode, execErr := b.executeTrade(signal, priceData, currentPos, tradeAmount)
+	// 盘口滑点超限时可能已缩减数量
+	tradeAmount = plannedAmount
+	execOK, execCode, execErr := b.executeTrade(signal, priceData, legs, tradeAmount)
 	if !execOK {
 		errMsg := planReason
 		if execErr != nil {
@@ -376,10 +470,11 @@ func (b *Bot) Run() {
 		},
 		"executed")
 
-	newPos, _ := b.exchange.FetchPosition(cfg.Symbol)
-	if newPos != nil {
-		_ = b.savePosition(*newPos)
+	newLegs, _ := b.exchange.FetchPositions(cfg.Symbol)
+	for _, leg := range newLegs {
+		_ = b.savePosition(leg)
 	}
+	newPos := models.PrimaryPosition(newLegs)
 	newBalance, _ := b.exchange.FetchBalance()
 	// FetchBalance 返回口径统一按“账户总权益”，不再叠加未实现盈亏，避免重复计算。
 	equity := newBalance
@@ -394,15 +489,13 @@ func (b *Bot) Run() {
 
 func (b *Bot) fetchPriceData() (models.PriceData, error) {
 	cfg := b.TradeConfig()
-	candles, err := b.exchange.FetchOHLCV(cfg.Symbol, cfg.Timeframe, cfg.DataPoints)
+	params, source := b.resolveIndicatorParams(cfg, nil)
+	candles, quality, err := b.fetchCheckedCandles(cfg.Symbol, cfg.Timeframe, cfg.DataPoints, params)
 	if err != nil {
 		return models.PriceData{}, err
 	}
-	if len(candles) < 2 {
-		return models.PriceData{}, fmt.Errorf("K线数据不足")
-	}
 
-	ind := indicators.Calculate(candles)
+	ind := indicators.CalculateWithParams(candles, params)
 	trend := indicators.AnalyzeTrend(candles, ind)
 	levels := indicators.AnalyzeLevels(candles, ind)
 
@@ -410,20 +503,30 @@ func (b *Bot) fetchPriceData() (models.PriceData, error) {
 	prev := candles[len(candles)-2]
 	priceChange := (cur.Close - prev.Close) / prev.Close * 100
 
-	return models.PriceData{
-		Symbol:      cfg.Symbol,
-		Price:       cur.Close,
-		Timestamp:   cur.Timestamp,
-		High:        cur.High,
-		Low:         cur.Low,
-		Volume:      cur.Volume,
-		Timeframe:   cfg.Timeframe,
-		PriceChange: priceChange,
-		KlineData:   candles,
-		Technical:   ind,
-		Trend:       trend,
-		Levels:      levels,
-	}, nil
+	pd := models.PriceData{
+		Symbol:          cfg.Symbol,
+		Price:           cur.Close,
+		Timestamp:       cur.Timestamp,
+		High:            cur.High,
+		Low:             cur.Low,
+		Volume:          cur.Volume,
+		Timeframe:       cfg.Timeframe,
+		PriceChange:     priceChange,
+		KlineData:       candles,
+		Technical:       ind,
+		Trend:           trend,
+		Levels:          levels,
+		Quality:         quality,
+		Indicators:      params,
+		IndicatorSource: source,
+	}
+	// 合约数据缺失不影响决策，提示词中标注为不可用即可
+	if stats, err := b.exchange.FetchMarketStats(cfg.Symbol); err == nil {
+		pd.Derivatives = stats
+	} else {
+		fmt.Printf("获取资金费率/持仓量失败: %v\n", err)
+	}
+	return pd, nil
 }
 
 func (b *Bot) analyzeWithRetry(pd models.PriceData, pos *models.Position) models.TradeSignal {
@@ -482,23 +585,24 @@ func (b *Bot) addSignal(sig models.TradeSignal) {
 	}
 }
 
-func (b *Bot) executeTrade(signal models.TradeSignal, pd models.PriceData, currentPos *models.Position, tradeAmount float64) (bool, string, error) {
+// executeTrade legs 为当前全部持仓腿；反手时只平掉与信号方向相反的一腿。
+func (b *Bot) executeTrade(signal models.TradeSignal, pd models.PriceData, legs []models.Position, tradeAmount float64) (bool, string, error) {
 	cfg := b.TradeConfig()
 	fmt.Printf("交易信号: %s | 信心: %s\n", signal.Signal, signal.Confidence)
 	fmt.Printf("理由: %s\n", signal.Reason)
 	fmt.Printf("止损: $%.2f | 止盈: $%.2f\n", signal.StopLoss, signal.TakeProfit)
 	fmt.Printf("开仓数量(按信心): %.4f\n", tradeAmount)
-	fmt.Printf("当前持仓: %v\n", currentPos)
+	fmt.Printf("当前持仓: %v\n", legs)
 
+	newSide := "long"
+	if signal.Signal == "SELL" {
+		newSide = "short"
+	}
 	// 防频繁反转
-	if currentPos != nil && signal.Signal != "HOLD" {
-		newSide := "long"
-		if signal.Signal == "SELL" {
-			newSide = "short"
-		}
-		if newSide != currentPos.Side {
+	if signal.Signal != "HOLD" {
+		if opposite := models.PositionLeg(legs, oppositeSide(newSide)); opposite != nil {
 			if signal.Confidence != "HIGH" {
-				fmt.Printf("非高信心反转信号，保持现有%s仓\n", currentPos.Side)
+				fmt.Printf("非高信心反转信号，保持现有%s仓\n", opposite.Side)
 				return false, "reverse_guard_low_confidence", nil
 			}
 			history := b.SignalHistory(2)
@@ -535,25 +639,40 @@ func (b *Bot) executeTrade(signal models.TradeSignal, pd models.PriceData, curre
 	var orders []models.OrderResult
 	switch signal.Signal {
 	case "BUY":
-		orders, execErr = b.openLong(currentPos, tradeAmount)
+		orders, execErr = b.openLong(legs, tradeAmount, pd.Price)
 	case "SELL":
-		orders, execErr = b.openShort(currentPos, tradeAmount)
+		orders, execErr = b.openShort(legs, tradeAmount, pd.Price)
 	default:
 		fmt.Println("HOLD - 不操作")
 		return true, "hold", nil
 	}
 
 	if execErr != nil {
+		for _, od := range orders {
+			_ = b.saveOrder(od)
+		}
 		fmt.Printf("订单执行失败: %v\n", execErr)
 		_ = b.saveRiskEvent("order_error", execErr.Error())
 		return false, "order_execute_failed", execErr
 	}
+	// 只有全部开仓单确认终态后才挂保护单，数量取确认的开仓成交量
+	entryFilled := 0.0
+	entryConfirmed := true
 	for _, od := range orders {
 		_ = b.saveOrder(od)
-		if err := b.confirmOrder(od); err != nil {
+		st, err := b.confirmOrder(od)
+		if err != nil {
 			fmt.Printf("订单确认失败: %v\n", err)
 			_ = b.saveRiskEvent("order_confirm_error", err.Error())
 		}
+		if od.ReduceOnly {
+			continue
+		}
+		if err != nil || st == nil {
+			entryConfirmed = false
+			continue
+		}
+		entryFilled += st.FilledSize
 	}
 	if len(orders) > 0 {
 		b.markOrderExecuted(time.Now())
@@ -561,12 +680,43 @@ func (b *Bot) executeTrade(signal models.TradeSignal, pd models.PriceData, curre
 
 	fmt.Println("订单执行成功")
 	time.Sleep(2 * time.Second)
-	newPos, _ := b.exchange.FetchPosition(cfg.Symbol)
-	fmt.Printf("更新后持仓: %v\n", newPos)
+	newLegs, _ := b.exchange.FetchPositions(cfg.Symbol)
+	fmt.Printf("更新后持仓: %v\n", newLegs)
+	switch {
+	case !entryConfirmed:
+		msg := "开仓单未确认成交，暂不挂出止损/止盈"
+		fmt.Println(msg)
+		_ = b.saveRiskEvent("protective_order_skipped", msg)
+	case entryFilled > 0:
+		b.attachProtectiveOrders(signal, models.PositionLeg(newLegs, newSide), entryFilled)
+	}
 	return true, "executed", nil
 }
 
 func (b *Bot) setRuntime(runAt time.Time, errMsg string, sig *models.TradeSignal, pd *models.PriceData, pos *models.Position) {
+	b.updateRuntime(runAt, errMsg, sig, pd, pos)
+	if !b.hasEventHandler() {
+		return
+	}
+	// emit 同步回调，但回调方可能保留事件稍后再序列化（如 SSE 回放缓冲），按值复制避免调用方后续修改
+	data := map[string]any{"run_at": runAt, "error": errMsg}
+	if sig != nil {
+		data["signal"] = *sig
+	}
+	if pd != nil {
+		data["price"] = *pd
+	}
+	if pos != nil {
+		data["position"] = *pos
+	}
+	kind := "result"
+	if errMsg == "" && sig == nil && pd == nil && pos == nil {
+		kind = "start"
+	}
+	b.emit("cycle", kind, data)
+}
+
+func (b *Bot) updateRuntime(runAt time.Time, errMsg string, sig *models.TradeSignal, pd *models.PriceData, pos *models.Position) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 	b.runtime.LastRunAt = runAt
@@ -635,11 +785,68 @@ func (b *Bot) ActiveExchange() string {
 	return b.exchange.ActiveExchange()
 }
 
+// ActiveExchangeEnv 当前交易所客户端的交易所与环境，二者在同一把锁下读取，不会跨越一次 ReloadClients。
+func (b *Bot) ActiveExchangeEnv() (string, string) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.exchange == nil {
+		return "binance", exchange.EnvMainnet
+	}
+	return b.exchange.ActiveExchange(), b.exchange.Environment()
+}
+
 func (b *Bot) FetchPosition() (*models.Position, error) {
 	cfg := b.TradeConfig()
 	return b.exchange.FetchPosition(cfg.Symbol)
 }
 
+func (b *Bot) FetchPositions() ([]models.Position, error) {
+	cfg := b.TradeConfig()
+	return b.exchange.FetchPositions(cfg.Symbol)
+}
+
+// FetchAllPositions 账户全部持仓。本机器人订单记录累计不出对应持仓腿的（包括当前交易对上手动开的仓）标记为外部持仓；
+// 未启用本地存储时无从核对，退回按交易对判断。
+func (b *Bot) FetchAllPositions() ([]models.Position, error) {
+	cfg := b.TradeConfig()
+	legs, err := b.exchange.FetchAllPositions()
+	if err != nil {
+		return nil, err
+	}
+	b.mu.RLock()
+	store := b.store
+	b.mu.RUnlock()
+	var own map[string]float64
+	if store != nil {
+		if botLegs, err := store.BotPositionLegs(); err == nil {
+			own = map[string]float64{}
+			for _, leg := range botLegs {
+				own[exchange.NormalizeSymbol(leg.Symbol)+"|"+leg.Side] += leg.Size
+			}
+		} else {
+			fmt.Printf("读取本机器人订单记录失败: %v\n", err)
+		}
+	}
+	for i := range legs {
+		symbol := exchange.NormalizeSymbol(legs[i].Symbol)
+		if own == nil {
+			legs[i].External = symbol != exchange.NormalizeSymbol(cfg.Symbol)
+			continue
+		}
+		legs[i].External = own[symbol+"|"+legs[i].Side] <= 0
+	}
+	return legs, nil
+}
+
+func (b *Bot) FetchBalances() ([]models.AssetBalance, error) {
+	return b.exchange.FetchBalances()
+}
+
+func (b *Bot) PositionMode() (string, error) {
+	cfg := b.TradeConfig()
+	return b.exchange.FetchPositionMode(cfg.Symbol)
+}
+
 func (b *Bot) TradeConfig() config.TradeConfig {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
@@ -786,52 +993,54 @@ func (b *Bot) DeleteBacktestRun(id int64) bool {
 	return b.store.DeleteBacktestRun(id) == nil
 }
 
-func (b *Bot) openLong(currentPos *models.Position, amount float64) ([]models.OrderResult, error) {
+func (b *Bot) openLong(legs []models.Position, amount, refPrice float64) ([]models.OrderResult, error) {
+	return b.openSide("long", legs, amount, refPrice)
+}
+
+func (b *Bot) openShort(legs []models.Position, amount, refPrice float64) ([]models.OrderResult, error) {
+	return b.openSide("short", legs, amount, refPrice)
+}
+
+// openSide 先平掉反向腿再开仓；同向腿已存在时保持现状。
+// 单向持仓模式下至多一腿，行为与直接平反向仓一致；双向模式下按腿平仓，不依赖净头寸。
+func (b *Bot) openSide(side string, legs []models.Position, amount, refPrice float64) ([]models.OrderResult, error) {
 	cfg := b.TradeConfig()
+	label := map[string]string{"long": "多", "short": "空"}
+	openSide, closeSide := "buy", "buy"
+	if side == "short" {
+		openSide = "sell"
+	} else {
+		closeSide = "sell"
+	}
 	var orders []models.OrderResult
-	if currentPos != nil && currentPos.Side == "short" {
-		fmt.Println("平空仓并开多仓...")
-		closeOrder, err := b.exchange.PlaceMarketOrderWithResult(cfg.Symbol, "buy", currentPos.Size, true)
+	if opposite := models.PositionLeg(legs, oppositeSide(side)); opposite != nil {
+		fmt.Printf("平%s仓...\n", label[opposite.Side])
+		b.cancelProtectiveOrders(cfg.Symbol, closeSide)
+		closeOrder, err := b.exchange.PlaceMarketOrderWithResult(cfg.Symbol, closeSide, opposite.Size, true)
 		if err != nil {
-			return nil, fmt.Errorf("平空仓失败: %w", err)
+			return nil, fmt.Errorf("平%s仓失败: %w", label[opposite.Side], err)
 		}
 		orders = append(orders, closeOrder)
 		time.Sleep(time.Second)
-	} else if currentPos != nil && currentPos.Side == "long" {
-		fmt.Println("已有多头持仓，保持现状")
-		return nil, nil
 	}
-	fmt.Println("开多仓...")
-	openOrder, err := b.exchange.PlaceMarketOrderWithResult(cfg.Symbol, "buy", amount, false)
+	if models.PositionLeg(legs, side) != nil {
+		fmt.Printf("已有%s头持仓，保持现状\n", label[side])
+		return orders, nil
+	}
+	fmt.Printf("开%s仓...\n", label[side])
+	openOrders, err := b.placeEntryOrder(openSide, amount, refPrice)
+	orders = append(orders, openOrders...)
 	if err != nil {
-		return nil, err
+		return orders, err
 	}
-	orders = append(orders, openOrder)
 	return orders, nil
 }
 
-func (b *Bot) openShort(currentPos *models.Position, amount float64) ([]models.OrderResult, error) {
-	cfg := b.TradeConfig()
-	var orders []models.OrderResult
-	if currentPos != nil && currentPos.Side == "long" {
-		fmt.Println("平多仓并开空仓...")
-		closeOrder, err := b.exchange.PlaceMarketOrderWithResult(cfg.Symbol, "sell", currentPos.Size, true)
-		if err != nil {
-			return nil, fmt.Errorf("平多仓失败: %w", err)
-		}
-		orders = append(orders, closeOrder)
-		time.Sleep(time.Second)
-	} else if currentPos != nil && currentPos.Side == "short" {
-		fmt.Println("已有空头持仓，保持现状")
-		return nil, nil
-	}
-	fmt.Println("开空仓...")
-	openOrder, err := b.exchange.PlaceMarketOrderWithResult(cfg.Symbol, "sell", amount, false)
-	if err != nil {
-		return nil, err
+func oppositeSide(side string) string {
+	if side == "long" {
+		return "short"
 	}
-	orders = append(orders, openOrder)
-	return orders, nil
+	return "long"
 }
 
 func (b *Bot) UpdateTradeSettings(update TradeSettingsUpdate) (config.TradeConfig, error) {
@@ -882,6 +1091,13 @@ func (b *Bot) UpdateTradeSettings(update TradeSettingsUpdate) (config.TradeConfi
 		}
 		next.Leverage = *update.Leverage
 	}
+	if update.MarginMode != nil {
+		mode := strings.ToLower(strings.TrimSpace(*update.MarginMode))
+		if mode != models.MarginModeCross && mode != models.MarginModeIsolated {
+			return current, fmt.Errorf("margin_mode 仅支持 cross 或 isolated")
+		}
+		next.MarginMode = mode
+	}
 	if update.MaxRiskPerTradePct != nil {
 		if *update.MaxRiskPerTradePct <= 0 || *update.MaxRiskPerTradePct > 1 {
 			return current, fmt.Errorf("max_risk_per_trade_pct 需在 (0,1] 之间")
@@ -982,7 +1198,14 @@ func (b *Bot) UpdateTradeSettings(update TradeSettingsUpdate) (config.TradeConfi
 		next.AutoStrategyRegenMinRR = *update.AutoStrategyRegenMinRR
 	}
 
-	if (update.Leverage != nil && next.Leverage != current.Leverage) || (update.Symbol != nil && next.Symbol != current.Symbol) {
+	symbolChanged := update.Symbol != nil && next.Symbol != current.Symbol
+	marginChanged := update.MarginMode != nil && next.MarginMode != current.MarginMode
+	if marginChanged || symbolChanged {
+		if err := b.exchange.SetMarginMode(next.Symbol, next.MarginMode); err != nil {
+			return current, fmt.Errorf("设置保证金模式失败: %w", err)
+		}
+	}
+	if (update.Leverage != nil && next.Leverage != current.Leverage) || symbolChanged || marginChanged {
 		if err := b.exchange.SetLeverage(next.Symbol, next.Leverage); err != nil {
 			return current, fmt.Errorf("设置杠杆失败: %w", err)
 		}
@@ -1049,12 +1272,14 @@ func suggestedAmountByConfidence(confidence string, cfg config.TradeConfig, bala
 	return low
 }
 
-func (b *Bot) buildRiskPosition(signal models.TradeSignal, pd models.PriceData, pos *models.Position) (float64, bool, string) {
+// buildRiskPosition 返回风控批准的仓位；拒绝时返回原因码与原因。
+func (b *Bot) buildRiskPosition(signal models.TradeSignal, pd models.PriceData, pos *models.Position) (float64, bool, string, string) {
 	cfg := b.TradeConfig()
 	snapshot, err := b.loadRiskSnapshot()
 	if err != nil {
-		return 0, false, err.Error()
+		return 0, false, "risk_blocked", err.Error()
 	}
+	inst, instOK := b.fetchInstrument(cfg.Symbol)
 	suggested := suggestedAmountByConfidence(signal.Confidence, cfg, snapshot.Balance, pd.Price)
 	plan := b.riskEngine.BuildOrderPlan(risk.OrderPlanInput{
 		Price:         pd.Price,
@@ -1062,16 +1287,26 @@ func (b *Bot) buildRiskPosition(signal models.TradeSignal, pd models.PriceData,
 		Confidence:    signal.Confidence,
 		SuggestedSize: suggested,
 		Leverage:      cfg.Leverage,
+		StepSize:      inst.StepSize,
+		MinQty:        inst.MinQty,
+		MinNotional:   inst.MinNotional,
 	}, snapshot)
 	if !plan.Approved {
-		return 0, false, plan.Reason
+		return 0, false, riskPlanCode(plan), plan.Reason
 	}
 	// 交易所精度保护
-	size := math.Round(plan.Size*10000) / 10000
+	size := roundPlanSize(plan.Size, inst, instOK)
 	if size <= 0 {
-		return 0, false, "风控后仓位过小"
+		return 0, false, "risk_blocked", "风控后仓位过小"
+	}
+	return size, true, "ok", ""
+}
+
+func riskPlanCode(plan risk.OrderPlan) string {
+	if plan.Code != "" {
+		return plan.Code
 	}
-	return size, true, ""
+	return "risk_blocked"
 }
 
 func (b *Bot) loadRiskSnapshot() (risk.Snapshot, error) {
@@ -1103,9 +1338,10 @@ func (b *Bot) loadRiskSnapshot() (risk.Snapshot, error) {
 	return snapshot, nil
 }
 
-func (b *Bot) confirmOrder(order models.OrderResult) error {
+// confirmOrder 轮询订单直至成交或撤销，返回终态；无订单 ID 时无从确认，返回 nil；超时返回错误。
+func (b *Bot) confirmOrder(order models.OrderResult) (*models.OrderStatus, error) {
 	if order.OrderID == "" {
-		return nil
+		return nil, nil
 	}
 	for i := 0; i < 6; i++ {
 		status, err := b.exchange.FetchOrder(order.Symbol, order.OrderID)
@@ -1123,40 +1359,21 @@ func (b *Bot) confirmOrder(order models.OrderResult) error {
 			_ = b.saveFill(fillID, status)
 		}
 		if status.State == "filled" || status.State == "canceled" {
-			return nil
+			return status, nil
 		}
 		time.Sleep(2 * time.Second)
 	}
-	return fmt.Errorf("订单%s状态确认超时", order.OrderID)
-}
-
-func (b *Bot) reconcileOpenOrders() error {
-	if b.store == nil {
-		return nil
-	}
-	cfg := b.TradeConfig()
-	ids, err := b.store.OpenOrders()
-	if err != nil {
-		return err
-	}
-	for _, id := range ids {
-		st, e := b.exchange.FetchOrder(cfg.Symbol, id)
-		if e != nil || st == nil {
-			continue
-		}
-		_ = b.saveOrderStatus(id, st.State, st)
-	}
-	return nil
+	return nil, fmt.Errorf("订单%s状态确认超时", order.OrderID)
 }
 
 func (b *Bot) saveAIDecision(sig models.TradeSignal, pd models.PriceData, approvedSize float64, approved bool, riskReason string, executed bool) {
-	if b.store == nil {
+	if b.store == nil && !b.hasEventHandler() {
 		return
 	}
 	cfg := b.TradeConfig()
 	balance, _ := b.exchange.FetchBalance()
 	suggested := suggestedAmountByConfidence(sig.Confidence, cfg, balance, pd.Price)
-	_ = b.store.SaveAIDecision(time.Now(), map[string]any{
+	payload := map[string]any{
 		"signal":         sig.Signal,
 		"confidence":     sig.Confidence,
 		"reason":         sig.Reason,
@@ -1170,31 +1387,54 @@ func (b *Bot) saveAIDecision(sig models.TradeSignal, pd models.PriceData, approv
 		"approved":       approved,
 		"executed":       executed,
 		"risk_reason":    riskReason,
-	})
+	}
+	if pd.IndicatorSource != "" {
+		payload["indicator_params"] = pd.Indicators
+		payload["indicator_source"] = pd.IndicatorSource
+	}
+	b.emit("signal", sig.Signal, payload)
+	if b.store != nil {
+		_ = b.store.SaveAIDecision(time.Now(), payload)
+	}
 }
 
 func (b *Bot) saveOrder(order models.OrderResult) error {
+	b.emit("order", order.State, order)
 	if b.store == nil {
 		return nil
 	}
-	return b.store.SaveOrder(order.OrderID, order.Symbol, order.Side, order.Size, order.ReduceOnly, order.State, order)
+	return b.store.SaveOrder(order.OrderID, order.Symbol, order.Side, order.Type, order.Size, order.ReduceOnly, order.State, order)
 }
 
 func (b *Bot) saveOrderStatus(orderID, status string, payload any) error {
+	b.emit("order", status, map[string]any{"order_id": orderID, "state": status, "detail": payload})
 	if b.store == nil {
 		return nil
 	}
-	return b.store.SaveOrder(orderID, "", "", 0, false, status, payload)
+	return b.store.SaveOrder(orderID, "", "", "", 0, false, status, payload)
 }
 
 func (b *Bot) saveFill(fillID string, status *models.OrderStatus) error {
-	if b.store == nil || status == nil {
+	if status == nil {
+		return nil
+	}
+	b.emit("fill", status.State, map[string]any{
+		"fill_id":     fillID,
+		"order_id":    status.OrderID,
+		"symbol":      status.Symbol,
+		"side":        status.Side,
+		"filled_size": status.FilledSize,
+		"avg_price":   status.AvgPrice,
+		"update_time": status.UpdateTime,
+	})
+	if b.store == nil {
 		return nil
 	}
 	return b.store.SaveFill(fillID, status.OrderID, status.Symbol, status.Side, status.FilledSize, status.AvgPrice, status.UpdateTime)
 }
 
 func (b *Bot) savePosition(pos models.Position) error {
+	b.emit("position", pos.Side, pos)
 	if b.store == nil {
 		return nil
 	}
@@ -1213,6 +1453,7 @@ func (b *Bot) saveEquity(balance float64, pos *models.Position) error {
 }
 
 func (b *Bot) saveRiskEvent(eventType, details string) error {
+	b.emit("risk", eventType, map[string]any{"event_type": eventType, "details": details})
 	if b.store == nil {
 		return nil
 	}
@@ -1256,7 +1497,11 @@ func (b *Bot) saveSkillStepAudit(
 		"output":        output,
 		"final_result":  finalResult,
 	}
-	_ = b.saveRiskEvent("skill_audit", mustJSON(payload))
+	// 阶段结果按周期事件推送，不计入风控事件
+	b.emit("cycle", "stage", payload)
+	if b.store != nil {
+		_ = b.store.SaveRiskEvent("skill_audit", mustJSON(payload))
+	}
 }
 
 func mustJSON(v any) string {
@@ -1298,7 +1543,7 @@ func validateSignalStrict(signal models.TradeSignal, price float64) (bool, strin
 	return true, "ok", ""
 }
 
-func (b *Bot) preflightOrderPlan(signal models.TradeSignal, pd models.PriceData, tradeAmount float64) (bool, string, string, map[string]any) {
+func (b *Bot) preflightOrderPlan(signal models.TradeSignal, pd models.PriceData, tradeAmount float64) (bool, string, string, float64, map[string]any) {
 	cfg := b.TradeConfig()
 	out := map[string]any{
 		"signal":       signal.Signal,
@@ -1309,29 +1554,64 @@ func (b *Bot) preflightOrderPlan(signal models.TradeSignal, pd models.PriceData,
 	}
 	if strings.ToUpper(strings.TrimSpace(signal.Signal)) == "HOLD" {
 		out["action"] = "hold"
-		return true, "ok", "", out
+		return true, "ok", "", tradeAmount, out
 	}
 	if tradeAmount <= 0 {
 		out["reason"] = "trade_amount <= 0"
-		return false, "order_plan_invalid", "开仓数量必须大于 0", out
+		return false, "order_plan_invalid", "开仓数量必须大于 0", tradeAmount, out
 	}
 	if cfg.Leverage <= 0 {
 		out["reason"] = "invalid leverage"
-		return false, "order_plan_invalid", "杠杆配置无效", out
+		return false, "order_plan_invalid", "杠杆配置无效", tradeAmount, out
 	}
 	balance, err := b.exchange.FetchBalance()
 	if err != nil {
 		out["reason"] = err.Error()
-		return false, "balance_unavailable", "读取余额失败", out
+		return false, "balance_unavailable", "读取余额失败", tradeAmount, out
+	}
+	side := "buy"
+	if strings.ToUpper(strings.TrimSpace(signal.Signal)) == "SELL" {
+		side = "sell"
+	}
+	inst, instOK := b.fetchInstrument(cfg.Symbol)
+	if instOK {
+		if ok, code, reason := checkInstrumentLimits(inst, tradeAmount, pd.Price, cfg.Leverage, out); !ok {
+			return false, code, reason, tradeAmount, out
+		}
+		// 去掉浮点尾差，交易所侧向下取整时不会少一个步长
+		tradeAmount = models.RoundToStep(tradeAmount, inst.StepSize)
+	}
+	size, ok, code, reason := b.checkSlippage(cfg, side, tradeAmount, inst, instOK, out)
+	if !ok {
+		return false, code, reason, tradeAmount, out
+	}
+	if size != tradeAmount {
+		tradeAmount = size
+		if instOK {
+			if ok, code, reason := checkInstrumentLimits(inst, tradeAmount, pd.Price, cfg.Leverage, out); !ok {
+				return false, code, reason, tradeAmount, out
+			}
+		}
 	}
 	requiredMargin := pd.Price * tradeAmount / float64(cfg.Leverage)
 	out["balance"] = balance
 	out["required_margin"] = requiredMargin
 	if requiredMargin > balance*0.8 {
 		out["reason"] = "insufficient_margin"
-		return false, "insufficient_margin", "保证金不足", out
+		return false, "insufficient_margin", "保证金不足", tradeAmount, out
+	}
+	if stats := pd.Derivatives; stats.MarkPrice > 0 {
+		// 正值表示每个结算周期支付的资金费，负值表示收取
+		out["funding_rate"] = stats.FundingRate
+		out["next_funding_time"] = stats.NextFundingTime
+		out["funding_cost_per_period"] = stats.FundingCost(side, pd.Price*tradeAmount)
+	}
+	out["entry_mode"] = entryOrderMode(cfg)
+	if entryOrderMode(cfg) == "maker" {
+		out["limit_price"] = makerEntryPrice(side, pd.Price, cfg.MakerEntryOffsetBps)
+		out["maker_timeout_sec"] = int(makerEntryTimeout(cfg).Seconds())
 	}
-	return true, "ok", "", out
+	return true, "ok", "", tradeAmount, out
 }
 
 // WaitForNextPeriod 等待到下一个 15 分钟整点，返回需等待秒数
@@ -0,0 +1,263 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"trade-go/exchange"
+	"trade-go/models"
+)
+
+const (
+	// 单个缺口最多按前收盘价补齐的 K 线数，更大的缺口截断其之前的数据
+	candleMaxFillBars = 3
+	// 以下比例均相对修复后的 K 线数
+	candleMaxFillRatio     = 0.05
+	candleMaxInvalidRatio  = 0.05
+	candleMaxZeroVolRatio  = 0.2
+	candleMaxOutlierRatio  = 0.02
+	candleOutlierFactor    = 12.0
+	candleOutlierMinReturn = 0.03
+)
+
+// CandleQualityError K 线无法修复，Code 作为 market-read 阶段的失败原因码。
+type CandleQualityError struct {
+	Code   string
+	Detail string
+}
+
+func (e *CandleQualityError) Error() string {
+	return fmt.Sprintf("K线数据质量不合格(%s): %s", e.Code, e.Detail)
+}
+
+// marketReadFailureCode K 线质量问题返回具体原因码，其余为 market_unavailable。
+func marketReadFailureCode(err error) string {
+	var qe *CandleQualityError
+	if errors.As(err, &qe) {
+		return qe.Code
+	}
+	return "market_unavailable"
+}
+
+func candleQualityError(code, format string, args ...any) error {
+	return &CandleQualityError{Code: code, Detail: fmt.Sprintf(format, args...)}
+}
+
+func isValidCandle(k models.OHLCV) bool {
+	for _, v := range []float64{k.Open, k.High, k.Low, k.Close} {
+		if !isPositiveNumber(v) {
+			return false
+		}
+	}
+	if math.IsNaN(k.Volume) || math.IsInf(k.Volume, 0) || k.Volume < 0 {
+		return false
+	}
+	return k.High >= math.Max(k.Open, k.Close) && k.Low <= math.Min(k.Open, k.Close)
+}
+
+// validateCandles 检查并修复 K 线序列：剔除非法 OHLC、按时间重排去重、校验周期间隔，
+// 小缺口按前收盘价补齐、大缺口截断，修正经成交量或振幅确认的单根尖刺（其余只标记），并标记未收盘的最后一根。
+// minBars 为指标参数完整预热所需根数（indicators.RequiredBars），修复后不足该数量（且少于请求数量）即阻断。
+// now 为零值时（模拟交易所回放历史行情）跳过依赖当前时间的检查。无法修复时返回 *CandleQualityError。
+func validateCandles(bars []models.OHLCV, timeframe string, limit, minBars int, now time.Time) ([]models.OHLCV, models.CandleQuality, error) {
+	var q models.CandleQuality
+	dur := exchange.TimeframeDuration(timeframe)
+	if dur <= 0 {
+		return nil, q, candleQualityError("candle_spacing", "不支持的K线周期: %s", timeframe)
+	}
+	required := minBars
+	if limit < required {
+		required = limit
+	}
+	if required < 2 {
+		required = 2
+	}
+
+	out := make([]models.OHLCV, 0, len(bars))
+	for _, k := range bars {
+		if isValidCandle(k) {
+			out = append(out, k)
+		}
+	}
+	if q.Invalid = len(bars) - len(out); q.Invalid > 0 {
+		if float64(q.Invalid) > candleMaxInvalidRatio*float64(len(bars)) {
+			return nil, q, candleQualityError("candle_invalid", "%d/%d 根K线价格或成交量非法", q.Invalid, len(bars))
+		}
+		q.Repairs = append(q.Repairs, fmt.Sprintf("剔除 %d 根非法K线", q.Invalid))
+	}
+
+	if !sort.SliceIsSorted(out, func(i, j int) bool { return out[i].Timestamp.Before(out[j].Timestamp) }) {
+		sort.SliceStable(out, func(i, j int) bool { return out[i].Timestamp.Before(out[j].Timestamp) })
+		q.Reordered = true
+		q.Repairs = append(q.Repairs, "按开盘时间重排")
+	}
+	// 同一开盘时间保留最后出现的一根（通常为最新更新）
+	dedup := out[:0]
+	for _, k := range out {
+		if n := len(dedup); n > 0 && dedup[n-1].Timestamp.Equal(k.Timestamp) {
+			dedup[n-1] = k
+			q.Duplicates++
+			continue
+		}
+		dedup = append(dedup, k)
+	}
+	out = dedup
+	if q.Duplicates > 0 {
+		q.Repairs = append(q.Repairs, fmt.Sprintf("合并 %d 根重复K线", q.Duplicates))
+	}
+	if len(out) < required {
+		return nil, q, candleQualityError("candle_insufficient", "有效K线 %d 根，至少需要 %d 根", len(out), required)
+	}
+
+	if !now.IsZero() {
+		last := out[len(out)-1].Timestamp
+		if last.After(now.Add(time.Minute)) {
+			return nil, q, candleQualityError("candle_future", "最新K线开盘时间 %s 晚于当前时间，请检查系统时钟", last.Format(time.RFC3339))
+		}
+		if last.Add(2 * dur).Before(now) {
+			return nil, q, candleQualityError("candle_stale", "最新K线开盘于 %s，已超过一个周期未更新", last.Format(time.RFC3339))
+		}
+		q.LastOpen = last.Add(dur).After(now)
+	}
+
+	// 补齐的 K 线，截断与按 limit 裁剪后重新计数
+	synthetic := map[time.Time]bool{}
+	// 月线长度不固定，只要求时间递增
+	if timeframe != "1M" {
+		filled := make([]models.OHLCV, 0, len(out))
+		for i, k := range out {
+			if i == 0 {
+				filled = append(filled, k)
+				continue
+			}
+			prev := filled[len(filled)-1]
+			diff := k.Timestamp.Sub(prev.Timestamp)
+			if diff%dur != 0 {
+				return nil, q, candleQualityError("candle_spacing", "%s 与 %s 间隔 %s，不是 %s 的整数倍",
+					prev.Timestamp.Format(time.RFC3339), k.Timestamp.Format(time.RFC3339), diff, timeframe)
+			}
+			if missing := int(diff/dur) - 1; missing > 0 {
+				q.Gaps++
+				if missing > candleMaxFillBars {
+					q.Truncated += len(filled)
+					filled = filled[:0]
+				} else {
+					for j := 1; j <= missing; j++ {
+						ts := prev.Timestamp.Add(time.Duration(j) * dur)
+						synthetic[ts] = true
+						filled = append(filled, models.OHLCV{
+							Timestamp: ts,
+							Open:      prev.Close,
+							High:      prev.Close,
+							Low:       prev.Close,
+							Close:     prev.Close,
+						})
+					}
+				}
+			}
+			filled = append(filled, k)
+		}
+		out = filled
+		if q.Truncated > 0 {
+			q.Repairs = append(q.Repairs, fmt.Sprintf("丢弃大缺口之前的 %d 根K线", q.Truncated))
+		}
+	}
+	if len(out) > limit && limit > 0 {
+		out = out[len(out)-limit:]
+	}
+	for _, k := range out {
+		if synthetic[k.Timestamp] {
+			q.FilledBars++
+		}
+	}
+	if q.FilledBars > 0 {
+		q.Repairs = append(q.Repairs, fmt.Sprintf("按前收盘价补齐 %d 根缺失K线", q.FilledBars))
+	}
+	if len(out) < required {
+		return nil, q, candleQualityError("candle_gap", "缺口 %d 段，截断后连续K线仅 %d 根，至少需要 %d 根", q.Gaps, len(out), required)
+	}
+	if float64(q.FilledBars) > candleMaxFillRatio*float64(len(out)) {
+		return nil, q, candleQualityError("candle_gap", "缺失K线 %d 根，超过可补齐上限", q.FilledBars)
+	}
+
+	closed := out
+	if q.LastOpen {
+		// 未收盘 K 线成交量尚在累积，不参与占位判断
+		closed = out[:len(out)-1]
+	}
+	for _, k := range closed {
+		if k.Volume == 0 && !synthetic[k.Timestamp] {
+			q.ZeroVolume++
+		}
+	}
+	if float64(q.ZeroVolume+q.FilledBars) > candleMaxZeroVolRatio*float64(len(closed)) {
+		return nil, q, candleQualityError("candle_zero_volume", "%d/%d 根已收盘K线成交量为 0（含补齐 %d 根），行情可能停滞",
+			q.ZeroVolume+q.FilledBars, len(closed), q.FilledBars)
+	}
+
+	repaired, flagged := repairCandleSpikes(out)
+	if q.Outliers = len(repaired); q.Outliers > 0 {
+		if float64(q.Outliers) > math.Max(1, candleMaxOutlierRatio*float64(len(out))) {
+			return nil, q, candleQualityError("candle_outlier", "%d 根K线收盘价异常跳变", q.Outliers)
+		}
+		q.Repairs = append(q.Repairs, fmt.Sprintf("修正 %d 根尖刺K线: %s", q.Outliers, joinCandleTimes(repaired)))
+	}
+	if q.Spikes = len(flagged); q.Spikes > 0 {
+		q.Flags = append(q.Flags, fmt.Sprintf("%d 根K线收盘价急跌急涨但成交量与振幅未确认为错误报价，保留原值: %s", q.Spikes, joinCandleTimes(flagged)))
+	}
+	q.Bars = len(out)
+	return out, q, nil
+}
+
+// repairCandleSpikes 收盘价偏离前后 K 线且下一根立即回归（远超常态波动）的 K 线视为尖刺。
+// 只有成交量未放大（不高于中位数），或下一根的高低区间未触及该收盘价（价格未连续成交）时才确认为错误报价，
+// 按前收盘与后开盘重建该 K 线；其余尖刺可能是真实的急跌急涨，只标记不改写。最后一根无法判断是否回归，不做处理。
+func repairCandleSpikes(bars []models.OHLCV) (repaired, flagged []time.Time) {
+	if len(bars) < 3 {
+		return nil, nil
+	}
+	rets := make([]float64, len(bars))
+	abs := make([]float64, 0, len(bars)-1)
+	vols := make([]float64, 0, len(bars))
+	for i := 1; i < len(bars); i++ {
+		rets[i] = math.Log(bars[i].Close / bars[i-1].Close)
+		abs = append(abs, math.Abs(rets[i]))
+	}
+	for _, k := range bars {
+		vols = append(vols, k.Volume)
+	}
+	sort.Float64s(abs)
+	sort.Float64s(vols)
+	threshold := math.Max(candleOutlierMinReturn, candleOutlierFactor*abs[len(abs)/2])
+	medianVol := vols[len(vols)/2]
+	for i := 1; i < len(bars)-1; i++ {
+		r, next := rets[i], rets[i+1]
+		if math.Abs(r) <= threshold || math.Abs(next) <= threshold || r*next >= 0 {
+			continue
+		}
+		k, after := &bars[i], bars[i+1]
+		untraded := k.Close > after.High || k.Close < after.Low
+		if k.Volume > medianVol && !untraded {
+			flagged = append(flagged, k.Timestamp)
+			continue
+		}
+		prevClose, nextOpen := bars[i-1].Close, after.Open
+		k.Open, k.Close = prevClose, nextOpen
+		k.High, k.Low = math.Max(prevClose, nextOpen), math.Min(prevClose, nextOpen)
+		// 后一根的回归收益按修正后的收盘价重算，避免连带误判
+		rets[i+1] = math.Log(after.Close / k.Close)
+		repaired = append(repaired, k.Timestamp)
+	}
+	return repaired, flagged
+}
+
+func joinCandleTimes(ts []time.Time) string {
+	parts := make([]string, len(ts))
+	for i, t := range ts {
+		parts[i] = t.UTC().Format(time.RFC3339)
+	}
+	return strings.Join(parts, ", ")
+}
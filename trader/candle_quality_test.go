@@ -0,0 +1,47 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"trade-go/models"
+)
+
+func TestSpikeRepairRequiresConfirmation(t *testing.T) {
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	series := func() []models.OHLCV {
+		bars := make([]models.OHLCV, 30)
+		for i := range bars {
+			p := 100 + 0.1*float64(i%2)
+			bars[i] = models.OHLCV{Timestamp: base.Add(time.Duration(i) * time.Hour), Open: p, High: p + 0.2, Low: p - 0.2, Close: p, Volume: 10}
+		}
+		return bars
+	}
+	spikeAt := base.Add(10 * time.Hour)
+
+	// 放量急跌且下一根从低位成交回升：可能是真实行情，只标记
+	bars := series()
+	bars[10] = models.OHLCV{Timestamp: spikeAt, Open: 100, High: 100.2, Low: 80, Close: 80, Volume: 500}
+	bars[11] = models.OHLCV{Timestamp: bars[11].Timestamp, Open: 80, High: 100.3, Low: 79.5, Close: 100.1, Volume: 400}
+	out, q, err := validateCandles(bars, "1h", len(bars), 10, time.Time{})
+	if err != nil {
+		t.Fatalf("真实急跌不应阻断: %v", err)
+	}
+	if q.Outliers != 0 || q.Spikes != 1 || out[10].Close != 80 || len(q.Flags) != 1 {
+		t.Fatalf("未确认的尖刺应保留原值并标记: %+v close=%v", q, out[10].Close)
+	}
+
+	// 成交量未放大、下一根高低区间也未触及：错误报价，修正并列出时间
+	bars = series()
+	bars[10] = models.OHLCV{Timestamp: spikeAt, Open: 100, High: 100.2, Low: 80, Close: 80, Volume: 8}
+	out, q, err = validateCandles(bars, "1h", len(bars), 10, time.Time{})
+	if err != nil {
+		t.Fatalf("单根错误报价应可修复: %v", err)
+	}
+	if q.Outliers != 1 || q.Spikes != 0 || out[10].Close != bars[11].Open {
+		t.Fatalf("确认的错误报价应按前后价格重建: %+v close=%v", q, out[10].Close)
+	}
+	if len(q.Repairs) != 1 || !strings.Contains(q.Repairs[0], spikeAt.Format(time.RFC3339)) {
+		t.Fatalf("修复记录应列出被修改 K 线的时间: %v", q.Repairs)
+	}
+}
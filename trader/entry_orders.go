@@ -0,0 +1,200 @@
+package trader
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"trade-go/config"
+	"trade-go/models"
+)
+
+func entryOrderMode(cfg config.TradeConfig) string {
+	if strings.ToLower(strings.TrimSpace(cfg.EntryOrderMode)) == "maker" {
+		return "maker"
+	}
+	return "market"
+}
+
+func makerEntryTimeout(cfg config.TradeConfig) time.Duration {
+	sec := cfg.MakerEntryTimeoutSec
+	if sec < 3 {
+		sec = 3
+	}
+	if sec > 600 {
+		sec = 600
+	}
+	return time.Duration(sec) * time.Second
+}
+
+// makerEntryPrice maker 挂单价：买单低于参考价、卖单高于参考价，偏移量按 bps 计。
+func makerEntryPrice(side string, refPrice, offsetBps float64) float64 {
+	if offsetBps < 0 {
+		offsetBps = 0
+	}
+	if side == "sell" {
+		return refPrice * (1 + offsetBps/10000)
+	}
+	return refPrice * (1 - offsetBps/10000)
+}
+
+// placeEntryOrder 按配置选择市价或 maker 开仓。
+// maker 模式下挂 post-only 限价单，超时或被拒后撤单，剩余数量转市价补齐。
+func (b *Bot) placeEntryOrder(side string, amount, refPrice float64) ([]models.OrderResult, error) {
+	cfg := b.TradeConfig()
+	if entryOrderMode(cfg) != "maker" || refPrice <= 0 {
+		od, err := b.exchange.PlaceMarketOrderWithResult(cfg.Symbol, side, amount, false)
+		if err != nil {
+			return nil, err
+		}
+		return []models.OrderResult{od}, nil
+	}
+
+	price := makerEntryPrice(side, refPrice, cfg.MakerEntryOffsetBps)
+	limitOrder, err := b.exchange.PlaceLimitOrder(cfg.Symbol, side, amount, price, models.TimeInForcePostOnly, false)
+	if err != nil {
+		fmt.Printf("maker 挂单失败，转市价: %v\n", err)
+		_ = b.saveRiskEvent("maker_entry_fallback", err.Error())
+		od, mErr := b.exchange.PlaceMarketOrderWithResult(cfg.Symbol, side, amount, false)
+		if mErr != nil {
+			return nil, mErr
+		}
+		return []models.OrderResult{od}, nil
+	}
+	if limitOrder.Symbol == "" {
+		limitOrder.Symbol = cfg.Symbol
+	}
+	fmt.Printf("maker 挂单 %s @ %.4f，超时 %s\n", limitOrder.OrderID, price, makerEntryTimeout(cfg))
+	_ = b.saveOrder(limitOrder)
+	orders := []models.OrderResult{limitOrder}
+
+	filled := 0.0
+	state := limitOrder.State
+	deadline := time.Now().Add(makerEntryTimeout(cfg))
+	for time.Now().Before(deadline) && state != "filled" && state != "canceled" {
+		time.Sleep(time.Second)
+		st, e := b.exchange.FetchOrder(cfg.Symbol, limitOrder.OrderID)
+		if e != nil || st == nil {
+			continue
+		}
+		state = st.State
+		filled = st.FilledSize
+		_ = b.saveOrderStatus(limitOrder.OrderID, st.State, st)
+	}
+	if state == "filled" {
+		return orders, nil
+	}
+	if state != "canceled" {
+		cancelErr := b.exchange.CancelOrder(cfg.Symbol, limitOrder.OrderID)
+		if cancelErr != nil {
+			fmt.Printf("撤销 maker 挂单失败: %v\n", cancelErr)
+		}
+		st, e := b.exchange.FetchOrder(cfg.Symbol, limitOrder.OrderID)
+		if e == nil && st != nil {
+			state = st.State
+			filled = st.FilledSize
+			_ = b.saveOrderStatus(limitOrder.OrderID, st.State, st)
+		}
+		if state == "filled" {
+			return orders, nil
+		}
+		// 未确认挂单已撤销时不补市价单，否则挂单后续成交会使仓位超出计划
+		if state != "canceled" {
+			reason := fmt.Sprintf("order=%s state=%s filled=%.4f", limitOrder.OrderID, state, filled)
+			if cancelErr != nil {
+				reason += " cancel_err=" + cancelErr.Error()
+			} else if e != nil {
+				reason += " fetch_err=" + e.Error()
+			}
+			_ = b.saveRiskEvent("maker_entry_cancel_unconfirmed", reason)
+			return orders, fmt.Errorf("maker 挂单 %s 未确认撤销(状态 %s)，不转市价", limitOrder.OrderID, state)
+		}
+	}
+
+	inst, instOK := b.fetchInstrument(cfg.Symbol)
+	remaining := roundPlanSize(amount-filled, inst, instOK)
+	if remaining <= 0 {
+		return orders, nil
+	}
+	if instOK && inst.MinQty > 0 && remaining < inst.MinQty {
+		fmt.Printf("剩余 %.8g 低于最小下单量 %.8g，不再补单\n", remaining, inst.MinQty)
+		return orders, nil
+	}
+	fmt.Printf("maker 挂单未完全成交(已成交 %.4f)，剩余 %.4f 转市价\n", filled, remaining)
+	_ = b.saveRiskEvent("maker_entry_fallback", fmt.Sprintf("order=%s filled=%.4f remaining=%.4f", limitOrder.OrderID, filled, remaining))
+	od, err := b.exchange.PlaceMarketOrderWithResult(cfg.Symbol, side, remaining, false)
+	if err != nil {
+		return orders, err
+	}
+	return append(orders, od), nil
+}
+
+// reconcileOpenOrders 与交易所挂单对账：
+// 本地记录但交易所已无挂单的订单补查最终状态；本机器人下的限价开仓单挂单超时则撤销。
+// 非本机器人下的挂单不做处理。
+func (b *Bot) reconcileOpenOrders() error {
+	if b.store == nil {
+		return nil
+	}
+	cfg := b.TradeConfig()
+	ids, err := b.store.OpenOrders()
+	if err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, id := range ids {
+		known[id] = true
+	}
+
+	live, liveErr := b.exchange.FetchOpenOrders(cfg.Symbol)
+	liveIDs := map[string]bool{}
+	if liveErr == nil {
+		maxAge := 2 * makerEntryTimeout(cfg)
+		for _, od := range live {
+			liveIDs[od.OrderID] = true
+			if !known[od.OrderID] || models.IsProtectiveOrderType(od.Type) {
+				continue
+			}
+			if od.Type == models.OrderTypeLimit && !od.ReduceOnly && restingOrderAge(od) > maxAge {
+				if err := b.exchange.CancelOrder(cfg.Symbol, od.OrderID); err != nil {
+					fmt.Printf("撤销超时挂单%s失败: %v\n", od.OrderID, err)
+					_ = b.saveOrderStatus(od.OrderID, od.State, od)
+					continue
+				}
+				_ = b.saveRiskEvent("resting_order_canceled", fmt.Sprintf("order=%s age>%s", od.OrderID, maxAge))
+				delete(liveIDs, od.OrderID)
+				continue
+			}
+			_ = b.saveOrderStatus(od.OrderID, od.State, od)
+		}
+	}
+
+	for _, id := range ids {
+		if liveIDs[id] {
+			continue
+		}
+		st, e := b.exchange.FetchOrder(cfg.Symbol, id)
+		if e != nil || st == nil {
+			continue
+		}
+		_ = b.saveOrderStatus(id, st.State, st)
+		if st.FilledSize > 0 && (st.State == "filled" || st.State == "canceled") {
+			fillID := fmt.Sprintf("%s-%s-%.4f", st.OrderID, st.UpdateTime, st.FilledSize)
+			_ = b.saveFill(fillID, st)
+		}
+	}
+	return liveErr
+}
+
+// restingOrderAge 挂单时长按创建时间计，部分成交会刷新更新时间；无创建时间时退回更新时间。
+func restingOrderAge(od models.OrderStatus) time.Duration {
+	ts := strings.TrimSpace(od.CreateTime)
+	if ts == "" {
+		ts = strings.TrimSpace(od.UpdateTime)
+	}
+	ms, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || ms <= 0 {
+		return 0
+	}
+	return time.Since(time.UnixMilli(ms))
+}
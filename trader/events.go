@@ -0,0 +1,22 @@
+package trader
+
+// EventHandler 接收实盘交易事件，用于向前端推送。topic 为 cycle/signal/order/fill/position/risk，
+// kind 为事件细分类型（如周期阶段、订单状态、风控事件类型）。回调在交易流程中同步执行，不应阻塞；
+// data 可被回调方保留，发布方不得再修改。
+type EventHandler func(topic, kind string, data any)
+
+// SetEventHandler 设置事件回调，nil 表示不再推送。
+func (b *Bot) SetEventHandler(h EventHandler) {
+	b.events.Store(h)
+}
+
+func (b *Bot) hasEventHandler() bool {
+	h, _ := b.events.Load().(EventHandler)
+	return h != nil
+}
+
+func (b *Bot) emit(topic, kind string, data any) {
+	if h, _ := b.events.Load().(EventHandler); h != nil {
+		h(topic, kind, data)
+	}
+}
@@ -0,0 +1,128 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"trade-go/exchange"
+)
+
+// 资金流水增量同步的最小间隔，避免每个交易周期都消耗较重的历史接口权重。
+const incomeSyncInterval = 5 * time.Minute
+
+// IncomeSyncResult 一次资金流水/成交历史同步结果。
+type IncomeSyncResult struct {
+	Exchange     string    `json:"exchange"`
+	IncomeAdded  int       `json:"income_added"`
+	FillsAdded   int       `json:"fills_added"`
+	IncomeCursor time.Time `json:"income_cursor"`
+	FillsCursor  time.Time `json:"fills_cursor"`
+	Skipped      bool      `json:"skipped"`
+	SyncedAt     time.Time `json:"synced_at"`
+	IncomeError  string    `json:"income_error,omitempty"`
+	FillsError   string    `json:"fills_error,omitempty"`
+}
+
+// SyncIncomeHistory 按交易所游标增量导入资金流水与当前交易对成交历史；
+// 首次同步回补近 7 天，force=false 时受 incomeSyncInterval 节流。
+func (b *Bot) SyncIncomeHistory(force bool) (IncomeSyncResult, error) {
+	out := IncomeSyncResult{Exchange: b.ActiveExchange(), SyncedAt: time.Now()}
+	if b.store == nil {
+		return out, fmt.Errorf("存储未初始化")
+	}
+	b.incomeSyncMu.Lock()
+	defer b.incomeSyncMu.Unlock()
+	if !force && time.Since(b.incomeSyncedAt) < incomeSyncInterval {
+		out.Skipped = true
+		return out, nil
+	}
+	b.incomeSyncedAt = time.Now()
+	cfg := b.TradeConfig()
+	backfillFrom := time.Now().Add(-7 * 24 * time.Hour)
+
+	since, ok, err := b.store.SyncCursor("income")
+	if err != nil {
+		return out, err
+	}
+	if !ok {
+		since = backfillFrom
+	}
+	events, err := b.exchange.FetchIncome(since)
+	// 翻页中途失败时已取到的部分仍入库（按 ID 去重），游标只推进到交易所确认已完整取回的位置
+	added, saveErr := b.store.SaveIncomeEvents(events)
+	out.IncomeAdded = added
+	out.IncomeCursor = since
+	if saveErr == nil {
+		var newest time.Time
+		if len(events) > 0 {
+			newest = events[len(events)-1].Time
+		}
+		out.IncomeCursor, err = nextSyncCursor(since, newest, err)
+		if out.IncomeCursor.After(since) {
+			saveErr = b.store.SetSyncCursor("income", out.IncomeCursor)
+		}
+	}
+	if err == nil {
+		err = saveErr
+	}
+	if err != nil {
+		out.IncomeError = err.Error()
+	}
+
+	fillStream := "fills:" + cfg.Symbol
+	since, ok, err = b.store.SyncCursor(fillStream)
+	if err != nil {
+		return out, err
+	}
+	if !ok {
+		since = backfillFrom
+	}
+	fills, err := b.exchange.FetchTradeFills(cfg.Symbol, since)
+	added, saveErr = b.store.SaveTradeFills(fills)
+	out.FillsAdded = added
+	out.FillsCursor = since
+	if saveErr == nil {
+		var newest time.Time
+		if len(fills) > 0 {
+			newest = fills[len(fills)-1].Time
+		}
+		out.FillsCursor, err = nextSyncCursor(since, newest, err)
+		if out.FillsCursor.After(since) {
+			saveErr = b.store.SetSyncCursor(fillStream, out.FillsCursor)
+		}
+	}
+	if err == nil {
+		err = saveErr
+	}
+	if err != nil {
+		out.FillsError = err.Error()
+	}
+	if out.IncomeError != "" {
+		return out, fmt.Errorf("同步资金流水失败: %s", out.IncomeError)
+	}
+	if out.FillsError != "" {
+		return out, fmt.Errorf("同步成交历史失败: %s", out.FillsError)
+	}
+	return out, nil
+}
+
+// nextSyncCursor 计算同步后的游标。升序翻页的交易所中途失败时，最新一条之前的记录都已取回；
+// 窗口内倒序翻页的交易所（OKX/Bybit）以 HistoryIncompleteError 报告完整区间的终点，游标不越过 Through。
+// 仅达到单次翻页上限不算失败，下次同步从 Through 继续回补。
+func nextSyncCursor(since, newest time.Time, fetchErr error) (time.Time, error) {
+	cursor := since
+	if newest.After(cursor) {
+		cursor = newest
+	}
+	var incomplete *exchange.HistoryIncompleteError
+	if errors.As(fetchErr, &incomplete) {
+		cursor = since
+		if incomplete.Through.After(since) {
+			cursor = incomplete.Through
+		}
+		if incomplete.Err == nil {
+			return cursor, nil
+		}
+	}
+	return cursor, fetchErr
+}
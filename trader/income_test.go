@@ -0,0 +1,74 @@
+package trader
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+	"trade-go/config"
+	"trade-go/exchange"
+	"trade-go/models"
+	"trade-go/storage"
+)
+
+func TestNextSyncCursor(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newest := since.Add(10 * time.Hour)
+	through := since.Add(2 * time.Hour)
+	boom := errors.New("boom")
+
+	cases := []struct {
+		name    string
+		newest  time.Time
+		err     error
+		want    time.Time
+		wantErr bool
+	}{
+		{"完整取回推进到最新记录", newest, nil, newest, false},
+		{"无新记录保持原游标", time.Time{}, nil, since, false},
+		{"升序翻页失败推进到最新记录", newest, boom, newest, true},
+		{"区间未走完不越过 Through", newest, &exchange.HistoryIncompleteError{Through: through}, through, false},
+		{"区间未走完且失败", newest, &exchange.HistoryIncompleteError{Through: through, Err: boom}, through, true},
+		{"首个窗口未走完保持原游标", newest, &exchange.HistoryIncompleteError{Through: since, Err: boom}, since, true},
+	}
+	for _, tc := range cases {
+		got, err := nextSyncCursor(since, tc.newest, tc.err)
+		if !got.Equal(tc.want) || (err != nil) != tc.wantErr {
+			t.Errorf("%s: got %s err=%v, want %s wantErr=%v", tc.name, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestSyncCursorScopedByEnvironment(t *testing.T) {
+	store, err := storage.Open(filepath.Join(t.TempDir(), "trade.db"))
+	if err != nil {
+		t.Fatalf("打开临时数据库失败: %v", err)
+	}
+	defer store.Close()
+	prev := config.Config
+	t.Cleanup(func() { config.Config = prev })
+	config.Config = &config.AppConfig{ActiveExchange: "binance", ExchangeEnv: exchange.EnvTestnet}
+
+	ts := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	if err := store.SetSyncCursor("income", ts); err != nil {
+		t.Fatalf("写入游标失败: %v", err)
+	}
+	ev := []models.IncomeEvent{{ID: "1", Type: models.IncomeRealizedPnL, Amount: 1, Asset: "USDT", Time: ts}}
+	if n, err := store.SaveIncomeEvents(ev); err != nil || n != 1 {
+		t.Fatalf("测试网流水写入失败: %d %v", n, err)
+	}
+
+	// 切回主网：不应续用测试网游标，同 ID 流水也应单独入库
+	config.Config.ExchangeEnv = exchange.EnvMainnet
+	if _, ok, err := store.SyncCursor("income"); err != nil || ok {
+		t.Fatalf("主网不应读到测试网游标: ok=%v err=%v", ok, err)
+	}
+	if n, err := store.SaveIncomeEvents(ev); err != nil || n != 1 {
+		t.Fatalf("主网流水不应被测试网记录去重: %d %v", n, err)
+	}
+
+	config.Config.ExchangeEnv = exchange.EnvTestnet
+	if got, ok, err := store.SyncCursor("income"); err != nil || !ok || !got.Equal(ts) {
+		t.Fatalf("测试网游标应保留: %s ok=%v err=%v", got, ok, err)
+	}
+}
@@ -0,0 +1,50 @@
+package trader
+
+import (
+	"trade-go/config"
+	"trade-go/indicators"
+	"trade-go/models"
+)
+
+// IndicatorParamsResolver 解析一次行情读取使用的指标参数（全局 → 习惯档位 → 生成策略），
+// strategies 为空表示实盘当前启用的策略；source 记录生效的配置层级。
+type IndicatorParamsResolver func(cfg config.TradeConfig, strategies []string) (p models.IndicatorParams, source string)
+
+// SetIndicatorParamsResolver 设置指标参数解析，nil 表示只使用全局配置。
+func (b *Bot) SetIndicatorParamsResolver(r IndicatorParamsResolver) {
+	b.indicatorResolver.Store(r)
+}
+
+// IndicatorParamsFromConfig 系统设置中的全局指标参数，未配置项取默认值。
+func IndicatorParamsFromConfig(cfg config.TradeConfig) models.IndicatorParams {
+	return indicators.NormalizeParams(models.IndicatorParams{
+		SMAFast:        cfg.SMAFastPeriod,
+		SMAShort:       cfg.ShortTermPeriod,
+		SMAMedium:      cfg.MediumTermPeriod,
+		SMALong:        cfg.LongTermPeriod,
+		EMAFast:        cfg.EMAFastPeriod,
+		EMASlow:        cfg.EMASlowPeriod,
+		MACDSignal:     cfg.MACDSignalPeriod,
+		RSIPeriod:      cfg.RSIPeriod,
+		BBPeriod:       cfg.BBPeriod,
+		BBMultiplier:   cfg.BBMultiplier,
+		VolumeMAPeriod: cfg.VolumeMAPeriod,
+		SRLookback:     cfg.SRLookback,
+	})
+}
+
+func (b *Bot) resolveIndicatorParams(cfg config.TradeConfig, strategies []string) (models.IndicatorParams, string) {
+	if r, _ := b.indicatorResolver.Load().(IndicatorParamsResolver); r != nil {
+		p, source := r(cfg, strategies)
+		return indicators.NormalizeParams(p), source
+	}
+	return IndicatorParamsFromConfig(cfg), "global"
+}
+
+// indicatorFetchLimit 配置的 K 线数不足以覆盖最长指标周期时按指标需要拉取。
+func indicatorFetchLimit(dataPoints int, p models.IndicatorParams) int {
+	if need := indicators.RequiredBars(p); need > dataPoints {
+		return need
+	}
+	return dataPoints
+}
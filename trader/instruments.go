@@ -0,0 +1,57 @@
+package trader
+
+import (
+	"fmt"
+	"math"
+	"trade-go/models"
+	"trade-go/risk"
+)
+
+// fetchInstrument 读取交易对规格；失败时返回 false，调用方按无规格处理。
+func (b *Bot) fetchInstrument(symbol string) (models.Instrument, bool) {
+	inst, err := b.exchange.FetchInstrument(symbol)
+	if err != nil {
+		fmt.Printf("读取交易对规格失败: %v\n", err)
+		return models.Instrument{}, false
+	}
+	return inst, true
+}
+
+// roundPlanSize 有规格时按步长取整，否则沿用 4 位小数精度保护。
+func roundPlanSize(size float64, inst models.Instrument, ok bool) float64 {
+	if ok && inst.StepSize > 0 {
+		return inst.RoundSize(size)
+	}
+	return math.Round(size*10000) / 10000
+}
+
+// sizeStepEpsilon 按步长比例判断数量是否已在步长上，容忍浮点运算尾差。
+const sizeStepEpsilon = 1e-6
+
+// onStep 数量与最近的步长整数倍相差不足步长的 sizeStepEpsilon 倍时视为已对齐。
+func onStep(size float64, inst models.Instrument) bool {
+	return math.Abs(models.RoundToStep(size, inst.StepSize)-size) <= inst.StepSize*sizeStepEpsilon
+}
+
+// checkInstrumentLimits 下单前按交易所规格校验数量、名义价值与杠杆。
+func checkInstrumentLimits(inst models.Instrument, size, price float64, leverage int, out map[string]any) (bool, string, string) {
+	out["instrument"] = inst
+	if inst.StepSize > 0 && !onStep(size, inst) {
+		out["reason"] = "size_not_on_step"
+		out["rounded_amount"] = inst.RoundSize(size)
+		return false, "order_plan_invalid", fmt.Sprintf("数量 %.8g 不符合步长 %.8g", size, inst.StepSize)
+	}
+	if inst.MinQty > 0 && size < inst.MinQty {
+		out["reason"] = risk.CodeBelowMinQty
+		return false, risk.CodeBelowMinQty, fmt.Sprintf("数量 %.8g 低于最小下单量 %.8g", size, inst.MinQty)
+	}
+	if inst.MinNotional > 0 && size*price < inst.MinNotional {
+		out["reason"] = risk.CodeBelowMinNotional
+		return false, risk.CodeBelowMinNotional, fmt.Sprintf("名义价值 %.4f 低于最小下单金额 %.4f", size*price, inst.MinNotional)
+	}
+	if inst.MaxLeverage > 0 && float64(leverage) > inst.MaxLeverage {
+		out["reason"] = "leverage_exceeds_max"
+		return false, "leverage_exceeds_max", fmt.Sprintf("杠杆 %d 超过交易所上限 %.0f", leverage, inst.MaxLeverage)
+	}
+	return true, "ok", ""
+}
@@ -0,0 +1,168 @@
+package trader
+
+import (
+	"fmt"
+	"time"
+	"trade-go/exchange"
+	"trade-go/models"
+	"trade-go/storage"
+)
+
+// KlineArchiveResult 归档读取结果。Warning 非空表示部分区间未能补齐，Bars 仅含已归档部分。
+type KlineArchiveResult struct {
+	Source        string         `json:"source"`
+	Bars          []models.OHLCV `json:"-"`
+	Fetched       int            `json:"fetched"`
+	MissingRanges int            `json:"missing_ranges"`
+	Warning       string         `json:"warning,omitempty"`
+}
+
+type klineRange struct {
+	start time.Time
+	end   time.Time
+}
+
+// ArchivedKlines 读取当前交易所 [start, end] 内已收盘的 K 线：优先使用 SQLite 归档，
+// 只下载缺失区间并写回归档；下载失败（如离线）时返回已归档部分并附带提示。
+func (b *Bot) ArchivedKlines(symbol, timeframe string, start, end time.Time) (KlineArchiveResult, error) {
+	b.mu.RLock()
+	client, store := b.exchange, b.store
+	b.mu.RUnlock()
+	out := KlineArchiveResult{Source: client.KlineSource()}
+	dur := exchange.TimeframeDuration(timeframe)
+	if dur <= 0 {
+		return out, fmt.Errorf("不支持的K线周期: %s", timeframe)
+	}
+	symbol = exchange.NormalizeSymbol(symbol)
+	// 只归档已收盘的 K 线
+	if last := time.Now().Add(-dur); end.After(last) {
+		end = last
+	}
+	if store == nil {
+		bars, err := client.FetchOHLCVRange(symbol, timeframe, start, end)
+		out.Bars, out.Fetched = bars, len(bars)
+		return out, err
+	}
+
+	stored, err := store.Klines(out.Source, symbol, timeframe, start, end)
+	if err != nil {
+		return out, err
+	}
+	missing := func() ([]klineRange, error) {
+		covered, err := store.KlineCoverage(out.Source, symbol, timeframe, start, end)
+		if err != nil {
+			return nil, err
+		}
+		return subtractKlineRanges(missingKlineRanges(stored, start, end, dur), covered), nil
+	}
+	ranges, err := missing()
+	if err != nil {
+		return out, err
+	}
+	var fetchErr error
+	for _, r := range ranges {
+		fetchedAt := time.Now()
+		bars, err := client.FetchOHLCVRange(symbol, timeframe, r.start, r.end)
+		n, saveErr := store.SaveKlines(out.Source, symbol, timeframe, bars)
+		out.Fetched += n
+		if err == nil {
+			err = saveErr
+		}
+		if err == nil {
+			// 交易所可能稍晚才提供刚收盘的 K 线，最近两个周期不记为已覆盖，下次仍会重试
+			covEnd := r.end
+			if recent := fetchedAt.Add(-2 * dur); covEnd.After(recent) {
+				covEnd = recent
+			}
+			err = store.SaveKlineCoverage(out.Source, symbol, timeframe, r.start, covEnd)
+		}
+		if err != nil {
+			fetchErr = err
+			break
+		}
+	}
+	if out.Fetched > 0 {
+		if stored, err = store.Klines(out.Source, symbol, timeframe, start, end); err != nil {
+			return out, err
+		}
+	}
+	out.Bars = stored
+	if fetchErr != nil {
+		if len(stored) == 0 {
+			return out, fetchErr
+		}
+		if ranges, err = missing(); err != nil {
+			return out, err
+		}
+		out.MissingRanges = len(ranges)
+		out.Warning = fmt.Sprintf("部分区间下载失败，已使用归档数据（缺失 %d 段）: %v", out.MissingRanges, fetchErr)
+	}
+	return out, nil
+}
+
+// RecentArchivedKlines 最近 limit 根已收盘 K 线，经归档读取；归档不可用（如模拟交易所不支持区间查询）时回退最新 K 线接口。
+func (b *Bot) RecentArchivedKlines(symbol, timeframe string, limit int) ([]models.OHLCV, error) {
+	dur := exchange.TimeframeDuration(timeframe)
+	if dur <= 0 || limit <= 0 {
+		return nil, fmt.Errorf("不支持的K线周期: %s", timeframe)
+	}
+	end := time.Now()
+	res, err := b.ArchivedKlines(symbol, timeframe, end.Add(-time.Duration(limit+1)*dur), end)
+	if err != nil {
+		b.mu.RLock()
+		client := b.exchange
+		b.mu.RUnlock()
+		return client.FetchOHLCV(symbol, timeframe, limit)
+	}
+	bars := res.Bars
+	if len(bars) > limit {
+		bars = bars[len(bars)-limit:]
+	}
+	return bars, nil
+}
+
+// missingKlineRanges 按已有 K 线推断缺失区间：头部、尾部以及相邻 K 线间隔超过 1.5 个周期之处。
+// 不依赖周期对齐方式（如 OKX 日线按 UTC+8 划分）。
+func missingKlineRanges(bars []models.OHLCV, start, end time.Time, dur time.Duration) []klineRange {
+	if end.Before(start) {
+		return nil
+	}
+	if len(bars) == 0 {
+		return []klineRange{{start, end}}
+	}
+	var out []klineRange
+	if first := bars[0].Timestamp; !first.Add(-dur).Before(start) {
+		out = append(out, klineRange{start, first.Add(-time.Millisecond)})
+	}
+	for i := 1; i < len(bars); i++ {
+		prev, next := bars[i-1].Timestamp, bars[i].Timestamp
+		if next.Sub(prev) > dur*3/2 {
+			out = append(out, klineRange{prev.Add(time.Millisecond), next.Add(-time.Millisecond)})
+		}
+	}
+	if last := bars[len(bars)-1].Timestamp; !last.Add(dur).After(end) {
+		out = append(out, klineRange{last.Add(time.Millisecond), end})
+	}
+	return out
+}
+
+// subtractKlineRanges 从缺失区间中扣除已下载过的区间（毫秒精度，区间两端均为闭区间）。
+func subtractKlineRanges(ranges []klineRange, covered []storage.KlineWindow) []klineRange {
+	for _, c := range covered {
+		next := ranges[:0:0]
+		for _, r := range ranges {
+			if c.End.Before(r.start) || c.Start.After(r.end) {
+				next = append(next, r)
+				continue
+			}
+			if c.Start.After(r.start) {
+				next = append(next, klineRange{r.start, c.Start.Add(-time.Millisecond)})
+			}
+			if c.End.Before(r.end) {
+				next = append(next, klineRange{c.End.Add(time.Millisecond), r.end})
+			}
+		}
+		ranges = next
+	}
+	return ranges
+}
@@ -0,0 +1,50 @@
+package trader
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+	"trade-go/models"
+	"trade-go/storage"
+)
+
+func TestKlineCoverageSkipsAlreadyRequestedGaps(t *testing.T) {
+	store, err := storage.Open(filepath.Join(t.TempDir(), "trade.db"))
+	if err != nil {
+		t.Fatalf("打开临时数据库失败: %v", err)
+	}
+	defer store.Close()
+
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return base.Add(time.Duration(h) * time.Hour) }
+	start, end := at(0), at(23)
+	// 只有 10:00~13:00 的 K 线：头部为上市前、14:00 之后为交易所缺口
+	var bars []models.OHLCV
+	for h := 10; h <= 13; h++ {
+		bars = append(bars, models.OHLCV{Timestamp: at(h), Open: 1, High: 1, Low: 1, Close: 1})
+	}
+	if got := missingKlineRanges(bars, start, end, time.Hour); len(got) != 2 {
+		t.Fatalf("应推断出头尾两段缺失: %+v", got)
+	}
+
+	// 两段都已向交易所请求过（返回为空），分两次记录且相邻区间应合并
+	for _, w := range [][2]time.Time{{at(0), at(10).Add(-time.Millisecond)}, {at(13), at(18)}, {at(18).Add(time.Millisecond), at(23)}} {
+		if err := store.SaveKlineCoverage("binance", "BTCUSDT", "1h", w[0], w[1]); err != nil {
+			t.Fatalf("记录覆盖区间失败: %v", err)
+		}
+	}
+	covered, err := store.KlineCoverage("binance", "BTCUSDT", "1h", start, end)
+	if err != nil || len(covered) != 2 {
+		t.Fatalf("相邻区间应合并为两段: %+v %v", covered, err)
+	}
+	if got := subtractKlineRanges(missingKlineRanges(bars, start, end, time.Hour), covered); len(got) != 0 {
+		t.Fatalf("已请求过的区间不应再次下载: %+v", got)
+	}
+
+	// 覆盖区间只扣除交集部分
+	later := at(30)
+	got := subtractKlineRanges([]klineRange{{at(20), later}}, covered)
+	if len(got) != 1 || !got[0].start.Equal(at(23).Add(time.Millisecond)) || !got[0].end.Equal(later) {
+		t.Fatalf("应只保留覆盖区间之后的部分: %+v", got)
+	}
+}
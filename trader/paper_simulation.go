@@ -85,13 +85,14 @@ func (b *Bot) RunPaperSimulation(in PaperSimulationInput) (PaperSimulationResult
 	cycleID := fmt.Sprintf("paper_cycle_%d", now.UnixNano())
 
 	marketReadAt := time.Now()
-	pd, err := b.fetchPriceDataByConfig(simCfg)
+	pd, err := b.fetchPriceDataByConfig(simCfg, out.EnabledStrategies)
 	if err != nil {
-		b.saveSkillStepAudit(cycleID, "market-read", "failed", "market_unavailable", "", marketReadAt,
+		code := marketReadFailureCode(err)
+		b.saveSkillStepAudit(cycleID, "market-read", "failed", code, "", marketReadAt,
 			map[string]any{"symbol": simCfg.Symbol, "timeframe": simCfg.Timeframe, "paper": true},
 			map[string]any{"error": err.Error()},
 			"blocked")
-		out.RiskReason = "market_unavailable"
+		out.RiskReason = code
 		out.ExecutionCode = "paper_market_unavailable"
 		return out, err
 	}
@@ -105,9 +106,12 @@ func (b *Bot) RunPaperSimulation(in PaperSimulationInput) (PaperSimulationResult
 	b.saveSkillStepAudit(cycleID, "market-read", "ok", "ok", "", marketReadAt,
 		map[string]any{"symbol": simCfg.Symbol, "timeframe": simCfg.Timeframe, "paper": true},
 		map[string]any{
-			"price":        pd.Price,
-			"price_change": pd.PriceChange,
-			"timestamp":    pd.Timestamp.Format(time.RFC3339),
+			"price":            pd.Price,
+			"price_change":     pd.PriceChange,
+			"timestamp":        pd.Timestamp.Format(time.RFC3339),
+			"quality":          pd.Quality,
+			"indicator_params": pd.Indicators,
+			"indicator_source": pd.IndicatorSource,
 		},
 		"continue")
 
@@ -187,9 +191,10 @@ func (b *Bot) RunPaperSimulation(in PaperSimulationInput) (PaperSimulationResult
 	if snapshot.ConsecutiveLosses < 0 {
 		snapshot.ConsecutiveLosses = 0
 	}
-	tradeAmount, allow, riskReason := buildRiskPositionByConfig(signal, pd, simCfg, snapshot)
+	inst, instOK := b.fetchInstrument(simCfg.Symbol)
+	tradeAmount, allow, riskCode, riskReason := buildRiskPositionByConfig(signal, pd, simCfg, snapshot, inst, instOK)
 	if !allow {
-		b.saveSkillStepAudit(cycleID, "risk-plan", "failed", "risk_blocked", "", riskPlanAt,
+		b.saveSkillStepAudit(cycleID, "risk-plan", "failed", riskCode, "", riskPlanAt,
 			map[string]any{"signal": signal, "price": pd.Price, "paper": true},
 			map[string]any{"approved": false, "reason": riskReason},
 			"blocked")
@@ -203,7 +208,7 @@ func (b *Bot) RunPaperSimulation(in PaperSimulationInput) (PaperSimulationResult
 		"continue")
 
 	orderPlanAt := time.Now()
-	planOK, planCode, planReason, planOutput := preflightOrderPlanByConfig(signal, pd, tradeAmount, simCfg, balance)
+	planOK, planCode, planReason, planOutput := preflightOrderPlanByConfig(signal, pd, tradeAmount, simCfg, balance, inst, instOK)
 	out.OrderPlan = planOutput
 	if !planOK {
 		b.saveSkillStepAudit(cycleID, "order-plan", "failed", planCode, "", orderPlanAt,
@@ -291,16 +296,14 @@ func (b *Bot) buildPaperConfig(in PaperSimulationInput) config.TradeConfig {
 	return cfg
 }
 
-func (b *Bot) fetchPriceDataByConfig(cfg config.TradeConfig) (models.PriceData, error) {
-	candles, err := b.exchange.FetchOHLCV(cfg.Symbol, cfg.Timeframe, cfg.DataPoints)
+func (b *Bot) fetchPriceDataByConfig(cfg config.TradeConfig, strategies []string) (models.PriceData, error) {
+	params, source := b.resolveIndicatorParams(cfg, strategies)
+	candles, quality, err := b.fetchCheckedCandles(cfg.Symbol, cfg.Timeframe, cfg.DataPoints, params)
 	if err != nil {
 		return models.PriceData{}, err
 	}
-	if len(candles) < 2 {
-		return models.PriceData{}, fmt.Errorf("K线数据不足")
-	}
 
-	ind := indicators.Calculate(candles)
+	ind := indicators.CalculateWithParams(candles, params)
 	trend := indicators.AnalyzeTrend(candles, ind)
 	levels := indicators.AnalyzeLevels(candles, ind)
 
@@ -309,18 +312,21 @@ func (b *Bot) fetchPriceDataByConfig(cfg config.TradeConfig) (models.PriceData,
 	priceChange := (cur.Close - prev.Close) / prev.Close * 100
 
 	return models.PriceData{
-		Symbol:      cfg.Symbol,
-		Price:       cur.Close,
-		Timestamp:   cur.Timestamp,
-		High:        cur.High,
-		Low:         cur.Low,
-		Volume:      cur.Volume,
-		Timeframe:   cfg.Timeframe,
-		PriceChange: priceChange,
-		KlineData:   candles,
-		Technical:   ind,
-		Trend:       trend,
-		Levels:      levels,
+		Symbol:          cfg.Symbol,
+		Price:           cur.Close,
+		Timestamp:       cur.Timestamp,
+		High:            cur.High,
+		Low:             cur.Low,
+		Volume:          cur.Volume,
+		Timeframe:       cfg.Timeframe,
+		PriceChange:     priceChange,
+		KlineData:       candles,
+		Technical:       ind,
+		Trend:           trend,
+		Levels:          levels,
+		Quality:         quality,
+		Indicators:      params,
+		IndicatorSource: source,
 	}, nil
 }
 
@@ -349,12 +355,12 @@ func (b *Bot) analyzeWithRetryWithStrategies(pd models.PriceData, pos *models.Po
 	return fb
 }
 
-func buildRiskPositionByConfig(signal models.TradeSignal, pd models.PriceData, cfg config.TradeConfig, snapshot risk.Snapshot) (float64, bool, string) {
+func buildRiskPositionByConfig(signal models.TradeSignal, pd models.PriceData, cfg config.TradeConfig, snapshot risk.Snapshot, inst models.Instrument, instOK bool) (float64, bool, string, string) {
 	if strings.ToUpper(strings.TrimSpace(signal.Signal)) == "HOLD" {
-		return 0, true, ""
+		return 0, true, "ok", ""
 	}
 	if !isPositiveNumber(snapshot.Balance) {
-		return 0, false, "模拟保证金无效"
+		return 0, false, "risk_blocked", "模拟保证金无效"
 	}
 	suggested := suggestedAmountByConfidence(signal.Confidence, cfg, snapshot.Balance, pd.Price)
 	engine := risk.NewEngine(&cfg)
@@ -364,18 +370,21 @@ func buildRiskPositionByConfig(signal models.TradeSignal, pd models.PriceData, c
 		Confidence:    signal.Confidence,
 		SuggestedSize: suggested,
 		Leverage:      cfg.Leverage,
+		StepSize:      inst.StepSize,
+		MinQty:        inst.MinQty,
+		MinNotional:   inst.MinNotional,
 	}, snapshot)
 	if !plan.Approved {
-		return 0, false, plan.Reason
+		return 0, false, riskPlanCode(plan), plan.Reason
 	}
-	size := math.Round(plan.Size*10000) / 10000
+	size := roundPlanSize(plan.Size, inst, instOK)
 	if size <= 0 {
-		return 0, false, "风控后仓位过小"
+		return 0, false, "risk_blocked", "风控后仓位过小"
 	}
-	return size, true, ""
+	return size, true, "ok", ""
 }
 
-func preflightOrderPlanByConfig(signal models.TradeSignal, pd models.PriceData, tradeAmount float64, cfg config.TradeConfig, balance float64) (bool, string, string, map[string]any) {
+func preflightOrderPlanByConfig(signal models.TradeSignal, pd models.PriceData, tradeAmount float64, cfg config.TradeConfig, balance float64, inst models.Instrument, instOK bool) (bool, string, string, map[string]any) {
 	out := map[string]any{
 		"signal":       signal.Signal,
 		"confidence":   signal.Confidence,
@@ -400,6 +409,11 @@ func preflightOrderPlanByConfig(signal models.TradeSignal, pd models.PriceData,
 		out["reason"] = "invalid_balance"
 		return false, "balance_unavailable", "模拟保证金无效", out
 	}
+	if instOK {
+		if ok, code, reason := checkInstrumentLimits(inst, tradeAmount, pd.Price, cfg.Leverage, out); !ok {
+			return false, code, reason, out
+		}
+	}
 	requiredMargin := pd.Price * tradeAmount / float64(cfg.Leverage)
 	out["balance"] = balance
 	out["required_margin"] = requiredMargin
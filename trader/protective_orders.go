@@ -0,0 +1,167 @@
+package trader
+
+import (
+	"fmt"
+	"strings"
+	"trade-go/models"
+	"trade-go/storage"
+)
+
+// attachProtectiveOrders 在开仓成交确认（filled > 0）后挂出交易所侧止损/止盈（只减仓）。
+// 同一持仓腿已存在的保护单会先撤销，新的括号单按成交后整条腿的持仓量 pos.Size 挂出，
+// 加仓时原有仓位同样受保护，保证每条腿同一时间只有一组覆盖全部持仓的括号单。
+func (b *Bot) attachProtectiveOrders(signal models.TradeSignal, pos *models.Position, filled float64) {
+	if pos == nil || pos.Size <= 0 || filled <= 0 {
+		return
+	}
+	size := pos.Size
+	symbol := pos.Symbol
+	if strings.TrimSpace(symbol) == "" {
+		symbol = b.TradeConfig().Symbol
+	}
+	closeSide := "sell"
+	if pos.Side == "short" {
+		closeSide = "buy"
+	}
+	b.cancelProtectiveOrders(symbol, closeSide)
+	ref := pos.EntryPrice
+	legs := []struct {
+		orderType string
+		price     float64
+		valid     bool
+	}{
+		{models.OrderTypeStopMarket, signal.StopLoss, signal.StopLoss > 0 && (ref <= 0 || (pos.Side == "long" && signal.StopLoss < ref) || (pos.Side == "short" && signal.StopLoss > ref))},
+		{models.OrderTypeTakeProfitMarket, signal.TakeProfit, signal.TakeProfit > 0 && (ref <= 0 || (pos.Side == "long" && signal.TakeProfit > ref) || (pos.Side == "short" && signal.TakeProfit < ref))},
+	}
+	for _, leg := range legs {
+		if !leg.valid {
+			msg := fmt.Sprintf("跳过%s: 触发价 %.4f 与持仓方向/开仓价 %.4f 不匹配", leg.orderType, leg.price, ref)
+			fmt.Println(msg)
+			_ = b.saveRiskEvent("protective_order_skipped", msg)
+			continue
+		}
+		od, err := b.exchange.PlaceProtectiveOrder(symbol, closeSide, leg.orderType, size, leg.price)
+		if err != nil {
+			fmt.Printf("挂出%s失败: %v\n", leg.orderType, err)
+			_ = b.saveRiskEvent("protective_order_error", fmt.Sprintf("%s: %v", leg.orderType, err))
+			continue
+		}
+		if od.Symbol == "" {
+			od.Symbol = symbol
+		}
+		fmt.Printf("已挂出%s: %s @ %.4f\n", leg.orderType, od.OrderID, leg.price)
+		_ = b.saveOrder(od)
+		b.trackProtectiveOrder(storage.ProtectiveOrder{
+			OrderID:   od.OrderID,
+			Symbol:    od.Symbol,
+			Side:      od.Side,
+			OrderType: od.Type,
+			Size:      od.Size,
+		})
+	}
+}
+
+// cancelProtectiveOrders 撤销仍挂着的保护单（反手/平仓时调用）。
+// closeSide 非空时只撤销该平仓方向（即对应持仓腿）的保护单，为空时全部撤销。
+func (b *Bot) cancelProtectiveOrders(symbol, closeSide string) {
+	for _, od := range b.openProtectiveOrders() {
+		if closeSide != "" && od.Side != closeSide {
+			continue
+		}
+		sym := od.Symbol
+		if sym == "" {
+			sym = symbol
+		}
+		if err := b.exchange.CancelProtectiveOrder(sym, od.OrderID); err != nil {
+			// 已触发或已被交易所撤销的条件单无法再撤，以查询结果为准。
+			if st, e := b.exchange.FetchProtectiveOrder(sym, od.OrderID); e == nil && st != nil && st.State != "live" {
+				_ = b.saveOrderStatus(od.OrderID, st.State, st)
+				b.untrackProtectiveOrder(od.OrderID)
+				continue
+			}
+			fmt.Printf("撤销保护单%s失败: %v\n", od.OrderID, err)
+			_ = b.saveRiskEvent("protective_order_cancel_error", fmt.Sprintf("%s: %v", od.OrderID, err))
+			continue
+		}
+		_ = b.saveOrderStatus(od.OrderID, "canceled", od)
+		b.untrackProtectiveOrder(od.OrderID)
+	}
+}
+
+// syncProtectiveOrders 刷新保护单状态；某条持仓腿已不存在时撤销其残留的保护单。
+func (b *Bot) syncProtectiveOrders(legs []models.Position) {
+	orders := b.openProtectiveOrders()
+	if len(orders) == 0 {
+		return
+	}
+	symbol := b.TradeConfig().Symbol
+	for _, od := range orders {
+		sym := od.Symbol
+		if sym == "" {
+			sym = symbol
+		}
+		st, err := b.exchange.FetchProtectiveOrder(sym, od.OrderID)
+		if err != nil || st == nil {
+			continue
+		}
+		if st.State != "live" {
+			_ = b.saveOrderStatus(od.OrderID, st.State, st)
+			b.untrackProtectiveOrder(od.OrderID)
+			if st.State == "filled" {
+				_ = b.saveRiskEvent("protective_order_triggered", fmt.Sprintf("%s %s", od.OrderType, od.OrderID))
+			}
+		}
+	}
+	if models.PositionLeg(legs, "long") == nil {
+		b.cancelProtectiveOrders(symbol, "sell")
+	}
+	if models.PositionLeg(legs, "short") == nil {
+		b.cancelProtectiveOrders(symbol, "buy")
+	}
+}
+
+func (b *Bot) openProtectiveOrders() []storage.ProtectiveOrder {
+	b.mu.RLock()
+	out := append([]storage.ProtectiveOrder(nil), b.protectiveOrders...)
+	store := b.store
+	b.mu.RUnlock()
+	if store == nil {
+		return out
+	}
+	rows, err := store.OpenProtectiveOrders()
+	if err != nil {
+		return out
+	}
+	seen := map[string]bool{}
+	for _, od := range out {
+		seen[od.OrderID] = true
+	}
+	for _, od := range rows {
+		if !seen[od.OrderID] {
+			seen[od.OrderID] = true
+			out = append(out, od)
+		}
+	}
+	return out
+}
+
+func (b *Bot) trackProtectiveOrder(od storage.ProtectiveOrder) {
+	if od.OrderID == "" {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.protectiveOrders = append(b.protectiveOrders, od)
+}
+
+func (b *Bot) untrackProtectiveOrder(orderID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	kept := b.protectiveOrders[:0]
+	for _, od := range b.protectiveOrders {
+		if od.OrderID != orderID {
+			kept = append(kept, od)
+		}
+	}
+	b.protectiveOrders = kept
+}
@@ -0,0 +1,266 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+	"trade-go/config"
+	"trade-go/exchange"
+	"trade-go/indicators"
+	"trade-go/models"
+	"trade-go/storage"
+)
+
+const simTestSymbol = "BTCUSDT"
+
+// fakeAI 本地 OpenAI 兼容接口，固定返回 reply 中的信号 JSON。
+type fakeAI struct {
+	mu    sync.Mutex
+	reply string
+	calls int
+}
+
+func (f *fakeAI) set(reply string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reply = reply
+}
+
+func (f *fakeAI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.calls++
+	content := f.reply
+	f.mu.Unlock()
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": content}}},
+	})
+}
+
+// simCandles 生成 n 根 15m 平稳上行 K 线，收盘价从 base 起每根上涨 step。
+func simCandles(start time.Time, n int, base, step float64) []models.OHLCV {
+	out := make([]models.OHLCV, 0, n)
+	for i := 0; i < n; i++ {
+		open := base + float64(i)*step
+		closePx := open + step
+		out = append(out, models.OHLCV{
+			Timestamp: start.Add(time.Duration(i) * 15 * time.Minute),
+			Open:      open,
+			High:      closePx + 0.2,
+			Low:       open - 0.2,
+			Close:     closePx,
+			Volume:    100 + float64(i%7),
+		})
+	}
+	return out
+}
+
+// newSimBot 离线环境：模拟交易所喂入 K 线、本地 AI 替身、临时 SQLite，全程不访问网络。
+func newSimBot(t *testing.T) (*Bot, *exchange.SimExchange, *fakeAI, *storage.Store) {
+	t.Helper()
+	ai := &fakeAI{}
+	aiSrv := httptest.NewServer(ai)
+	t.Cleanup(aiSrv.Close)
+
+	t.Setenv("ACTIVE_EXCHANGE", "sim")
+	t.Setenv("ENABLED_STRATEGIES", "")
+	prev := config.Config
+	t.Cleanup(func() { config.Config = prev })
+	config.Load()
+	config.Config.ActiveExchange = "sim"
+	config.Config.AIAPIKey = "test"
+	config.Config.AIBaseURL = aiSrv.URL
+	config.Config.AIModel = "test-model"
+	trade := &config.Config.Trade
+	trade.Symbol = simTestSymbol
+	trade.Timeframe = "15m"
+	trade.TestMode = false
+	trade.DataPoints = 120
+	trade.Leverage = 10
+	trade.MarginMode = models.MarginModeCross
+	trade.PositionSizingMode = "contracts"
+	trade.HighConfidenceAmount = 1
+	trade.LowConfidenceAmount = 0.5
+	trade.EntryOrderMode = "market"
+	trade.AutoReviewEnabled = false
+	trade.AutoStrategyRegenEnabled = false
+
+	sim := exchange.NewSimExchange(exchange.SimConfig{
+		InitialBalance: 100000,
+		TakerFeeRate:   0.0005,
+		MakerFeeRate:   0.0002,
+	})
+	sim.FeedCandles(simTestSymbol, simCandles(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 120, 100, 0.05)...)
+
+	store, err := storage.Open(filepath.Join(t.TempDir(), "trade.db"))
+	if err != nil {
+		t.Fatalf("打开临时数据库失败: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	bot := NewBot()
+	bot.SetExchangeClient(exchange.NewSimClient(sim))
+	bot.SetStore(store)
+	if err := bot.Setup(); err != nil {
+		t.Fatalf("Setup 失败: %v", err)
+	}
+	return bot, sim, ai, store
+}
+
+func signalJSON(signal string, stopLoss, takeProfit float64) string {
+	return fmt.Sprintf(`{"signal":%q,"reason":"offline test","stop_loss":%.2f,"take_profit":%.2f,"confidence":"HIGH","strategy_combo":"sim_test"}`,
+		signal, stopLoss, takeProfit)
+}
+
+func TestSimBotRunOpensPositionWithBrackets(t *testing.T) {
+	bot, sim, ai, store := newSimBot(t)
+	ai.set(signalJSON("BUY", 100, 115))
+
+	bot.Run()
+
+	if snap := bot.Snapshot(); snap.LastError != "" {
+		t.Fatalf("交易周期失败: %s", snap.LastError)
+	}
+	legs, err := sim.FetchPositions(simTestSymbol)
+	if err != nil || len(legs) != 1 || legs[0].Side != "long" || legs[0].Size != 1 {
+		t.Fatalf("应开多 1 个单位: %+v %v", legs, err)
+	}
+	open, _ := sim.FetchOpenOrders(simTestSymbol)
+	types := map[string]float64{}
+	for _, od := range open {
+		types[od.Type] = od.Size
+	}
+	if types[models.OrderTypeStopMarket] != 1 || types[models.OrderTypeTakeProfitMarket] != 1 {
+		t.Fatalf("应挂出与成交量一致的止损/止盈: %+v", open)
+	}
+	if all, err := bot.FetchAllPositions(); err != nil || len(all) != 1 || all[0].External {
+		t.Fatalf("机器人自己开的仓不应标记为外部持仓: %+v %v", all, err)
+	}
+	tracked, err := store.OpenProtectiveOrders()
+	if err != nil || len(tracked) != 2 {
+		t.Fatalf("保护单应写入本地记录: %+v %v", tracked, err)
+	}
+
+	// 价格跌破止损：交易所侧触发平仓，下一轮对账清理本地保护单记录
+	last := simCandles(time.Date(2024, 1, 2, 6, 0, 0, 0, time.UTC), 1, 99, -0.5)
+	sim.FeedCandles(simTestSymbol, last...)
+	if legs, _ := sim.FetchPositions(simTestSymbol); len(legs) != 0 {
+		t.Fatalf("止损触发后应无持仓: %+v", legs)
+	}
+	ai.set(signalJSON("HOLD", 0, 0))
+	bot.Run()
+	tracked, err = store.OpenProtectiveOrders()
+	if err != nil || len(tracked) != 0 {
+		t.Fatalf("对账后不应残留保护单记录: %+v %v", tracked, err)
+	}
+	if open, _ := sim.FetchOpenOrders(simTestSymbol); len(open) != 0 {
+		t.Fatalf("止盈单应随持仓关闭撤销: %+v", open)
+	}
+}
+
+func TestSimReconcileCancelsStaleRestingOrder(t *testing.T) {
+	bot, sim, _, store := newSimBot(t)
+	cfg := bot.TradeConfig()
+	od, err := sim.PlaceLimitOrder(simTestSymbol, "buy", 0.5, 90, models.TimeInForceGTC, false)
+	if err != nil {
+		t.Fatalf("挂单失败: %v", err)
+	}
+	_ = bot.saveOrder(od)
+	// 挂单创建时间为最后一根 K 线时间，远早于 2×超时
+	if err := bot.reconcileOpenOrders(); err != nil {
+		t.Fatalf("对账失败: %v", err)
+	}
+	st, _ := sim.FetchOrder(cfg.Symbol, od.OrderID)
+	if st == nil || st.State != "canceled" {
+		t.Fatalf("超时挂单应被撤销: %+v", st)
+	}
+
+	// 非本机器人下的挂单不处理
+	foreign, _ := sim.PlaceLimitOrder(simTestSymbol, "buy", 0.5, 90, models.TimeInForceGTC, false)
+	if err := bot.reconcileOpenOrders(); err != nil {
+		t.Fatalf("对账失败: %v", err)
+	}
+	if st, _ := sim.FetchOrder(cfg.Symbol, foreign.OrderID); st == nil || st.State != "live" {
+		t.Fatalf("外部挂单不应被撤销: %+v", st)
+	}
+	if ids, _ := store.OpenOrders(); len(ids) != 0 {
+		t.Fatalf("本地不应残留未结订单: %v", ids)
+	}
+}
+
+func TestSimManualPositionOnTradedSymbolIsExternal(t *testing.T) {
+	bot, sim, _, _ := newSimBot(t)
+	if _, err := sim.PlaceMarketOrderWithResult(simTestSymbol, "sell", 0.3, false); err != nil {
+		t.Fatalf("手动开仓失败: %v", err)
+	}
+	all, err := bot.FetchAllPositions()
+	if err != nil || len(all) != 1 || all[0].Side != "short" || !all[0].External {
+		t.Fatalf("当前交易对上手动开的仓应标记为外部持仓: %+v %v", all, err)
+	}
+}
+
+func TestSimPaperSimulationIsDryRun(t *testing.T) {
+	bot, sim, ai, _ := newSimBot(t)
+	ai.set(signalJSON("BUY", 100, 115))
+
+	out, err := bot.RunPaperSimulation(PaperSimulationInput{
+		Symbol:                  simTestSymbol,
+		Balance:                 10000,
+		PositionSizingMode:      "margin_pct",
+		HighConfidenceMarginPct: 0.05,
+		Leverage:                5,
+	})
+	if err != nil {
+		t.Fatalf("模拟盘失败: %v", err)
+	}
+	if !out.Approved || out.ExecutionCode != "paper_simulated" || out.ApprovedSize <= 0 || out.Price <= 0 {
+		t.Fatalf("模拟盘结果异常: %+v", out)
+	}
+	if legs, _ := sim.FetchPositions(simTestSymbol); len(legs) != 0 {
+		t.Fatalf("模拟盘不应下单: %+v", legs)
+	}
+	if fills := sim.Fills(); len(fills) != 0 {
+		t.Fatalf("模拟盘不应产生成交: %+v", fills)
+	}
+}
+
+func TestSyntheticCandlesOffline(t *testing.T) {
+	a, err := exchange.SyntheticCandles(simTestSymbol, "15m", 150)
+	if err != nil || len(a) != 150 {
+		t.Fatalf("合成行情失败: %d %v", len(a), err)
+	}
+	b, _ := exchange.SyntheticCandles(simTestSymbol, "15m", 150)
+	if a[100] != b[100] {
+		t.Fatalf("同一时段合成行情应一致: %+v %+v", a[100], b[100])
+	}
+	if _, _, err := validateCandles(a, "15m", 150, indicators.RequiredBars(indicators.DefaultParams()), time.Now()); err != nil {
+		t.Fatalf("合成行情应通过质量校验: %v", err)
+	}
+	sim := exchange.NewSimExchange(exchange.SimConfig{Source: exchange.SyntheticCandles})
+	got, err := sim.FetchOHLCV(simTestSymbol, "1h", 50)
+	if err != nil || len(got) != 50 {
+		t.Fatalf("模拟交易所应由合成行情供数: %d %v", len(got), err)
+	}
+}
+
+func TestProtectiveOrdersCoverWholeLegAfterAdd(t *testing.T) {
+	bot, sim, _, _ := newSimBot(t)
+	signal := models.TradeSignal{Signal: "BUY", StopLoss: 100, TakeProfit: 115}
+	bot.attachProtectiveOrders(signal, &models.Position{Symbol: simTestSymbol, Side: "long", Size: 2, EntryPrice: 105}, 2)
+
+	// 加仓 1 个单位后整条腿为 3：旧括号单撤销，新括号单覆盖全部持仓而非本次成交量
+	bot.attachProtectiveOrders(signal, &models.Position{Symbol: simTestSymbol, Side: "long", Size: 3, EntryPrice: 105}, 1)
+	open, _ := sim.FetchOpenOrders(simTestSymbol)
+	if len(open) != 2 {
+		t.Fatalf("每条腿只应保留一组括号单: %+v", open)
+	}
+	for _, od := range open {
+		if od.Size != 3 {
+			t.Fatalf("保护单数量应为整条腿持仓: %+v", open)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package trader
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"trade-go/config"
+	"trade-go/models"
+	"trade-go/risk"
+)
+
+// 盘口取前 50 档，足以覆盖常规下单量
+const orderBookDepth = 50
+
+// checkSlippage 按盘口深度预估市价单滑点；超限时按 SlippageAction 缩减数量或拒单，返回最终下单数量。
+func (b *Bot) checkSlippage(cfg config.TradeConfig, side string, size float64, inst models.Instrument, instOK bool, out map[string]any) (float64, bool, string, string) {
+	if cfg.MaxSlippageBps <= 0 {
+		return size, true, "ok", ""
+	}
+	book, err := b.exchange.FetchOrderBook(cfg.Symbol, orderBookDepth)
+	if err != nil {
+		// 盘口读取失败不阻断下单，仅记录
+		out["slippage_error"] = err.Error()
+		return size, true, "ok", ""
+	}
+	est := risk.EstimateSlippage(book, side, size)
+	out["slippage_estimate"] = est
+	out["max_slippage_bps"] = cfg.MaxSlippageBps
+	if !est.Insufficient && est.SlippageBps <= cfg.MaxSlippageBps {
+		return size, true, "ok", ""
+	}
+
+	reason := fmt.Sprintf("预估滑点 %.2f bps 超过上限 %.2f bps", est.SlippageBps, cfg.MaxSlippageBps)
+	if est.Insufficient {
+		reason = fmt.Sprintf("盘口前 %d 档深度不足以成交 %.8g", orderBookDepth, size)
+	}
+	if strings.ToLower(strings.TrimSpace(cfg.SlippageAction)) == "block" {
+		out["reason"] = risk.CodeSlippageExceeded
+		return size, false, risk.CodeSlippageExceeded, reason
+	}
+
+	reduced := math.Min(size, risk.MaxSizeWithinSlippage(book, side, cfg.MaxSlippageBps))
+	if instOK && inst.StepSize > 0 {
+		reduced = inst.RoundSize(reduced)
+	} else {
+		reduced = models.FloorToStep(reduced, 0.0001)
+	}
+	if reduced <= 0 || (instOK && inst.MinQty > 0 && reduced < inst.MinQty) {
+		out["reason"] = risk.CodeSlippageExceeded
+		return size, false, risk.CodeSlippageExceeded, reason + "，缩减后数量低于最小下单量"
+	}
+	fmt.Printf("⚠️ %s，下单数量 %.8g 缩减为 %.8g\n", reason, size, reduced)
+	out["downsized_from"] = size
+	out["trade_amount"] = reduced
+	out["slippage_estimate_original"] = est
+	out["slippage_estimate"] = risk.EstimateSlippage(book, side, reduced)
+	return reduced, true, "ok", ""
+}
@@ -0,0 +1,95 @@
+package trader
+
+import (
+	"fmt"
+	"trade-go/exchange"
+	"trade-go/models"
+)
+
+// StartUserStream 订阅交易所私有推送，订单、成交与持仓变化实时落库；
+// 不支持的交易所返回错误，confirmOrder / reconcileOpenOrders 的 REST 轮询照常兜底。
+func (b *Bot) StartUserStream() error {
+	b.userStreamMu.Lock()
+	defer b.userStreamMu.Unlock()
+	if b.userStream != nil {
+		return nil
+	}
+	b.mu.RLock()
+	client := b.exchange
+	b.mu.RUnlock()
+	s, err := client.StartUserStream(b.handleUserEvent)
+	if err != nil {
+		return err
+	}
+	b.userStream = s
+	return nil
+}
+
+func (b *Bot) StopUserStream() {
+	b.userStreamMu.Lock()
+	defer b.userStreamMu.Unlock()
+	if b.userStream != nil {
+		_ = b.userStream.Stop()
+		b.userStream = nil
+	}
+}
+
+// UserStreamStatus 未启动私有推送时 ok 为 false。
+func (b *Bot) UserStreamStatus() (exchange.UserStreamStatus, bool) {
+	b.userStreamMu.Lock()
+	defer b.userStreamMu.Unlock()
+	if b.userStream == nil {
+		return exchange.UserStreamStatus{}, false
+	}
+	return b.userStream.Status(), true
+}
+
+// restartUserStream 交易所凭证重载后用新客户端重建已启动的私有推送。
+func (b *Bot) restartUserStream() {
+	b.userStreamMu.Lock()
+	running := b.userStream != nil
+	b.userStreamMu.Unlock()
+	if !running {
+		return
+	}
+	b.StopUserStream()
+	go func() {
+		if err := b.StartUserStream(); err != nil {
+			fmt.Printf("私有推送重建失败，回退REST轮询: %v\n", err)
+		}
+	}()
+}
+
+func (b *Bot) handleUserEvent(ev exchange.UserEvent) {
+	if b.store == nil && !b.hasEventHandler() {
+		return
+	}
+	switch ev.Kind {
+	case exchange.UserEventOrder:
+		st := ev.Order
+		if st == nil || st.OrderID == "" {
+			return
+		}
+		b.emit("order", st.State, st)
+		_ = b.store.SaveOrder(st.OrderID, st.Symbol, st.Side, st.Type, st.Size, st.ReduceOnly, st.State, st)
+		if st.FilledSize > 0 {
+			// 与 confirmOrder 相同的 fill_id 规则，REST 兜底写入时按主键去重
+			fillID := fmt.Sprintf("%s-%s-%.4f", st.OrderID, st.UpdateTime, st.FilledSize)
+			_ = b.saveFill(fillID, st)
+		}
+		if ev.Trade != nil {
+			// 只写入成交明细，不推进 fills 同步游标，缺口仍由 SyncIncomeHistory 回补
+			_, _ = b.store.SaveTradeFills([]models.TradeFill{*ev.Trade})
+		}
+	case exchange.UserEventPosition:
+		cfg := b.TradeConfig()
+		for _, leg := range ev.Positions {
+			// Binance 推送不含杠杆，当前交易对按配置补齐
+			if leg.Leverage == 0 && exchange.NormalizeSymbol(leg.Symbol) == exchange.NormalizeSymbol(cfg.Symbol) {
+				leg.Leverage = float64(cfg.Leverage)
+			}
+			_ = b.savePosition(leg)
+		}
+	}
+	// 余额推送不落库，权益曲线仍按交易周期写入
+}